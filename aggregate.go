@@ -0,0 +1,85 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aggregate is an aggregation function called in the retrieve block of an
+// array level, as in products(stock > 0){bucket(price, [0, 10, 100])}.
+//
+// A level with aggregations returns, in place of its elements, an object
+// holding the result of each aggregation over the elements matching its
+// filters. The result is named after the call, bucket(price) here, unless
+// an as clause names it.
+type aggregate struct {
+	as string
+	fn aggregator
+}
+
+type aggregator interface {
+	// aggregate returns the JSON result of the function over elements.
+	aggregate(elements []*Value, opts *Options) string
+}
+
+// aggregators are the parsers of the arguments of the aggregation
+// functions, by name.
+var aggregators = map[string]func(args []string) (aggregator, error){
+	"avg":      parseNumeric("avg"),
+	"bucket":   parseBucket,
+	"count":    parseCounter,
+	"distinct": parseDistinct,
+	"max":      parseNumeric("max"),
+	"min":      parseNumeric("min"),
+	"sum":      parseNumeric("sum"),
+}
+
+// parseAggregate parses cmd as an aggregation. It reports false when cmd
+// does not call an aggregation function.
+func parseAggregate(cmd string) (*aggregate, bool, error) {
+	i := strings.IndexByte(cmd, '(')
+	if i < 0 {
+		return nil, false, nil
+	}
+	name := cmd[:i]
+	parse, ok := aggregators[name]
+	if !ok {
+		return nil, false, nil
+	}
+	n, err := scanGroup(cmd[i:], '(', ')')
+	if err != nil {
+		return nil, true, err
+	}
+	rest := strings.TrimSpace(cmd[i+n:])
+	if strings.HasPrefix(rest, "{") {
+		return nil, false, nil
+	}
+	args := splitComa(cmd[i+1 : i+n-1])
+	fn, err := parse(args)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s : %q", err, cmd)
+	}
+	agg := &aggregate{as: name + "()", fn: fn}
+	if len(args) > 0 {
+		agg.as = name + "(" + args[0] + ")"
+	}
+	if len(rest) > 0 {
+		if !strings.HasPrefix(rest, "as ") || !isName(strings.TrimSpace(rest[len("as "):])) {
+			return nil, true, fmt.Errorf("mal formated aggregation : %q", cmd)
+		}
+		agg.as = strings.TrimSpace(rest[len("as "):])
+	}
+	return agg, true, nil
+}
+
+// parseField parses the argument of an aggregation naming a field of the
+// elements, possibly nested as in address.city.
+func parseField(arg string) (Path, error) {
+	path := Path(strings.Split(arg, "."))
+	for _, key := range path {
+		if !isName(key) {
+			return nil, fmt.Errorf("mal formated field %q", arg)
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,94 @@
+package jsonq
+
+import (
+	"sort"
+)
+
+// Analysis maps the structure of a JSON document.
+type Analysis struct {
+	// Paths holds the statistics of every path of the document, keyed by
+	// its dot separated keys. Array elements are merged under the path of
+	// their array followed by "[]", so "users[].name" gathers the names of
+	// all the users. The root is keyed by "".
+	Paths map[string]*PathStats
+
+	// MaxDepth is the nesting depth of the document: 0 for a scalar, 1 for
+	// an object or array of scalars, and so on.
+	MaxDepth int
+}
+
+// PathStats describes the values found at a path.
+type PathStats struct {
+	// Count is the number of values found at the path.
+	Count int
+
+	// Types counts the values of each type found at the path.
+	Types map[Type]int
+
+	// ArrayLengths counts the arrays found at the path by length.
+	ArrayLengths map[int]int
+}
+
+// SortedPaths returns the paths of a in lexical order.
+func (a *Analysis) SortedPaths() []string {
+	paths := make([]string, 0, len(a.Paths))
+	for path := range a.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Analyze walks v and returns the occurrences, types and array lengths
+// found at each of its paths, giving a quick map of an unfamiliar payload.
+func Analyze(v *Value) *Analysis {
+	a := &Analysis{Paths: map[string]*PathStats{}}
+	if v != nil {
+		a.MaxDepth = a.add("", v)
+	}
+	return a
+}
+
+// add records v at path and returns the depth of v.
+func (a *Analysis) add(path string, v *Value) int {
+	s := a.Paths[path]
+	if s == nil {
+		s = &PathStats{Types: map[Type]int{}}
+		a.Paths[path] = s
+	}
+	s.Count++
+	t := v.Type()
+	s.Types[t]++
+
+	depth := 0
+	switch t {
+	case TypeObject:
+		v.o.unescapeKeys()
+		prefix := path
+		if prefix != "" {
+			prefix += "."
+		}
+		for _, kv := range v.o.kvs {
+			if d := a.add(prefix+kv.k, kv.v) + 1; d > depth {
+				depth = d
+			}
+		}
+		if depth == 0 {
+			depth = 1
+		}
+	case TypeArray:
+		if s.ArrayLengths == nil {
+			s.ArrayLengths = map[int]int{}
+		}
+		s.ArrayLengths[len(v.a)]++
+		for _, e := range v.a {
+			if d := a.add(path+"[]", e) + 1; d > depth {
+				depth = d
+			}
+		}
+		if depth == 0 {
+			depth = 1
+		}
+	}
+	return depth
+}
@@ -0,0 +1,50 @@
+package jsonq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users":[{"name":"a","age":20},{"name":"b","age":null,"tags":["x","y"]},{"name":"c","tags":[]}],"total":3}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	a := Analyze(v)
+
+	expectedPaths := []string{"", "total", "users", "users[]", "users[].age", "users[].name", "users[].tags", "users[].tags[]"}
+	if paths := a.SortedPaths(); !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("unexpected paths; got %q; want %q", paths, expectedPaths)
+	}
+	if a.MaxDepth != 4 {
+		t.Fatalf("unexpected max depth; got %d; want 4", a.MaxDepth)
+	}
+
+	f := func(path string, count int, types map[Type]int, lengths map[int]int) {
+		t.Helper()
+		s := a.Paths[path]
+		if s.Count != count {
+			t.Fatalf("unexpected count at %q; got %d; want %d", path, s.Count, count)
+		}
+		if !reflect.DeepEqual(s.Types, types) {
+			t.Fatalf("unexpected types at %q; got %v; want %v", path, s.Types, types)
+		}
+		if !reflect.DeepEqual(s.ArrayLengths, lengths) {
+			t.Fatalf("unexpected array lengths at %q; got %v; want %v", path, s.ArrayLengths, lengths)
+		}
+	}
+	f("users", 1, map[Type]int{TypeArray: 1}, map[int]int{3: 1})
+	f("users[]", 3, map[Type]int{TypeObject: 3}, nil)
+	f("users[].age", 2, map[Type]int{TypeNumber: 1, TypeNull: 1}, nil)
+	f("users[].tags", 2, map[Type]int{TypeArray: 2}, map[int]int{0: 1, 2: 1})
+	f("users[].tags[]", 2, map[Type]int{TypeString: 2}, nil)
+
+	v, err = p.Parse(`1`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if a := Analyze(v); a.MaxDepth != 0 || a.Paths[""].Count != 1 {
+		t.Fatalf("unexpected analysis of a scalar: %+v", a)
+	}
+}
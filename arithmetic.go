@@ -0,0 +1,237 @@
+package jsonq
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// arithmetic is the value of a filter computed with +, -, * and /, such as
+// price * quantity in (total > price * quantity). Its operands are numbers
+// and the fields of the filtered object, * and / binding tighter than +
+// and -. The operators are separated by spaces, since - and / may be part
+// of the words of a filter value.
+//
+// The value is computed for each object, with exact decimals when
+// Options.Decimal is set. When a field is missing or not a number, or on a
+// division by zero, the filter behaves as a filter on a missing key.
+type arithmetic struct {
+	operands []operand
+	// ops[i] combines the operands i and i+1.
+	ops []byte
+}
+
+// operand is a number, or a field when field is set.
+type operand struct {
+	field Path
+	n     interface{}
+}
+
+// parseArithmetic parses the value s of a filter as an arithmetic
+// expression. It reports false when s is not one.
+func parseArithmetic(s string) (*arithmetic, bool, error) {
+	words := strings.Fields(s)
+	if len(words) < 3 || len(words)%2 == 0 || !isArithmeticOp(words[1]) {
+		return nil, false, nil
+	}
+	a := &arithmetic{}
+	for i, word := range words {
+		if i%2 == 1 {
+			if !isArithmeticOp(word) {
+				return nil, true, fmt.Errorf("mal formated arithmetic %q: %q is not an operator", s, word)
+			}
+			a.ops = append(a.ops, word[0])
+			continue
+		}
+		switch n := typed(word).(type) {
+		case int64, float64:
+			if i > 0 && a.ops[len(a.ops)-1] == '/' && (n == int64(0) || n == 0.0) {
+				return nil, true, fmt.Errorf("division by zero in %q", s)
+			}
+			a.operands = append(a.operands, operand{n: n})
+			continue
+		}
+		field, err := parseField(word)
+		if err != nil {
+			return nil, true, fmt.Errorf("mal formated arithmetic %q: %q is neither a number nor a field", s, word)
+		}
+		a.operands = append(a.operands, operand{field: field})
+	}
+	return a, true, nil
+}
+
+func isArithmeticOp(word string) bool {
+	return len(word) == 1 && strings.IndexByte("+-*/", word[0]) >= 0
+}
+
+// constant returns the value of a when it has no field. It is computed
+// with decimals, so that 0.1 + 0.2 is the float closest to 0.3, both with
+// and without Options.Decimal.
+func (a *arithmetic) constant() (float64, bool) {
+	for _, op := range a.operands {
+		if op.field != nil {
+			return 0, false
+		}
+	}
+	values, _ := a.decimals(nil)
+	r, ok := sumDecimals(a.ops, values)
+	if !ok {
+		return 0, false
+	}
+	n, _ := r.Float64()
+	return n, true
+}
+
+// eval returns the value of a for o: an exact *big.Rat with decimals, a
+// float64 otherwise. Numbers which are not decimals, such as 1e1000,
+// fall back to floats.
+func (a *arithmetic) eval(o *Object, decimal bool) (interface{}, bool) {
+	if decimal {
+		if values, ok := a.decimals(o); ok {
+			r, ok := sumDecimals(a.ops, values)
+			return r, ok
+		}
+	}
+	values, ok := a.floats(o)
+	if !ok {
+		return nil, false
+	}
+	n, ok := sumFloats(a.ops, values)
+	return n, ok
+}
+
+// value returns the value of the field of op in o, and whether it is a
+// number.
+func (op operand) value(o *Object) (*Value, bool) {
+	v := o.Get(op.field[0]).Get(op.field[1:]...)
+	return v, v != nil && v.Type() == TypeNumber
+}
+
+// floats returns the values of the operands of a for o.
+func (a *arithmetic) floats(o *Object) ([]float64, bool) {
+	values := make([]float64, len(a.operands))
+	for i, op := range a.operands {
+		if op.field == nil {
+			if n, ok := op.n.(int64); ok {
+				values[i] = float64(n)
+			} else {
+				values[i] = op.n.(float64)
+			}
+			continue
+		}
+		v, ok := op.value(o)
+		if !ok {
+			return nil, false
+		}
+		values[i] = v.n
+	}
+	return values, true
+}
+
+// decimals returns the values of the operands of a for o, as decimals.
+func (a *arithmetic) decimals(o *Object) ([]*big.Rat, bool) {
+	values := make([]*big.Rat, len(a.operands))
+	for i, op := range a.operands {
+		if op.field == nil {
+			if n, ok := op.n.(int64); ok {
+				values[i] = new(big.Rat).SetInt64(n)
+			} else {
+				values[i] = floatDecimal(op.n.(float64))
+			}
+			continue
+		}
+		v, ok := op.value(o)
+		if !ok {
+			return nil, false
+		}
+		if values[i], ok = v.decimal(); !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// sumFloats combines values with ops, computing the terms of the sum left
+// to right.
+func sumFloats(ops []byte, values []float64) (float64, bool) {
+	sum, term := 0.0, values[0]
+	sign := 1.0
+	for i, op := range ops {
+		switch op {
+		case '*':
+			term *= values[i+1]
+		case '/':
+			if values[i+1] == 0 {
+				return 0, false
+			}
+			term /= values[i+1]
+		case '+', '-':
+			sum += sign * term
+			term, sign = values[i+1], 1
+			if op == '-' {
+				sign = -1
+			}
+		}
+	}
+	return sum + sign*term, true
+}
+
+// sumDecimals is sumFloats with decimals.
+func sumDecimals(ops []byte, values []*big.Rat) (*big.Rat, bool) {
+	sum, term := new(big.Rat), new(big.Rat).Set(values[0])
+	negative := false
+	for i, op := range ops {
+		switch op {
+		case '*':
+			term.Mul(term, values[i+1])
+		case '/':
+			if values[i+1].Sign() == 0 {
+				return nil, false
+			}
+			term.Quo(term, values[i+1])
+		case '+', '-':
+			if negative {
+				term.Neg(term)
+			}
+			sum.Add(sum, term)
+			term, negative = new(big.Rat).Set(values[i+1]), op == '-'
+		}
+	}
+	if negative {
+		term.Neg(term)
+	}
+	return sum.Add(sum, term), true
+}
+
+// fields returns the fields of the operands of a.
+func (a *arithmetic) fields() []Path {
+	var fields []Path
+	for _, op := range a.operands {
+		if op.field != nil {
+			fields = append(fields, op.field)
+		}
+	}
+	return fields
+}
+
+// String returns the expression of a.
+func (a *arithmetic) String() string {
+	if a == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i, op := range a.operands {
+		if i > 0 {
+			b.WriteString(" " + string(a.ops[i-1]) + " ")
+		}
+		if op.field != nil {
+			b.WriteString(op.field.String())
+		} else if n, ok := op.n.(int64); ok {
+			b.WriteString(strconv.FormatInt(n, 10))
+		} else {
+			b.WriteString(strconv.FormatFloat(op.n.(float64), 'g', -1, 64))
+		}
+	}
+	return b.String()
+}
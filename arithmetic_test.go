@@ -0,0 +1,122 @@
+package jsonq
+
+import "testing"
+
+func TestKeepArithmetic(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"orders": [
+		{"id": 1, "total": 30, "price": 10, "quantity": 3, "age": 21},
+		{"id": 2, "total": 25, "price": 10, "quantity": 3, "age": 20},
+		{"id": 3, "total": 50, "price": 10, "quantity": 3, "shipping": {"cost": 15}},
+		{"id": 4, "total": 40, "price": "10", "quantity": 3},
+		{"id": 5, "total": 8, "price": 10, "quantity": 0}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{orders(total >= price * quantity){id}}`, `{"orders":[{"id":1},{"id":3},{"id":4},{"id":5}]}`},
+		{`{orders(total = price * quantity + shipping.cost + 5){id}}`, `{"orders":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5}]}`},
+		{`{orders(shipping? && total = price * quantity + shipping.cost + 5){id}}`, `{"orders":[{"id":3}]}`},
+		{`{orders(age >= 18 + 3){id}}`, `{"orders":[{"id":1},{"id":3},{"id":4},{"id":5}]}`},
+		{`{orders(total <= 100 - price * quantity * 2 - 40 / 4 && id < 3){id}}`, `{"orders":[{"id":1},{"id":2}]}`},
+		{`{orders(total > total / quantity){id}}`, `{"orders":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5}]}`},
+		{`{orders(price = total / quantity){id}}`, `{"orders":[{"id":1},{"id":5}]}`},
+		{`{orders(id = 10 / 4 * 2 - 4){id}}`, `{"orders":[{"id":1}]}`},
+		{`{orders(name = "a + b"){id}}`, `{"orders":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	q := MustParseQuery(`{orders(total >= price * quantity){id}}`)
+	q.SetOptions(Options{ThreeValued: true})
+	if got, err := v.Keep(*q); err != nil || got != `{"orders":[{"id":1},{"id":3},{"id":5}]}` {
+		t.Errorf("Keep with the three-valued logic = %s, %v", got, err)
+	}
+}
+
+func TestKeepArithmeticDecimal(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": [
+		{"id": 1, "p": 0.1, "q": 0.2, "t": 0.3},
+		{"id": 2, "p": 1, "q": 3, "t": 0.3333333333333333},
+		{"id": 3, "p": 0.7, "q": 0.1, "t": 0.8},
+		{"id": 4, "p": 1e1000, "q": 1, "t": 1e1000}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{a(t = p + q){id}}`, `{"a":[{"id":1},{"id":3},{"id":4}]}`},
+		{`{a(t = p / q){id}}`, `{"a":[{"id":4}]}`},
+		{`{a(t > p / q - 0.1 * 3){id}}`, `{"a":[{"id":1},{"id":2}]}`},
+		{`{a(t = 0.1 + 0.2){id}}`, `{"a":[{"id":1}]}`},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		q.SetOptions(Options{Decimal: true})
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseArithmetic(t *testing.T) {
+	for _, tt := range []struct {
+		filter string
+		val    interface{}
+		calc   string
+	}{
+		{"age >= 18 + 3", 21.0, ""},
+		{"age >= 0.1 + 0.2", 0.3, ""},
+		{"age >= 9223372036854775807 * 4", 3.6893488147419103e19, ""},
+		{"age >= 18 / 4", 4.5, ""},
+		{"age >= 1.5 * 2", 3.0, ""},
+		{"total > price * quantity", nil, "price * quantity"},
+		{"total > a.b - 2.5", nil, "a.b - 2.5"},
+	} {
+		f, err := parseCondition(tt.filter, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.filter, err)
+		}
+		if f.val != tt.val || f.calc.String() != tt.calc {
+			t.Errorf("parseCondition(%s) = %#v, %q, want %#v, %q", tt.filter, f.val, f.calc, tt.val, tt.calc)
+		}
+	}
+
+	for _, filter := range []string{
+		"a > b * ",
+		"a > b * c d",
+		"a > b % c",
+		"a > b * \"c\"",
+		"a > b / 0",
+		"a > b..c + 1",
+	} {
+		if _, err := parseCondition(filter, false); err == nil {
+			t.Errorf("parseCondition(%s) expecting non-nil error", filter)
+		}
+	}
+	if _, err := NewMatcher("total > price * quantity"); err == nil {
+		t.Errorf("NewMatcher expecting non-nil error")
+	}
+	if _, err := NewMatcher("age > 18 + 3"); err != nil {
+		t.Errorf("NewMatcher: unexpected error: %s", err)
+	}
+}
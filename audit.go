@@ -0,0 +1,99 @@
+package jsonq
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AuditRecord describes an execution of Keep or Retrieve, for the audit
+// trails of services running queries written by their users.
+type AuditRecord struct {
+	// QueryHash is the hexadecimal SHA-256 of the text of the query.
+	QueryHash string
+	// Caller is the identity given by Options.Caller.
+	Caller string
+	// Fields are the keys the query reads, filtered or selected, from the
+	// root of the query and sorted, such as users.name. A wildcard reads
+	// users.* and a descent **.price.
+	Fields []string
+	// ResultSize is the size of the output in bytes.
+	ResultSize int
+	Duration   time.Duration
+	// Err is the error of the execution, if any.
+	Err error
+}
+
+// audit passes the record of the execution to the audit hook of the
+// request, if any.
+func (e *execution) audit(output string, err error) {
+	hook := e.request.opts.Audit
+	if hook == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(e.request.source))
+	hook(AuditRecord{
+		QueryHash:  fmt.Sprintf("%x", sum),
+		Caller:     e.request.opts.Caller,
+		Fields:     e.request.fields(),
+		ResultSize: len(output),
+		Duration:   time.Since(e.start),
+		Err:        err,
+	})
+}
+
+// fields returns the keys read by q, as listed by AuditRecord.Fields.
+func (q *Query) fields() []string {
+	set := map[string]struct{}{}
+	q.collectFields(Path{}, set)
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (q *Query) collectFields(path Path, set map[string]struct{}) {
+	for _, filter := range q.filters {
+		p := path.child(filter.key)
+		for _, key := range filter.sub {
+			p = p.child(key)
+		}
+		set[p.String()] = struct{}{}
+		if filter.calc != nil {
+			for _, field := range filter.calc.fields() {
+				set[path.child(field.String()).String()] = struct{}{}
+			}
+		}
+	}
+	for _, retrieve := range q.retrieve {
+		set[path.child(retrieve).String()] = struct{}{}
+	}
+	if q.all {
+		set[path.child("*").String()] = struct{}{}
+	}
+	for name, next := range q.next {
+		set[path.child(name).String()] = struct{}{}
+		if next != nil {
+			next.collectFields(path.child(name), set)
+		}
+	}
+	for _, j := range q.joins {
+		j.q.collectFields(path.child(j.as), set)
+	}
+	for _, c := range q.counts {
+		set[path.child(c.name).String()] = struct{}{}
+		c.q.collectFields(path.child(c.name), set)
+	}
+	if q.grouping != nil {
+		for _, field := range q.grouping.fields() {
+			set[path.child(field.String()).String()] = struct{}{}
+		}
+		q.grouping.q.collectFields(path, set)
+	}
+	if q.descent != nil {
+		q.descent.collectFields(path.child("**"), set)
+	}
+}
@@ -0,0 +1,44 @@
+package jsonq
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"name": "Al", "age": 30, "password": "x"}, {"name": "Bo", "age": 3}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	var records []AuditRecord
+	audit := func(r AuditRecord) { records = append(records, r) }
+
+	q := MustParseQuery("{users(age > 18){name, address{city}}, **{password}}")
+	q.SetOptions(Options{Audit: audit, Caller: "alice"})
+	out, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	q = MustParseQuery("{users{*}}")
+	q.SetOptions(Options{Audit: audit, MaxResultSize: 10})
+	if _, err := v.Retrieve(*q); !errors.Is(err, ErrResultTooLarge) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	r := records[0]
+	if r.Caller != "alice" || r.ResultSize != len(out) || r.Err != nil || len(r.QueryHash) != 64 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if got := fmt.Sprint(r.Fields); got != "[**.password users users.address users.address.city users.age users.name]" {
+		t.Errorf("unexpected fields: %s", got)
+	}
+	r = records[1]
+	if !errors.Is(r.Err, ErrResultTooLarge) || r.QueryHash == records[0].QueryHash || fmt.Sprint(r.Fields) != "[users users.*]" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
@@ -0,0 +1,75 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bucket counts the elements whose field falls between each pair of
+// consecutive bounds: bucket(price, [0, 10, 50, 100]) counts the prices in
+// [0, 10), [10, 50) and [50, 100]. The last bucket includes its upper
+// bound. Values out of the bounds, and values that are not numbers, are
+// not counted.
+//
+// The result is an object keyed by bucket, {"0-10":3,"10-50":0,"50-100":1}.
+type bucket struct {
+	field  Path
+	bounds []float64
+	labels []string
+}
+
+func parseBucket(args []string) (aggregator, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("bucket expects a field and a list of bounds")
+	}
+	field, err := parseField(args[0])
+	if err != nil {
+		return nil, err
+	}
+	list := args[1]
+	if len(list) < 2 || list[0] != '[' || list[len(list)-1] != ']' {
+		return nil, fmt.Errorf("bucket expects a list of bounds, got %q", list)
+	}
+	b := &bucket{field: field}
+	for _, bound := range strings.Split(list[1:len(list)-1], ",") {
+		bound = strings.TrimSpace(bound)
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bucket bound %q is not a number", bound)
+		}
+		if len(b.bounds) > 0 && f <= b.bounds[len(b.bounds)-1] {
+			return nil, fmt.Errorf("bucket bounds must increase")
+		}
+		b.bounds = append(b.bounds, f)
+		b.labels = append(b.labels, bound)
+	}
+	if len(b.bounds) < 2 {
+		return nil, fmt.Errorf("bucket expects at least two bounds")
+	}
+	return b, nil
+}
+
+func (b *bucket) aggregate(elements []*Value, opts *Options) string {
+	counts := make([]int, len(b.bounds)-1)
+	last := b.bounds[len(b.bounds)-1]
+	for _, element := range elements {
+		v := element.Get(b.field...)
+		if v == nil || v.Type() != TypeNumber || v.n < b.bounds[0] || v.n > last {
+			continue
+		}
+		i := 0
+		for i < len(counts)-1 && v.n >= b.bounds[i+1] {
+			i++
+		}
+		counts[i]++
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	for i, count := range counts {
+		writeField(&w, i == 0, b.labels[i]+"-"+b.labels[i+1], strconv.Itoa(count))
+	}
+	w.WriteRune('}')
+	return w.String()
+}
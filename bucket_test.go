@@ -0,0 +1,52 @@
+package jsonq
+
+import "testing"
+
+func TestKeepBucket(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"products": [
+		{"price": 5, "stock": 1}, {"price": 8, "stock": 1}, {"price": 10, "stock": 1},
+		{"price": 100, "stock": 1}, {"price": 150, "stock": 1}, {"price": "n/a", "stock": 1},
+		{"price": 20, "stock": 0}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"{products(stock > 0){bucket(price, [0, 10, 50, 100])}}",
+			`{"products":{"bucket(price)":{"0-10":2,"10-50":1,"50-100":1}}}`,
+		},
+		{
+			"{products{bucket(price, [0,50]) as cheap, bucket(price, [50, 200]) as expensive}}",
+			`{"products":{"cheap":{"0-50":4},"expensive":{"50-200":2}}}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseBucketErrors(t *testing.T) {
+	for _, query := range []string{
+		"{products{bucket(price)}}",
+		"{products{bucket(price, [10])}}",
+		"{products{bucket(price, [10, 5])}}",
+		"{products{bucket(price, [a, 5])}}",
+		"{products{bucket(price, 10)}}",
+		"{products{bucket(price, [0, 5]) as}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package jsonq
+
+import (
+	"sort"
+	"strconv"
+)
+
+// AppendCanonical appends the canonical JSON of v to dst and returns
+// the result.
+//
+// The canonical form has no whitespace, object keys sorted in byte order,
+// strings escaped the same way whatever their original escaping, and
+// numbers in their shortest form, so 1.0, 1 and 1e0 are all written 1.
+// Two documents holding the same data have the same canonical form.
+func (v *Value) AppendCanonical(dst []byte) []byte {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		kvs := make([]kv, len(v.o.kvs))
+		copy(kvs, v.o.kvs)
+		sort.Slice(kvs, func(i, j int) bool {
+			return kvs[i].k < kvs[j].k
+		})
+		dst = append(dst, '{')
+		for i, kv := range kvs {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendQuoted(dst, kv.k)
+			dst = append(dst, ':')
+			dst = kv.v.AppendCanonical(dst)
+		}
+		return append(dst, '}')
+	case TypeArray:
+		dst = append(dst, '[')
+		for i, vv := range v.a {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = vv.AppendCanonical(dst)
+		}
+		return append(dst, ']')
+	case TypeString:
+		return appendQuoted(dst, v.s)
+	case TypeNumber:
+		return strconv.AppendFloat(dst, v.n, 'g', -1, 64)
+	case TypeTrue:
+		return append(dst, "true"...)
+	case TypeFalse:
+		return append(dst, "false"...)
+	default:
+		return append(dst, "null"...)
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// appendQuoted appends the JSON string of s to dst, escaping only the
+// characters that must be.
+func appendQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,26 @@
+package jsonq
+
+import "testing"
+
+func TestValueAppendCanonical(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"b": 1, "a": [true, false, null]}`, `{"a":[true,false,null],"b":1}`},
+		{`{"x": {"z": 1.0, "y": 1e2}}`, `{"x":{"y":100,"z":1}}`},
+		{`"aA\/\n"`, `"aA/\n"`},
+		{`[ ]`, `[]`},
+		{`{"b": 1, "a": 2}`, `{"a":2,"b":1}`},
+	}
+	var p Parser
+	for _, tt := range tests {
+		v, err := p.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", tt.in, err)
+		}
+		if got := string(v.AppendCanonical(nil)); got != tt.want {
+			t.Errorf("AppendCanonical(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
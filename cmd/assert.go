@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/qdequele/jsonq"
+)
+
+var (
+	assert      = flag.String("assert", "", "fail when the query, such as 'deployments(replicas<2){name}', matches the input")
+	expectMatch = flag.Bool("expect-match", false, "make --assert fail when the query does not match the input")
+)
+
+// runAssert runs query on the JSON of each of the files, or of the
+// standard input when there is none, and reports whether they all pass the
+// assertion: the query matches none of them, or all of them when
+// --expect-match is set. The failures are written to w.
+//
+// A query not starting with '{' or '(' selects a member of the document,
+// as if it was enclosed in braces.
+func runAssert(query string, files []string, w io.Writer) (bool, error) {
+	query, err := libraryQuery(query)
+	if err != nil {
+		return false, err
+	}
+	if query = strings.TrimSpace(query); query != "" && query[0] != '{' && query[0] != '(' {
+		query = "{" + query + "}"
+	}
+	request, err := jsonq.ParseQuery(query)
+	if err != nil {
+		return false, err
+	}
+	passed := true
+	check := func(name string, data []byte) error {
+		var p jsonq.Parser
+		v, err := p.ParseBytes(data)
+		if err != nil {
+			return err
+		}
+		result, err := v.Retrieve(*request)
+		if err != nil {
+			return err
+		}
+		if v, err = p.Parse(result); err != nil {
+			return err
+		}
+		switch found := hasMatch(v); {
+		case found && !*expectMatch:
+			passed = false
+			_, err = fmt.Fprintf(w, "%s: %s\n", name, result)
+		case !found && *expectMatch:
+			passed = false
+			_, err = fmt.Fprintf(w, "%s: no match\n", name)
+		}
+		return err
+	}
+	if len(files) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return false, err
+		}
+		if err := check("<stdin>", data); err != nil {
+			return false, err
+		}
+		return passed, nil
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return false, err
+		}
+		if err := check(name, data); err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return passed, nil
+}
+
+// hasMatch reports whether a query result holds something: a scalar or an
+// array element. Queries matching nothing give objects of empty arrays
+// and objects.
+func hasMatch(v *jsonq.Value) bool {
+	switch v.Type() {
+	case jsonq.TypeObject:
+		found := false
+		v.GetObject().Visit(func(key []byte, v *jsonq.Value) {
+			found = found || hasMatch(v)
+		})
+		return found
+	case jsonq.TypeArray:
+		return len(v.GetArray()) > 0
+	}
+	return true
+}
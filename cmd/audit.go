@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qdequele/jsonq"
+)
+
+// auditLog writes the audit records of the queries run by the server as
+// JSON lines.
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// openAuditLog opens the audit log at path, appending to it, or standard
+// error for "-".
+func openAuditLog(path string) (*auditLog, error) {
+	if path == "-" {
+		return &auditLog{w: os.Stderr}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{w: f}, nil
+}
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	QueryHash  string    `json:"query_hash"`
+	Caller     string    `json:"caller"`
+	Fields     []string  `json:"fields"`
+	ResultSize int       `json:"result_size"`
+	DurationUS int64     `json:"duration_us"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (l *auditLog) record(r jsonq.AuditRecord) {
+	entry := auditEntry{
+		Time:       time.Now().UTC(),
+		QueryHash:  r.QueryHash,
+		Caller:     r.Caller,
+		Fields:     r.Fields,
+		ResultSize: r.ResultSize,
+		DurationUS: r.Duration.Microseconds(),
+	}
+	if r.Err != nil {
+		entry.Error = r.Err.Error()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit: %s", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		log.Printf("audit: %s", err)
+	}
+}
+
+// caller returns the identity of the client of r in the audit log: the
+// name of its tenant, or its address when requests are not authenticated.
+func caller(r *http.Request) string {
+	if t := requestTenant(r); t != nil {
+		return t.quota.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
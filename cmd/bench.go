@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qdequele/jsonq"
+)
+
+// jqRuns is the number of jq processes run to time it.
+const jqRuns = 10
+
+// runBench runs the bench subcommand, timing the parsing of a file and a
+// query on it, and writes the report to w:
+//
+//	jsonq bench file.json query [jq-filter]
+//
+// encoding/json decoding the file into an interface{} gives the baseline.
+// When jq is installed, it is timed running jq-filter, "." by default, on
+// the file; its times include starting a process.
+func runBench(args []string, w io.Writer) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("usage: jsonq bench file.json query [jq-filter]")
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	request, err := jsonq.ParseQuery(args[1])
+	if err != nil {
+		return err
+	}
+	var p jsonq.Parser
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if _, err := v.Retrieve(*request); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tNS/OP\tMB/S\tB/OP\tALLOCS/OP")
+	report := func(name string, r testing.BenchmarkResult) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%d\t%d\n", name, r.NsPerOp(), throughput(len(data), r.NsPerOp()), r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+
+	report("jsonq parse", testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		var p jsonq.Parser
+		for i := 0; i < b.N; i++ {
+			if _, err := p.ParseBytes(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}))
+	report("jsonq query", testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := v.Retrieve(*request); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}))
+	report("jsonq parse+query", testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		var p jsonq.Parser
+		for i := 0; i < b.N; i++ {
+			v, err := p.ParseBytes(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := v.Retrieve(*request); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}))
+	report("encoding/json", testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v interface{}
+			if err := json.Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}))
+
+	filter := "."
+	if len(args) == 3 {
+		filter = args[2]
+	}
+	if jq, err := exec.LookPath("jq"); err == nil {
+		start := time.Now()
+		for i := 0; i < jqRuns; i++ {
+			if out, err := exec.Command(jq, "-c", filter, args[0]).CombinedOutput(); err != nil {
+				return fmt.Errorf("jq: %w: %s", err, out)
+			}
+		}
+		ns := time.Since(start).Nanoseconds() / jqRuns
+		fmt.Fprintf(tw, "jq %s\t%d\t%.2f\t-\t-\n", filter, ns, throughput(len(data), ns))
+	}
+	return tw.Flush()
+}
+
+// throughput returns the MB/s processing size bytes in ns nanoseconds.
+func throughput(size int, ns int64) float64 {
+	if ns <= 0 {
+		return 0
+	}
+	return float64(size) / 1e6 / (float64(ns) / 1e9)
+}
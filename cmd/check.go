@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/qdequele/jsonq"
+)
+
+// runCheck runs the check subcommand, which validates a query against the
+// response schema of an operation of a JSON OpenAPI document:
+//
+//	jsonq check openapi.json GET /users/{id} [status] '{name, email}'
+//
+// The query may be a named query of the library, such as @users. The
+// issues are written to w, and passed is false when there is any.
+func runCheck(args []string, w io.Writer) (passed bool, err error) {
+	if len(args) != 4 && len(args) != 5 {
+		return false, fmt.Errorf("check takes an OpenAPI document, a method, a path, an optional status and a query")
+	}
+	spec, method, path, query := args[0], args[1], args[2], args[len(args)-1]
+	status := ""
+	if len(args) == 5 {
+		status = args[3]
+	}
+	query, err = libraryQuery(query)
+	if err != nil {
+		return false, err
+	}
+	request, err := jsonq.ParseQuery(query)
+	if err != nil {
+		return false, err
+	}
+	data, err := ioutil.ReadFile(spec)
+	if err != nil {
+		return false, err
+	}
+	var p jsonq.Parser
+	doc, err := p.ParseBytes(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", spec, err)
+	}
+	issues, err := request.CheckOpenAPI(doc, method, path, status)
+	if err != nil {
+		return false, err
+	}
+	for _, issue := range issues {
+		if _, err := fmt.Fprintln(w, issue); err != nil {
+			return false, err
+		}
+	}
+	return len(issues) == 0, nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qdequele/jsonq"
+)
+
+// sampleSize is the number of bytes of each input file read to complete
+// field names.
+const sampleSize = 1 << 20
+
+var (
+	subcommands = []string{"bench", "check", "completion", "keys", "schema", "patch", "serve", "set"}
+	shells      = []string{"bash", "zsh", "fish"}
+	// formats are the output formats of exporter.
+	formats = []string{"json", "csv", "tsv", "yaml", "table", "raw"}
+)
+
+// runCompletion writes the completion script of shell to w. The scripts
+// call jsonq __complete to get the candidates.
+func runCompletion(shell string, w io.Writer) error {
+	script, ok := map[string]string{
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+	}[shell]
+	if !ok {
+		return fmt.Errorf("no completion for shell %q, want one of %s", shell, strings.Join(shells, ", "))
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+const bashCompletion = `_jsonq() {
+	local cur=${COMP_WORDS[COMP_CWORD]} IFS=$'\n'
+	local partial=${cur##*[^[:alnum:]_.@-]}
+	local candidates=($(jsonq __complete "$COMP_POINT" "$COMP_LINE" 2>/dev/null))
+	if [ ${#candidates[@]} -eq 0 ]; then
+		compopt -o default
+		COMPREPLY=()
+		return
+	fi
+	if [ "$cur" != "$partial" ]; then
+		compopt -o nospace
+	fi
+	COMPREPLY=("${candidates[@]/#/${cur%"$partial"}}")
+}
+complete -F _jsonq jsonq
+`
+
+const zshCompletion = `#compdef jsonq
+_jsonq() {
+	local -a candidates
+	candidates=(${(f)"$(jsonq __complete $CURSOR "$BUFFER" 2>/dev/null)"})
+	if (( ! $#candidates )); then
+		_files
+		return
+	fi
+	if compset -P '*[^[:alnum:]_.@-]'; then
+		compadd -S '' -- $candidates
+	else
+		compadd -- $candidates
+	fi
+}
+compdef _jsonq jsonq
+`
+
+const fishCompletion = `function __jsonq_complete
+	set -l cur (commandline -ct | string replace -r -- '^[\'"]' '')
+	set -l cursor (string length -- (commandline -cp | string collect))
+	set -l candidates (jsonq __complete $cursor (commandline -p | string collect) 2>/dev/null)
+	if test (count $candidates) -eq 0
+		__fish_complete_path $cur
+		return
+	end
+	set -l prefix (string replace -r -- '[[:alnum:]_.@-]*$' '' $cur)
+	printf '%s\n' $prefix$candidates
+end
+complete -c jsonq -f -a '(__jsonq_complete)'
+`
+
+// runComplete writes to w the candidates completing the command line at
+// cursor, counted in characters, one per line. A candidate replaces the
+// trailing name characters of the current word: the completion scripts
+// keep what precedes them, such as the start of a query. Nothing is
+// written when the word is a file, which the shell completes itself.
+func runComplete(cursor int, line string, w io.Writer) error {
+	cut := 0
+	for i := 0; i < cursor && cut < len(line); i++ {
+		_, n := utf8.DecodeRuneInString(line[cut:])
+		cut += n
+	}
+	before := splitWords(line[:cut])
+	cur := ""
+	if len(before) > 0 && before[len(before)-1].end == cut {
+		cur = before[len(before)-1].text
+		before = before[:len(before)-1]
+	}
+	var after []string
+	for _, word := range splitWords(line) {
+		if word.start >= cut {
+			after = append(after, word.text)
+		}
+	}
+	args := command(texts(before), false)
+	after = command(after, true)
+	partial := cur[nameStart(cur):]
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(cur, "-") && strings.Contains(cur, "="):
+		candidates = flagValues(cur[:strings.IndexByte(cur, '=')], "", after)
+	case len(args) > 0 && takesValue(args[len(args)-1]):
+		candidates = flagValues(args[len(args)-1], cur, after)
+	case strings.HasPrefix(cur, "-"):
+		dashes := "-"
+		if strings.HasPrefix(cur, "--") {
+			dashes = "--"
+		}
+		flag.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, dashes+f.Name)
+		})
+	default:
+		pos := positional(args)
+		switch {
+		case len(pos) == 1 && pos[0] == "completion":
+			candidates = shells
+		case len(pos) > 0 || hasFlag(args, "query-file") || hasFlag(args, "grep") || hasFlag(args, "assert"):
+		case strings.ContainsAny(cur, "{("):
+			candidates = fields(cur, positional(after))
+		case strings.HasPrefix(cur, "@"):
+			candidates = libraryNames()
+		default:
+			candidates = subcommands
+		}
+	}
+
+	sort.Strings(candidates)
+	for _, c := range candidates {
+		if strings.HasPrefix(c, partial) {
+			if _, err := fmt.Fprintln(w, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flagValues returns the candidate values of the flag arg, cur being the
+// partial value.
+func flagValues(arg, cur string, after []string) []string {
+	switch strings.TrimLeft(arg, "-") {
+	case "format":
+		return formats
+	case "grep":
+		return fields("("+cur, positional(after))
+	case "assert":
+		return fields("{"+cur, positional(after))
+	}
+	return nil
+}
+
+// fields returns the field names completing the partial query, sampled
+// from the files.
+func fields(query string, files []string) []string {
+	level, ok := queryContext(query)
+	if !ok {
+		return nil
+	}
+	names := sampleFields(files)[strings.Join(level, ".")]
+	candidates := make([]string, 0, len(names))
+	for name := range names {
+		candidates = append(candidates, name)
+	}
+	return candidates
+}
+
+// queryContext returns the path of the level the partial query ends in,
+// and whether it ends in a field name, rather than in a quoted literal or
+// a filter value.
+func queryContext(query string) ([]string, bool) {
+	// levels holds the names of the open levels, "" for the root.
+	var levels []string
+	var name, filtered string
+	// depth counts the open parentheses of filters, which may be nested.
+	depth, inValue := 0, false
+	start := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '"' {
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(query) {
+				return nil, false
+			}
+			start = i + 1
+			continue
+		}
+		if isNameByte(c) {
+			continue
+		}
+		if word := query[start:i]; word != "" {
+			name = word
+		}
+		start = i + 1
+		switch c {
+		case '(':
+			if depth == 0 {
+				filtered = name
+			}
+			depth++
+			inValue = false
+		case ')':
+			if depth--; depth == 0 {
+				name = filtered
+			}
+		case '{':
+			levels = append(levels, name)
+			name = ""
+		case '}':
+			if len(levels) > 0 {
+				levels = levels[:len(levels)-1]
+			}
+			name = ""
+		case ',':
+			name = ""
+		case '&', '|', '!':
+			inValue = false
+		case ' ', '\t':
+		default:
+			inValue = depth > 0
+		}
+	}
+	if depth > 0 {
+		if inValue {
+			return nil, false
+		}
+		levels = append(levels, filtered)
+	}
+	path := levels[:0]
+	for _, level := range levels {
+		if level != "" {
+			path = append(path, level)
+		}
+	}
+	return path, true
+}
+
+// sampleFields returns the field names found in the first sampleSize bytes
+// of each of the files, by level: a level joins the names of its parents
+// with dots, arrays being transparent as they are in queries.
+func sampleFields(files []string) map[string]map[string]bool {
+	fields := map[string]map[string]bool{}
+	type frame struct {
+		object bool
+		key    string
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		t := jsonq.NewTokenizer(io.LimitReader(f, sampleSize))
+		var frames []frame
+		for {
+			tok, err := t.Next()
+			if err != nil {
+				// The sample usually ends in the middle of a value.
+				break
+			}
+			switch tok.Kind {
+			case jsonq.ObjectStart, jsonq.ArrayStart:
+				frames = append(frames, frame{object: tok.Kind == jsonq.ObjectStart})
+			case jsonq.ObjectEnd, jsonq.ArrayEnd:
+				frames = frames[:len(frames)-1]
+			case jsonq.Key:
+				frames[len(frames)-1].key = tok.Value
+				if nameStart(tok.Value) > 0 || tok.Value == "" {
+					continue
+				}
+				var level []string
+				for _, f := range frames[:len(frames)-1] {
+					if f.object {
+						level = append(level, f.key)
+					}
+				}
+				path := strings.Join(level, ".")
+				if fields[path] == nil {
+					fields[path] = map[string]bool{}
+				}
+				fields[path][tok.Value] = true
+			}
+		}
+		f.Close()
+	}
+	return fields
+}
+
+func libraryNames() []string {
+	path, err := libraryPath()
+	if err != nil {
+		return nil
+	}
+	library, err := loadLibrary(path)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(library))
+	for name := range library {
+		names = append(names, "@"+name)
+	}
+	return names
+}
+
+// shellWord is a word of a command line, unquoted, and its byte offsets.
+type shellWord struct {
+	text       string
+	start, end int
+}
+
+// splitWords splits line into words as a shell does, removing quotes and
+// escapes. An unterminated quote extends to the end of line.
+func splitWords(line string) []shellWord {
+	var words []shellWord
+	var b strings.Builder
+	var quote byte
+	in, start := false, 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if quote != 0 {
+			switch {
+			case c == quote:
+				quote = 0
+			case c == '\\' && quote == '"' && i+1 < len(line):
+				i++
+				b.WriteByte(line[i])
+			default:
+				b.WriteByte(c)
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' {
+			if in {
+				words = append(words, shellWord{text: b.String(), start: start, end: i})
+				b.Reset()
+				in = false
+			}
+			continue
+		}
+		if !in {
+			in, start = true, i
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				b.WriteByte(line[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if in {
+		words = append(words, shellWord{text: b.String(), start: start, end: len(line)})
+	}
+	return words
+}
+
+func texts(words []shellWord) []string {
+	s := make([]string, len(words))
+	for i, word := range words {
+		s[i] = word.text
+	}
+	return s
+}
+
+// command returns the arguments of the jsonq command among the words
+// preceding the cursor, the program name excluded, or among the words
+// following it when after is set.
+func command(words []string, after bool) []string {
+	for i, word := range words {
+		switch word {
+		case "|", "||", "&&", ";":
+			if after {
+				return words[:i]
+			}
+			words = words[i+1:]
+		}
+	}
+	if !after && len(words) > 0 {
+		return words[1:]
+	}
+	return words
+}
+
+// positional returns the arguments following the flags of args, as the
+// flag package parses them.
+func positional(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args[i+1:]
+		case len(arg) < 2 || arg[0] != '-':
+			return args[i:]
+		case takesValue(arg):
+			i++
+		}
+	}
+	return nil
+}
+
+// takesValue reports whether the flag arg is followed by its value.
+func takesValue(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || strings.Contains(arg, "=") {
+		return false
+	}
+	f := flag.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+// hasFlag reports whether the flag name is set in args.
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		arg = strings.TrimLeft(arg, "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// nameStart returns the offset of the trailing name characters of s.
+func nameStart(s string) int {
+	i := len(s)
+	for i > 0 && isNameByte(s[i-1]) {
+		i--
+	}
+	return i
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '@' || c == '-'
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/qdequele/jsonq"
+)
+
+// runDiscover runs the keys or schema subcommand on the JSON of the file,
+// or of the standard input when there is none. keys prints the paths of
+// the document, one per line; schema adds their types, occurrences and
+// array lengths.
+func runDiscover(command string, files []string, w io.Writer) error {
+	var data []byte
+	var err error
+	switch len(files) {
+	case 0:
+		data, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		data, err = ioutil.ReadFile(files[0])
+	default:
+		return fmt.Errorf("%s takes a single file", command)
+	}
+	if err != nil {
+		return err
+	}
+	var p jsonq.Parser
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	a := jsonq.Analyze(v)
+
+	if command == "keys" {
+		for _, path := range a.SortedPaths() {
+			if path != "" {
+				fmt.Fprintln(w, path)
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tTYPES\tCOUNT\tLENGTHS")
+	for _, path := range a.SortedPaths() {
+		s := a.Paths[path]
+		if path == "" {
+			path = "."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", path, types(s), s.Count, lengths(s))
+	}
+	return tw.Flush()
+}
+
+// types returns the types found at a path, most frequent first.
+func types(s *jsonq.PathStats) string {
+	names := make([]string, 0, len(s.Types))
+	counts := map[string]int{}
+	for t, n := range s.Types {
+		name := t.String()
+		if t == jsonq.TypeTrue || t == jsonq.TypeFalse {
+			name = "bool"
+		}
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name] += n
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, "|")
+}
+
+// lengths returns the range of the lengths of the arrays found at a path.
+func lengths(s *jsonq.PathStats) string {
+	if len(s.ArrayLengths) == 0 {
+		return "-"
+	}
+	min, max := -1, 0
+	for n := range s.ArrayLengths {
+		if min < 0 || n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+	if min == max {
+		return fmt.Sprint(min)
+	}
+	return fmt.Sprintf("%d-%d", min, max)
+}
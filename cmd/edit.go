@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/qdequele/jsonq"
+)
+
+// runEdit runs the patch or set subcommand and writes the edited document
+// to w:
+//
+//	jsonq patch doc.json patch.json
+//	jsonq set doc.json path value
+//
+// A patch is a JSON patch when it is an array, and a JSON merge patch
+// otherwise. The path of set is written as "a.b[3].c", and its value is
+// JSON, or a string when it isn't valid JSON.
+func runEdit(command string, args []string, w io.Writer) error {
+	if len(args) != 2 && command == "patch" || len(args) != 3 && command == "set" {
+		return fmt.Errorf("usage: jsonq patch doc.json patch.json | jsonq set doc.json path value")
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var pd, pv jsonq.Parser
+	doc, err := pd.ParseBytes(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	switch command {
+	case "patch":
+		data, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		patch, err := pv.ParseBytes(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[1], err)
+		}
+		if patch.Type() == jsonq.TypeArray {
+			if doc, err = jsonq.ApplyPatch(doc, patch); err != nil {
+				return err
+			}
+		} else {
+			doc = jsonq.ApplyMergePatch(doc, patch)
+		}
+	case "set":
+		path, err := jsonq.ParsePath(args[1])
+		if err != nil {
+			return err
+		}
+		value, err := pv.Parse(args[2])
+		if err != nil {
+			if value, err = pv.Parse(quote(args[2])); err != nil {
+				return err
+			}
+		}
+		if err := doc.SetPath(path, value); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(w, doc)
+	return err
+}
+
+// quote returns s as a JSON string.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
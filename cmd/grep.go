@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/qdequele/jsonq"
+)
+
+var grep = flag.String("grep", "", "print the lines of NDJSON input passing the filters, such as 'level = error && status >= 500'")
+
+// maxLineSize is the size of the longest NDJSON line runGrep accepts.
+const maxLineSize = 64 << 20
+
+// runGrep writes to w the lines of the files, or of the standard input
+// when there is none, passing filters, verbatim. It reports whether a line
+// matched.
+func runGrep(filters string, files []string, w io.Writer) (bool, error) {
+	m, err := jsonq.NewMatcher(filters)
+	if err != nil {
+		return false, err
+	}
+	out := bufio.NewWriter(w)
+	matched := false
+	grepReader := func(r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if m.MatchBytes(line) {
+				matched = true
+				out.Write(line)
+				out.WriteByte('\n')
+			}
+		}
+		return scanner.Err()
+	}
+	if len(files) == 0 {
+		if err := grepReader(os.Stdin); err != nil {
+			return matched, err
+		}
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return matched, err
+		}
+		err = grepReader(f)
+		f.Close()
+		if err != nil {
+			return matched, err
+		}
+	}
+	return matched, out.Flush()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var queryFile = flag.String("query-file", "", "read the query from `file`; all the arguments are then input files")
+
+// queryArgs returns the query and the input files of the command line. The
+// query is read from the query file when there is one, otherwise it is the
+// first argument, where @name stands for the query named name in the
+// library.
+func queryArgs(args []string) (string, []string, error) {
+	if *queryFile != "" {
+		data, err := ioutil.ReadFile(*queryFile)
+		if err != nil {
+			return "", nil, err
+		}
+		return strings.TrimSpace(string(data)), args, nil
+	}
+	query, err := libraryQuery(args[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return query, args[1:], nil
+}
+
+// libraryQuery returns query, or the query named name in the library when
+// query is @name.
+func libraryQuery(query string) (string, error) {
+	if !strings.HasPrefix(query, "@") {
+		return query, nil
+	}
+	path, err := libraryPath()
+	if err != nil {
+		return "", err
+	}
+	library, err := loadLibrary(path)
+	if err != nil {
+		return "", err
+	}
+	named, ok := library[query[1:]]
+	if !ok {
+		return "", fmt.Errorf("no query named %q in %s", query[1:], path)
+	}
+	return named, nil
+}
+
+// libraryPath returns the path of the named queries library,
+// ~/.jsonq/queries.toml.
+func libraryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".jsonq", "queries.toml"), nil
+}
+
+func loadLibrary(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	library, err := parseLibrary(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return library, nil
+}
+
+// parseLibrary parses the subset of TOML a queries library uses: string
+// keys, bare or quoted, set to basic, literal or multi-line strings, and
+// # comments. Table headers, such as [queries], are allowed and ignored:
+//
+//	cheap-products = "{products(price < 10){name, price}}"
+//	errors = '''
+//	{logs(level = error){time, message}}
+//	'''
+func parseLibrary(data string) (map[string]string, error) {
+	library := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' || text[0] == '[' {
+			continue
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: missing '='", line)
+		}
+		name := strings.Trim(strings.TrimSpace(text[:eq]), `"'`)
+		value := strings.TrimSpace(text[eq+1:])
+
+		var query string
+		switch {
+		case strings.HasPrefix(value, `"""`), strings.HasPrefix(value, `'''`):
+			delim := value[:3]
+			value = value[3:]
+			for !strings.Contains(value, delim) {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				line++
+				value += "\n" + scanner.Text()
+			}
+			query = value[:strings.Index(value, delim)]
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			query = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1:end])
+		case strings.HasPrefix(value, `'`):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			query = value[1 : end+1]
+		default:
+			return nil, fmt.Errorf("line %d: the query of %q must be a string", line, name)
+		}
+		library[name] = strings.TrimSpace(query)
+	}
+	return library, scanner.Err()
+}
+
+// closingQuote returns the index of the '"' closing the basic string
+// starting s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
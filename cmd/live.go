@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/qdequele/jsonq"
+)
+
+// liveConfig enables the live queries: clients subscribe to a query on
+// /subscribe and receive its results for every document posted to
+// /publish afterwards.
+type liveConfig struct {
+	// Buffer is the number of results waiting for a slow client, 64 by
+	// default. The oldest ones are dropped beyond it.
+	Buffer int `json:"buffer"`
+	// Keepalive is the interval between the comments sent to idle
+	// clients, 15s by default.
+	Keepalive duration `json:"keepalive"`
+}
+
+// subscribe streams the results of the query q of the URL as server-sent
+// events, one per matching document published, until the client leaves.
+func (s *server) subscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "GET a live query", http.StatusMethodNotAllowed)
+		return
+	}
+	p, status, err := s.prepare(r, r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if p.export != nil {
+		http.Error(w, "live results are only sent as JSON", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// The stream outlives the write timeout of the server.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	done := r.Context().Done()
+	results := make(chan string)
+	// stopped is closed when the stream ends, before Unsubscribe waits for
+	// the handler, so that it doesn't wait for the stream to take a result.
+	stopped := make(chan struct{})
+	sub, err := s.live.Subscribe(p.request, func(output string) {
+		select {
+		case results <- output:
+		case <-stopped:
+		}
+	}, s.cfg.Live.Buffer, jsonq.DropOldest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.live.Unsubscribe(sub)
+	defer close(stopped)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": subscribed\n\n")
+	flusher.Flush()
+	keepalive := time.NewTicker(time.Duration(s.cfg.Live.Keepalive))
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.closing:
+			return
+		case output := <-results:
+			fmt.Fprintf(w, "data: %s\n\n", output)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// publish runs the live queries on the posted document.
+func (s *server) publish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a JSON document", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	if err := s.live.Publish(data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stream is the event stream of a live query.
+type stream struct {
+	body  io.Closer
+	lines chan string
+}
+
+// subscribeTest subscribes to query on ts and waits for the subscription.
+func subscribeTest(t *testing.T, ts *httptest.Server, query string) *stream {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/subscribe?q=" + url.QueryEscape(query))
+	if err != nil {
+		t.Fatalf("cannot subscribe: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("subscribe answered %s, %q", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	st := &stream{body: resp.Body, lines: make(chan string)}
+	go func() {
+		defer close(st.lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				st.lines <- line
+			}
+		}
+	}()
+	if got := st.next(t); got != ": subscribed" {
+		t.Fatalf("first event %q, want the subscription comment", got)
+	}
+	return st
+}
+
+// next returns the next line of the stream, or "EOF" at its end.
+func (st *stream) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case line, ok := <-st.lines:
+		if !ok {
+			return "EOF"
+		}
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no event on the stream")
+		return ""
+	}
+}
+
+func publishTest(t *testing.T, ts *httptest.Server, doc string) {
+	t.Helper()
+	resp, err := http.Post(ts.URL+"/publish", "application/json", strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("cannot publish: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("publish answered %s", resp.Status)
+	}
+}
+
+func TestLiveStream(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.Live = &liveConfig{Keepalive: duration(50 * time.Millisecond)}
+	s := newServer(cfg)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	st := subscribeTest(t, ts, "(level=error){msg}")
+	defer st.body.Close()
+	publishTest(t, ts, `{"level": "info", "msg": "a"}`)
+	publishTest(t, ts, `{"level": "error", "msg": "b"}`)
+	for {
+		got := st.next(t)
+		if got == ": keepalive" {
+			continue
+		}
+		if got != `data: {"msg":"b"}` {
+			t.Errorf("event %q, want the result of the second document", got)
+		}
+		break
+	}
+	for got := st.next(t); got != ": keepalive"; got = st.next(t) {
+		t.Errorf("event %q, want a keepalive", got)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodPost, "/subscribe?q={msg}", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/subscribe?q={msg", http.StatusBadRequest},
+		{http.MethodGet, "/subscribe?q={msg}&format=csv", http.StatusBadRequest},
+		{http.MethodGet, "/publish", http.StatusMethodNotAllowed},
+	} {
+		req, _ := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %s", tt.method, tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s answered %s, want %d", tt.method, tt.path, resp.Status, tt.status)
+		}
+	}
+}
+
+func TestLiveShutdown(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.Live = &liveConfig{}
+	s := newServer(cfg)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	st := subscribeTest(t, ts, "{id}")
+	defer st.body.Close()
+	// Results keep coming while the server shuts down, so the stream may
+	// end with the handler of its subscription waiting to pass one.
+	stop := make(chan struct{})
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.live.Publish([]byte(`{"id": 1}`))
+			}
+		}
+	}()
+	if got := st.next(t); got != `data: {"id":1}` {
+		t.Errorf("event %q, want a result", got)
+	}
+	close(s.closing)
+	for got := st.next(t); got != "EOF"; got = st.next(t) {
+		if got != `data: {"id":1}` {
+			t.Errorf("event %q, want a result or the end of the stream", got)
+		}
+	}
+	close(stop)
+	<-published
+
+	closed := make(chan struct{})
+	go func() {
+		s.live.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the live queries didn't stop")
+	}
+}
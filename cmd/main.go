@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"runtime/pprof"
+	"strconv"
 
 	"github.com/qdequele/jsonq"
 )
@@ -25,6 +26,89 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *grep != "" {
+		matched, err := runGrep(*grep, flag.Args(), os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !matched {
+			// Like grep, exit with 1 when no line matched.
+			pprof.StopCPUProfile()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *assert != "" {
+		passed, err := runAssert(*assert, flag.Args(), os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !passed {
+			pprof.StopCPUProfile()
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch flag.Arg(0) {
+	case "completion":
+		if err := runCompletion(flag.Arg(1), os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "__complete":
+		cursor, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runComplete(cursor, flag.Arg(2), os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "keys", "schema":
+		if err := runDiscover(flag.Arg(0), flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "check":
+		passed, err := runCheck(flag.Args()[1:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !passed {
+			pprof.StopCPUProfile()
+			os.Exit(1)
+		}
+		return
+	case "serve":
+		if err := runServe(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "bench":
+		if err := runBench(flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "patch", "set":
+		if err := runEdit(flag.Arg(0), flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 || *queryFile != "" {
+		query, files, err := queryArgs(flag.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runQuery(query, files, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	querry := `{gr, uuid}`
 
 	var p jsonq.Parser
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyConfig sets the upstream API whose responses the server filters on
+// the /proxy/path routes.
+type proxyConfig struct {
+	// Upstream is the base URL of the API, such as https://api.example.com.
+	Upstream string `json:"upstream"`
+	// CacheEntries is the number of upstream documents cached, 1024 by
+	// default, the least recently used being evicted first. A negative
+	// value disables the cache.
+	CacheEntries int `json:"cache_entries"`
+}
+
+// proxy fetches documents from an upstream API and caches them with the
+// results of the queries run on them, keyed by the upstream ETag and the
+// query.
+//
+// The cache follows the Cache-Control of the upstream responses: no-store
+// and private responses are not cached, and the others are served without
+// asking the upstream until their max-age or s-maxage, then revalidated
+// with If-None-Match. A client sending Cache-Control: no-cache forces the
+// revalidation, and no-store bypasses the cache.
+type proxy struct {
+	upstream *url.URL
+	client   *http.Client
+	maxSize  int64
+	entries  int
+
+	mu sync.Mutex
+	// cache holds the elements of recent by target, and recent the
+	// entries from the most to the least recently used.
+	cache  map[string]*list.Element
+	recent *list.List
+}
+
+// proxyEntry is a cached upstream document.
+type proxyEntry struct {
+	target             string
+	etag, cacheControl string
+	expires            time.Time
+	doc                []byte
+	// results holds the results of the queries run on doc, by planKey.
+	results map[string]result
+}
+
+func newProxy(cfg proxyConfig, timeout time.Duration, maxSize int64) (*proxy, error) {
+	upstream, err := url.Parse(cfg.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("proxy upstream: %w", err)
+	}
+	if upstream.Scheme != "http" && upstream.Scheme != "https" {
+		return nil, fmt.Errorf("proxy upstream: %q is not an http URL", cfg.Upstream)
+	}
+	entries := cfg.CacheEntries
+	if entries == 0 {
+		entries = 1024
+	}
+	return &proxy{
+		upstream: upstream,
+		client:   &http.Client{Timeout: timeout},
+		maxSize:  maxSize,
+		entries:  entries,
+		cache:    map[string]*list.Element{},
+		recent:   list.New(),
+	}, nil
+}
+
+// lookup returns the entry cached for target, if any, as the most recently
+// used. px.mu must be held.
+func (px *proxy) lookup(target string) *proxyEntry {
+	e, ok := px.cache[target]
+	if !ok {
+		return nil
+	}
+	px.recent.MoveToFront(e)
+	return e.Value.(*proxyEntry)
+}
+
+// proxied runs the query q on the document of the upstream at the path
+// following /proxy, with the other parameters of the URL.
+func (s *server) proxied(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "GET a proxied document", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	p, status, err := s.prepare(r, params.Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	params.Del("q")
+	params.Del("format")
+	target := *s.proxy.upstream
+	target.Path = strings.TrimSuffix(target.Path, "/") + strings.TrimPrefix(r.URL.Path, "/proxy")
+	target.RawQuery = params.Encode()
+
+	resp, status, err := s.proxy.get(r, target.String(), p, s.execute)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("X-Cache", resp.cacheStatus)
+	if resp.cacheControl != "" {
+		w.Header().Set("Cache-Control", resp.cacheControl)
+	}
+	if resp.etag != "" {
+		sum := sha256.Sum256([]byte(resp.etag + "\x00" + planKey(p)))
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		if matchETag(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	resp.write(w)
+}
+
+// planKey identifies the results of p in the cache.
+func planKey(p *plan) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", p.maxResult, p.format, p.query)
+}
+
+// proxyResponse is a result with the cache headers of its upstream
+// document and its cache status: HIT, REVALIDATED or MISS.
+type proxyResponse struct {
+	result
+	etag, cacheControl, cacheStatus string
+}
+
+// get returns the result of p on the document at target, from the cache
+// when possible. It fails with the status to answer.
+func (px *proxy) get(r *http.Request, target string, p *plan, execute func(*plan, []byte) (result, int, error)) (proxyResponse, int, error) {
+	request := parseCacheControl(r.Header.Get("Cache-Control"))
+	_, noStore := request["no-store"]
+	_, noCache := request["no-cache"]
+	key := planKey(p)
+	respond := func(entry *proxyEntry, res result, cacheStatus string) proxyResponse {
+		px.mu.Lock()
+		defer px.mu.Unlock()
+		return proxyResponse{res, entry.etag, entry.cacheControl, cacheStatus}
+	}
+
+	var cached *proxyEntry
+	px.mu.Lock()
+	if !noStore {
+		cached = px.lookup(target)
+	}
+	if cached != nil && !noCache && time.Now().Before(cached.expires) {
+		res, ok := cached.results[key]
+		px.mu.Unlock()
+		if !ok {
+			var status int
+			var err error
+			if res, status, err = execute(p, cached.doc); err != nil {
+				return proxyResponse{}, status, err
+			}
+			px.store(target, cached, key, res)
+		}
+		return respond(cached, res, "HIT"), 0, nil
+	}
+	px.mu.Unlock()
+
+	entry, cacheStatus, status, err := px.fetch(target, cached)
+	if err != nil {
+		return proxyResponse{}, status, err
+	}
+	if cacheStatus == "REVALIDATED" {
+		px.mu.Lock()
+		res, ok := entry.results[key]
+		px.mu.Unlock()
+		if ok {
+			return respond(entry, res, cacheStatus), 0, nil
+		}
+	}
+	res, status, err := execute(p, entry.doc)
+	if err != nil {
+		return proxyResponse{}, status, err
+	}
+	if !noStore {
+		px.store(target, entry, key, res)
+	}
+	return respond(entry, res, cacheStatus), 0, nil
+}
+
+// fetch gets the document at target from the upstream, revalidating cached
+// when it has an ETag.
+func (px *proxy) fetch(target string, cached *proxyEntry) (*proxyEntry, string, int, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cached != nil && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := px.client.Do(req)
+	if err != nil {
+		return nil, "", http.StatusBadGateway, fmt.Errorf("upstream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	entry := &proxyEntry{
+		target:       target,
+		etag:         resp.Header.Get("ETag"),
+		cacheControl: resp.Header.Get("Cache-Control"),
+		expires:      time.Now().Add(maxAge(resp.Header.Get("Cache-Control"))),
+		results:      map[string]result{},
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		px.mu.Lock()
+		defer px.mu.Unlock()
+		if entry.cacheControl != "" {
+			cached.cacheControl = entry.cacheControl
+		}
+		cached.expires = entry.expires
+		return cached, "REVALIDATED", 0, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", http.StatusBadGateway, fmt.Errorf("upstream: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, px.maxSize+1))
+	if err != nil {
+		return nil, "", http.StatusBadGateway, fmt.Errorf("upstream: %w", err)
+	}
+	if int64(len(data)) > px.maxSize {
+		return nil, "", http.StatusBadGateway, fmt.Errorf("upstream: document larger than %d bytes", px.maxSize)
+	}
+	entry.doc = data
+	return entry, "MISS", 0, nil
+}
+
+// store caches res as the result of the query key on the document entry,
+// when the upstream allows it.
+func (px *proxy) store(target string, entry *proxyEntry, key string, res result) {
+	if px.entries < 0 {
+		return
+	}
+	px.mu.Lock()
+	defer px.mu.Unlock()
+	directives := parseCacheControl(entry.cacheControl)
+	for _, d := range []string{"no-store", "private"} {
+		if _, ok := directives[d]; ok {
+			return
+		}
+	}
+	if entry.etag == "" && !time.Now().Before(entry.expires) {
+		return
+	}
+	if e, ok := px.cache[target]; ok && e.Value == entry {
+		px.recent.MoveToFront(e)
+	} else {
+		if ok {
+			px.recent.Remove(e)
+		} else if len(px.cache) >= px.entries {
+			oldest := px.recent.Back()
+			px.recent.Remove(oldest)
+			delete(px.cache, oldest.Value.(*proxyEntry).target)
+		}
+		px.cache[target] = px.recent.PushFront(entry)
+	}
+	entry.results[key] = res
+}
+
+// parseCacheControl returns the directives of a Cache-Control header, with
+// their value if any.
+func parseCacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, d := range strings.Split(header, ",") {
+		name, value := strings.TrimSpace(d), ""
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, value = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+		}
+		if name != "" {
+			directives[strings.ToLower(name)] = value
+		}
+	}
+	return directives
+}
+
+// maxAge returns how long a response with the Cache-Control header stays
+// fresh for a shared cache, zero when it must be revalidated.
+func maxAge(header string) time.Duration {
+	directives := parseCacheControl(header)
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[d]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether the If-None-Match header matches etag.
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upstream is an API serving {"path": ...} documents with the cache
+// headers set by the test, and counting the requests per path.
+type upstream struct {
+	mu           sync.Mutex
+	etag         string
+	cacheControl string
+	requests     map[string]int
+	// revalidations counts the requests matching etag.
+	revalidations int
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.requests[r.URL.Path]++
+	if u.cacheControl != "" {
+		w.Header().Set("Cache-Control", u.cacheControl)
+	}
+	if u.etag != "" {
+		w.Header().Set("ETag", u.etag)
+		if r.Header.Get("If-None-Match") == u.etag {
+			u.revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"path": "` + r.URL.Path + `", "n": 1}`))
+}
+
+// set sets the cache headers of the next responses.
+func (u *upstream) set(etag, cacheControl string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.etag, u.cacheControl = etag, cacheControl
+}
+
+func (u *upstream) count(path string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.requests[path]
+}
+
+// newProxyTest returns a server proxying an upstream, and the upstream.
+func newProxyTest(t *testing.T, entries int) (*httptest.Server, *upstream) {
+	t.Helper()
+	u := &upstream{requests: map[string]int{}}
+	up := httptest.NewServer(u)
+	t.Cleanup(up.Close)
+	cfg := defaultServerConfig
+	cfg.Proxy = &proxyConfig{Upstream: up.URL, CacheEntries: entries}
+	s := newServer(cfg)
+	var err error
+	if s.proxy, err = newProxy(*cfg.Proxy, time.Second, cfg.MaxBodyBytes); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	return ts, u
+}
+
+// get queries path through the proxy of ts, and returns the response with
+// its body.
+func get(t *testing.T, ts *httptest.Server, path, query string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/proxy"+path+"?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %s", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %s", path, err)
+	}
+	return resp, string(body)
+}
+
+func TestProxyMaxAge(t *testing.T) {
+	ts, u := newProxyTest(t, 0)
+	u.set("", "max-age=60")
+
+	for i, want := range []string{"MISS", "HIT", "HIT"} {
+		resp, body := get(t, ts, "/docs", "{path}", nil)
+		if got := resp.Header.Get("X-Cache"); got != want || body != `{"path":"/docs"}`+"\n" {
+			t.Errorf("request %d: X-Cache %s, %q, want %s", i, got, body, want)
+		}
+		if resp.Header.Get("Cache-Control") != "max-age=60" {
+			t.Errorf("request %d: Cache-Control %q", i, resp.Header.Get("Cache-Control"))
+		}
+	}
+	// Another query on the cached document.
+	if resp, body := get(t, ts, "/docs", "{n}", nil); resp.Header.Get("X-Cache") != "HIT" || body != `{"n":1}`+"\n" {
+		t.Errorf("other query: X-Cache %s, %q", resp.Header.Get("X-Cache"), body)
+	}
+	if n := u.count("/docs"); n != 1 {
+		t.Errorf("%d upstream requests, want 1", n)
+	}
+
+	// The client may force the revalidation, or bypass the cache.
+	if resp, _ := get(t, ts, "/docs", "{path}", http.Header{"Cache-Control": {"no-cache"}}); resp.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("no-cache: X-Cache %s, want MISS", resp.Header.Get("X-Cache"))
+	}
+	if resp, _ := get(t, ts, "/docs", "{path}", http.Header{"Cache-Control": {"no-store"}}); resp.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("no-store: X-Cache %s, want MISS", resp.Header.Get("X-Cache"))
+	}
+	if n := u.count("/docs"); n != 3 {
+		t.Errorf("%d upstream requests, want 3", n)
+	}
+
+	u.set("", "private, max-age=60")
+	for i := 0; i < 2; i++ {
+		if resp, _ := get(t, ts, "/private", "{path}", nil); resp.Header.Get("X-Cache") != "MISS" {
+			t.Errorf("private request %d: X-Cache %s, want MISS", i, resp.Header.Get("X-Cache"))
+		}
+	}
+}
+
+func TestProxyETag(t *testing.T) {
+	ts, u := newProxyTest(t, 0)
+	u.set(`"v1"`, "")
+
+	resp, body := get(t, ts, "/docs", "{path}", nil)
+	if resp.Header.Get("X-Cache") != "MISS" || body != `{"path":"/docs"}`+"\n" {
+		t.Fatalf("first request: X-Cache %s, %q", resp.Header.Get("X-Cache"), body)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" || etag == `"v1"` {
+		t.Fatalf("ETag %q, want one derived from the upstream and the query", etag)
+	}
+
+	// Without max-age, the document is revalidated on every request.
+	resp, body = get(t, ts, "/docs", "{path}", nil)
+	if resp.Header.Get("X-Cache") != "REVALIDATED" || body != `{"path":"/docs"}`+"\n" || resp.Header.Get("ETag") != etag {
+		t.Errorf("second request: X-Cache %s, ETag %s, %q", resp.Header.Get("X-Cache"), resp.Header.Get("ETag"), body)
+	}
+	u.mu.Lock()
+	if u.revalidations != 1 {
+		t.Errorf("%d revalidations, want 1", u.revalidations)
+	}
+	u.mu.Unlock()
+
+	// The client revalidates its own copy.
+	resp, body = get(t, ts, "/docs", "{path}", http.Header{"If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("If-None-Match: %s, %q, want 304", resp.Status, body)
+	}
+	if resp, _ := get(t, ts, "/docs", "{n}", http.Header{"If-None-Match": {etag}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("If-None-Match of another query: %s, want 200", resp.Status)
+	}
+
+	// A new version of the document.
+	u.set(`"v2"`, "")
+	resp, _ = get(t, ts, "/docs", "{path}", http.Header{"If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != "MISS" || resp.Header.Get("ETag") == etag {
+		t.Errorf("new version: %s, X-Cache %s, ETag %s", resp.Status, resp.Header.Get("X-Cache"), resp.Header.Get("ETag"))
+	}
+}
+
+func TestProxyLRU(t *testing.T) {
+	ts, u := newProxyTest(t, 2)
+	u.set("", "max-age=60")
+
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/a", "MISS"},
+		{"/b", "MISS"},
+		{"/a", "HIT"},
+		// /b is the least recently used.
+		{"/c", "MISS"},
+		{"/a", "HIT"},
+		{"/c", "HIT"},
+		{"/b", "MISS"},
+		{"/a", "MISS"},
+	} {
+		if resp, _ := get(t, ts, tt.path, "{path}", nil); resp.Header.Get("X-Cache") != tt.want {
+			t.Errorf("%s: X-Cache %s, want %s", tt.path, resp.Header.Get("X-Cache"), tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/qdequele/jsonq"
+)
+
+var format = flag.String("format", "json", "output format: json, csv, tsv, yaml, table or raw")
+
+// runQuery runs query on the JSON of each of the files, or of the standard
+// input when there is none, and writes the results to w in the output
+// format.
+func runQuery(query string, files []string, w io.Writer) error {
+	request, err := jsonq.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	export, err := exporter(*format)
+	if err != nil {
+		return err
+	}
+	run := func(data []byte) error {
+		var p jsonq.Parser
+		v, err := p.ParseBytes(data)
+		if err != nil {
+			return err
+		}
+		result, err := v.Retrieve(*request)
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			_, err = fmt.Fprintln(w, result)
+			return err
+		}
+		if v, err = p.Parse(result); err != nil {
+			return err
+		}
+		return export(w, v)
+	}
+	if len(files) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return run(data)
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := run(data); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// exporter returns the function writing results in format, or nil for
+// JSON, which is written as returned by the query.
+func exporter(format string) (func(w io.Writer, v *jsonq.Value) error, error) {
+	switch format {
+	case "json":
+		return nil, nil
+	case "csv":
+		return func(w io.Writer, v *jsonq.Value) error { return jsonq.WriteCSV(w, rows(v), ',') }, nil
+	case "tsv":
+		return func(w io.Writer, v *jsonq.Value) error { return jsonq.WriteCSV(w, rows(v), '\t') }, nil
+	case "yaml":
+		return jsonq.WriteYAML, nil
+	case "table":
+		return func(w io.Writer, v *jsonq.Value) error { return jsonq.WriteTable(w, rows(v)) }, nil
+	case "raw":
+		return jsonq.WriteRaw, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// rows returns the array of a result holding a single array, such as the
+// users of {"users":[...]}, so tabular formats get a row per element.
+func rows(v *jsonq.Value) *jsonq.Value {
+	o, err := v.Object()
+	if err != nil || o.Len() != 1 {
+		return v
+	}
+	var array *jsonq.Value
+	o.Visit(func(key []byte, v *jsonq.Value) {
+		if v.Type() == jsonq.TypeArray {
+			array = v
+		}
+	})
+	if array == nil {
+		return v
+	}
+	return array
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tenantQuota limits the queries of the clients using an API key. Zero
+// values mean no limit.
+type tenantQuota struct {
+	// Name identifies the tenant in the audit log, where its key must not
+	// appear.
+	Name string `json:"name"`
+	// QPS is the number of queries per second allowed on average, and
+	// Burst the number allowed at once, QPS rounded up by default.
+	QPS   float64 `json:"qps"`
+	Burst int     `json:"burst"`
+	// MaxComplexity is the largest jsonq.Query.Complexity allowed.
+	MaxComplexity int `json:"max_complexity"`
+	// MaxResultBytes is the size of the largest result returned.
+	MaxResultBytes int `json:"max_result_bytes"`
+}
+
+type tenantKey struct{}
+
+// tenant is the quota of an API key and the state of its rate limit.
+type tenant struct {
+	quota  tenantQuota
+	bucket tokenBucket
+}
+
+// quota authenticates the requests with their API key, given in the
+// X-API-Key header or as a bearer token, and answers 429 Too Many Requests
+// to those exceeding the queries per second of the key. The tenant is
+// passed to next in the request context. Requests are not authenticated
+// when no tenant is configured.
+func (s *server) quota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.tenants) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+			key = auth[len("Bearer "):]
+		}
+		t, ok := s.tenants[key]
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or unknown API key", http.StatusUnauthorized)
+			return
+		}
+		if wait := t.bucket.take(time.Now()); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "queries per second quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, t)))
+	})
+}
+
+// requestTenant returns the tenant of r, nil when requests are not
+// authenticated.
+func requestTenant(r *http.Request) *tenant {
+	t, _ := r.Context().Value(tenantKey{}).(*tenant)
+	return t
+}
+
+func newTenants(quotas map[string]tenantQuota) map[string]*tenant {
+	tenants := make(map[string]*tenant, len(quotas))
+	for key, quota := range quotas {
+		t := &tenant{quota: quota}
+		if quota.QPS > 0 {
+			burst := float64(quota.Burst)
+			if burst <= 0 {
+				burst = math.Ceil(quota.QPS)
+			}
+			t.bucket = tokenBucket{rate: quota.QPS, burst: burst, tokens: burst}
+		}
+		tenants[key] = t
+	}
+	return tenants
+}
+
+// tokenBucket is a rate limiter holding up to burst tokens, refilled at
+// rate tokens per second. A zero rate never limits.
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+// take takes a token at now, and returns zero, or the time to wait for a
+// token when there is none left.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := tokenBucket{rate: 2, burst: 3, tokens: 3}
+	for i := 0; i < 3; i++ {
+		if wait := b.take(now); wait != 0 {
+			t.Fatalf("take %d within the burst: wait %s", i, wait)
+		}
+	}
+	if wait := b.take(now); wait != 500*time.Millisecond {
+		t.Errorf("take beyond the burst: wait %s, want 500ms", wait)
+	}
+	// A token every half second, up to the burst.
+	if wait := b.take(now.Add(250 * time.Millisecond)); wait != 250*time.Millisecond {
+		t.Errorf("take after 250ms: wait %s, want 250ms", wait)
+	}
+	if wait := b.take(now.Add(500 * time.Millisecond)); wait != 0 {
+		t.Errorf("take after 500ms: wait %s, want none", wait)
+	}
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if wait := b.take(now); wait != 0 {
+			t.Fatalf("take %d after an hour: wait %s", i, wait)
+		}
+	}
+	if wait := b.take(now); wait == 0 {
+		t.Errorf("the bucket refilled beyond its burst")
+	}
+
+	var unlimited tokenBucket
+	for i := 0; i < 100; i++ {
+		if wait := unlimited.take(now); wait != 0 {
+			t.Fatalf("zero rate bucket: wait %s", wait)
+		}
+	}
+}
+
+func TestNewTenants(t *testing.T) {
+	tenants := newTenants(map[string]tenantQuota{
+		"a": {QPS: 2.5},
+		"b": {QPS: 1, Burst: 10},
+		"c": {},
+	})
+	if b := &tenants["a"].bucket; b.rate != 2.5 || b.burst != 3 || b.tokens != 3 {
+		t.Errorf("default burst: rate %v, burst %v, tokens %v", b.rate, b.burst, b.tokens)
+	}
+	if b := &tenants["b"].bucket; b.burst != 10 || b.tokens != 10 {
+		t.Errorf("burst: burst %v, tokens %v", b.burst, b.tokens)
+	}
+	if b := &tenants["c"].bucket; b.rate != 0 {
+		t.Errorf("no QPS: rate %v", b.rate)
+	}
+}
+
+func TestServeQuota(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.MaxResultBytes = 100
+	cfg.Tenants = map[string]tenantQuota{
+		"slow":    {Name: "slow", QPS: 0.001, Burst: 2},
+		"simple":  {Name: "simple", MaxComplexity: 3},
+		"small":   {Name: "small", MaxResultBytes: 20},
+		"default": {Name: "default"},
+	}
+	ts := httptest.NewServer(newServer(cfg).handler())
+	defer ts.Close()
+
+	doc := `{"users": [{"id": 1, "name": "ann"}, {"id": 2, "name": "bob"}]}`
+	query := func(q string) string { return "/query?q=" + url.QueryEscape(q) }
+	key := func(k string) http.Header { return http.Header{"X-Api-Key": {k}} }
+	bearer := func(k string) http.Header { return http.Header{"Authorization": {"Bearer " + k}} }
+	for _, tt := range []struct {
+		name   string
+		query  string
+		header http.Header
+		status int
+	}{
+		{"missing key", "{users{id}}", nil, http.StatusUnauthorized},
+		{"unknown key", "{users{id}}", key("other"), http.StatusUnauthorized},
+		{"unknown bearer", "{users{id}}", bearer("other"), http.StatusUnauthorized},
+		{"basic auth", "{users{id}}", http.Header{"Authorization": {"Basic ZGVmYXVsdA=="}}, http.StatusUnauthorized},
+		{"key", "{users{id}}", key("default"), http.StatusOK},
+		{"bearer", "{users{id}}", bearer("default"), http.StatusOK},
+		{"key before bearer", "{users{id}}", http.Header{"X-Api-Key": {"default"}, "Authorization": {"Bearer other"}}, http.StatusOK},
+		{"within complexity", "{users{id}}", key("simple"), http.StatusOK},
+		{"over complexity", "{users(id > 1){id, name}}", key("simple"), http.StatusTooManyRequests},
+		{"within result size", "{users(id=1){id}}", key("small"), http.StatusOK},
+		{"over result size", "{users{id, name}}", key("small"), http.StatusTooManyRequests},
+	} {
+		status, body := do(t, ts, http.MethodPost, query(tt.query), doc, tt.header)
+		if status != tt.status {
+			t.Errorf("%s: %s answered %d %q, want %d", tt.name, tt.query, status, body, tt.status)
+		}
+	}
+
+	// The limits of the server apply to the tenants, with their own status.
+	users := `{"users": [` + strings.Repeat(`{"id": 1, "name": "ann"},`, 10) + `{"id": 2}]}`
+	if status, _ := do(t, ts, http.MethodPost, query("{users{id, name}}"), users, key("default")); status != http.StatusUnprocessableEntity {
+		t.Errorf("over the result size of the server: %d, want 422", status)
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, _ := do(t, ts, http.MethodPost, query("{users{id}}"), doc, key("slow")); status != http.StatusOK {
+			t.Fatalf("query %d within the burst: %d", i, status)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+query("{users{id}}"), nil)
+	req.Header = bearer("slow")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("over QPS: %s, Retry-After %q, want 429 with a delay", resp.Status, resp.Header.Get("Retry-After"))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qdequele/jsonq"
+)
+
+// registryConfig locates the named queries the server runs on the
+// /queries/name routes.
+type registryConfig struct {
+	// Dir holds .toml files of named queries, in the format of the
+	// queries library.
+	Dir string `json:"dir"`
+	// ReloadInterval is the interval between the checks for changes in
+	// Dir, 5s by default.
+	ReloadInterval duration `json:"reload_interval"`
+}
+
+// registry maps route names to queries, reloaded from a directory when
+// its files change. The queries are all validated before replacing the
+// previous ones, so a broken file never takes a route down.
+type registry struct {
+	dir     string
+	mu      sync.RWMutex
+	queries map[string]string
+	// stamp identifies the state of the files loaded, and failed the
+	// state of the files that last failed to load.
+	stamp, failed string
+}
+
+// newRegistry loads the queries of dir, failing when one is invalid.
+func newRegistry(dir string) (*registry, error) {
+	r := &registry{dir: dir}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// get returns the query of the route name.
+func (r *registry) get(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	query, ok := r.queries[name]
+	return query, ok
+}
+
+// reload loads the queries again when the files of the registry changed,
+// and reports whether it did.
+func (r *registry) reload() (bool, error) {
+	files, err := filepath.Glob(filepath.Join(r.dir, "*.toml"))
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(files)
+	var stamp strings.Builder
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(&stamp, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+	}
+	if stamp.String() == r.stamp && r.queries != nil || stamp.String() == r.failed {
+		return false, nil
+	}
+	if err := r.load(files); err != nil {
+		r.failed = stamp.String()
+		return false, err
+	}
+	r.stamp = stamp.String()
+	return true, nil
+}
+
+// load replaces the queries with those of files once they are all valid.
+func (r *registry) load(files []string) error {
+	queries := map[string]string{}
+	origins := map[string]string{}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		library, err := parseLibrary(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for route, query := range library {
+			if origin, ok := origins[route]; ok {
+				return fmt.Errorf("%s: route %q already defined in %s", name, route, origin)
+			}
+			if _, err := jsonq.ParseQuery(query); err != nil {
+				return fmt.Errorf("%s: route %q: %w", name, route, err)
+			}
+			queries[route], origins[route] = query, name
+		}
+	}
+	r.mu.Lock()
+	r.queries = queries
+	r.mu.Unlock()
+	return nil
+}
+
+// watch reloads the registry every interval until stop is closed. Invalid
+// changes are logged and the previous queries kept.
+func (r *registry) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			switch {
+			case err != nil:
+				log.Printf("registry not reloaded: %s", err)
+			case reloaded:
+				log.Printf("registry reloaded from %s", r.dir)
+			}
+		}
+	}
+}
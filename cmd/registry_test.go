@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryReload(t *testing.T) {
+	dir := t.TempDir()
+	// The files are stamped a second apart, for the changes to show on
+	// file systems with coarse modification times.
+	stamp := time.Now().Add(-time.Hour)
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("cannot write %s: %s", name, err)
+		}
+		stamp = stamp.Add(time.Second)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatalf("cannot stamp %s: %s", name, err)
+		}
+	}
+	write("users.toml", `names = "{users{name}}"`)
+
+	cfg := defaultServerConfig
+	cfg.Registry = &registryConfig{Dir: dir}
+	s := newServer(cfg)
+	var err error
+	if s.registry, err = newRegistry(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.registry.watch(5*time.Millisecond, stop)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	doc := `{"users": [{"id": 1, "name": "ann"}]}`
+	// eventually waits for the named query to answer want.
+	eventually := func(name string, status int, want string) {
+		t.Helper()
+		var gotStatus int
+		var got string
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if gotStatus, got = do(t, ts, http.MethodPost, "/queries/"+name, doc, nil); gotStatus == status && got == want {
+				return
+			}
+		}
+		t.Fatalf("/queries/%s = %d, %q, want %d, %q", name, gotStatus, got, status, want)
+	}
+	eventually("names", http.StatusOK, `{"users":[{"name":"ann"}]}`+"\n")
+
+	write("users.toml", "names = \"{users{id}}\"\nall = \"{users}\"\n")
+	eventually("names", http.StatusOK, `{"users":[{"id":1}]}`+"\n")
+	eventually("all", http.StatusOK, `{"users":[{"id":1,"name":"ann"}]}`+"\n")
+
+	// Invalid changes keep the previous queries, for as long as they last.
+	for _, files := range []map[string]string{
+		{"users.toml": `names = "{users{id}"`},
+		{"users.toml": `names = "{users{id}}`},
+		{"more.toml": `all = "{users{name}}"`},
+	} {
+		for name, content := range files {
+			write(name, content)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if status, got := do(t, ts, http.MethodPost, "/queries/names", doc, nil); status != http.StatusOK || got != `{"users":[{"id":1}]}`+"\n" {
+			t.Errorf("%v: /queries/names = %d, %q, want the previous query", files, status, got)
+		}
+		if status, got := do(t, ts, http.MethodPost, "/queries/all", doc, nil); status != http.StatusOK || got != `{"users":[{"id":1,"name":"ann"}]}`+"\n" {
+			t.Errorf("%v: /queries/all = %d, %q, want the previous query", files, status, got)
+		}
+		os.Remove(filepath.Join(dir, "more.toml"))
+		write("users.toml", "names = \"{users{id}}\"\nall = \"{users}\"\n")
+	}
+	write("users.toml", `names = "{users{name}}"`)
+	eventually("names", http.StatusOK, `{"users":[{"name":"ann"}]}`+"\n")
+	eventually("all", http.StatusNotFound, "404 page not found\n")
+
+	if status, _ := do(t, ts, http.MethodPost, "/queries/unknown", doc, nil); status != http.StatusNotFound {
+		t.Errorf("/queries/unknown = %d, want 404", status)
+	}
+}
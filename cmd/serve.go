@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/qdequele/jsonq"
+)
+
+// serverConfig is the configuration of the serve subcommand, read from a
+// JSON file. Durations are written as "10s" or "1m30s"; the missing
+// settings keep their default.
+type serverConfig struct {
+	// Addr is the address the server listens on, such as ":8080".
+	Addr string `json:"addr"`
+	// MaxBodyBytes is the size of the largest document accepted.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// MaxResultBytes is the size of the largest result returned, zero for
+	// no limit.
+	MaxResultBytes int `json:"max_result_bytes"`
+	// MaxConcurrent is the number of queries run at once. The requests
+	// exceeding it are answered with 503 Service Unavailable.
+	MaxConcurrent   int      `json:"max_concurrent"`
+	ReadTimeout     duration `json:"read_timeout"`
+	WriteTimeout    duration `json:"write_timeout"`
+	QueryTimeout    duration `json:"query_timeout"`
+	ShutdownTimeout duration `json:"shutdown_timeout"`
+	// DrainPeriod is the time the server keeps serving once it is no
+	// longer ready, for the load balancers to stop sending it requests
+	// before it shuts down.
+	DrainPeriod duration `json:"drain_period"`
+	// Live, when set, enables the live queries.
+	Live *liveConfig `json:"live"`
+	// Proxy, when set, serves the documents of an upstream API filtered.
+	Proxy *proxyConfig `json:"proxy"`
+	// Registry, when set, serves the named queries of a directory.
+	Registry *registryConfig `json:"registry"`
+	// AuditLog, when set, is the file the audit records of the queries are
+	// appended to, or "-" for standard error.
+	AuditLog string `json:"audit_log"`
+	// Tenants holds the quotas of the API keys. When it is set, queries
+	// require one of its keys.
+	Tenants map[string]tenantQuota `json:"tenants"`
+}
+
+var defaultServerConfig = serverConfig{
+	Addr:            ":8080",
+	MaxBodyBytes:    10 << 20,
+	MaxConcurrent:   64,
+	ReadTimeout:     duration(10 * time.Second),
+	WriteTimeout:    duration(30 * time.Second),
+	QueryTimeout:    duration(10 * time.Second),
+	ShutdownTimeout: duration(15 * time.Second),
+	DrainPeriod:     duration(5 * time.Second),
+}
+
+// duration is a time.Duration written as a string in JSON.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+func loadServerConfig(path string) (serverConfig, error) {
+	cfg := defaultServerConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.MaxConcurrent <= 0 || cfg.MaxBodyBytes <= 0 {
+		return cfg, fmt.Errorf("%s: max_concurrent and max_body_bytes must be positive", path)
+	}
+	return cfg, nil
+}
+
+// runServe runs the serve subcommand, an HTTP server running queries on
+// the documents posted to it:
+//
+//	jsonq serve [config.json]
+//	curl -d @doc.json 'localhost:8080/query?q={users{name}}&format=csv'
+//
+// /healthz answers 200 while the process is up, and /readyz while it
+// accepts queries. On SIGINT or SIGTERM, the server stops being ready,
+// keeps serving for the drain period, then waits for the running queries,
+// up to the shutdown timeout.
+func runServe(args []string) error {
+	cfg := defaultServerConfig
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if cfg, err = loadServerConfig(args[0]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("usage: jsonq serve [config.json]")
+	}
+
+	s := newServer(cfg)
+	if cfg.AuditLog != "" {
+		var err error
+		if s.audit, err = openAuditLog(cfg.AuditLog); err != nil {
+			return err
+		}
+	}
+	if cfg.Proxy != nil {
+		var err error
+		if s.proxy, err = newProxy(*cfg.Proxy, time.Duration(cfg.QueryTimeout), cfg.MaxBodyBytes); err != nil {
+			return err
+		}
+	}
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	if cfg.Registry != nil {
+		var err error
+		if s.registry, err = newRegistry(cfg.Registry.Dir); err != nil {
+			return err
+		}
+		interval := time.Duration(cfg.Registry.ReloadInterval)
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		go s.registry.watch(interval, stopWatch)
+	}
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: time.Duration(cfg.ReadTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		MaxHeaderBytes:    1 << 20,
+	}
+	srv.RegisterOnShutdown(func() { close(s.closing) })
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", cfg.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-stop:
+		log.Printf("%s: shutting down", sig)
+	}
+	return s.shutdown(srv)
+}
+
+// shutdown stops srv, the server of s. /readyz fails during the drain
+// period, before srv stops accepting requests and waits for the running
+// ones, up to the shutdown timeout.
+func (s *server) shutdown(srv *http.Server) error {
+	atomic.StoreInt32(&s.ready, 0)
+	time.Sleep(time.Duration(s.cfg.DrainPeriod))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.ShutdownTimeout))
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if s.live != nil {
+		s.live.Close()
+	}
+	return err
+}
+
+type server struct {
+	cfg serverConfig
+	// ready is 1 while the server accepts queries.
+	ready int32
+	// slots holds a value per running query.
+	slots    chan struct{}
+	tenants  map[string]*tenant
+	registry *registry
+	proxy    *proxy
+	live     *jsonq.Engine
+	audit    *auditLog
+	// closing is closed when the server shuts down, ending the live
+	// queries.
+	closing chan struct{}
+}
+
+func newServer(cfg serverConfig) *server {
+	s := &server{
+		cfg:     cfg,
+		ready:   1,
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+		tenants: newTenants(cfg.Tenants),
+		closing: make(chan struct{}),
+	}
+	if cfg.Live != nil {
+		live := *cfg.Live
+		if live.Buffer <= 0 {
+			live.Buffer = 64
+		}
+		if live.Keepalive <= 0 {
+			live.Keepalive = duration(15 * time.Second)
+		}
+		s.cfg.Live = &live
+		s.live = jsonq.NewEngine()
+	}
+	return s
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&s.ready) == 0 {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/query", s.guard(s.query))
+	if s.registry != nil {
+		mux.Handle("/queries/", s.guard(s.named))
+	}
+	if s.proxy != nil {
+		mux.Handle("/proxy/", s.guard(s.proxied))
+	}
+	if s.live != nil {
+		// Subscriptions last, so they are neither timed out nor counted
+		// as running queries.
+		mux.Handle("/subscribe", s.quota(http.HandlerFunc(s.subscribe)))
+		mux.Handle("/publish", s.guard(s.publish))
+	}
+	return mux
+}
+
+// guard applies the quotas, the concurrent queries limit and the query
+// timeout to h. A query keeps its slot until it returns, even when it is
+// answered with a timeout, since it can't be interrupted.
+func (s *server) guard(h http.HandlerFunc) http.Handler {
+	return s.quota(http.TimeoutHandler(s.limit(h), time.Duration(s.cfg.QueryTimeout), "query timeout\n"))
+}
+
+// limit answers 503 Service Unavailable to the requests exceeding the
+// concurrent queries limit.
+func (s *server) limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent queries", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// query runs the query q of the URL.
+func (s *server) query(w http.ResponseWriter, r *http.Request) {
+	s.run(w, r, r.URL.Query().Get("q"))
+}
+
+// named runs the query of the registry named by the URL, /queries/name.
+func (s *server) named(w http.ResponseWriter, r *http.Request) {
+	query, ok := s.registry.get(strings.TrimPrefix(r.URL.Path, "/queries/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.run(w, r, query)
+}
+
+// run runs query on the posted document and writes the result in the
+// output format of the format parameter, JSON by default.
+func (s *server) run(w http.ResponseWriter, r *http.Request, query string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a JSON document", http.StatusMethodNotAllowed)
+		return
+	}
+	p, status, err := s.prepare(r, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	res, status, err := s.execute(p, data)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	res.write(w)
+}
+
+// plan is a query ready to run for a request.
+type plan struct {
+	query   string
+	request *jsonq.Query
+	format  string
+	export  func(io.Writer, *jsonq.Value) error
+	// maxResult is the size of the largest result allowed, and quotaStatus
+	// the status answered when it is exceeded.
+	maxResult, quotaStatus int
+}
+
+// prepare parses query with the output format of the format parameter,
+// and applies the limits of the server and of the tenant of r. It fails
+// with the status to answer.
+func (s *server) prepare(r *http.Request, query string) (*plan, int, error) {
+	request, err := jsonq.ParseQuery(query)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	p := &plan{query: query, request: request, maxResult: s.cfg.MaxResultBytes}
+	// Exceeding the quota of the tenant is answered with 429 Too Many
+	// Requests, and the limits of the server with 422.
+	p.quotaStatus = http.StatusUnprocessableEntity
+	if t := requestTenant(r); t != nil {
+		if max := t.quota.MaxComplexity; max > 0 && request.Complexity() > max {
+			return nil, http.StatusTooManyRequests, fmt.Errorf("query complexity %d exceeds the quota of %d", request.Complexity(), max)
+		}
+		if max := t.quota.MaxResultBytes; max > 0 && (p.maxResult == 0 || max < p.maxResult) {
+			p.maxResult, p.quotaStatus = max, http.StatusTooManyRequests
+		}
+	}
+	opts := jsonq.Options{MaxResultSize: p.maxResult}
+	if s.audit != nil {
+		opts.Audit, opts.Caller = s.audit.record, caller(r)
+	}
+	request.SetOptions(opts)
+	if p.format = r.URL.Query().Get("format"); p.format == "" {
+		p.format = "json"
+	}
+	if p.export, err = exporter(p.format); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return p, 0, nil
+}
+
+// result is the response to a query.
+type result struct {
+	contentType string
+	body        []byte
+}
+
+func (res result) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", res.contentType)
+	w.Write(res.body)
+}
+
+// execute runs p on the document data, failing with the status to answer.
+func (s *server) execute(p *plan, data []byte) (result, int, error) {
+	var parser jsonq.Parser
+	v, err := parser.ParseBytes(data)
+	if err != nil {
+		return result{}, http.StatusBadRequest, err
+	}
+	out, err := v.Retrieve(*p.request)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, jsonq.ErrResultTooLarge) {
+			status = p.quotaStatus
+		}
+		return result{}, status, err
+	}
+
+	if p.export == nil {
+		return result{"application/json", []byte(out + "\n")}, 0, nil
+	}
+	if v, err = parser.Parse(out); err != nil {
+		return result{}, http.StatusInternalServerError, err
+	}
+	res := result{contentType: "text/plain; charset=utf-8"}
+	if p.format == "csv" {
+		res.contentType = "text/csv; charset=utf-8"
+	}
+	var b bytes.Buffer
+	if err := p.export(&b, v); err != nil {
+		return result{}, http.StatusInternalServerError, err
+	}
+	res.body = b.Bytes()
+	return res, 0, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// do sends a request to ts and returns the status and body of the response.
+func do(t *testing.T, ts *httptest.Server, method, path, body string, header http.Header) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, path, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, path, err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("cannot write %s: %s", name, err)
+		}
+		return path
+	}
+
+	cfg, err := loadServerConfig(write("ok.json", `{"addr": ":9090", "query_timeout": "1m30s", "max_result_bytes": 100, "live": {}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Addr != ":9090" || cfg.QueryTimeout != duration(90*time.Second) || cfg.MaxResultBytes != 100 || cfg.Live == nil {
+		t.Errorf("unexpected configuration %+v", cfg)
+	}
+	if cfg.MaxConcurrent != defaultServerConfig.MaxConcurrent || cfg.DrainPeriod != defaultServerConfig.DrainPeriod {
+		t.Errorf("the missing settings lost their default: %+v", cfg)
+	}
+
+	for name, content := range map[string]string{
+		"syntax.json":     `{"addr": ":9090"`,
+		"duration.json":   `{"query_timeout": "soon"}`,
+		"number.json":     `{"query_timeout": 10}`,
+		"concurrent.json": `{"max_concurrent": 0}`,
+		"body.json":       `{"max_body_bytes": -1}`,
+	} {
+		if _, err := loadServerConfig(write(name, content)); err == nil {
+			t.Errorf("%s: expecting non-nil error", name)
+		}
+	}
+	if _, err := loadServerConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expecting non-nil error on a missing file")
+	}
+}
+
+func TestServeQuery(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.MaxBodyBytes = 64
+	cfg.MaxResultBytes = 45
+	ts := httptest.NewServer(newServer(cfg).handler())
+	defer ts.Close()
+
+	doc := `{"users": [{"id": 1, "name": "ann"}, {"id": 2, "name": "bob"}]}`
+	for _, tt := range []struct {
+		method, query, body string
+		status              int
+		want                string
+	}{
+		{http.MethodPost, "{users(id=1){name}}", doc, http.StatusOK, `{"users":[{"name":"ann"}]}` + "\n"},
+		{http.MethodPost, "{users{name}}&format=csv", doc, http.StatusOK, "name\nann\nbob\n"},
+		{http.MethodGet, "{users{name}}", "", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "{users{name}", doc, http.StatusBadRequest, ""},
+		{http.MethodPost, "{users{name}}&format=xml", doc, http.StatusBadRequest, ""},
+		{http.MethodPost, "{users{name}}", `{"users": [`, http.StatusBadRequest, ""},
+		{http.MethodPost, "{users{name}}", doc + strings.Repeat(" ", 64), http.StatusRequestEntityTooLarge, ""},
+		{http.MethodPost, "{users{id, name}}", doc, http.StatusUnprocessableEntity, ""},
+	} {
+		query := tt.query
+		format := ""
+		if i := strings.Index(query, "&"); i >= 0 {
+			query, format = query[:i], query[i:]
+		}
+		status, body := do(t, ts, tt.method, "/query?q="+url.QueryEscape(query)+format, tt.body, nil)
+		if status != tt.status || tt.want != "" && body != tt.want {
+			t.Errorf("%s %s = %d, %q, want %d, %q", tt.method, tt.query, status, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestServeConcurrency(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.MaxConcurrent = 1
+	cfg.QueryTimeout = duration(50 * time.Millisecond)
+	s := newServer(cfg)
+	release := make(chan struct{})
+	var running int32
+	ts := httptest.NewServer(s.guard(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		if r.URL.Query().Get("block") != "" {
+			<-release
+		}
+		w.Write([]byte("done"))
+	}))
+	defer ts.Close()
+
+	// The query times out, but keeps its slot while it runs.
+	if status, body := do(t, ts, http.MethodGet, "/?block=1", "", nil); status != http.StatusServiceUnavailable || body != "query timeout\n" {
+		t.Errorf("blocked query = %d, %q, want a timeout", status, body)
+	}
+	if atomic.LoadInt32(&running) != 1 {
+		t.Fatalf("the blocked query is not running")
+	}
+	if status, body := do(t, ts, http.MethodGet, "/", "", nil); status != http.StatusServiceUnavailable || body != "too many concurrent queries\n" {
+		t.Errorf("query beyond the limit = %d, %q, want it rejected", status, body)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&running) != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the blocked query didn't return")
+		}
+	}
+	if status, body := do(t, ts, http.MethodGet, "/", "", nil); status != http.StatusOK || body != "done" {
+		t.Errorf("query once the slot is free = %d, %q", status, body)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	cfg := defaultServerConfig
+	cfg.DrainPeriod = duration(300 * time.Millisecond)
+	cfg.ShutdownTimeout = duration(time.Second)
+	s := newServer(cfg)
+	ts := httptest.NewUnstartedServer(s.handler())
+	ts.Config.RegisterOnShutdown(func() { close(s.closing) })
+	ts.Start()
+	defer ts.Close()
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if status, _ := do(t, ts, http.MethodGet, path, "", nil); status != http.StatusOK {
+			t.Errorf("%s = %d, want 200", path, status)
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.shutdown(ts.Config) }()
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&s.ready) != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the server is still ready")
+		}
+	}
+	// During the drain period, the server is up and serves the queries,
+	// but is no longer ready.
+	if status, _ := do(t, ts, http.MethodGet, "/readyz", "", nil); status != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while draining = %d, want 503", status)
+	}
+	if status, _ := do(t, ts, http.MethodGet, "/healthz", "", nil); status != http.StatusOK {
+		t.Errorf("/healthz while draining = %d, want 200", status)
+	}
+	if status, _ := do(t, ts, http.MethodPost, "/query?q="+url.QueryEscape("{id}"), `{"id": 1}`, nil); status != http.StatusOK {
+		t.Errorf("query while draining = %d, want 200", status)
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the server didn't shut down")
+	}
+	if _, err := http.Get(ts.URL + "/healthz"); err == nil {
+		t.Errorf("the server still answers once shut down")
+	}
+}
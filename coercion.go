@@ -0,0 +1,84 @@
+package jsonq
+
+// CoercionPolicy controls the implicit conversions applied by filters.
+//
+// Filter values are typed when the query is parsed: true and false are
+// booleans, null is nil, integers are int64, other numbers float64 and
+// everything else a string. Document values keep their JSON type, numbers
+// being float64. Then, with the zero CoercionPolicy:
+//
+//   - int64 and float64 compare with each other, so (n = 1) matches 1.0;
+//   - booleans only match JSON true and false, so (b = true) doesn't match
+//     "true" or 1;
+//   - strings never match numbers or booleans, whatever the operation.
+//
+// A comparison between values of different types is always false, even
+// for !=.
+type CoercionPolicy struct {
+	// Bools lets booleans compare with the strings "true", "false", "1",
+	// "0" and with the numbers 1 and 0, on both sides of a filter.
+	Bools bool
+
+	// StrictNumbers stops integers from comparing to other numbers. A
+	// document number is an integer when its JSON token is one, so 1.0
+	// doesn't match (n = 1) and 1 doesn't match (n = 1.0).
+	StrictNumbers bool
+}
+
+// apply converts base and compared according to p. ok is false if the
+// policy forbids the comparison.
+func (p CoercionPolicy) apply(base, compared interface{}) (b, c interface{}, ok bool) {
+	if p.Bools {
+		if bb, isBool := base.(bool); isBool {
+			if cb, ok := toBool(compared); ok {
+				return bb, cb, true
+			}
+		} else if cb, isBool := compared.(bool); isBool {
+			if bb, ok := toBool(base); ok {
+				return bb, cb, true
+			}
+		}
+	}
+	if p.StrictNumbers {
+		switch base.(type) {
+		case int64:
+			if _, isFloat := compared.(float64); isFloat {
+				return base, compared, false
+			}
+		case float64:
+			if _, isInt := compared.(int64); isInt {
+				return base, compared, false
+			}
+		}
+	}
+	return base, compared, true
+}
+
+func toBool(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		switch b {
+		case "true", "1":
+			return true, true
+		case "false", "0":
+			return false, true
+		}
+	case int64:
+		switch b {
+		case 1:
+			return true, true
+		case 0:
+			return false, true
+		}
+	case float64:
+		switch b {
+		case 1:
+			return true, true
+		case 0:
+			return false, true
+		}
+	}
+	return false, false
+}
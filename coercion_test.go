@@ -0,0 +1,37 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestCoercionPolicy(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"b": true}, {"b": "true"}, {"b": 1}, {"b": "0"}, {"b": 1.0}, {"b": 1.5}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query  string
+		policy CoercionPolicy
+		want   int
+	}{
+		{"(b = true){b}", CoercionPolicy{}, 1},
+		{"(b = true){b}", CoercionPolicy{Bools: true}, 4},
+		{"(b = false){b}", CoercionPolicy{Bools: true}, 1},
+		{"(b != true){b}", CoercionPolicy{Bools: true}, 1},
+		{`(b = "1"){b}`, CoercionPolicy{}, 0},
+		{`(b = "true"){b}`, CoercionPolicy{}, 1},
+		{"(b = 1){b}", CoercionPolicy{}, 2},
+		{"(b = 1){b}", CoercionPolicy{StrictNumbers: true}, 1},
+		{"(b = 1.0){b}", CoercionPolicy{StrictNumbers: true}, 1},
+		{"(b > 1.0){b}", CoercionPolicy{StrictNumbers: true}, 1},
+		{"(b > 0){b}", CoercionPolicy{StrictNumbers: true}, 1},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		q.SetOptions(Options{Coercion: tt.policy})
+		if got := len(q.Match(v)); got != tt.want {
+			t.Errorf("%s with %+v matched %d values, want %d", tt.query, tt.policy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package jsonq
+
+// Collator compares strings according to the rules of a locale.
+//
+// CompareString returns an integer comparing a and b: 0 if they are equal,
+// a negative number if a sorts before b and a positive one otherwise.
+//
+// *collate.Collator from golang.org/x/text/collate satisfies Collator,
+// so accented names can be ordered per language:
+//
+//	q.SetOptions(jsonq.Options{Collator: collate.New(language.French)})
+type Collator interface {
+	CompareString(a, b string) int
+}
+
+// collate applies the ordering operations to two strings with c.
+//
+// handled is false when the operation or the operands are not concerned
+// by collation, in which case the default comparison applies.
+func (o Operation) collate(c Collator, base, compared interface{}) (ok, handled bool) {
+	b, isString := base.(string)
+	if !isString {
+		return false, false
+	}
+	v, isString := compared.(string)
+	if !isString {
+		return false, false
+	}
+	switch o {
+	case sup:
+		return c.CompareString(v, b) > 0, true
+	case supEq:
+		return c.CompareString(v, b) >= 0, true
+	case inf:
+		return c.CompareString(v, b) < 0, true
+	case infEq:
+		return c.CompareString(v, b) <= 0, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,39 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+// foldingCollator orders strings ignoring the accents of a few letters.
+type foldingCollator struct{}
+
+var accentReplacer = strings.NewReplacer("é", "e", "è", "e", "É", "E")
+
+func (foldingCollator) CompareString(a, b string) int {
+	return strings.Compare(accentReplacer.Replace(a), accentReplacer.Replace(b))
+}
+
+func TestCollator(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"name": "Émile"}, {"name": "Zoé"}, {"name": "Eva"}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("(name < F){name}")
+	if got := len(q.Match(v)); got != 1 {
+		t.Errorf("byte ordering matched %d names, want 1", got)
+	}
+	q.SetOptions(Options{Collator: foldingCollator{}})
+	if got := len(q.Match(v)); got != 2 {
+		t.Errorf("collated ordering matched %d names, want 2", got)
+	}
+	q = MustParseQuery("(name > Emile){name}")
+	if got := len(q.Match(v)); got != 3 {
+		t.Errorf("byte ordering matched %d names, want 3", got)
+	}
+	q.SetOptions(Options{Collator: foldingCollator{}})
+	if got := len(q.Match(v)); got != 2 {
+		t.Errorf("collated ordering matched %d names, want 2", got)
+	}
+}
@@ -0,0 +1,157 @@
+package jsonq
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Comment is a comment retained by ParseWithComments.
+type Comment struct {
+	// Text is the comment, delimiters included, such as "// the port".
+	Text string
+	// Path leads to the member or element the comment precedes, or to the
+	// object or array the comment closes when End is set.
+	Path Path
+	// End is set for the comments following the last member or element of
+	// an object or array, or the whole document for an empty Path.
+	End bool
+}
+
+// ParseWithComments parses s containing JSON with // line and /* block */
+// comments, as found in human-maintained configuration files, and returns
+// the comments attached to the paths of the values they precede, so a
+// program editing the configuration can restore them when writing it back.
+//
+// The returned value is valid until the next call to Parse*.
+func (p *Parser) ParseWithComments(s string) (*Value, []Comment, error) {
+	stripped, spans, err := stripComments(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := p.Parse(stripped)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(spans) == 0 {
+		return v, nil, nil
+	}
+	comments, err := attachComments(s, stripped, spans)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, comments, nil
+}
+
+// commentSpan locates a comment in a document.
+type commentSpan struct {
+	start, end int
+}
+
+// stripComments returns s with its comments replaced by spaces, so the
+// offsets of the JSON tokens are kept, and the spans of the comments.
+func stripComments(s string) (string, []commentSpan, error) {
+	var spans []commentSpan
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 == len(s) {
+				return "", nil, fmt.Errorf("%w: %q", ErrUnexpectedValue, s[i:])
+			}
+			start := i
+			switch s[i+1] {
+			case '/':
+				end := strings.IndexByte(s[i:], '\n')
+				if end < 0 {
+					end = len(s) - i
+				}
+				i += end
+			case '*':
+				end := strings.Index(s[i+2:], "*/")
+				if end < 0 {
+					return "", nil, fmt.Errorf("%w of comment", ErrUnexpectedEnd)
+				}
+				i += end + 4
+			default:
+				return "", nil, fmt.Errorf("%w: %q", ErrUnexpectedValue, s[i:i+2])
+			}
+			spans = append(spans, commentSpan{start: start, end: i})
+			i--
+		}
+	}
+	if len(spans) == 0 {
+		return s, nil, nil
+	}
+	b := []byte(s)
+	for _, span := range spans {
+		for i := span.start; i < span.end; i++ {
+			b[i] = ' '
+		}
+	}
+	return string(b), spans, nil
+}
+
+// attachComments returns the comments of s located by spans, attached to
+// the path of the token following them in stripped.
+func attachComments(s, stripped string, spans []commentSpan) ([]Comment, error) {
+	comments := make([]Comment, 0, len(spans))
+	attach := func(offset int, path Path, end bool) {
+		for len(comments) < len(spans) && spans[len(comments)].start < offset {
+			span := spans[len(comments)]
+			comments = append(comments, Comment{
+				Text: s[span.start:span.end],
+				Path: append(Path{}, path...),
+				End:  end,
+			})
+		}
+	}
+
+	t := NewTokenizer(strings.NewReader(stripped))
+	// path holds the keys of the containers of the current token; arrays
+	// hold their current index, -1 before the first element.
+	var path Path
+	var arrays []bool
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		inArray := len(arrays) > 0 && arrays[len(arrays)-1]
+		switch tok.Kind {
+		case Key:
+			path[len(path)-1] = tok.Value
+			attach(t.Offset(), path, false)
+			continue
+		case ObjectEnd, ArrayEnd:
+			attach(t.Offset(), path[:len(path)-1], true)
+			path = path[:len(path)-1]
+			arrays = arrays[:len(arrays)-1]
+			continue
+		}
+		if inArray {
+			index, _ := strconv.Atoi(path[len(path)-1])
+			path[len(path)-1] = strconv.Itoa(index + 1)
+		}
+		attach(t.Offset(), path, false)
+		switch tok.Kind {
+		case ObjectStart:
+			path = append(path, "")
+			arrays = append(arrays, false)
+		case ArrayStart:
+			path = append(path, "-1")
+			arrays = append(arrays, true)
+		}
+	}
+	attach(len(s), Path{}, true)
+	return comments, nil
+}
@@ -0,0 +1,60 @@
+package jsonq
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParserParseWithComments(t *testing.T) {
+	const config = `// server settings
+{
+	"host": "localhost", // not "//" nor "/*"
+	/* the port
+	   to listen on */
+	"port": /* default */ 8080,
+	"tags": [
+		"a", // first
+		"b"
+		// after the last tag
+	]
+	// end of server
+}
+// end of file`
+	var p Parser
+	v, comments, err := p.ParseWithComments(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.GetInt("port") != 8080 || string(v.GetStringBytes("host")) != "localhost" {
+		t.Fatalf("unexpected value: %s", v)
+	}
+	expected := []Comment{
+		{Text: "// server settings", Path: Path{}},
+		{Text: `// not "//" nor "/*"`, Path: Path{"port"}},
+		{Text: "/* the port\n\t   to listen on */", Path: Path{"port"}},
+		{Text: "/* default */", Path: Path{"port"}},
+		{Text: "// first", Path: Path{"tags", "1"}},
+		{Text: "// after the last tag", Path: Path{"tags"}, End: true},
+		{Text: "// end of server", Path: Path{}, End: true},
+		{Text: "// end of file", Path: Path{}, End: true},
+	}
+	if !reflect.DeepEqual(comments, expected) {
+		t.Fatalf("unexpected comments;\ngot\n%+v\nwant\n%+v", comments, expected)
+	}
+
+	v, comments, err = p.ParseWithComments(`{"url": "http://x/*y*/"}`)
+	if err != nil || comments != nil || string(v.GetStringBytes("url")) != "http://x/*y*/" {
+		t.Fatalf("unexpected result without comments: %s, %+v, %v", v, comments, err)
+	}
+
+	f := func(s string, expected error) {
+		t.Helper()
+		if _, _, err := p.ParseWithComments(s); !errors.Is(err, expected) {
+			t.Fatalf("expecting %q for %q; got %v", expected, s, err)
+		}
+	}
+	f(`{"a": 1} /* open`, ErrUnexpectedEnd)
+	f(`{"a": 1} /`, ErrUnexpectedValue)
+	f(`{"a": /x 1}`, ErrUnexpectedValue)
+}
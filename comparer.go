@@ -0,0 +1,93 @@
+package jsonq
+
+import (
+	"regexp"
+	"strings"
+)
+
+// StringComparer compares the strings of a document with the literals of
+// the filters. value is the string of the document and literal the one of
+// the filter.
+//
+// DefaultStringComparer holds the comparisons of the filters. Other
+// strategies, such as accent insensitive ones, embed it to change some of
+// them only, and are set with the options of a query:
+//
+//	q.SetOptions(jsonq.Options{Strings: accentInsensitive{}})
+//
+// The strict operations === and !== ignore the comparer, and a Collator,
+// when set, still orders the strings for >, >=, < and <=.
+type StringComparer interface {
+	// Compare returns 0 if value equals literal, a negative number if
+	// value sorts before it and a positive one otherwise. It serves =, !=,
+	// >, >=, < and <=.
+	Compare(value, literal string) int
+
+	// Contains reports whether value contains literal, for : and !:.
+	Contains(value, literal string) bool
+
+	// Match reports whether the regular expression pattern matches value,
+	// for :: and !::. Both fail on an error.
+	Match(value, pattern string) (bool, error)
+}
+
+// DefaultStringComparer is the StringComparer of the queries without
+// Options.Strings. It compares the bytes of the strings for equality and
+// ordering, while containment and regular expressions ignore the case and
+// the double quotes around the literal.
+type DefaultStringComparer struct{}
+
+// Compare compares the bytes of value and literal.
+func (DefaultStringComparer) Compare(value, literal string) int {
+	return strings.Compare(value, literal)
+}
+
+// Contains reports whether value contains literal, ignoring the case.
+func (DefaultStringComparer) Contains(value, literal string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(strings.Trim(literal, `"`)))
+}
+
+// Match matches value with pattern, ignoring the case.
+func (DefaultStringComparer) Match(value, pattern string) (bool, error) {
+	return regexp.MatchString(strings.ToLower(strings.Trim(pattern, `"`)), strings.ToLower(value))
+}
+
+var defaultStrings DefaultStringComparer
+
+// compareStrings applies the string operations to two strings with c.
+//
+// handled is false when the operation or the operands are not concerned,
+// in which case the default comparison applies.
+func (o Operation) compareStrings(c StringComparer, base, compared interface{}) (ok, handled bool) {
+	literal, isString := base.(string)
+	if !isString {
+		return false, false
+	}
+	value, isString := compared.(string)
+	if !isString {
+		return false, false
+	}
+	switch o {
+	case eq:
+		return c.Compare(value, literal) == 0, true
+	case diff:
+		return c.Compare(value, literal) != 0, true
+	case sup:
+		return c.Compare(value, literal) > 0, true
+	case supEq:
+		return c.Compare(value, literal) >= 0, true
+	case inf:
+		return c.Compare(value, literal) < 0, true
+	case infEq:
+		return c.Compare(value, literal) <= 0, true
+	case contain:
+		return c.Contains(value, literal), true
+	case notContain:
+		return !c.Contains(value, literal), true
+	case like, notLike:
+		matched, err := c.Match(value, literal)
+		return err == nil && matched == (o == like), true
+	default:
+		return false, false
+	}
+}
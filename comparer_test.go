@@ -0,0 +1,52 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+// accentInsensitive compares strings ignoring the accents of a few letters.
+type accentInsensitive struct {
+	DefaultStringComparer
+}
+
+func (accentInsensitive) Compare(value, literal string) int {
+	return strings.Compare(accentReplacer.Replace(value), accentReplacer.Replace(literal))
+}
+
+func (c accentInsensitive) Contains(value, literal string) bool {
+	return c.DefaultStringComparer.Contains(accentReplacer.Replace(value), accentReplacer.Replace(literal))
+}
+
+func TestStringComparer(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"name": "Émile"}, {"name": "Zoé"}, {"name": "Eva"}, {"name": "René", "n": 1}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query      string
+		byte, fold int
+	}{
+		{`(name = Zoe){name}`, 0, 1},
+		{`(name != Zoe){name}`, 4, 3},
+		{`(name < F){name}`, 1, 2},
+		{`(name : "e"){name}`, 3, 4},
+		{`(name !: "ene"){name}`, 4, 3},
+		{`(name :: "^ren"){name}`, 1, 1},
+		{`(name !:: "^e"){name}`, 3, 3},
+		{`(name !:: "("){name}`, 0, 0},
+		{`(name === Zoe){name}`, 0, 0},
+		{`(n = 1){name}`, 4, 4},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		if got := len(q.Match(v)); got != tt.byte {
+			t.Errorf("%s matched %d names, want %d", tt.query, got, tt.byte)
+		}
+		q.SetOptions(Options{Strings: accentInsensitive{}})
+		if got := len(q.Match(v)); got != tt.fold {
+			t.Errorf("%s matched %d names with the comparer, want %d", tt.query, got, tt.fold)
+		}
+	}
+}
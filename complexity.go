@@ -0,0 +1,39 @@
+package jsonq
+
+// Complexity returns a rough measure of the work q does on a document: a
+// point per level, filter, retrieved key and transformation, such as a
+// join, a lookup or an aggregate. Servers running queries from untrusted
+// clients can reject those above a budget.
+func (q *Query) Complexity() int {
+	n := 1 + len(q.filters) + len(q.retrieve) + len(q.lookups) + len(q.running) + len(q.zips) + len(q.computed) + len(q.aggregates) + len(q.counts)
+	if q.all {
+		n++
+	}
+	if q.sample != nil {
+		n++
+	}
+	if q.top != nil {
+		n++
+	}
+	if q.sorting != nil {
+		n++
+	}
+	if q.pivot != nil {
+		n++
+	}
+	if q.grouping != nil {
+		n += 1 + q.grouping.q.Complexity()
+	}
+	for _, j := range q.joins {
+		n += 1 + j.q.Complexity()
+	}
+	if q.descent != nil {
+		n += q.descent.Complexity()
+	}
+	for _, next := range q.next {
+		if next != nil {
+			n += next.Complexity()
+		}
+	}
+	return n
+}
@@ -0,0 +1,26 @@
+package jsonq
+
+import "testing"
+
+func TestQueryComplexity(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want int
+	}{
+		{"{}", 1},
+		{"{a, b}", 3},
+		{"{users{*, !password}}", 3},
+		{"(x > 1 && y < 2){a}", 4},
+		{"{users(age > 18){name, tags{label}}}", 6},
+		{"{orders{id, join(customers.id = customer_id) as customer{name}}}", 6},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.cmd)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", tt.cmd, err)
+		}
+		if got := q.Complexity(); got != tt.want {
+			t.Errorf("%q complexity = %d, want %d", tt.cmd, got, tt.want)
+		}
+	}
+}
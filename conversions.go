@@ -0,0 +1,102 @@
+package jsonq
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The conversion functions of the standard library turn raw values into
+// display ready ones. A missing or mistyped value gives null.
+
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// toBytes converts a number of bytes to unit: bytes(size, "MiB").
+func toBytes(o *Object, args []*Value) (string, error) {
+	if err := expectArgs(args, 2); err != nil {
+		return "", err
+	}
+	if args[1] == nil || args[1].Type() != TypeString {
+		return "", fmt.Errorf("expects a unit")
+	}
+	unit, ok := byteUnits[args[1].s]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", args[1].s)
+	}
+	if args[0] == nil || args[0].Type() != TypeNumber {
+		return "null", nil
+	}
+	return strconv.FormatFloat(args[0].n/unit, 'g', -1, 64), nil
+}
+
+// currency formats an amount of minor units, such as cents, as a string
+// with precision decimals and a currency code: currency(price, "EUR", 2)
+// turns 1234 into "12.34 EUR". The precision defaults to 2.
+func currency(o *Object, args []*Value) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", fmt.Errorf("expects 2 or 3 arguments, got %d", len(args))
+	}
+	if args[1] == nil || args[1].Type() != TypeString {
+		return "", fmt.Errorf("expects a currency code")
+	}
+	precision := 2
+	if len(args) == 3 {
+		if args[2] == nil || args[2].Type() != TypeNumber || args[2].n < 0 || args[2].n > 18 || args[2].n != math.Trunc(args[2].n) {
+			return "", fmt.Errorf("expects a precision between 0 and 18")
+		}
+		precision = int(args[2].n)
+	}
+	if args[0] == nil || args[0].Type() != TypeNumber {
+		return "null", nil
+	}
+	n, err := args[0].IntStrict()
+	if err != nil {
+		return "null", nil
+	}
+	amount := int64(n)
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absInt64(amount), 10)
+	if len(digits) <= precision {
+		digits = strings.Repeat("0", precision-len(digits)+1) + digits
+	}
+	if precision > 0 {
+		digits = digits[:len(digits)-precision] + "." + digits[len(digits)-precision:]
+	}
+	return strconv.Quote(sign + digits + " " + args[1].s), nil
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// rfc3339 formats Unix seconds as an RFC 3339 timestamp in UTC:
+// rfc3339(created_at).
+func rfc3339(o *Object, args []*Value) (string, error) {
+	if err := expectArgs(args, 1); err != nil {
+		return "", err
+	}
+	if args[0] == nil || args[0].Type() != TypeNumber {
+		return "null", nil
+	}
+	sec, frac := math.Modf(args[0].n)
+	t := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	return strconv.Quote(t.Format(time.RFC3339Nano)), nil
+}
@@ -0,0 +1,41 @@
+package jsonq
+
+import "testing"
+
+func TestKeepConversions(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"files": [
+		{"size": 3145728, "price": 1234, "at": 1710428966},
+		{"size": 512, "price": -5, "at": 1710428966.25},
+		{"size": "big", "price": 1.5}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{`bytes(size, "MiB")`, `{"files":[{"x":3},{"x":0.00048828125},{"x":null}]}`},
+		{`bytes(size, "KB")`, `{"files":[{"x":3145.728},{"x":0.512},{"x":null}]}`},
+		{`currency(price, "EUR")`, `{"files":[{"x":"12.34 EUR"},{"x":"-0.05 EUR"},{"x":null}]}`},
+		{`currency(price, "JPY", 0)`, `{"files":[{"x":"1234 JPY"},{"x":"-5 JPY"},{"x":null}]}`},
+		{`currency(price, "BHD", 3)`, `{"files":[{"x":"1.234 BHD"},{"x":"-0.005 BHD"},{"x":null}]}`},
+		{`rfc3339(at)`, `{"files":[{"x":"2024-03-14T15:09:26Z"},{"x":"2024-03-14T15:09:26.25Z"},{"x":null}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery("{files{x: " + tt.field + "}}"))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.field, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+
+	for _, field := range []string{`bytes(size, "MB", 2)`, `bytes(size, "parsecs")`, `currency(price, "EUR", 1.5)`, `rfc3339()`} {
+		if _, err := v.Keep(*MustParseQuery("{files{x: " + field + "}}")); err == nil {
+			t.Errorf("Keep(%s) expecting non-nil error", field)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// count counts the values of a level of an object, written in a retrieve
+// block with the level and its filters:
+//
+//	{orders{id, count(items(price > 100)) as expensive}}
+//
+// writes, for each order, the number of its items priced over 100. A
+// level that is not an array counts as one value, when it passes the
+// filters. Nothing is written when the level is missing. The result is
+// named count(items) unless an as clause names it.
+//
+// count() without level is an aggregation: orders(status = paid){count()}
+// returns the number of paid orders instead of the orders.
+type count struct {
+	as   string
+	name string
+	q    *Query
+}
+
+// parseCount parses cmd as the count of a level. It reports false when cmd
+// does not count a level.
+func parseCount(cmd string, strict bool) (*count, bool, error) {
+	if !strings.HasPrefix(cmd, "count(") {
+		return nil, false, nil
+	}
+	n, err := scanGroup(cmd[len("count"):], '(', ')')
+	if err != nil {
+		return nil, true, err
+	}
+	arg := strings.TrimSpace(cmd[len("count(") : len("count")+n-1])
+	if arg == "" {
+		return nil, false, nil
+	}
+	q, name, err := parseQuery(arg, strict)
+	if err != nil {
+		return nil, true, err
+	}
+	// A count reads nothing of the values, whatever a slice implies.
+	q.all = false
+	if !isName(name) || q.selects() || len(q.aggregates)+len(q.running) > 0 ||
+		q.sample != nil || q.top != nil || q.sorting != nil || q.pivot != nil || q.grouping != nil {
+		return nil, true, fmt.Errorf("count expects a level and its filters : %q", cmd)
+	}
+	c := &count{as: "count(" + name + ")", name: name, q: q}
+	if rest := strings.TrimSpace(cmd[len("count")+n:]); len(rest) > 0 {
+		if !strings.HasPrefix(rest, "as ") || !isName(strings.TrimSpace(rest[len("as "):])) {
+			return nil, true, fmt.Errorf("mal formated count : %q", cmd)
+		}
+		c.as = strings.TrimSpace(rest[len("as "):])
+	}
+	return c, true, nil
+}
+
+// keep returns the JSON count of the level in o. ok is false when o does
+// not have the level.
+func (c *count) keep(o *Object) (value string, ok bool) {
+	v := o.Get(c.name)
+	if v == nil {
+		return "", false
+	}
+	if v.Type() != TypeArray {
+		if c.counts(v) {
+			return "1", true
+		}
+		return "0", true
+	}
+	a := v.a
+	if c.q.slice != nil {
+		from, to := c.q.slice.bounds(len(a))
+		a = a[from:to]
+	}
+	n := 0
+	for _, element := range a {
+		if c.counts(element) {
+			n++
+		}
+	}
+	return strconv.Itoa(n), true
+}
+
+// counts reports whether v is counted: objects must pass the filters of
+// the level.
+func (c *count) counts(v *Value) bool {
+	return v.Type() != TypeObject || c.q.accept(&v.o)
+}
+
+// counter is the count() aggregation.
+type counter struct{}
+
+func parseCounter(args []string) (aggregator, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("count expects a level, or no argument")
+	}
+	return counter{}, nil
+}
+
+func (counter) aggregate(elements []*Value, opts *Options) string {
+	return strconv.Itoa(len(elements))
+}
@@ -0,0 +1,80 @@
+package jsonq
+
+import "testing"
+
+func TestKeepCount(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"orders": [
+		{"id": 1, "status": "paid", "items": [{"price": 50}, {"price": 150}, {"price": 300}], "customer": {"vip": true}},
+		{"id": 2, "status": "paid", "items": [], "customer": {"vip": false}},
+		{"id": 3, "status": "open", "items": [{"price": 120}, "gift"]},
+		{"id": 4, "status": "open"}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"{orders{count()}}", `{"orders":{"count()":4}}`},
+		{"{orders(status = paid){count() as paid}}", `{"orders":{"paid":2}}`},
+		{"{orders(status = gone){count()}}", `{"orders":{"count()":0}}`},
+		{"{count(orders)}", `{"count(orders)":4}`},
+		{"{count(orders(status = open)) as open}", `{"open":2}`},
+		{"{orders{id, count(items)}}", `{"orders":[{"id":1,"count(items)":3},{"id":2,"count(items)":0},{"id":3,"count(items)":2},{"id":4}]}`},
+		{"{orders{id, count(items(price > 100)) as expensive}}", `{"orders":[{"id":1,"expensive":2},{"id":2,"expensive":0},{"id":3,"expensive":2},{"id":4}]}`},
+		{"{orders{count(items[0:2](price > 100)) as n}}", `{"orders":[{"n":1},{"n":0},{"n":2},{}]}`},
+		{"{orders{count(customer(vip = true)) as vip}}", `{"orders":[{"vip":1},{"vip":0},{},{}]}`},
+		{"{orders(id = 1){*, !customer, count(items) as items}}", `{"orders":[{"id":1,"status":"paid","items":3}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	got, err := v.Retrieve(*MustParseQuery("{orders{id, count(items(price > 100)) as n}}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := `{"orders":[{"id":1,"n":2},{"id":2,"n":0},{"id":3,"n":2},{"id":4}]}`; got != want {
+		t.Errorf("Retrieve = %s, want %s", got, want)
+	}
+}
+
+func TestCountThreeValued(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"items": [{"price": 150}, {"name": "x"}, {"price": 50}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{count(items(price > 100)) as n}")
+	if got, _ := v.Keep(*q); got != `{"n":2}` {
+		t.Errorf("Keep = %s, want %s", got, `{"n":2}`)
+	}
+	q.SetOptions(Options{ThreeValued: true})
+	if got, _ := v.Keep(*q); got != `{"n":1}` {
+		t.Errorf("Keep with ThreeValued = %s, want %s", got, `{"n":1}`)
+	}
+}
+
+func TestParseCountErrors(t *testing.T) {
+	for _, query := range []string{
+		"{count(items{name})}",
+		"{count(items{top(1, by: price)})}",
+		"{count(items.price)}",
+		"{count(items) as}",
+		"{count(items) total}",
+		"{orders{count(x, y)}}",
+		"{orders{count(items(price >))}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
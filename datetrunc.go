@@ -0,0 +1,71 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// datetrunc truncates a timestamp to the start of its year, month, week,
+// day, hour, minute or second: datetrunc(created_at, "day"). Weeks start
+// on Monday.
+//
+// A string timestamp is read and written as RFC 3339 and keeps its
+// offset. A number is read as Unix seconds and truncated in UTC. Anything
+// else gives null.
+func datetrunc(o *Object, args []*Value) (string, error) {
+	if err := expectArgs(args, 2); err != nil {
+		return "", err
+	}
+	if args[1] == nil || args[1].Type() != TypeString {
+		return "", fmt.Errorf("expects a unit")
+	}
+	unit := args[1].s
+	ts := args[0]
+	if ts == nil {
+		return "null", nil
+	}
+	switch ts.Type() {
+	case TypeString:
+		t, err := time.Parse(time.RFC3339Nano, ts.s)
+		if err != nil {
+			return "null", nil
+		}
+		t, err = truncate(t, unit)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Quote(t.Format(time.RFC3339)), nil
+	case TypeNumber:
+		t, err := truncate(time.Unix(int64(ts.n), 0).UTC(), unit)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	default:
+		return "null", nil
+	}
+}
+
+func truncate(t time.Time, unit string) (time.Time, error) {
+	y, m, d := t.Date()
+	switch unit {
+	case "year":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location()), nil
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()), nil
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location()), nil
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location()), nil
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case "minute":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location()), nil
+	case "second":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, t.Location()), nil
+	default:
+		return t, fmt.Errorf("unknown unit %q", unit)
+	}
+}
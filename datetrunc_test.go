@@ -0,0 +1,39 @@
+package jsonq
+
+import "testing"
+
+func TestKeepDatetrunc(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"events": [
+		{"id": 1, "at": "2024-03-14T15:09:26.5+02:00"},
+		{"id": 2, "at": 1710428966},
+		{"id": 3, "at": "yesterday"},
+		{"id": 4}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		unit string
+		want string
+	}{
+		{"year", `{"events":[{"id":1,"t":"2024-01-01T00:00:00+02:00"},{"id":2,"t":1704067200},{"id":3,"t":null},{"id":4,"t":null}]}`},
+		{"week", `{"events":[{"id":1,"t":"2024-03-11T00:00:00+02:00"},{"id":2,"t":1710115200},{"id":3,"t":null},{"id":4,"t":null}]}`},
+		{"day", `{"events":[{"id":1,"t":"2024-03-14T00:00:00+02:00"},{"id":2,"t":1710374400},{"id":3,"t":null},{"id":4,"t":null}]}`},
+		{"minute", `{"events":[{"id":1,"t":"2024-03-14T15:09:00+02:00"},{"id":2,"t":1710428940},{"id":3,"t":null},{"id":4,"t":null}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(`{events{id, t: datetrunc(at, "` + tt.unit + `")}}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.unit, got, tt.want)
+		}
+	}
+
+	_, err = v.Keep(*MustParseQuery(`{events{id, t: datetrunc(at, "fortnight")}}`))
+	if pe, ok := err.(*PathError); !ok || pe.Path.String() != "events.0.t" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package jsonq
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimalPrecision is the number of decimal places of the divisions which
+// do not fall on a decimal, such as the average of 1, 1 and 2.
+const decimalPrecision = 16
+
+// maxDecimalExponent bounds the exponents of the numbers read as
+// decimals: larger ones, such as in 1e1000000, would take a lot of memory.
+const maxDecimalExponent = 400
+
+// decimal returns the number of v as an exact decimal, read from its JSON
+// token. ok is false when its exponent is out of bounds.
+func (v *Value) decimal() (r *big.Rat, ok bool) {
+	if i := strings.IndexAny(v.s, "eE"); i >= 0 {
+		if exp, err := strconv.Atoi(v.s[i+1:]); err != nil || exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return nil, false
+		}
+	}
+	if r, ok := new(big.Rat).SetString(v.s); ok {
+		return r, true
+	}
+	if math.IsInf(v.n, 0) || math.IsNaN(v.n) {
+		return nil, false
+	}
+	return floatDecimal(v.n), true
+}
+
+// floatDecimal returns the decimal of the shortest representation of the
+// finite f, so that 0.1 is 1/10 instead of the float closest to it.
+func floatDecimal(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return r
+}
+
+// formatDecimal returns the JSON number of r: exact when r is a decimal,
+// rounded to decimalPrecision places otherwise.
+func formatDecimal(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// r is a decimal when its denominator only has the factors 2 and 5,
+	// with as many places as the largest of their powers.
+	d := new(big.Int).Set(r.Denom())
+	places := decimalPrecision
+	if twos, fives := divideAll(d, 2), divideAll(d, 5); d.IsInt64() && d.Int64() == 1 {
+		places = twos
+		if fives > twos {
+			places = fives
+		}
+	}
+	return strings.TrimSuffix(strings.TrimRight(r.FloatString(places), "0"), ".")
+}
+
+// divideAll divides d by p as long as p divides it, and returns the
+// number of divisions.
+func divideAll(d *big.Int, p int64) int {
+	n := 0
+	q, m, bp := new(big.Int), new(big.Int), big.NewInt(p)
+	for {
+		q.QuoRem(d, bp, m)
+		if m.Sign() != 0 {
+			return n
+		}
+		d.Set(q)
+		n++
+	}
+}
+
+// checkDecimal applies f to the number of v as decimals. handled is false
+// when f does not compare numbers, or v is not a decimal.
+func (f Filter) checkDecimal(v *Value, opts *Options) (ok, handled bool) {
+	var literal *big.Rat
+	switch n := f.val.(type) {
+	case int64:
+		literal = new(big.Rat).SetInt64(n)
+	case *big.Rat:
+		// The value of an arithmetic expression.
+		literal = n
+	case float64:
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return false, false
+		}
+		literal = floatDecimal(n)
+	default:
+		return false, false
+	}
+	if f.coercion(opts).StrictNumbers {
+		_, isInt := v.typedNumber().(int64)
+		if _, literalInt := f.val.(int64); isInt != literalInt {
+			return f.op == notSame, true
+		}
+	}
+	d, ok := v.decimal()
+	if !ok {
+		return false, false
+	}
+	c := d.Cmp(literal)
+	switch f.op {
+	case eq, same:
+		return c == 0, true
+	case diff, notSame:
+		return c != 0, true
+	case sup:
+		return c > 0, true
+	case supEq:
+		return c >= 0, true
+	case inf:
+		return c < 0, true
+	case infEq:
+		return c <= 0, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,99 @@
+package jsonq
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDecimalFilters(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"n": 0.3}, {"n": 3e-1}, {"n": 0.30000000000000004}, {"n": 10}, {"n": 1e9999}, {"n": "0.3"}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query        string
+		float, exact int
+	}{
+		{"(n = 0.3){n}", 0, 2},
+		{"(n > 0.3){n}", 5, 3},
+		{"(n <= 0.3){n}", 0, 2},
+		{"(n != 0.3){n}", 5, 3},
+		{"(n === 10){n}", 1, 1},
+		{"(n >= 10i){n}", 2, 2},
+		{"(n > 1e300){n}", 1, 1},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		if got := len(q.Match(v)); got != tt.float {
+			t.Errorf("%s matched %d numbers, want %d", tt.query, got, tt.float)
+		}
+		q.SetOptions(Options{Decimal: true})
+		if got := len(q.Match(v)); got != tt.exact {
+			t.Errorf("%s matched %d decimals, want %d", tt.query, got, tt.exact)
+		}
+	}
+
+	q := MustParseQuery("(n = 10.0){n}")
+	q.SetOptions(Options{Decimal: true, Coercion: CoercionPolicy{StrictNumbers: true}})
+	if got := len(q.Match(v)); got != 0 {
+		t.Errorf("strict numbers matched %d decimals, want 0", got)
+	}
+}
+
+func TestDecimalAggregations(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"lines": [{"amount": 0.1}, {"amount": 0.2}, {"amount": 0.10}, {"amount": "x"}, {"amount": 1E+2}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{lines{sum(amount), avg(amount), min(amount), max(amount)}}")
+	got, err := v.Keep(*q)
+	if want := `{"lines":{"sum(amount)":100.4,"avg(amount)":25.1,"min(amount)":0.1,"max(amount)":100}}`; err != nil || got != want {
+		t.Errorf("Keep = %s, %v, want %s", got, err, want)
+	}
+	q.SetOptions(Options{Decimal: true})
+	got, err = v.Keep(*q)
+	if want := `{"lines":{"sum(amount)":100.4,"avg(amount)":25.1,"min(amount)":0.1,"max(amount)":100}}`; err != nil || got != want {
+		t.Errorf("Keep with decimals = %s, %v, want %s", got, err, want)
+	}
+
+	q = MustParseQuery("{lines(amount < 1){sum(amount), avg(amount)}}")
+	q.SetOptions(Options{Decimal: true})
+	got, err = v.Keep(*q)
+	if want := `{"lines":{"sum(amount)":0.4,"avg(amount)":0.1333333333333333}}`; err != nil || got != want {
+		t.Errorf("Keep with decimals = %s, %v, want %s", got, err, want)
+	}
+
+	v, err = p.Parse(`{"lines": [{"amount": 0.1}, {"amount": 0.2}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q = MustParseQuery("{lines{sum(amount)}}")
+	if got, err := v.Keep(*q); err != nil || got != `{"lines":{"sum(amount)":0.30000000000000004}}` {
+		t.Errorf("Keep = %s, %v", got, err)
+	}
+	q.SetOptions(Options{Decimal: true})
+	if got, err := v.Keep(*q); err != nil || got != `{"lines":{"sum(amount)":0.3}}` {
+		t.Errorf("Keep with decimals = %s, %v", got, err)
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		r    string
+		want string
+	}{
+		{"3", "3"},
+		{"-7/4", "-1.75"},
+		{"1/3", "0.3333333333333333"},
+		{"2/3", "0.6666666666666667"},
+		{"1/1024", "0.0009765625"},
+		{"3/50", "0.06"},
+	} {
+		r, _ := new(big.Rat).SetString(tt.r)
+		if got := formatDecimal(r); got != tt.want {
+			t.Errorf("formatDecimal(%s) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseDescent parses the level ** of a retrieve block, which selects keys
+// at any depth : {**{price}} collects every price of the document, like
+// the JSONPath $..price. ok is false for the other attributes.
+func (q *Query) parseDescent(attr string, strict bool) (ok bool, err error) {
+	if !strings.HasPrefix(attr, "**") {
+		return false, nil
+	}
+	d, name, err := parseQuery(attr[2:], strict)
+	if err != nil {
+		return true, err
+	}
+	if name != "" || !d.selects() {
+		return true, fmt.Errorf("mal formated descent : %q", attr)
+	}
+	if len(d.joins)+len(d.lookups)+len(d.zips)+len(d.computed)+len(d.counts)+len(d.aggregates)+len(d.running) > 0 ||
+		d.sample != nil || d.top != nil || d.sorting != nil || d.pivot != nil || d.grouping != nil {
+		return true, fmt.Errorf("a descent only selects keys and levels : %q", attr)
+	}
+	if q.descent != nil {
+		q.descent.merge(d)
+	} else {
+		q.descent = d
+	}
+	return true, nil
+}
+
+// collects reports whether the descent level d selects the key.
+func (d *Query) collects(key string) bool {
+	return d.all || d.retrieves(key) || d.produces(key)
+}
+
+// collection holds the values collected by a descent, by key.
+type collection struct {
+	keys   []string
+	values map[string][]string
+}
+
+func (c *collection) add(key, value string) {
+	if _, ok := c.values[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.values[key] = append(c.values[key], value)
+}
+
+// descend returns the values selected by the descent level d in v and all
+// its descendants, in document order. The objects rejected by the filters
+// of d are still searched.
+func (d *Query) descend(v *Value, path Path, e *execution) (*collection, error) {
+	c := &collection{values: map[string][]string{}}
+	return c, d.collect(v, path, e, c)
+}
+
+func (d *Query) collect(v *Value, path Path, e *execution, c *collection) error {
+	switch v.Type() {
+	case TypeArray:
+		for index, uValue := range v.a {
+			if err := d.collect(uValue, path.child(strconv.Itoa(index)), e, c); err != nil {
+				return err
+			}
+		}
+	case TypeObject:
+		o := &v.o
+		if d.accept(o) {
+			d.eachField(o, func(key string, val *Value) {
+				c.add(key, val.raw())
+			})
+			for _, name := range d.levelNames() {
+				if o.Get(name) == nil {
+					continue
+				}
+				value, ok, err := d.keepLevel(o, name, path, e)
+				if err != nil {
+					return err
+				}
+				if ok {
+					c.add(name, value)
+				}
+			}
+		}
+		o.unescapeKeys()
+		for _, kv := range o.kvs {
+			if err := d.collect(kv.v, path.child(kv.k), e, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDescent writes the values collected by the descent of the request
+// from v, an array per key.
+func (request Query) writeDescent(w *bytes.Buffer, first bool, v *Value, path Path, e *execution) error {
+	c, err := request.descent.descend(v, path, e)
+	if err != nil {
+		return err
+	}
+	for _, key := range c.keys {
+		writeField(w, first, key, "["+strings.Join(c.values[key], ",")+"]")
+		first = false
+	}
+	return nil
+}
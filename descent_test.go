@@ -0,0 +1,44 @@
+package jsonq
+
+import "testing"
+
+func TestKeepDescent(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"price": 1, "order": {"items": [{"price": 2, "currency": "EUR", "product": {"name": "a", "price": 3}}, {"price": 4, "currency": "USD"}]}, "total": 10}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{**{price}}", `{"price":[1,2,3,4]}`},
+		{"{total, **{price, currency}}", `{"total":10,"price":[1,2,3,4],"currency":["EUR","USD"]}`},
+		{"{**(currency = EUR){price}}", `{"price":[1,2,3]}`},
+		{"{**{product{name}}}", `{"product":[{"name":"a"}]}`},
+		{"{order{**{name}}}", `{"order":{"name":["a"]}}`},
+		{"{*, **{price}}", `{"order":{"items":[{"price":2,"currency":"EUR","product":{"name":"a","price":3}},{"price":4,"currency":"USD"}]},"total":10,"price":[1,2,3,4]}`},
+		{"{**{missing}}", `{}`},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.cmd)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) unexpected error: %s", tt.cmd, err)
+		}
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestParseDescentErrors(t *testing.T) {
+	for _, cmd := range []string{"{**}", "{**(a = 1)}", "{**price}", "{**{a, sample(2)}}", "{price, **{price}}"} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting an error", cmd)
+		}
+	}
+}
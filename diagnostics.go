@@ -0,0 +1,95 @@
+package jsonq
+
+import (
+	"sort"
+	"sync"
+)
+
+// Diagnostic describes filters that compared values of incompatible types.
+type Diagnostic struct {
+	// Path is the filtered key, from the root of the query.
+	Path Path
+	// Want is the type of the filter value.
+	Want Type
+	// Got is the type found in the document.
+	Got Type
+	// Count is the number of comparisons that hit the mismatch.
+	Count int
+}
+
+type diagnosticKey struct {
+	path string
+	want Type
+	got  Type
+}
+
+// Diagnostics collects type mismatches found while executing queries.
+//
+// A filter comparing a number literal to a string field, or a string
+// literal to a number field, can never match. Diagnostics surfaces these
+// cases instead of letting them silently filter everything out.
+// Filters skipped because a previous filter of the level already failed
+// are not observed.
+//
+// Diagnostics may be shared by concurrent executions.
+type Diagnostics struct {
+	mu    sync.Mutex
+	items map[diagnosticKey]*Diagnostic
+}
+
+func (d *Diagnostics) observe(path Path, val interface{}, v *Value) {
+	var want, got Type
+	switch val.(type) {
+	case int64, float64:
+		want = TypeNumber
+	case string:
+		want = TypeString
+	default:
+		return
+	}
+	switch got = v.Type(); got {
+	case TypeNumber, TypeString:
+		if got == want {
+			return
+		}
+	default:
+		return
+	}
+
+	key := diagnosticKey{path.String(), want, got}
+	d.mu.Lock()
+	if d.items == nil {
+		d.items = map[diagnosticKey]*Diagnostic{}
+	}
+	item := d.items[key]
+	if item == nil {
+		item = &Diagnostic{Path: path, Want: want, Got: got}
+		d.items[key] = item
+	}
+	item.Count++
+	d.mu.Unlock()
+}
+
+// List returns the recorded diagnostics sorted by path.
+func (d *Diagnostics) List() []Diagnostic {
+	d.mu.Lock()
+	list := make([]Diagnostic, 0, len(d.items))
+	for _, item := range d.items {
+		list = append(list, *item)
+	}
+	d.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if pi, pj := list[i].Path.String(), list[j].Path.String(); pi != pj {
+			return pi < pj
+		}
+		return list[i].Want < list[j].Want
+	})
+	return list
+}
+
+// Reset forgets the recorded diagnostics.
+func (d *Diagnostics) Reset() {
+	d.mu.Lock()
+	d.items = nil
+	d.mu.Unlock()
+}
@@ -0,0 +1,31 @@
+package jsonq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"id": "1", "age": 20}, {"id": "2", "age": "21"}, {"id": 3, "age": 22}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{users(age > 18 && id = 1){id}}")
+	var d Diagnostics
+	q.SetOptions(Options{Diagnostics: &d})
+	q.Match(v)
+
+	want := []Diagnostic{
+		{Path: Path{"users", "age"}, Want: TypeNumber, Got: TypeString, Count: 1},
+		{Path: Path{"users", "id"}, Want: TypeNumber, Got: TypeString, Count: 1},
+	}
+	if got := d.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+
+	d.Reset()
+	if got := d.List(); len(got) != 0 {
+		t.Errorf("List() after Reset = %+v, want nothing", got)
+	}
+}
@@ -0,0 +1,49 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// distinct is the aggregation of the different values of a field:
+// users{distinct(country)} returns the array of the countries of the
+// users, each once, in the order they first appear. Values equal in
+// canonical form, such as 1 and 1.0, are the same. Elements missing the
+// field are left out.
+type distinct struct {
+	field Path
+}
+
+func parseDistinct(args []string) (aggregator, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("distinct expects a field")
+	}
+	field, err := parseField(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return &distinct{field: field}, nil
+}
+
+func (d *distinct) aggregate(elements []*Value, opts *Options) string {
+	seen := map[string]struct{}{}
+	w := bytes.Buffer{}
+	w.WriteRune('[')
+	for _, element := range elements {
+		v := element.Get(d.field...)
+		if v == nil {
+			continue
+		}
+		key := string(v.AppendCanonical(nil))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		if len(seen) > 1 {
+			w.WriteRune(',')
+		}
+		w.WriteString(v.raw())
+	}
+	w.WriteRune(']')
+	return w.String()
+}
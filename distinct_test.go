@@ -0,0 +1,43 @@
+package jsonq
+
+import "testing"
+
+func TestKeepDistinct(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [
+		{"name": "Al", "country": "FR", "age": 30, "address": {"city": "Paris"}},
+		{"name": "Bo", "country": "US", "age": 30.0},
+		{"name": "Cy", "country": "FR", "age": 41, "address": {"city": "Lyon"}},
+		{"name": "Di", "age": null},
+		{"name": "Ed", "country": "DE", "address": {"city": "Paris"}}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{users{distinct(country)}}`, `{"users":{"distinct(country)":["FR","US","DE"]}}`},
+		{`{users{distinct(age) as ages, count()}}`, `{"users":{"ages":[30,41,null],"count()":5}}`},
+		{`{users{distinct(address.city)}}`, `{"users":{"distinct(address.city)":["Paris","Lyon"]}}`},
+		{`{users(age > 40){distinct(country)}}`, `{"users":{"distinct(country)":["FR","DE"]}}`},
+		{`{users(name = Zo){distinct(country)}}`, `{"users":{"distinct(country)":[]}}`},
+		{`{users{group_by(country){distinct(age)}}}`, `{"users":{"FR":{"distinct(age)":[30,41]},"US":{"distinct(age)":[30.0]},"null":{"distinct(age)":[null]},"DE":{"distinct(age)":[]}}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"{users{distinct()}}", "{users{distinct(a, b)}}", "{users{distinct(a..b)}}"} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
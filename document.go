@@ -0,0 +1,72 @@
+package jsonq
+
+// Document is a parsed JSON document that may be read from concurrent
+// goroutines.
+//
+// Values parsed by a Parser are only valid until its next Parse, and
+// finish decoding their strings, numbers and keys lazily, on first access,
+// so they cannot be shared between goroutines. A Document owns its buffer
+// and decodes everything when it is built, so it stays valid and is never
+// modified afterwards: Get, Keep, Retrieve and Check may be called
+// concurrently, without a parser per goroutine.
+type Document struct {
+	p Parser
+	v *Value
+}
+
+// NewDocument parses data into a Document. data is copied.
+func NewDocument(data []byte) (*Document, error) {
+	d := &Document{}
+	v, err := d.p.ParseBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	freeze(v)
+	d.v = v
+	return d, nil
+}
+
+// freeze decodes v and all its values, so that reading them no longer
+// modifies them.
+func freeze(v *Value) {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			freeze(kv.v)
+		}
+	case TypeArray:
+		for _, e := range v.a {
+			freeze(e)
+		}
+	}
+}
+
+// Value returns the root of d. It must not be modified, by Interpolate or
+// ResolveRefs for instance.
+func (d *Document) Value() *Value {
+	return d.v
+}
+
+// Get returns the value at the keys path of d, or nil. See Value.Get.
+func (d *Document) Get(keys ...string) *Value {
+	return d.v.Get(keys...)
+}
+
+// Keep returns the JSON of the parts of d selected by the request. See
+// Value.Keep.
+func (d *Document) Keep(request Query) (string, error) {
+	return d.v.Keep(request)
+}
+
+// Retrieve is Keep without the filters of the root level of the request.
+// See Value.Retrieve.
+func (d *Document) Retrieve(request Query) (string, error) {
+	return d.v.Retrieve(request)
+}
+
+// Check returns an error when d does not match the request. See
+// Value.Check.
+func (d *Document) Check(request Query) error {
+	return d.v.Check(request)
+}
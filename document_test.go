@@ -0,0 +1,42 @@
+package jsonq
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDocumentConcurrent(t *testing.T) {
+	data := []byte(`{"users": [{"id": 1, "name": "Al", "score": 1.5e1}, {"id": 2, "name": "Bo", "score": 3}]}`)
+	d, err := NewDocument(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := range data {
+		data[i] = ' '
+	}
+	q := MustParseQuery("{users(score > 10){name}}")
+	const want = `{"users":[{"name":"Al"}]}`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got, err := d.Keep(*q); err != nil || got != want {
+					t.Errorf("Keep() = %s, %v, want %s", got, err, want)
+					return
+				}
+				if got := string(d.Get("users", "0", "name").GetStringBytes()); got != "Al" {
+					t.Errorf("Get() = %s, want Al", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := NewDocument([]byte(`{"a": }`)); err == nil {
+		t.Errorf("expecting non-nil error")
+	}
+}
@@ -0,0 +1,57 @@
+package jsonq
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors of the parser and of the getters wrap these sentinels, so
+// callers may branch on them with errors.Is.
+var (
+	// ErrUnexpectedTail is returned when data follows the parsed JSON.
+	ErrUnexpectedTail = errors.New("unexpected tail")
+	// ErrMissingColon is returned when an object key isn't followed by ':'.
+	ErrMissingColon = errors.New("missing ':' after object key")
+	// ErrMissingComma is returned when an array or object value isn't
+	// followed by ',' or by the end of the array or object.
+	ErrMissingComma = errors.New("missing ','")
+	// ErrUnexpectedEnd is returned when the JSON ends before its last
+	// array, object or string is closed.
+	ErrUnexpectedEnd = errors.New("unexpected end")
+	// ErrUnexpectedValue is returned when a value is not valid JSON.
+	ErrUnexpectedValue = errors.New("unexpected value found")
+	// ErrKeyNotFound is returned when a key is missing from an object.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrOverflow is returned when a number doesn't fit the requested type.
+	ErrOverflow = errors.New("overflows int")
+	// ErrNotInteger is returned when an integer is requested from a number
+	// with a fractional part.
+	ErrNotInteger = errors.New("isn't an integer")
+	// ErrUnknownType is returned when a value has none of the JSON types.
+	ErrUnknownType = errors.New("type not recognized")
+	// ErrNoMatch is returned by Check when the value doesn't match the
+	// filters of the query.
+	ErrNoMatch = errors.New("no match")
+)
+
+// ErrWrongType is returned when a value isn't of the requested type. Use
+// errors.As to get it.
+//
+// Want is TypeTrue when a bool is requested.
+type ErrWrongType struct {
+	// Path leads to the value, when known.
+	Path Path
+	Want Type
+	Got  Type
+}
+
+func (e *ErrWrongType) Error() string {
+	want := e.Want.String()
+	if e.Want == TypeTrue || e.Want == TypeFalse {
+		want = "bool"
+	}
+	if len(e.Path) > 0 {
+		return fmt.Sprintf("value at %s doesn't contain %s; it contains %s", e.Path, want, e.Got)
+	}
+	return fmt.Sprintf("value doesn't contain %s; it contains %s", want, e.Got)
+}
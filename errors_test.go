@@ -0,0 +1,90 @@
+package jsonq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{`{"a": 1} x`, ErrUnexpectedTail},
+		{`{"a" 1}`, ErrMissingColon},
+		{`{"a": [1 2]}`, ErrMissingComma},
+		{`{"a": 1 "b": 2}`, ErrMissingComma},
+		{`[1, 2`, ErrUnexpectedEnd},
+		{`{"a": tru}`, ErrUnexpectedValue},
+	}
+	var p Parser
+	for _, tt := range tests {
+		_, err := p.Parse(tt.in)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%s) error = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestValueErrors(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"s": "x", "big": 1e30, "f": 1.5, "b": true}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	var wt *ErrWrongType
+	if _, err := v.Get("s").Int(); !errors.As(err, &wt) || wt.Want != TypeNumber || wt.Got != TypeString {
+		t.Errorf("Int() error = %#v", err)
+	}
+	if _, err := v.Get("f").Bool(); !errors.As(err, &wt) || err.Error() != "value doesn't contain bool; it contains number" {
+		t.Errorf("Bool() error = %v", err)
+	}
+	if _, err := v.Get("b").Array(); !errors.As(err, &wt) || wt.Got != TypeTrue {
+		t.Errorf("Array() error = %#v", err)
+	}
+	if _, err := v.Get("big").Int(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Int() error = %v", err)
+	}
+	if _, err := v.Get("f").IntStrict(); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("IntStrict() error = %v", err)
+	}
+	if _, err := v.Search("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Search() error = %v", err)
+	}
+
+	q := MustParseQuery("{missing{x}}")
+	q.SetOptions(Options{Scalars: ScalarError})
+	if _, err := v.Keep(*q); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Keep() error = %v", err)
+	}
+}
+
+func TestErrorPaths(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": {"s": "x"}, "n": [1, 2], "o": [{"x": 1}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	var wt *ErrWrongType
+	if err := v.SetPath(Path{"a", "s", "k"}, valueNull); !errors.As(err, &wt) || wt.Path.String() != (Path{"a", "s"}).String() || wt.Got != TypeString {
+		t.Errorf("SetPath() error = %#v", err)
+	}
+	var pp Parser
+	patch, err := pp.Parse(`[{"op": "remove", "path": "/n/0"}, 1]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if _, err := ApplyPatch(v, patch); !errors.As(err, &wt) || wt.Path.String() != (Path{"1"}).String() || err.Error() != "value at 1 doesn't contain object; it contains number" {
+		t.Errorf("ApplyPatch() error = %v", err)
+	}
+
+	if err := v.Check(*MustParseQuery("(n = 3){n}")); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := v.Get("o").Check(*MustParseQuery("(x = 3){x}")); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Check() on an array error = %v", err)
+	}
+	if _, err := (Value{t: Type(-1)}).Keep(*MustParseQuery("{x}")); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Keep() on an unknown type error = %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package jsonq
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// execution holds the state of a single Keep or Retrieve call.
+type execution struct {
+	root       *Value
+	bestEffort bool
+	errors     []*PathError
+	joins      map[*join]map[string][]*Value
+
+	unique bool
+	seen   map[[sha256.Size]byte]struct{}
+	p      Parser
+	buf    []byte
+
+	seed int64
+	r    *rand.Rand
+
+	request Query
+	maxSize int
+	// start is the time the execution started, when it is audited.
+	start time.Time
+}
+
+func newExecution(request Query, root *Value) *execution {
+	e := &execution{
+		root:       root,
+		bestEffort: request.opts.BestEffort,
+		unique:     request.opts.Unique,
+		seed:       request.opts.Seed,
+		request:    request,
+		maxSize:    request.opts.MaxResultSize,
+	}
+	if request.opts.Audit != nil {
+		e.start = time.Now()
+	}
+	return e
+}
+
+// rand returns the source of the random choices of the execution.
+func (e *execution) rand() *rand.Rand {
+	if e.r == nil {
+		seed := e.seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		e.r = rand.New(rand.NewSource(seed))
+	}
+	return e.r
+}
+
+// duplicate reports whether an array element with the same canonical form
+// as output was already emitted during the execution.
+func (e *execution) duplicate(output string) bool {
+	if !e.unique {
+		return false
+	}
+	v, err := e.p.Parse(output)
+	if err != nil {
+		return false
+	}
+	e.buf = v.AppendCanonical(e.buf[:0])
+	sum := sha256.Sum256(e.buf)
+	if _, ok := e.seen[sum]; ok {
+		return true
+	}
+	if e.seen == nil {
+		e.seen = map[[sha256.Size]byte]struct{}{}
+	}
+	e.seen[sum] = struct{}{}
+	return false
+}
+
+// ErrResultTooLarge is returned by Keep and Retrieve when their output
+// would be larger than Options.MaxResultSize.
+var ErrResultTooLarge = errors.New("result too large")
+
+// limit returns ErrResultTooLarge when an output of size bytes is too
+// large.
+func (e *execution) limit(size int) error {
+	if e.maxSize > 0 && size > e.maxSize {
+		return ErrResultTooLarge
+	}
+	return nil
+}
+
+// fail reports err for the part of the document at path. In best effort
+// mode, err is recorded and nil is returned so the execution goes on.
+func (e *execution) fail(path Path, err error) error {
+	if !e.bestEffort {
+		return &PathError{Path: path, Err: err}
+	}
+	e.errors = append(e.errors, &PathError{Path: path, Err: err})
+	return nil
+}
+
+// result returns the output of the execution, along with the errors
+// recorded in best effort mode. In stats mode, the output is replaced by
+// the summary of its fields.
+func (e *execution) result(output string, err error) (string, error) {
+	if err == nil && e.request.opts.Stats {
+		output, err = summarize(e.request, output)
+	}
+	if err == nil && len(e.errors) > 0 {
+		err = &PartialError{Errors: e.errors}
+	}
+	e.audit(output, err)
+	return output, err
+}
+
+// PathError is an error on a part of a JSON document.
+type PathError struct {
+	// Path leads to the failing part of the document.
+	Path Path
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned along with the output of a best effort
+// execution when parts of the document failed and were left out.
+type PartialError struct {
+	Errors []*PathError
+}
+
+func (e *PartialError) Error() string {
+	var bb bytes.Buffer
+	fmt.Fprintf(&bb, "%d parts of the document failed", len(e.Errors))
+	for _, err := range e.Errors {
+		fmt.Fprintf(&bb, "; %s: %s", err.Path, err.Err)
+	}
+	return bb.String()
+}
@@ -0,0 +1,219 @@
+package jsonq
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteCSV writes v to w as CSV records separated by comma, such as ',' or
+// '\t' for TSV.
+//
+// An array of objects gives a header of their keys, in the order they are
+// first found, then one record per object. An object is a single record,
+// and an array of scalars a single "value" column. Strings are written
+// unquoted, null as an empty field, and nested objects and arrays as JSON.
+func WriteCSV(w io.Writer, v *Value, comma rune) error {
+	header, rows := tableRows(v)
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteTable writes v to w as a text table with aligned columns. Its rows
+// are the CSV records of WriteCSV.
+func WriteTable(w io.Writer, v *Value) error {
+	header, rows := tableRows(v)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				io.WriteString(tw, "\t")
+			}
+			// Tabs and newlines would break the alignment.
+			io.WriteString(tw, strings.NewReplacer("\t", " ", "\n", " ").Replace(cell))
+		}
+		io.WriteString(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+// WriteRaw writes the scalars of v to w, one per line and in document
+// order, strings unquoted, so they can be piped to shell commands.
+func WriteRaw(w io.Writer, v *Value) error {
+	bw := bufio.NewWriter(w)
+	writeRaw(bw, v)
+	return bw.Flush()
+}
+
+func writeRaw(w *bufio.Writer, v *Value) {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			writeRaw(w, kv.v)
+		}
+	case TypeArray:
+		for _, e := range v.a {
+			writeRaw(w, e)
+		}
+	default:
+		w.WriteString(cell(v))
+		w.WriteByte('\n')
+	}
+}
+
+// WriteYAML writes v to w as a YAML document in block style. Strings are
+// double quoted, which YAML reads as JSON strings.
+func WriteYAML(w io.Writer, v *Value) error {
+	bw := bufio.NewWriter(w)
+	writeYAML(bw, v, 0)
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+func writeYAML(w *bufio.Writer, v *Value, indent int) {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		if len(v.o.kvs) == 0 {
+			w.WriteString("{}")
+			return
+		}
+		for i, kv := range v.o.kvs {
+			if i > 0 {
+				w.WriteByte('\n')
+				w.WriteString(strings.Repeat("  ", indent))
+			}
+			w.WriteString(yamlKey(kv.k))
+			w.WriteByte(':')
+			writeYAMLChild(w, kv.v, indent)
+		}
+	case TypeArray:
+		if len(v.a) == 0 {
+			w.WriteString("[]")
+			return
+		}
+		for i, e := range v.a {
+			if i > 0 {
+				w.WriteByte('\n')
+				w.WriteString(strings.Repeat("  ", indent))
+			}
+			w.WriteString("- ")
+			writeYAML(w, e, indent+1)
+		}
+	case TypeString:
+		w.Write(appendQuoted(nil, v.s))
+	default:
+		w.WriteString(v.String())
+	}
+}
+
+// writeYAMLChild writes the value of an object member, on the next lines
+// when it is a non-empty object or array.
+func writeYAMLChild(w *bufio.Writer, v *Value, indent int) {
+	nested := v.Type() == TypeObject && len(v.o.kvs) > 0 || v.Type() == TypeArray && len(v.a) > 0
+	if !nested {
+		w.WriteByte(' ')
+		writeYAML(w, v, indent)
+		return
+	}
+	w.WriteByte('\n')
+	w.WriteString(strings.Repeat("  ", indent+1))
+	writeYAML(w, v, indent+1)
+}
+
+// yamlKey returns key as a plain YAML scalar when it is safe, quoted
+// otherwise.
+func yamlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0 || c == '-' && i > 0) {
+			return string(appendQuoted(nil, key))
+		}
+	}
+	switch strings.ToLower(key) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+		return string(appendQuoted(nil, key))
+	}
+	return key
+}
+
+// tableRows returns the header and rows of v as a table: see WriteCSV.
+func tableRows(v *Value) ([]string, [][]string) {
+	var elements []*Value
+	switch v.Type() {
+	case TypeArray:
+		elements = v.a
+	default:
+		elements = []*Value{v}
+	}
+
+	var header []string
+	columns := map[string]int{}
+	scalars := false
+	for _, e := range elements {
+		if e.Type() != TypeObject {
+			scalars = true
+			continue
+		}
+		e.o.unescapeKeys()
+		for _, kv := range e.o.kvs {
+			if _, ok := columns[kv.k]; !ok {
+				columns[kv.k] = len(header)
+				header = append(header, kv.k)
+			}
+		}
+	}
+	valueColumn := -1
+	if scalars {
+		if _, ok := columns["value"]; !ok {
+			columns["value"] = len(header)
+			header = append(header, "value")
+		}
+		valueColumn = columns["value"]
+	}
+
+	rows := make([][]string, len(elements))
+	for i, e := range elements {
+		row := make([]string, len(header))
+		if e.Type() == TypeObject {
+			for _, kv := range e.o.kvs {
+				row[columns[kv.k]] = cell(kv.v)
+			}
+		} else {
+			row[valueColumn] = cell(e)
+		}
+		rows[i] = row
+	}
+	return header, rows
+}
+
+// cell returns the text of v in a table cell or a raw output line.
+func cell(v *Value) string {
+	switch v.Type() {
+	case TypeString:
+		return v.s
+	case TypeNull:
+		return ""
+	case TypeNumber:
+		if len(v.s) > 0 {
+			return v.s
+		}
+		return strconv.FormatFloat(v.n, 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,71 @@
+package jsonq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testExport(t *testing.T, write func(v *Value) (string, error), s, expected string) {
+	t.Helper()
+	var p Parser
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", s, err)
+	}
+	out, err := write(v)
+	if err != nil {
+		t.Fatalf("unexpected error when exporting %q: %s", s, err)
+	}
+	if out != expected {
+		t.Fatalf("unexpected export of %q;\ngot\n%s\nwant\n%s", s, out, expected)
+	}
+}
+
+const exportFixture = `[{"name":"Al","age":30,"tags":["a"]},{"age":null,"name":"B,o","city":"Lyon"},7]`
+
+func TestWriteCSV(t *testing.T) {
+	f := func(s string, comma rune, expected string) {
+		t.Helper()
+		testExport(t, func(v *Value) (string, error) {
+			var b bytes.Buffer
+			err := WriteCSV(&b, v, comma)
+			return b.String(), err
+		}, s, expected)
+	}
+	f(exportFixture, ',', "name,age,tags,city,value\nAl,30,\"[\"\"a\"\"]\",,\n\"B,o\",,,Lyon,\n,,,,7\n")
+	f(exportFixture, '\t', "name\tage\ttags\tcity\tvalue\nAl\t30\t\"[\"\"a\"\"]\"\t\t\nB,o\t\t\tLyon\t\n\t\t\t\t7\n")
+	f(`{"a":true,"b":"x"}`, ',', "a,b\ntrue,x\n")
+	f(`["x","y"]`, ',', "value\nx\ny\n")
+}
+
+func TestWriteTable(t *testing.T) {
+	testExport(t, func(v *Value) (string, error) {
+		var b bytes.Buffer
+		err := WriteTable(&b, v)
+		return b.String(), err
+	}, `[{"name":"Al","age":30},{"name":"Bob\tby","age":4}]`, "name    age\nAl      30\nBob by  4\n")
+}
+
+func TestWriteRaw(t *testing.T) {
+	testExport(t, func(v *Value) (string, error) {
+		var b bytes.Buffer
+		err := WriteRaw(&b, v)
+		return b.String(), err
+	}, `{"a":["x y",1.50],"b":{"c":null,"d":false}}`, "x y\n1.50\n\nfalse\n")
+}
+
+func TestWriteYAML(t *testing.T) {
+	f := func(s, expected string) {
+		t.Helper()
+		testExport(t, func(v *Value) (string, error) {
+			var b bytes.Buffer
+			err := WriteYAML(&b, v)
+			return b.String(), err
+		}, s, expected)
+	}
+	f(`1`, "1\n")
+	f(`{}`, "{}\n")
+	f(`{"name":"Al","empty":[],"yes":true,"a b":null}`, "name: \"Al\"\nempty: []\n\"yes\": true\n\"a b\": null\n")
+	f(`{"users":[{"name":"Al","tags":["a","b"]},[1,2]],"meta":{"n":2}}`,
+		"users:\n  - name: \"Al\"\n    tags:\n      - \"a\"\n      - \"b\"\n  - - 1\n    - 2\nmeta:\n  \"n\": 2\n")
+}
@@ -0,0 +1,255 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type exprOp int8
+
+const (
+	exprFilter exprOp = iota
+	exprAnd
+	exprOr
+)
+
+// filterExpr is the boolean expression combining the filters of a query,
+// such as (a > 1 && b < 2) || c = x. Its leaves are the indexes of the
+// filters in Query.filters.
+type filterExpr struct {
+	op     exprOp
+	filter int
+	args   []*filterExpr
+}
+
+// String returns e with the indexes of its filters, such as (0 && 1) || 2.
+func (e *filterExpr) String() string {
+	if e.op == exprFilter {
+		return strconv.Itoa(e.filter)
+	}
+	sep := " && "
+	if e.op == exprOr {
+		sep = " || "
+	}
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg.String()
+		if arg.op != exprFilter {
+			args[i] = "(" + args[i] + ")"
+		}
+	}
+	return strings.Join(args, sep)
+}
+
+// eval evaluates e given the value of each filter : ok is false when its
+// key is missing.
+//
+// present is false when the keys of all the filters of e are missing : e
+// is then ignored by the enclosing expression, unless threeValued is set
+// where missing keys evaluate to unknown.
+func (e *filterExpr) eval(threeValued bool, value func(i int) (t truth, ok bool)) (t truth, present bool) {
+	switch e.op {
+	case exprFilter:
+		t, ok := value(e.filter)
+		if !ok {
+			return truthUnknown, threeValued
+		}
+		return t, true
+	case exprAnd:
+		t = truthTrue
+		for _, arg := range e.args {
+			at, ok := arg.eval(threeValued, value)
+			if !ok {
+				continue
+			}
+			t, present = t.and(at), true
+			if t == truthFalse {
+				break
+			}
+		}
+	case exprOr:
+		t = truthFalse
+		for _, arg := range e.args {
+			at, ok := arg.eval(threeValued, value)
+			if !ok {
+				continue
+			}
+			t, present = t.or(at), true
+			if t == truthTrue {
+				break
+			}
+		}
+	}
+	return t, present
+}
+
+// shift returns e with the indexes of its filters increased by n.
+func (e *filterExpr) shift(n int) *filterExpr {
+	s := &filterExpr{op: e.op, filter: e.filter + n}
+	for _, arg := range e.args {
+		s.args = append(s.args, arg.shift(n))
+	}
+	return s
+}
+
+// andExpr returns a && b, where nil stands for an empty expression.
+func andExpr(a, b *filterExpr) *filterExpr {
+	return joinExpr(exprAnd, a, b)
+}
+
+// orExpr returns a || b, where nil stands for an empty expression.
+func orExpr(a, b *filterExpr) *filterExpr {
+	return joinExpr(exprOr, a, b)
+}
+
+func joinExpr(op exprOp, a, b *filterExpr) *filterExpr {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	e := &filterExpr{op: op}
+	for _, arg := range []*filterExpr{a, b} {
+		if arg.op == op {
+			e.args = append(e.args, arg.args...)
+		} else {
+			e.args = append(e.args, arg)
+		}
+	}
+	return e
+}
+
+// condition returns the expression combining the filters of q, which are
+// all required when q was not parsed.
+func (q Query) condition() *filterExpr {
+	if q.expr != nil || len(q.filters) == 0 {
+		return q.expr
+	}
+	e := &filterExpr{op: exprAnd}
+	for i := range q.filters {
+		e.args = append(e.args, &filterExpr{op: exprFilter, filter: i})
+	}
+	return e
+}
+
+// filterParser parses the filters of a level: conditions combined with
+// && and ||, && binding tighter, and grouped with parentheses.
+type filterParser struct {
+	s       string
+	pos     int
+	strict  bool
+	filters []*Filter
+}
+
+func newFilter(cmd string, strict bool) ([]*Filter, *filterExpr, error) {
+	p := filterParser{s: cmd, strict: strict}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.s) {
+		return nil, nil, fmt.Errorf("Format error in filters : unexpected %q in %q", p.s[p.pos:], cmd)
+	}
+	return p.filters, e, nil
+}
+
+func (p *filterParser) parseOr() (*filterExpr, error) {
+	return p.parseList(exprOr, "||", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*filterExpr, error) {
+	return p.parseList(exprAnd, "&&", p.parseOperand)
+}
+
+// parseList parses operands separated by the operator op.
+func (p *filterParser) parseList(op exprOp, token string, operand func() (*filterExpr, error)) (*filterExpr, error) {
+	e, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	list := &filterExpr{op: op, args: []*filterExpr{e}}
+	for p.skipSpaces(); strings.HasPrefix(p.s[p.pos:], token); p.skipSpaces() {
+		p.pos += len(token)
+		e, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if e.op == op {
+			list.args = append(list.args, e.args...)
+		} else {
+			list.args = append(list.args, e)
+		}
+	}
+	if len(list.args) == 1 {
+		return list.args[0], nil
+	}
+	return list, nil
+}
+
+// parseOperand parses a condition, a quantified condition or a
+// parenthesized expression.
+func (p *filterParser) parseOperand() (*filterExpr, error) {
+	p.skipSpaces()
+	if p.pos < len(p.s) && p.s[p.pos] == '(' {
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); p.pos == len(p.s) || p.s[p.pos] != ')' {
+			return nil, fmt.Errorf("Format error in filters : missing ')' in %q", p.s)
+		}
+		p.pos++
+		return e, nil
+	}
+	for _, q := range []Quantifier{quantAny, quantAll} {
+		rest := p.s[p.pos:]
+		if !strings.HasPrefix(rest, string(q)+"(") {
+			continue
+		}
+		n, err := scanGroup(rest[len(q):], '(', ')')
+		if err != nil {
+			return nil, err
+		}
+		filter, err := parseQuantified(q, rest[len(q)+1:len(q)+n-1], p.strict)
+		if err != nil {
+			return nil, err
+		}
+		p.pos += len(q) + n
+		p.filters = append(p.filters, filter)
+		return &filterExpr{op: exprFilter, filter: len(p.filters) - 1}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) {
+		rest := p.s[p.pos:]
+		if strings.HasPrefix(rest, "&&") || strings.HasPrefix(rest, "||") || rest[0] == ')' {
+			break
+		}
+		switch rest[0] {
+		case '"':
+			n, err := scanQuoted(rest)
+			if err != nil {
+				return nil, err
+			}
+			p.pos += n
+			continue
+		case '(', '|':
+			return nil, fmt.Errorf("Format error in filters : %q", p.s)
+		}
+		p.pos++
+	}
+	filter, err := parseCondition(p.s[start:p.pos], p.strict)
+	if err != nil {
+		return nil, err
+	}
+	p.filters = append(p.filters, filter)
+	return &filterExpr{op: exprFilter, filter: len(p.filters) - 1}, nil
+}
+
+func (p *filterParser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
@@ -0,0 +1,82 @@
+package jsonq
+
+import "testing"
+
+func leaf(i int) *filterExpr { return &filterExpr{op: exprFilter, filter: i} }
+
+func allOf(args ...*filterExpr) *filterExpr { return &filterExpr{op: exprAnd, args: args} }
+
+func anyOf(args ...*filterExpr) *filterExpr { return &filterExpr{op: exprOr, args: args} }
+
+func TestNewFilterExpr(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"a = 1", "0"},
+		{"a = 1 && b = 2 && c = 3", "0 && 1 && 2"},
+		{"a = 1 || b = 2 && c = 3", "0 || (1 && 2)"},
+		{"(a > 1 && b < 2) || c = x", "(0 && 1) || 2"},
+		{"(a = 1 || b = 2) && (c = 3 || d = 4)", "(0 || 1) && (2 || 3)"},
+		{"((a = 1 || (b = 2 || c = 3)))", "0 || 1 || 2"},
+		{`a = ")" || b = "&&"`, "0 || 1"},
+	}
+	for _, tt := range tests {
+		_, e, err := newFilter(tt.cmd, false)
+		if err != nil {
+			t.Errorf("newFilter(%q) unexpected error: %s", tt.cmd, err)
+			continue
+		}
+		if got := e.String(); got != tt.want {
+			t.Errorf("newFilter(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+
+	for _, cmd := range []string{"", "(a = 1", "a = 1)", "()", "a = 1 &&", "|| a = 1", "a = 1 | b = 2", "(a = 1)(b = 2)"} {
+		if _, _, err := newFilter(cmd, false); err == nil {
+			t.Errorf("newFilter(%q) expecting non-nil error", cmd)
+		}
+	}
+}
+
+func TestFilterExprEval(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"a": 2, "b": 1, "c": "y"}, {"a": 0, "b": 1, "c": "x"}, {"a": 2, "b": 5, "c": "y"}, {"c": "y"}, {"a": 2}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd         string
+		want        []truth
+		threeValued []truth
+	}{
+		{
+			`(a > 1 && b < 2) || c = x`,
+			[]truth{truthTrue, truthTrue, truthFalse, truthFalse, truthTrue},
+			[]truth{truthTrue, truthTrue, truthFalse, truthUnknown, truthUnknown},
+		},
+		{
+			`(a > 1 || c = x) && b < 2`,
+			[]truth{truthTrue, truthTrue, truthFalse, truthFalse, truthTrue},
+			[]truth{truthTrue, truthTrue, truthFalse, truthUnknown, truthUnknown},
+		},
+		{
+			`a > 1 || b > 1`,
+			[]truth{truthTrue, truthFalse, truthTrue, truthTrue, truthTrue},
+			[]truth{truthTrue, truthFalse, truthTrue, truthUnknown, truthTrue},
+		},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery("(" + tt.cmd + "){}")
+		for i, e := range v.GetArray() {
+			q.SetOptions(Options{})
+			if got := q.eval(&e.o); got != tt.want[i] {
+				t.Errorf("%q on %s = %d, want %d", tt.cmd, e, got, tt.want[i])
+			}
+			q.SetOptions(Options{ThreeValued: true})
+			if got := q.eval(&e.o); got != tt.threeValued[i] {
+				t.Errorf("three-valued %q on %s = %d, want %d", tt.cmd, e, got, tt.threeValued[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package jsonq
+
+import (
+	"strconv"
+)
+
+// Find walks v depth-first, in document order, and returns the first value
+// for which fn returns true along with its keys path. Array indexes in the
+// path are decimal numbers, so it may be passed back to Get.
+//
+// The path passed to fn is only valid during the call; fn must copy it to
+// retain it. nil and a nil path are returned when no value matches.
+func (v *Value) Find(fn func(path []string, v *Value) bool) (*Value, []string) {
+	if v == nil {
+		return nil, nil
+	}
+	path := make([]string, 0, 8)
+	found, path := v.find(path, fn)
+	if found == nil {
+		return nil, nil
+	}
+	return found, append([]string(nil), path...)
+}
+
+func (v *Value) find(path []string, fn func(path []string, v *Value) bool) (*Value, []string) {
+	if fn(path, v) {
+		return v, path
+	}
+	switch v.t {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			if found, p := kv.v.find(append(path, kv.k), fn); found != nil {
+				return found, p
+			}
+		}
+	case TypeArray:
+		for i, e := range v.a {
+			if found, p := e.find(append(path, strconv.Itoa(i)), fn); found != nil {
+				return found, p
+			}
+		}
+	}
+	return nil, nil
+}
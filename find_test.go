@@ -0,0 +1,50 @@
+package jsonq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValueFind(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users":[{"id":1,"name":"a"},{"id":2,"name":"b","tags":{"id":2}}],"a\"b":{"id":3}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	f := func(id int, expectedPath []string) {
+		t.Helper()
+		found, path := v.Find(func(path []string, v *Value) bool {
+			return v.GetInt("id") == id
+		})
+		if !reflect.DeepEqual(path, expectedPath) {
+			t.Fatalf("unexpected path for id %d; got %q; want %q", id, path, expectedPath)
+		}
+		if expectedPath == nil {
+			if found != nil {
+				t.Fatalf("unexpected value for id %d: %s", id, found)
+			}
+			return
+		}
+		if found != v.Get(path...) {
+			t.Fatalf("unexpected value for id %d; got %s; want %s", id, found, v.Get(path...))
+		}
+	}
+	f(1, []string{"users", "0"})
+	f(2, []string{"users", "1"})
+	f(3, []string{`a"b`})
+	f(4, nil)
+
+	found, path := v.Find(func(path []string, v *Value) bool { return true })
+	if found != v || len(path) != 0 {
+		t.Fatalf("expecting the root to match first; got %s at %q", found, path)
+	}
+
+	visited := 0
+	v.Find(func(path []string, v *Value) bool {
+		visited++
+		return v.Type() == TypeString
+	})
+	if visited != 5 {
+		t.Fatalf("expecting Find to stop at the first match; visited %d values", visited)
+	}
+}
@@ -0,0 +1,121 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// format builds a string from a template and values:
+// format("{} ({})", name, sku).
+//
+// {} takes the next argument after the template and {1} the argument at
+// the given position, counting from 0. {price} or {address.city} takes a
+// field of the object. {{ and }} write literal braces. Strings are written
+// without their quotes, missing values as nothing and other values as JSON.
+func format(o *Object, args []*Value) (string, error) {
+	if len(args) == 0 || args[0] == nil || args[0].Type() != TypeString {
+		return "", fmt.Errorf("expects a template")
+	}
+	template, args := args[0].s, args[1:]
+	var b strings.Builder
+	next := 0
+	err := walkTemplate(template, &b, func(placeholder string) error {
+		var v *Value
+		if placeholder == "" {
+			if next >= len(args) {
+				return fmt.Errorf("missing argument %d", next)
+			}
+			v = args[next]
+			next++
+		} else if index, err := strconv.Atoi(placeholder); err == nil {
+			if index < 0 || index >= len(args) {
+				return fmt.Errorf("missing argument %d", index)
+			}
+			v = args[index]
+		} else {
+			field, err := parseField(placeholder)
+			if err != nil {
+				return err
+			}
+			v = o.Get(field[0]).Get(field[1:]...)
+		}
+		switch {
+		case v == nil:
+		case v.Type() == TypeString:
+			b.WriteString(v.s)
+		default:
+			b.WriteString(v.raw())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Quote(b.String()), nil
+}
+
+// checkFormat checks the arguments of a call to format when the query is
+// parsed: the template must be a string literal, and its placeholders
+// valid and within the arguments.
+func checkFormat(args []argument) error {
+	if len(args) == 0 || args[0].literal == nil || args[0].literal.Type() != TypeString {
+		return fmt.Errorf("expects a template")
+	}
+	n, next := len(args)-1, 0
+	return walkTemplate(args[0].literal.s, nil, func(placeholder string) error {
+		if placeholder == "" {
+			if next >= n {
+				return fmt.Errorf("missing argument %d", next)
+			}
+			next++
+			return nil
+		}
+		if index, err := strconv.Atoi(placeholder); err == nil {
+			if index < 0 || index >= n {
+				return fmt.Errorf("missing argument %d", index)
+			}
+			return nil
+		}
+		_, err := parseField(placeholder)
+		return err
+	})
+}
+
+// walkTemplate writes the text of template to b, if not nil, and calls
+// placeholder with the content of each of its placeholders, in order.
+func walkTemplate(template string, b *strings.Builder, placeholder func(string) error) error {
+	write := func(c byte) {
+		if b != nil {
+			b.WriteByte(c)
+		}
+	}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c == '}' {
+			if i+1 < len(template) && template[i+1] == '}' {
+				i++
+			}
+			write('}')
+			continue
+		}
+		if c != '{' {
+			write(c)
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '{' {
+			write('{')
+			i++
+			continue
+		}
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return fmt.Errorf("unclosed placeholder in %q", template)
+		}
+		if err := placeholder(template[i+1 : i+end]); err != nil {
+			return err
+		}
+		i += end
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package jsonq
+
+import "testing"
+
+func TestKeepFormat(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"products": [
+		{"name": "Chair", "sku": "C-1", "price": 12.5, "dims": {"w": 40}},
+		{"name": "Table", "price": null}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{`format("{} ({})", name, sku)`, `{"products":[{"x":"Chair (C-1)"},{"x":"Table ()"}]}`},
+		{`format("{1}: {0}/{1}", name, price)`, `{"products":[{"x":"12.5: Chair/12.5"},{"x":"null: Table/null"}]}`},
+		{`format("{name} is {dims.w}cm {{wide}}")`, `{"products":[{"x":"Chair is 40cm {wide}"},{"x":"Table is cm {wide}"}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery("{products{x: " + tt.field + "}}"))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.field, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+
+	for _, field := range []string{
+		`format()`,
+		`format(price)`,
+		`format(3)`,
+		`format("{} {}", name)`,
+		`format("{1}{}", name)`,
+		`format("{3}", name)`,
+		`format("{-1}", name)`,
+		`format("{name")`,
+		`format("{a..b}")`,
+	} {
+		if _, err := ParseQuery("{products{x: " + field + "}}"); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", field)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// computed is a field computed for every object of a level by a function
+// of the standard library. It is written name: fn(args) in the retrieve
+// block of the level:
+//
+//	events{id, day: datetrunc(created_at, "day")}
+//
+// An argument is either a JSON literal, such as "day", 2 or true, or the
+// path of a field of the object. A missing field is passed as nil.
+type computed struct {
+	as   string
+	name string
+	fn   function
+	args []argument
+}
+
+// function is a function of the standard library, called for the object o
+// of a level. It returns the JSON of its result.
+type function func(o *Object, args []*Value) (string, error)
+
+// functions is the standard function library, by name.
+var functions = map[string]function{
+	"bytes":     toBytes,
+	"currency":  currency,
+	"datetrunc": datetrunc,
+	"distance":  distance,
+	"format":    format,
+	"rfc3339":   rfc3339,
+}
+
+// checks validates the arguments of the calls to some functions when the
+// query is parsed, rather than on every object.
+var checks = map[string]func(args []argument) error{
+	"format": checkFormat,
+}
+
+type argument struct {
+	field   Path
+	literal *Value
+}
+
+// parseComputed parses cmd as a computed field. It reports false when cmd
+// does not define one.
+func parseComputed(cmd string) (*computed, bool, error) {
+	i := strings.IndexByte(cmd, ':')
+	if i < 0 || !isName(strings.TrimSpace(cmd[:i])) {
+		return nil, false, nil
+	}
+	c, err := parseCall(strings.TrimSpace(cmd[i+1:]))
+	if err != nil {
+		return nil, true, fmt.Errorf("mal formated computed field : %q: %s", cmd, err)
+	}
+	c.as = strings.TrimSpace(cmd[:i])
+	return c, true, nil
+}
+
+// isCall reports whether s looks like the call of a function, fn(args).
+func isCall(s string) bool {
+	open := strings.IndexByte(s, '(')
+	return open > 0 && s[len(s)-1] == ')' && isName(s[:open])
+}
+
+// parseCall parses call, the call of a function of the library with its
+// arguments, as an unnamed computed field.
+func parseCall(call string) (*computed, error) {
+	if !isCall(call) {
+		return nil, fmt.Errorf("%q is not a function call", call)
+	}
+	open := strings.IndexByte(call, '(')
+	c := &computed{name: call[:open]}
+	fn, ok := functions[c.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", c.name)
+	}
+	c.fn = fn
+	for _, arg := range splitComa(call[open+1 : len(call)-1]) {
+		var p Parser
+		if literal, err := p.Parse(arg); err == nil {
+			c.args = append(c.args, argument{literal: literal})
+			continue
+		}
+		field, err := parseField(arg)
+		if err != nil {
+			return nil, fmt.Errorf("mal formated argument %q of %s", arg, c.name)
+		}
+		c.args = append(c.args, argument{field: field})
+	}
+	if check, ok := checks[c.name]; ok {
+		if err := check(c.args); err != nil {
+			return nil, fmt.Errorf("%s: %s", c.name, err)
+		}
+	}
+	return c, nil
+}
+
+// keep returns the JSON of the field computed for o.
+func (c *computed) keep(o *Object) (string, error) {
+	args := make([]*Value, len(c.args))
+	for i, arg := range c.args {
+		if arg.literal != nil {
+			args[i] = arg.literal
+		} else {
+			args[i] = o.Get(arg.field[0]).Get(arg.field[1:]...)
+		}
+	}
+	value, err := c.fn(o, args)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", c.name, err)
+	}
+	return value, nil
+}
+
+// fields returns the fields passed to c.
+func (c *computed) fields() []Path {
+	var fields []Path
+	for _, arg := range c.args {
+		if arg.literal == nil {
+			fields = append(fields, arg.field)
+		}
+	}
+	return fields
+}
+
+// expectArgs returns an error unless there are n arguments.
+func expectArgs(args []*Value, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expects %d arguments, got %d", n, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,571 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateOptions controls the documents fabricated by Query.Generate and
+// GenerateSchema.
+type GenerateOptions struct {
+	// Seed seeds the random values, so that the same document is generated
+	// every time. Zero picks a new seed for every document.
+	Seed int64
+	// MaxItems is the largest number of elements of the generated arrays,
+	// 3 by default.
+	MaxItems int
+	// MaxDepth is the depth beyond which the optional properties of
+	// recursive schemas are left out, 5 by default.
+	MaxDepth int
+}
+
+// Generate fabricates a random JSON document with the shape q selects: the
+// keys it retrieves, filters and orders, and its sub levels. The values of
+// filtered keys pass the filters when they can, so that q keeps the
+// document. A sub level with filters or array directives, such as
+// items(price > 10){name} or items[0:3], is an array of objects, and any
+// other sub level an object.
+//
+// The fields read by joins, lookups, zips, computed fields and
+// aggregations are not generated.
+func (q *Query) Generate(opts GenerateOptions) string {
+	g := newGenerator(opts, nil)
+	return g.object(q)
+}
+
+// GenerateSchema fabricates a random JSON document valid against the JSON
+// Schema schema: it follows the types, formats, enums, bounds and required
+// properties of the schema. The local refs of schema are followed, and one
+// of the variants of anyOf and oneOf is picked.
+func GenerateSchema(schema *Value, opts GenerateOptions) (string, error) {
+	g := newGenerator(opts, schema)
+	out := g.schema(schema, 0)
+	if g.err != nil {
+		return "", g.err
+	}
+	return out, nil
+}
+
+type generator struct {
+	r    *rand.Rand
+	opts GenerateOptions
+	c    schemaChecker
+	err  error
+}
+
+func newGenerator(opts GenerateOptions, root *Value) *generator {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 3
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 5
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &generator{r: rand.New(rand.NewSource(seed)), opts: opts, c: schemaChecker{root: root}}
+}
+
+// object generates an object selected by the level q.
+func (g *generator) object(q *Query) string {
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	written := map[string]bool{}
+	g.fields(q, func(key, value string) {
+		if written[key] {
+			return
+		}
+		if len(written) > 0 {
+			w.WriteRune(',')
+		}
+		written[key] = true
+		w.WriteString(strconv.Quote(key))
+		w.WriteRune(':')
+		w.WriteString(value)
+	})
+	w.WriteRune('}')
+	return w.String()
+}
+
+// fields generates the fields of an object selected by q, passing them to
+// add.
+func (g *generator) fields(q *Query, add func(key, value string)) {
+	var keys []string
+	filters := map[string][]*Filter{}
+	var nested []*Filter
+	for _, f := range q.filters {
+		if f.calc != nil {
+			continue
+		}
+		if len(f.sub) > 0 {
+			nested = append(nested, f)
+			continue
+		}
+		if _, ok := filters[f.key]; !ok {
+			keys = append(keys, f.key)
+		}
+		filters[f.key] = append(filters[f.key], f)
+	}
+	for _, key := range keys {
+		if value, ok := g.filtered(filters[key], &q.opts); ok {
+			add(key, value)
+		}
+	}
+	for _, f := range nested {
+		// A single element, holding the path, passes any and all.
+		if value, ok := g.filtered([]*Filter{f}, &q.opts); ok {
+			for i := len(f.sub) - 1; i >= 0; i-- {
+				value = "{" + strconv.Quote(f.sub[i]) + ":" + value + "}"
+			}
+			add(f.key, "["+value+"]")
+		}
+	}
+	for _, key := range q.retrieve {
+		add(key, g.scalar())
+	}
+	orderings := []*ordering{}
+	if q.sorting != nil {
+		orderings = append(orderings, q.sorting)
+	}
+	if q.top != nil {
+		orderings = append(orderings, q.top.by)
+	}
+	for _, o := range orderings {
+		value := strconv.Itoa(g.r.Intn(1000))
+		for i := len(o.field) - 1; i > 0; i-- {
+			value = "{" + strconv.Quote(o.field[i]) + ":" + value + "}"
+		}
+		add(o.field[0], value)
+	}
+	for _, name := range q.nextNames() {
+		if next := q.next[name]; next == nil {
+			add(name, g.scalar())
+		} else {
+			add(name, g.level(next))
+		}
+	}
+	for _, c := range q.counts {
+		add(c.name, g.array(c.q))
+	}
+	if q.grouping != nil {
+		// A few values, for the elements to share groups. The arguments of
+		// a computed value are left to the function.
+		if field := q.grouping.field; field != nil {
+			value := strconv.Itoa(g.r.Intn(3))
+			for i := len(field) - 1; i > 0; i-- {
+				value = "{" + strconv.Quote(field[i]) + ":" + value + "}"
+			}
+			add(field[0], value)
+		}
+		g.fields(q.grouping.q, add)
+	}
+	if q.descent != nil {
+		g.fields(q.descent, add)
+	}
+}
+
+// level generates the value of the sub level q.
+func (g *generator) level(q *Query) string {
+	if q.slice != nil || len(q.filters)+len(q.aggregates)+len(q.running) > 0 ||
+		q.top != nil || q.sorting != nil || q.sample != nil || q.pivot != nil || q.grouping != nil {
+		return g.array(q)
+	}
+	return g.object(q)
+}
+
+// array generates an array of objects selected by q, long enough for the
+// slice of q to select some.
+func (g *generator) array(q *Query) string {
+	n := 1 + g.r.Intn(g.opts.MaxItems)
+	if q.slice != nil {
+		for _, bound := range []int{q.slice.start, q.slice.end} {
+			if bound < 0 {
+				bound = -bound
+			}
+			if bound >= n {
+				n = bound + 1
+			}
+		}
+	}
+	elements := make([]string, n)
+	for i := range elements {
+		elements[i] = g.object(q)
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+// scalar generates a random string, number or boolean.
+func (g *generator) scalar() string {
+	switch g.r.Intn(3) {
+	case 0:
+		return strconv.Quote(g.word())
+	case 1:
+		return strconv.Itoa(g.r.Intn(1000))
+	default:
+		return strconv.FormatBool(g.r.Intn(2) == 0)
+	}
+}
+
+// word generates a random lowercase word.
+func (g *generator) word() string {
+	b := make([]byte, 4+g.r.Intn(5))
+	for i := range b {
+		b[i] = byte('a' + g.r.Intn(26))
+	}
+	return string(b)
+}
+
+// filtered generates a value passing the filters on a key, if it finds
+// one, and the best guess otherwise. ok is false when the key must be left
+// out.
+func (g *generator) filtered(filters []*Filter, opts *Options) (value string, ok bool) {
+	var first interface{}
+	for attempt := 0; attempt < 20; attempt++ {
+		candidate, ok := g.candidate(filters, attempt)
+		if !ok {
+			return "", false
+		}
+		if attempt == 0 {
+			first = candidate
+		}
+		passed := true
+		for _, f := range filters {
+			if _, ok := f.presence(true); !ok && !f.check(candidate, opts) {
+				passed = false
+				break
+			}
+		}
+		if passed {
+			return encode(candidate), true
+		}
+	}
+	return encode(first), true
+}
+
+// candidate returns a value likely to pass the filters on a key, as found
+// in a document: a string, a float64, a bool or nil. The first attempt
+// takes the values of the filters, the next ones are more random.
+func (g *generator) candidate(filters []*Filter, attempt int) (interface{}, bool) {
+	lo, hi := math.Inf(-1), math.Inf(1)
+	numeric, integral, text, boolean := false, true, false, false
+	var needles []string
+	for _, f := range filters {
+		switch f.op {
+		case notExists:
+			return nil, false
+		case eq, same:
+			if attempt == 0 {
+				if n, ok := f.val.(int64); ok {
+					return float64(n), true
+				}
+				return f.val, true
+			}
+		}
+		switch val := f.val.(type) {
+		case int64, float64:
+			n, isInt := toFloat(val)
+			numeric, integral = true, integral && isInt
+			step := 0.5
+			if integral {
+				step = 1
+			}
+			switch f.op {
+			case sup:
+				lo = math.Max(lo, n+step)
+			case supEq:
+				lo = math.Max(lo, n)
+			case inf:
+				hi = math.Min(hi, n-step)
+			case infEq:
+				hi = math.Min(hi, n)
+			}
+		case string:
+			text = true
+			switch f.op {
+			case contain, eq, same:
+				needles = append(needles, strings.ToLower(val))
+			case like:
+				if re, err := regexp.Compile(strings.TrimPrefix(val, "^")); err == nil {
+					prefix, _ := re.LiteralPrefix()
+					needles = append(needles, strings.ToLower(prefix))
+				}
+			case sup, supEq:
+				needles = append(needles, val)
+			case inf, infEq:
+				if attempt == 0 {
+					return "", true
+				}
+			}
+		case bool:
+			boolean = true
+			if f.op == diff || f.op == notSame {
+				return !val, true
+			}
+		}
+	}
+	switch {
+	case numeric:
+		return g.number(lo, hi, integral), true
+	case text:
+		if attempt == 0 {
+			return strings.Join(needles, ""), true
+		}
+		return strings.Join(needles, "") + g.word(), true
+	case boolean:
+		return g.r.Intn(2) == 0, true
+	default:
+		if g.r.Intn(2) == 0 {
+			return g.word(), true
+		}
+		return float64(g.r.Intn(1000)), true
+	}
+}
+
+func toFloat(val interface{}) (n float64, integral bool) {
+	switch val := val.(type) {
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, val == math.Trunc(val)
+	}
+	return 0, false
+}
+
+// number generates a number between lo and hi, as far as they allow.
+func (g *generator) number(lo, hi float64, integral bool) float64 {
+	switch {
+	case math.IsInf(lo, -1) && math.IsInf(hi, 1):
+		lo, hi = 0, 1000
+	case math.IsInf(lo, -1):
+		lo = hi - 100
+	case math.IsInf(hi, 1):
+		hi = lo + 100
+	}
+	if hi < lo {
+		return lo
+	}
+	if integral {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+		if hi < lo {
+			return lo
+		}
+		return lo + float64(g.r.Int63n(int64(hi-lo)+1))
+	}
+	// Two decimals read better, as long as they stay in the bounds.
+	if n := math.Round((lo+g.r.Float64()*(hi-lo))*100) / 100; n >= lo && n <= hi {
+		return n
+	}
+	return lo
+}
+
+// encode returns the JSON of a value of a document.
+func encode(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return "null"
+	}
+}
+
+// schema generates a value valid against s at depth in the document.
+func (g *generator) schema(s *Value, depth int) string {
+	if g.err != nil {
+		return "null"
+	}
+	if s = g.c.resolve(s); s == nil {
+		g.err = fmt.Errorf("cannot resolve a $ref of the schema")
+		return "null"
+	}
+	if c := s.Get("const"); c != nil {
+		return c.String()
+	}
+	if enum := s.GetArray("enum"); len(enum) > 0 {
+		return enum[g.r.Intn(len(enum))].String()
+	}
+	if example := s.Get("example"); example != nil {
+		return example.String()
+	}
+	if examples := s.GetArray("examples"); len(examples) > 0 {
+		return examples[g.r.Intn(len(examples))].String()
+	}
+	for _, variants := range []string{"oneOf", "anyOf"} {
+		if a := s.GetArray(variants); len(a) > 0 {
+			return g.schema(a[g.r.Intn(len(a))], depth)
+		}
+	}
+	if parts := s.GetArray("allOf"); len(parts) > 0 {
+		return g.objectSchema(g.c.flatten(s, nil, 0), depth)
+	}
+	switch g.schemaType(s) {
+	case "object":
+		return g.objectSchema([]*Value{s}, depth)
+	case "array":
+		return g.arraySchema(s, depth)
+	case "string":
+		return strconv.Quote(g.stringSchema(s))
+	case "integer":
+		return strconv.FormatFloat(g.number(g.bounds(s, 1)), 'f', -1, 64)
+	case "number":
+		lo, hi, _ := g.bounds(s, 0.01)
+		return strconv.FormatFloat(g.number(lo, hi, false), 'f', -1, 64)
+	case "boolean":
+		return strconv.FormatBool(g.r.Intn(2) == 0)
+	case "null":
+		return "null"
+	default:
+		return g.scalar()
+	}
+}
+
+// schemaType picks one of the types allowed by s, null only if it is the
+// only one.
+func (g *generator) schemaType(s *Value) string {
+	var types []string
+	for name := range schemaTypes([]*Value{s}) {
+		if name != "null" {
+			types = append(types, name)
+		}
+	}
+	if len(types) == 0 {
+		if t := schemaTypes([]*Value{s}); t["null"] {
+			return "null"
+		}
+		return ""
+	}
+	// Map iteration order is random, the seed must decide alone.
+	sort.Strings(types)
+	return types[g.r.Intn(len(types))]
+}
+
+// objectSchema generates an object with the properties of the schemas of
+// set. Beyond the maximum depth, only the required ones are generated.
+func (g *generator) objectSchema(set []*Value, depth int) string {
+	required := map[string]bool{}
+	for _, s := range set {
+		for _, name := range s.GetArray("required") {
+			required[string(name.GetStringBytes())] = true
+		}
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	written := map[string]bool{}
+	for _, s := range set {
+		properties := s.GetObject("properties")
+		if properties == nil {
+			continue
+		}
+		properties.Visit(func(key []byte, p *Value) {
+			k := string(key)
+			if written[k] || depth >= g.opts.MaxDepth && !required[k] {
+				return
+			}
+			if len(written) > 0 {
+				w.WriteRune(',')
+			}
+			written[k] = true
+			w.WriteString(strconv.Quote(k))
+			w.WriteRune(':')
+			w.WriteString(g.schema(p, depth+1))
+		})
+	}
+	w.WriteRune('}')
+	return w.String()
+}
+
+// arraySchema generates an array of the items of s, of minItems up to
+// maxItems elements, within the MaxItems option.
+func (g *generator) arraySchema(s *Value, depth int) string {
+	min, max := s.GetInt("minItems"), g.opts.MaxItems
+	if s.Exists("maxItems") && s.GetInt("maxItems") < max {
+		max = s.GetInt("maxItems")
+	}
+	if depth >= g.opts.MaxDepth {
+		max = min
+	}
+	if max < min {
+		max = min
+	}
+	n := min + g.r.Intn(max-min+1)
+	elements := make([]string, n)
+	for i := range elements {
+		if items := s.Get("items"); items != nil {
+			elements[i] = g.schema(items, depth+1)
+		} else {
+			elements[i] = g.scalar()
+		}
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+// bounds returns the range of the numbers of s, step being the gap below
+// an exclusive bound.
+func (g *generator) bounds(s *Value, step float64) (lo, hi float64, integral bool) {
+	lo, hi = math.Inf(-1), math.Inf(1)
+	if s.Exists("minimum") {
+		lo = s.GetFloat64("minimum")
+		if s.GetBool("exclusiveMinimum") {
+			lo += step
+		}
+	}
+	if m := s.Get("exclusiveMinimum"); m != nil && m.Type() == TypeNumber {
+		lo = math.Max(lo, m.n+step)
+	}
+	if s.Exists("maximum") {
+		hi = s.GetFloat64("maximum")
+		if s.GetBool("exclusiveMaximum") {
+			hi -= step
+		}
+	}
+	if m := s.Get("exclusiveMaximum"); m != nil && m.Type() == TypeNumber {
+		hi = math.Min(hi, m.n-step)
+	}
+	return lo, hi, step == 1
+}
+
+// stringSchema generates a string of the format of s, or a word of its
+// length bounds.
+func (g *generator) stringSchema(s *Value) string {
+	at := time.Date(2020+g.r.Intn(5), time.Month(1+g.r.Intn(12)), 1+g.r.Intn(28), g.r.Intn(24), g.r.Intn(60), g.r.Intn(60), 0, time.UTC)
+	switch string(s.GetStringBytes("format")) {
+	case "date-time":
+		return at.Format(time.RFC3339)
+	case "date":
+		return at.Format("2006-01-02")
+	case "time":
+		return at.Format("15:04:05Z")
+	case "email":
+		return g.word() + "@example.com"
+	case "uri", "url":
+		return "https://example.com/" + g.word()
+	case "hostname":
+		return g.word() + ".example.com"
+	case "ipv4":
+		return fmt.Sprintf("192.0.2.%d", 1+g.r.Intn(254))
+	case "uuid":
+		b := make([]byte, 16)
+		g.r.Read(b)
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+	word := g.word()
+	if min := s.GetInt("minLength"); len(word) < min {
+		word += strings.Repeat("x", min-len(word))
+	}
+	if s.Exists("maxLength") && len(word) > s.GetInt("maxLength") {
+		word = word[:s.GetInt("maxLength")]
+	}
+	return word
+}
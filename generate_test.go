@@ -0,0 +1,174 @@
+package jsonq
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestQueryGenerate(t *testing.T) {
+	for _, query := range []string{
+		`{id, name}`,
+		`{users(age > 18 && age <= 20 && name :: "^al"){name, address{city}}}`,
+		`{orders(status = paid && total >= 9.5 && !note? && tags : urgent){id, sort(total desc), items[0:4](price != 0){sku}}}`,
+		`{(kind === "event" && enabled = true){**{price}, count(items(qty < 3)) as n}}`,
+		`{players{top(2, by: stats.score), name}}`,
+		`{orders(total > 3){group_by(customer.id){count(), sum(total)}}}`,
+		`{orders(any(items.price > 100) && all(items.tags.name != promo)){id}}`,
+	} {
+		q := MustParseQuery(query)
+		for seed := int64(1); seed <= 20; seed++ {
+			out := q.Generate(GenerateOptions{Seed: seed})
+			var p Parser
+			v, err := p.Parse(out)
+			if err != nil {
+				t.Fatalf("%s: generated invalid JSON %s: %s", query, out, err)
+			}
+			if v.Type() != TypeObject {
+				t.Fatalf("%s: generated %s, want an object", query, out)
+			}
+			if !(&Query{filters: q.filters, expr: q.expr, opts: q.opts}).accept(&v.o) {
+				t.Errorf("%s: generated %s, rejected by the root filters", query, out)
+			}
+			kept, err := v.Keep(*q)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", query, err)
+			}
+			if kept == "" || kept == "{}" {
+				t.Errorf("%s: generated %s, which the query does not keep", query, out)
+			}
+		}
+	}
+}
+
+func TestQueryGenerateFilters(t *testing.T) {
+	q := MustParseQuery(`{users(age > 18 && age <= 20 && name :: "^al" && role != admin && !deleted?){name}}`)
+	for seed := int64(1); seed <= 50; seed++ {
+		out := q.Generate(GenerateOptions{Seed: seed, MaxItems: 5})
+		var p Parser
+		v, err := p.Parse(out)
+		if err != nil {
+			t.Fatalf("generated invalid JSON %s: %s", out, err)
+		}
+		users := v.GetArray("users")
+		if len(users) == 0 || len(users) > 5 {
+			t.Fatalf("generated %s, want 1 to 5 users", out)
+		}
+		for _, u := range users {
+			if age := u.GetInt("age"); age < 19 || age > 20 {
+				t.Errorf("generated age %d, want 19 or 20", age)
+			}
+			if name := string(u.GetStringBytes("name")); !regexp.MustCompile("^al").MatchString(name) {
+				t.Errorf("generated name %q, want it to start with al", name)
+			}
+			if role := string(u.GetStringBytes("role")); role == "admin" {
+				t.Errorf("generated role %q", role)
+			}
+			if u.Exists("deleted") {
+				t.Errorf("generated %s with deleted", u)
+			}
+		}
+	}
+
+	a := q.Generate(GenerateOptions{Seed: 7})
+	b := q.Generate(GenerateOptions{Seed: 7})
+	if a != b {
+		t.Errorf("Generate with the same seed = %s and %s, want the same document", a, b)
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	var p Parser
+	schema, err := p.Parse(`{
+		"$defs": {
+			"node": {"type": "object", "required": ["id"], "properties": {
+				"id": {"type": "integer", "minimum": 1, "exclusiveMaximum": 10},
+				"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+			}}
+		},
+		"type": "object",
+		"required": ["id", "tree"],
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"email": {"type": "string", "format": "email"},
+			"created": {"type": "string", "format": "date-time"},
+			"status": {"enum": ["open", "closed"]},
+			"version": {"const": 2},
+			"score": {"type": "number", "minimum": 0, "maximum": 1},
+			"code": {"type": "string", "minLength": 10, "maxLength": 12},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 2},
+			"owner": {"oneOf": [{"type": "null"}, {"type": "object", "properties": {"name": {"type": "string"}}}]},
+			"pet": {"allOf": [
+				{"type": "object", "properties": {"name": {"type": "string"}}},
+				{"properties": {"age": {"type": ["integer", "null"], "maximum": 30, "minimum": 0}}}
+			]},
+			"tree": {"$ref": "#/$defs/node"}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	date := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`)
+	for seed := int64(1); seed <= 20; seed++ {
+		out, err := GenerateSchema(schema, GenerateOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var p Parser
+		v, err := p.Parse(out)
+		if err != nil {
+			t.Fatalf("generated invalid JSON %s: %s", out, err)
+		}
+		if id := string(v.GetStringBytes("id")); !uuid.MatchString(id) {
+			t.Errorf("generated id %q, want a uuid", id)
+		}
+		if created := string(v.GetStringBytes("created")); !date.MatchString(created) {
+			t.Errorf("generated created %q, want a date-time", created)
+		}
+		if status := string(v.GetStringBytes("status")); status != "open" && status != "closed" {
+			t.Errorf("generated status %q", status)
+		}
+		if version := v.GetInt("version"); version != 2 {
+			t.Errorf("generated version %d, want 2", version)
+		}
+		if score := v.GetFloat64("score"); score < 0 || score > 1 {
+			t.Errorf("generated score %f", score)
+		}
+		if code := v.GetStringBytes("code"); len(code) < 10 || len(code) > 12 {
+			t.Errorf("generated code %q", code)
+		}
+		if tags := v.GetArray("tags"); len(tags) != 2 || !tags[0].IsString() {
+			t.Errorf("generated tags %s", v.Get("tags"))
+		}
+		if owner := v.Get("owner"); owner == nil || !owner.IsNull() && !owner.IsObject() {
+			t.Errorf("generated owner %s", owner)
+		}
+		if pet := v.Get("pet"); !pet.Exists("name") || !pet.Exists("age") {
+			t.Errorf("generated pet %s, want a name and an age", pet)
+		}
+		checkNode(t, v.Get("tree"), 1)
+	}
+}
+
+func checkNode(t *testing.T, node *Value, depth int) {
+	if id := node.GetInt("id"); id < 1 || id > 9 {
+		t.Errorf("generated node id %d, want 1 to 9", id)
+	}
+	if depth > 5 && len(node.GetArray("children")) > 0 {
+		t.Errorf("generated children at depth %d", depth)
+	}
+	for _, child := range node.GetArray("children") {
+		checkNode(t, child, depth+1)
+	}
+}
+
+func TestGenerateSchemaErrors(t *testing.T) {
+	var p Parser
+	schema, err := p.Parse(`{"type": "object", "properties": {"a": {"$ref": "other.json#/a"}}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if _, err := GenerateSchema(schema, GenerateOptions{Seed: 1}); err == nil {
+		t.Errorf("GenerateSchema expecting non-nil error")
+	}
+}
@@ -0,0 +1,134 @@
+package jsonq
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Point is a position of a GeoJSON document, in degrees.
+type Point struct {
+	Lon, Lat float64
+}
+
+// BBox is a GeoJSON bounding box, in degrees. A box crossing the
+// antimeridian has a West edge greater than its East edge.
+type BBox struct {
+	West, South, East, North float64
+}
+
+// Contains reports whether p lies in b, edges included.
+func (b BBox) Contains(p Point) bool {
+	if p.Lat < b.South || p.Lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return p.Lon >= b.West && p.Lon <= b.East
+	}
+	return p.Lon >= b.West || p.Lon <= b.East
+}
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+// Distance returns the great-circle distance between p and to in meters,
+// on a spherical Earth.
+func (p Point) Distance(to Point) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(to.Lat - p.Lat)
+	dLon := rad(to.Lon - p.Lon)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(p.Lat))*math.Cos(rad(to.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// GetPoint returns the point at the given keys path. The value may be a
+// Point geometry, a Feature whose geometry is a Point, or a bare position
+// such as [2.35, 48.85]. The altitude of a position is ignored.
+//
+// ok is false for non-existing keys path or for any other value.
+func (v *Value) GetPoint(keys ...string) (p Point, ok bool) {
+	v = v.Get(keys...)
+	if v == nil {
+		return Point{}, false
+	}
+	switch v.Type() {
+	case TypeArray:
+		return position(v.a)
+	case TypeObject:
+		switch string(v.GetStringBytes("type")) {
+		case "Point":
+			return position(v.GetArray("coordinates"))
+		case "Feature":
+			if geometry := v.Get("geometry"); geometry != nil && geometry.Type() == TypeObject {
+				return geometry.GetPoint()
+			}
+		}
+	}
+	return Point{}, false
+}
+
+// GetBBox returns the bbox member of the GeoJSON object at the given keys
+// path, of 4 numbers or 6 with altitudes.
+//
+// ok is false for non-existing keys path or for a missing or invalid bbox.
+func (v *Value) GetBBox(keys ...string) (b BBox, ok bool) {
+	a := v.Get(keys...).GetArray("bbox")
+	n := len(a)
+	if n != 4 && n != 6 {
+		return BBox{}, false
+	}
+	for _, c := range a {
+		if c.Type() != TypeNumber {
+			return BBox{}, false
+		}
+	}
+	return BBox{West: a[0].n, South: a[1].n, East: a[n/2].n, North: a[n/2+1].n}, true
+}
+
+func position(a []*Value) (Point, bool) {
+	if len(a) < 2 || a[0].Type() != TypeNumber || a[1].Type() != TypeNumber {
+		return Point{}, false
+	}
+	return Point{Lon: a[0].n, Lat: a[1].n}, true
+}
+
+// InBBox returns a filter function accepting the values whose point, as
+// read by GetPoint, lies in b. It suits Find and the filtering of arrays
+// of features.
+func InBBox(b BBox) func(v *Value) bool {
+	return func(v *Value) bool {
+		p, ok := v.GetPoint()
+		return ok && b.Contains(p)
+	}
+}
+
+// WithinDistance returns a filter function accepting the values whose
+// point, as read by GetPoint, is at most meters away from center.
+func WithinDistance(center Point, meters float64) func(v *Value) bool {
+	return func(v *Value) bool {
+		p, ok := v.GetPoint()
+		return ok && center.Distance(p) <= meters
+	}
+}
+
+// distance returns the distance in meters between a point of the object
+// and a longitude and a latitude: distance(location, 2.35, 48.85). The
+// point is read as by GetPoint, and anything else gives null.
+func distance(o *Object, args []*Value) (string, error) {
+	if err := expectArgs(args, 3); err != nil {
+		return "", err
+	}
+	if args[1] == nil || args[1].Type() != TypeNumber || args[2] == nil || args[2].Type() != TypeNumber {
+		return "", fmt.Errorf("expects a longitude and a latitude")
+	}
+	if args[0] == nil {
+		return "null", nil
+	}
+	p, ok := args[0].GetPoint()
+	if !ok {
+		return "null", nil
+	}
+	d := p.Distance(Point{Lon: args[1].n, Lat: args[2].n})
+	return strconv.FormatFloat(d, 'f', -1, 64), nil
+}
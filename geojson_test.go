@@ -0,0 +1,176 @@
+package jsonq
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetPoint(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"point": {"type": "Point", "coordinates": [2.35, 48.85, 35]},
+		"feature": {"type": "Feature", "geometry": {"type": "Point", "coordinates": [-0.12, 51.5]}, "properties": {}},
+		"position": [13.4, 52.52],
+		"line": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]},
+		"short": [1],
+		"text": ["a", "b"],
+		"empty": {"type": "Feature", "geometry": null}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		key  string
+		want Point
+		ok   bool
+	}{
+		{"point", Point{2.35, 48.85}, true},
+		{"feature", Point{-0.12, 51.5}, true},
+		{"position", Point{13.4, 52.52}, true},
+		{"line", Point{}, false},
+		{"short", Point{}, false},
+		{"text", Point{}, false},
+		{"empty", Point{}, false},
+		{"missing", Point{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := v.GetPoint(tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("GetPoint(%s) = %v, %t, want %v, %t", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetBBox(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[
+		{"type": "FeatureCollection", "bbox": [-10, 40, 10, 55], "features": []},
+		{"type": "Polygon", "bbox": [-10, 40, 0, 10, 55, 3000]},
+		{"type": "Polygon", "bbox": [-10, 40, 10]},
+		{"type": "Polygon", "bbox": [-10, 40, "10", 55]},
+		{"type": "Polygon"}
+	]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	want := BBox{West: -10, South: 40, East: 10, North: 55}
+	for i, ok := range []bool{true, true, false, false, false} {
+		got, gotOK := v.GetBBox(string(rune('0' + i)))
+		if gotOK != ok || (ok && got != want) {
+			t.Errorf("GetBBox(%d) = %v, %t, want %v, %t", i, got, gotOK, want, ok)
+		}
+	}
+}
+
+func TestBBoxContains(t *testing.T) {
+	europe := BBox{West: -10, South: 35, East: 30, North: 70}
+	pacific := BBox{West: 170, South: -20, East: -170, North: 20}
+	tests := []struct {
+		b    BBox
+		p    Point
+		want bool
+	}{
+		{europe, Point{2.35, 48.85}, true},
+		{europe, Point{-10, 35}, true},
+		{europe, Point{-74, 40.7}, false},
+		{europe, Point{2.35, 71}, false},
+		{pacific, Point{178, 0}, true},
+		{pacific, Point{-175, 0}, true},
+		{pacific, Point{0, 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.b.Contains(tt.p); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %t, want %t", tt.b, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPointDistance(t *testing.T) {
+	paris, london := Point{2.3522, 48.8566}, Point{-0.1276, 51.5072}
+	if d := paris.Distance(london); math.Abs(d-343.5e3) > 1e3 {
+		t.Errorf("Distance(paris, london) = %f, want about 343.5 km", d)
+	}
+	if d := paris.Distance(paris); d != 0 {
+		t.Errorf("Distance(paris, paris) = %f, want 0", d)
+	}
+}
+
+func TestGeoFilters(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.35, 48.85]}, "properties": {"name": "Paris"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.29, 48.86]}, "properties": {"name": "Trocadero"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-0.12, 51.5]}, "properties": {"name": "London"}},
+		{"type": "Feature", "geometry": null, "properties": {"name": "Nowhere"}}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	names := func(accept func(*Value) bool) []string {
+		var names []string
+		for _, f := range v.GetArray("features") {
+			if accept(f) {
+				names = append(names, string(f.GetStringBytes("properties", "name")))
+			}
+		}
+		return names
+	}
+	if got := names(InBBox(BBox{West: 2, South: 48, East: 3, North: 49})); len(got) != 2 || got[0] != "Paris" || got[1] != "Trocadero" {
+		t.Errorf("InBBox = %v, want [Paris Trocadero]", got)
+	}
+	if got := names(WithinDistance(Point{2.35, 48.85}, 1000)); len(got) != 1 || got[0] != "Paris" {
+		t.Errorf("WithinDistance(1km) = %v, want [Paris]", got)
+	}
+	if got := names(WithinDistance(Point{2.35, 48.85}, 500e3)); len(got) != 3 {
+		t.Errorf("WithinDistance(500km) = %v, want 3 features", got)
+	}
+
+	found, path := v.Find(func(path []string, v *Value) bool {
+		return v.IsObject() && InBBox(BBox{West: -1, South: 51, East: 0, North: 52})(v)
+	})
+	if found == nil || string(found.GetStringBytes("properties", "name")) != "London" || len(path) != 2 {
+		t.Errorf("Find(InBBox) = %s at %v, want London", found, path)
+	}
+}
+
+func TestKeepDistance(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"shops": [
+		{"name": "a", "location": {"type": "Point", "coordinates": [2.35, 48.85]}},
+		{"name": "b", "location": [2.35, 48.86]},
+		{"name": "c", "location": "here"},
+		{"name": "d"}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	got, err := v.Keep(*MustParseQuery(`{shops{name, d: distance(location, 2.35, 48.85)}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out, err := p.Parse(got)
+	if err != nil {
+		t.Fatalf("cannot parse output %s: %s", got, err)
+	}
+	shops := out.GetArray("shops")
+	if len(shops) != 4 {
+		t.Fatalf("Keep = %s, want 4 shops", got)
+	}
+	if d := shops[0].GetFloat64("d"); d != 0 {
+		t.Errorf("distance of a = %f, want 0", d)
+	}
+	if d := shops[1].GetFloat64("d"); math.Abs(d-1112) > 1 {
+		t.Errorf("distance of b = %f, want about 1112", d)
+	}
+	for _, i := range []int{2, 3} {
+		if !shops[i].Get("d").IsNull() {
+			t.Errorf("distance of %s = %s, want null", shops[i].GetStringBytes("name"), shops[i].Get("d"))
+		}
+	}
+
+	for _, field := range []string{`distance(location)`, `distance(location, "2.35", 48.85)`} {
+		if _, err := v.Keep(*MustParseQuery("{shops{d: " + field + "}}")); err == nil {
+			t.Errorf("Keep(%s) expecting non-nil error", field)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// group is the group_by directive of an array level. It splits the
+// elements matching the filters of the level by the value of a field, and
+// returns an object holding, for each value, the result of the block of
+// the directive over the elements of the group:
+//
+//	{orders{group_by(customer_id){count(), sum(total)}}}
+//
+// returns the number of orders and their sum for each customer. A block of
+// keys and levels returns the array of the elements of each group instead,
+// and group_by(customer_id) alone returns them whole. Elements missing the
+// field are grouped under null, like those where it is null. Strings are
+// written as they are and the other values in canonical form, in the order
+// they appear.
+//
+// The elements may also be grouped by a value computed by the function
+// library, either called in the directive or defined as a computed field of
+// the level:
+//
+//	{events{group_by(datetrunc(at, "day")){count()}}}
+//	{events{day: datetrunc(at, "day"), group_by(day){count()}}}
+type group struct {
+	field Path
+	// expr computes the value of the elements in place of field.
+	expr *computed
+	q    *Query
+}
+
+// parseGroup parses cmd as a group_by directive. It reports false when cmd
+// is not one.
+func parseGroup(cmd string, strict bool) (*group, bool, error) {
+	if !strings.HasPrefix(cmd, "group_by(") {
+		return nil, false, nil
+	}
+	n, err := scanGroup(cmd[len("group_by"):], '(', ')')
+	if err != nil {
+		return nil, true, err
+	}
+	g := &group{}
+	by := strings.TrimSpace(cmd[len("group_by(") : len("group_by")+n-1])
+	if isCall(by) {
+		if g.expr, err = parseCall(by); err != nil {
+			return nil, true, fmt.Errorf("mal formated group_by : %q: %s", cmd, err)
+		}
+	} else if g.field, err = parseField(by); err != nil {
+		return nil, true, fmt.Errorf("group_by expects a field or a function call : %q", cmd)
+	}
+	rest := strings.TrimSpace(cmd[len("group_by")+n:])
+	if rest == "" {
+		q := newQuery()
+		q.all = true
+		g.q = &q
+		return g, true, nil
+	}
+	if !strings.HasPrefix(rest, "{") {
+		return nil, true, fmt.Errorf("mal formated group_by : %q", cmd)
+	}
+	q, name, err := parseQuery(rest, strict)
+	if err != nil {
+		return nil, true, err
+	}
+	if name != "" || q.slice != nil || len(q.filters) > 0 {
+		return nil, true, fmt.Errorf("mal formated group_by : %q", cmd)
+	}
+	g.q = q
+	return g, true, nil
+}
+
+// resolve makes g group by the computed field of q named like its field,
+// if any.
+func (g *group) resolve(q *Query) {
+	if g.expr != nil || len(g.field) != 1 {
+		return
+	}
+	for _, c := range q.computed {
+		if c.as == g.field[0] {
+			g.expr, g.field = c, nil
+			return
+		}
+	}
+}
+
+// fields returns the fields of the elements read to group them.
+func (g *group) fields() []Path {
+	if g.expr != nil {
+		return g.expr.fields()
+	}
+	return []Path{g.field}
+}
+
+// key returns the name of the group of element.
+func (g *group) key(element *Value) (string, error) {
+	v := element.Get(g.field...)
+	if g.expr != nil {
+		v = nil
+		if o, err := element.Object(); err == nil {
+			computed, err := g.expr.keep(o)
+			if err != nil {
+				return "null", err
+			}
+			var p Parser
+			if v, err = p.Parse(computed); err != nil {
+				return "null", err
+			}
+		}
+	}
+	if v == nil || v.Type() == TypeNull {
+		return "null", nil
+	}
+	if v.Type() == TypeString {
+		return v.s, nil
+	}
+	return string(v.AppendCanonical(nil)), nil
+}
+
+// keep returns the JSON object of the groups of values.
+func (g *group) keep(values []*Value, path Path, e *execution) (string, error) {
+	var keys []string
+	groups := map[string][]*Value{}
+	for _, v := range values {
+		key, err := g.key(v)
+		if err != nil {
+			if err = e.fail(path, fmt.Errorf("group_by: %s", err)); err != nil {
+				return "", err
+			}
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], v)
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	for i, key := range keys {
+		nValue, err := keepArray(*g.q, groups[key], 0, path.child(key), e)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			w.WriteRune(',')
+		}
+		w.Write(appendQuoted(nil, key))
+		w.WriteRune(':')
+		w.WriteString(nValue)
+	}
+	w.WriteRune('}')
+	return w.String(), e.limit(w.Len())
+}
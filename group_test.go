@@ -0,0 +1,88 @@
+package jsonq
+
+import "testing"
+
+func TestKeepGroup(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"orders": [
+		{"id": 1, "customer_id": "c1", "total": 10, "status": "paid"},
+		{"id": 2, "customer_id": 7, "total": 4.5, "status": "paid"},
+		{"id": 3, "customer_id": "c1", "total": 20, "status": "open"},
+		{"id": 4, "customer_id": 7.0, "total": 1, "status": "paid"},
+		{"id": 5, "total": 3, "status": "paid"},
+		{"id": 6, "customer_id": "say \"hi\"", "total": 2, "status": "paid", "shop": {"city": "Paris"}}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{orders{group_by(customer_id){count(), sum(total)}}}`, `{"orders":{"c1":{"count()":2,"sum(total)":30},"7":{"count()":2,"sum(total)":5.5},"null":{"count()":1,"sum(total)":3},"say \"hi\"":{"count()":1,"sum(total)":2}}}`},
+		{`{orders(status = paid && total > 2){group_by(customer_id){id}}}`, `{"orders":{"c1":[{"id":1}],"7":[{"id":2}],"null":[{"id":5}]}}`},
+		{`{orders(id < 3){group_by(status)}}`, `{"orders":{"paid":[{"id":1,"customer_id":"c1","total":10,"status":"paid"},{"id":2,"customer_id":7,"total":4.5,"status":"paid"}]}}`},
+		{`{orders(status = open){group_by(customer_id){max(total) as highest}}}`, `{"orders":{"c1":{"highest":20}}}`},
+		{`{orders{sort(total desc), top(3, by: total), group_by(status){id}}}`, `{"orders":{"open":[{"id":3}],"paid":[{"id":1},{"id":2}]}}`},
+		{`{orders(shop?){group_by(shop.city){count()}}}`, `{"orders":{"Paris":{"count()":1}}}`},
+		{`{orders(id > 10){group_by(status){count()}}}`, `{"orders":{}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestKeepGroupComputed(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"events": [
+		{"id": 1, "at": "2024-03-14T15:09:26Z", "n": 2},
+		{"id": 2, "at": "2024-03-15T08:00:00Z", "n": 1},
+		{"id": 3, "at": "2024-03-14T23:59:59Z", "n": 4},
+		{"id": 4, "at": "yesterday", "n": 8},
+		{"id": 5, "n": 16}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{events{group_by(datetrunc(at, "day")){count(), sum(n)}}}`, `{"events":{"2024-03-14T00:00:00Z":{"count()":2,"sum(n)":6},"2024-03-15T00:00:00Z":{"count()":1,"sum(n)":1},"null":{"count()":2,"sum(n)":24}}}`},
+		{`{events{day: datetrunc(at, "day"), group_by(day){id}}}`, `{"events":{"2024-03-14T00:00:00Z":[{"id":1},{"id":3}],"2024-03-15T00:00:00Z":[{"id":2}],"null":[{"id":4},{"id":5}]}}`},
+		{`{events{group_by(day){id}, day: datetrunc(at, "year")}}`, `{"events":{"2024-01-01T00:00:00Z":[{"id":1},{"id":2},{"id":3}],"null":[{"id":4},{"id":5}]}}`},
+		{`{events(n < 4){group_by(format("{} items", n)){id}}}`, `{"events":{"2 items":[{"id":1}],"1 items":[{"id":2}]}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseGroupErrors(t *testing.T) {
+	for _, query := range []string{
+		"{orders{group_by(){count()}}}",
+		"{orders{group_by(a..b)}}",
+		"{orders{group_by(unknown(at)){count()}}}",
+		"{orders{group_by(status) as s}}",
+		"{orders{group_by(status)(total > 3){count()}}}",
+		"{orders{group_by(status){count()}, sum(total)}}",
+		"{orders{group_by(status){count()}, pivot(status, total)}}",
+		"{**{group_by(status){count()}}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -57,6 +57,9 @@ func GetBytes(data []byte, keys ...string) []byte {
 //
 // 0 is returned on error. Use Parser for proper error handling.
 //
+// The fractional part of the number is dropped and numbers out of the
+// int range saturate to the closest bound.
+//
 // Parser is faster for obtaining multiple fields from JSON.
 func GetInt(data []byte, keys ...string) int {
 	p := handyPool.Get()
@@ -0,0 +1,45 @@
+package jsonq
+
+import (
+	"hash"
+	"strconv"
+)
+
+// Hash resets h, writes v to it and returns the checksum. With canonical,
+// v is written in its canonical form, as by AppendCanonical, so documents
+// differing only by key order or number spelling hash the same. Otherwise
+// v is written as by String.
+func (v *Value) Hash(h hash.Hash, canonical bool) []byte {
+	h.Reset()
+	if canonical {
+		h.Write(v.AppendCanonical(nil))
+	} else {
+		h.Write([]byte(v.String()))
+	}
+	return h.Sum(nil)
+}
+
+// HashPaths returns the canonical checksum of v and of every value it
+// contains, keyed by their JSON pointer (RFC 6901), "" being v itself, so
+// change-detection systems can find the sub-documents that changed by
+// comparing two maps.
+func (v *Value) HashPaths(h hash.Hash) map[string][]byte {
+	sums := map[string][]byte{}
+	v.hashPaths(h, "", sums)
+	return sums
+}
+
+func (v *Value) hashPaths(h hash.Hash, ptr string, sums map[string][]byte) {
+	sums[ptr] = v.Hash(h, true)
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			kv.v.hashPaths(h, ptr+"/"+escapePointer(kv.k), sums)
+		}
+	case TypeArray:
+		for i, e := range v.a {
+			e.hashPaths(h, ptr+"/"+strconv.Itoa(i), sums)
+		}
+	}
+}
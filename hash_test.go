@@ -0,0 +1,43 @@
+package jsonq
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestValueHash(t *testing.T) {
+	a, b := parsePair(t, `{"a":1,"b":[1.0,"x"]}`, `{ "b": [1, "x"], "a": 1e0 }`)
+	h := sha256.New()
+	if !bytes.Equal(a.Hash(h, true), b.Hash(h, true)) {
+		t.Fatalf("expecting equal canonical hashes for %s and %s", a, b)
+	}
+	if bytes.Equal(a.Hash(h, false), b.Hash(h, false)) {
+		t.Fatalf("expecting different raw hashes for %s and %s", a, b)
+	}
+	sum := sha256.Sum256([]byte(a.String()))
+	if !bytes.Equal(a.Hash(h, false), sum[:]) {
+		t.Fatalf("unexpected raw hash for %s", a)
+	}
+}
+
+func TestValueHashPaths(t *testing.T) {
+	a, b := parsePair(t, `{"a":{"x":1},"b/c":[1,2]}`, `{"a":{"x":1},"b/c":[1,3]}`)
+	h := sha256.New()
+	sa, sb := a.HashPaths(h), b.HashPaths(h)
+	if len(sa) != 6 || len(sb) != 6 {
+		t.Fatalf("unexpected number of paths; got %d and %d; want 6", len(sa), len(sb))
+	}
+	var changed []string
+	for _, ptr := range []string{"", "/a", "/a/x", "/b~1c", "/b~1c/0", "/b~1c/1"} {
+		if sa[ptr] == nil {
+			t.Fatalf("missing hash for %q", ptr)
+		}
+		if !bytes.Equal(sa[ptr], sb[ptr]) {
+			changed = append(changed, ptr)
+		}
+	}
+	if len(changed) != 3 || changed[0] != "" || changed[1] != "/b~1c" || changed[2] != "/b~1c/1" {
+		t.Fatalf("unexpected changed paths: %q", changed)
+	}
+}
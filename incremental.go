@@ -0,0 +1,169 @@
+package jsonq
+
+import (
+	"bytes"
+)
+
+// Incremental keeps the result of a query on a document changed in place,
+// as a watched configuration is: a change re-evaluates only the parts of
+// the result it may affect, instead of the whole document.
+//
+// A change to a value the query does not read leaves the result as it is.
+// When the query has no directive at its root level and joins no arrays,
+// the result is assembled from the levels of the root object, and a change
+// under one of them only re-evaluates it. Otherwise, the whole document is.
+//
+// An Incremental may not be used from concurrent goroutines.
+type Incremental struct {
+	q    Query
+	doc  *Value
+	need *need
+	// levels caches the results of the levels of the root object, by
+	// name, when the result is assembled from them.
+	levels map[string]*levelResult
+	output string
+	err    error
+}
+
+// levelResult is the result of a level of the root object.
+type levelResult struct {
+	value  string
+	ok     bool
+	errors []*PathError
+	err    error
+}
+
+// NewIncremental evaluates q on doc, which is then changed through the
+// returned Incremental. doc is changed in place, and must stay valid as
+// long as the Incremental is used.
+func NewIncremental(q *Query, doc *Value) *Incremental {
+	inc := &Incremental{q: *q, doc: doc, need: q.needs()}
+	opts := q.opts
+	if !inc.need.all && !q.hasJoins() && doc.Type() == TypeObject && !opts.Unique && opts.Audit == nil && opts.Seed == 0 {
+		inc.levels = map[string]*levelResult{}
+		for name := range inc.q.next {
+			inc.evaluate(name)
+		}
+	}
+	inc.update()
+	return inc
+}
+
+// Result returns the result of the query on the document, as Value.Keep
+// does.
+func (inc *Incremental) Result() (string, error) {
+	return inc.output, inc.err
+}
+
+// Set sets the value at path in the document, as Value.SetPath does, and
+// re-evaluates the query. It reports whether the result changed.
+func (inc *Incremental) Set(path Path, value *Value) (bool, error) {
+	affected := inc.affects(path)
+	if err := inc.doc.SetPath(path, value); err != nil {
+		return false, err
+	}
+	return inc.change(path, affected), nil
+}
+
+// Del deletes the value at path in the document and re-evaluates the
+// query. It reports whether the result changed.
+func (inc *Incremental) Del(path Path) (bool, error) {
+	affected := inc.affects(path)
+	if _, err := removeAt(inc.doc, path); err != nil {
+		return false, err
+	}
+	return inc.change(path, affected), nil
+}
+
+// affects reports whether changing the value at path may change the result
+// of the query. It is called before the change, the values missing along
+// path being objects to create.
+func (inc *Incremental) affects(path Path) bool {
+	if inc.q.hasJoins() {
+		return true
+	}
+	n, v := inc.need, inc.doc
+	for _, key := range path {
+		if n.all {
+			return true
+		}
+		if v != nil && v.Type() == TypeArray {
+			// What is needed of an array is needed of its elements.
+			v = v.step(key)
+			continue
+		}
+		if n = n.keys[key]; n == nil {
+			return false
+		}
+		if v != nil {
+			v = v.step(key)
+		}
+	}
+	return true
+}
+
+// change re-evaluates the query once the value at path changed, and
+// reports whether its result changed.
+func (inc *Incremental) change(path Path, affected bool) bool {
+	if !affected {
+		return false
+	}
+	if inc.levels != nil && inc.q.next[path[0]] != nil {
+		inc.evaluate(path[0])
+	}
+	output := inc.output
+	inc.update()
+	return inc.output != output
+}
+
+// evaluate evaluates the level name of the root object.
+func (inc *Incremental) evaluate(name string) {
+	e := newExecution(inc.q, inc.doc)
+	l := &levelResult{}
+	l.value, l.ok, l.err = inc.q.keepLevel(&inc.doc.o, name, Path{}, e)
+	l.errors = e.errors
+	inc.levels[name] = l
+}
+
+// update sets the result of the query, assembling it from the levels of the
+// root object when they are cached.
+func (inc *Incremental) update() {
+	if inc.levels == nil {
+		inc.output, inc.err = inc.doc.Keep(inc.q)
+		return
+	}
+	e := newExecution(inc.q, inc.doc)
+	inc.output, inc.err = e.result(inc.assemble(e))
+}
+
+// assemble returns the JSON of the root object from the cached results of
+// its levels, as Value.keep does.
+func (inc *Incremental) assemble(e *execution) (string, error) {
+	o := &inc.doc.o
+	if !inc.q.accept(o) {
+		return "", nil
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	first := true
+	inc.q.eachField(o, func(key string, val *Value) {
+		writeField(&w, first, key, val.raw())
+		first = false
+	})
+	for _, name := range inc.q.levelNames() {
+		l := inc.levels[name]
+		if l.err != nil {
+			return "", l.err
+		}
+		e.errors = append(e.errors, l.errors...)
+		if l.ok {
+			writeField(&w, first, name, l.value)
+			first = false
+		}
+	}
+	w.WriteRune('}')
+	if err := e.limit(w.Len()); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
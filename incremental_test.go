@@ -0,0 +1,107 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	for _, query := range []string{
+		"{name, db{host, port}, users(active=true){id}}",
+		"(name=app){db{host}, users{id}}",
+		"{db{host, join(users.id = port) as user{active}}}",
+		"{name, users{*, !active}}",
+		"{*, !secret}",
+		"{users{id}, count(users) as n}",
+	} {
+		q, err := ParseQuery(query)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", query, err)
+		}
+		q.SetOptions(Options{Deterministic: true})
+		var p Parser
+		doc, err := p.Parse(`{"name": "app", "secret": "s", "db": {"host": "a", "port": 1}, "users": [{"id": 1, "active": true}, {"id": 2, "active": false}]}`)
+		if err != nil {
+			t.Fatalf("cannot parse json: %s", err)
+		}
+		inc := NewIncremental(q, doc)
+		for _, tt := range []struct {
+			path  Path
+			value string
+		}{
+			{Path{"db", "host"}, `"b"`},
+			{Path{"db", "port"}, `2`},
+			{Path{"users", "1", "active"}, `true`},
+			{Path{"users", "2"}, `{"id": 3, "active": true}`},
+			{Path{"secret"}, `"t"`},
+			{Path{"other", "key"}, `1`},
+			{Path{"name"}, `"other"`},
+			{Path{"db"}, `"none"`},
+			{Path{"name"}, `"app"`},
+			{Path{"users", "0"}, `-`},
+			{Path{"db"}, `-`},
+		} {
+			before, _ := inc.Result()
+			var changed bool
+			if tt.value == "-" {
+				changed, err = inc.Del(tt.path)
+			} else {
+				var value Parser
+				v, perr := value.Parse(tt.value)
+				if perr != nil {
+					t.Fatalf("cannot parse %s: %s", tt.value, perr)
+				}
+				changed, err = inc.Set(tt.path, v)
+			}
+			if err != nil {
+				t.Fatalf("%s: cannot change %s: %s", query, tt.path, err)
+			}
+			got, err := inc.Result()
+			want, werr := doc.Keep(*q)
+			if got != want || (err == nil) != (werr == nil) {
+				t.Errorf("%s: after changing %s, result %s, %v; want %s, %v", query, tt.path, got, err, want, werr)
+			}
+			if changed != (got != before) {
+				t.Errorf("%s: changing %s reported changed %t, from %s to %s", query, tt.path, changed, before, got)
+			}
+		}
+	}
+}
+
+func TestIncrementalAffects(t *testing.T) {
+	q, err := ParseQuery("(kind=a){db{host}, users{id}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var p Parser
+	doc, err := p.Parse(`{"kind": "a", "db": {"host": "a", "port": 1}, "users": [{"id": 1}], "other": 1}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	inc := NewIncremental(q, doc)
+	for _, tt := range []struct {
+		path Path
+		want bool
+	}{
+		{Path{"kind"}, true},
+		{Path{"db", "host"}, true},
+		{Path{"db", "port"}, false},
+		{Path{"db"}, true},
+		{Path{"users", "0", "id"}, true},
+		{Path{"users", "0", "name"}, false},
+		{Path{"other"}, false},
+		{Path{"missing", "key"}, false},
+	} {
+		if got := inc.affects(tt.path); got != tt.want {
+			t.Errorf("affects(%s) = %t, want %t", tt.path, got, tt.want)
+		}
+	}
+	if inc.levels == nil {
+		t.Errorf("the result is not assembled from the levels of the root")
+	}
+	if _, err := inc.Set(Path{}, valueNull); err == nil {
+		t.Errorf("expecting non-nil error when setting the root")
+	}
+	if _, err := inc.Del(Path{"missing"}); err == nil {
+		t.Errorf("expecting non-nil error when deleting a missing key")
+	}
+}
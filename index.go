@@ -0,0 +1,151 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Index records where the values of a JSON document are, so that many
+// queries against one large document, kept in a cache for instance, only
+// parse the parts they need instead of the whole document each time.
+//
+// An Index is immutable and may be used from concurrent goroutines.
+type Index struct {
+	data string
+	root *indexNode
+}
+
+// indexNode is the position of a value in the document and, for an
+// indexed object, those of its keys.
+type indexNode struct {
+	start, end int
+	keys       map[string]*indexNode
+}
+
+// NewIndex indexes the keys of the objects of data down to depth levels:
+// with depth 1, only the keys of the root object are indexed.
+//
+// data is copied. The values below depth are not validated until they are
+// parsed.
+func NewIndex(data []byte, depth int) (*Index, error) {
+	idx := &Index{data: string(data)}
+	s := skipWS(idx.data)
+	root, tail, err := idx.index(s, depth)
+	if err != nil {
+		return nil, fmt.Errorf("cannot index JSON: %w; unindexed tail: %q", err, tail)
+	}
+	if tail = skipWS(tail); len(tail) > 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedTail, tail)
+	}
+	idx.root = root
+	return idx, nil
+}
+
+func (idx *Index) offset(s string) int {
+	return len(idx.data) - len(s)
+}
+
+func (idx *Index) index(s string, depth int) (*indexNode, string, error) {
+	n := &indexNode{start: idx.offset(s)}
+	if depth <= 0 || len(s) == 0 || s[0] != '{' {
+		tail, err := skipValue(s)
+		n.end = idx.offset(tail)
+		return n, tail, err
+	}
+	n.keys = map[string]*indexNode{}
+	s = skipWS(s[1:])
+	if len(s) > 0 && s[0] == '}' {
+		n.end = idx.offset(s[1:])
+		return n, s[1:], nil
+	}
+	for {
+		if len(s) == 0 || s[0] != '"' {
+			return nil, s, fmt.Errorf(`cannot find opening '"" for object key`)
+		}
+		k, tail, err := parseRawKey(s[1:])
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		if strings.IndexByte(k, '\\') >= 0 {
+			k = unescapeStringBestEffort(string(append([]byte(nil), k...)))
+		}
+		s = skipWS(tail)
+		if len(s) == 0 || s[0] != ':' {
+			return nil, s, ErrMissingColon
+		}
+		child, tail, err := idx.index(skipWS(s[1:]), depth-1)
+		if err != nil {
+			return nil, tail, err
+		}
+		if _, ok := n.keys[k]; !ok {
+			n.keys[k] = child
+		}
+		s = skipWS(tail)
+		if len(s) == 0 {
+			return nil, s, fmt.Errorf("%w of object", ErrUnexpectedEnd)
+		}
+		if s[0] == '}' {
+			n.end = idx.offset(s[1:])
+			return n, s[1:], nil
+		}
+		if s[0] != ',' {
+			return nil, s, fmt.Errorf("%w after object value", ErrMissingComma)
+		}
+		s = skipWS(s[1:])
+	}
+}
+
+// Raw returns the JSON of the value at the keys path, or nil when the path
+// is missing or goes below the indexed depth.
+func (idx *Index) Raw(keys ...string) []byte {
+	n := idx.root
+	for _, key := range keys {
+		if n = n.keys[key]; n == nil {
+			return nil
+		}
+	}
+	return []byte(idx.data[n.start:n.end])
+}
+
+// ParseFor parses, with p, the parts of the document needed by q, as
+// Parser.ParseFor does, without going through the indexed values q does
+// not need.
+func (idx *Index) ParseFor(p *Parser, q *Query) (*Value, error) {
+	n := q.needs()
+	if n.all || q.hasJoins() {
+		return p.Parse(idx.data)
+	}
+	var bb bytes.Buffer
+	idx.assemble(&bb, idx.root, n)
+	return p.ParseFor(q, bb.Bytes())
+}
+
+// assemble writes to bb the parts of the value at node needed by n, the
+// keys of objects in document order.
+func (idx *Index) assemble(bb *bytes.Buffer, node *indexNode, n *need) {
+	if n.all || node.keys == nil {
+		bb.WriteString(idx.data[node.start:node.end])
+		return
+	}
+	keys := make([]string, 0, len(n.keys))
+	for key := range n.keys {
+		if node.keys[key] != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return node.keys[keys[i]].start < node.keys[keys[j]].start
+	})
+	bb.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			bb.WriteByte(',')
+		}
+		bb.Write(appendQuoted(nil, key))
+		bb.WriteByte(':')
+		idx.assemble(bb, node.keys[key], n.keys[key])
+	}
+	bb.WriteByte('}')
+}
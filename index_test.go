@@ -0,0 +1,75 @@
+package jsonq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	const doc = ` {
+		"meta": {"count": 3, "big": [1, 2, {"x": "}"}]},
+		"users": [{"id": 1, "name": "Al"}, {"id": 2, "name": "Bo"}],
+		"config": {"db": {"host": "h", "port": 1}, "a\"b": true},
+		"meta": "duplicate"
+	}`
+	idx, err := NewIndex([]byte(doc), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"meta", "big"}, `[1, 2, {"x": "}"}]`},
+		{[]string{"meta"}, `{"count": 3, "big": [1, 2, {"x": "}"}]}`},
+		{[]string{"config", "db"}, `{"host": "h", "port": 1}`},
+		{[]string{"config", `a"b`}, `true`},
+		{[]string{"config", "db", "host"}, ``},
+		{[]string{"missing"}, ``},
+	}
+	for _, tt := range tests {
+		if got := string(idx.Raw(tt.keys...)); got != tt.want {
+			t.Errorf("Raw(%q) = %s, want %s", tt.keys, got, tt.want)
+		}
+	}
+
+	var whole, p Parser
+	all, err := whole.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	for _, query := range []string{
+		"{users(id > 1){name}}",
+		"{config{db{host}}, meta{count}}",
+		"{users{pivot(id, name)}}",
+		"{config}",
+		"{missing, users{id}}",
+	} {
+		q := MustParseQuery(query)
+		q.SetOptions(Options{Deterministic: true})
+		v, err := idx.ParseFor(&p, q)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", query, err)
+		}
+		want, _ := all.Keep(*q)
+		if got, err := v.Keep(*q); err != nil || got != want {
+			t.Errorf("%s: Keep() = %s, %v, want %s", query, got, err, want)
+		}
+	}
+
+	// The needed parts are assembled in document order.
+	const assembled = `{"meta":{"count":3},"users":[{"id": 1, "name": "Al"}, {"id": 2, "name": "Bo"}],"config":{"db":{"host": "h", "port": 1}}}`
+	for i := 0; i < 10; i++ {
+		var bb bytes.Buffer
+		idx.assemble(&bb, idx.root, MustParseQuery("{users{id}, config{db{port}}, meta{count}}").needs())
+		if got := bb.String(); got != assembled {
+			t.Fatalf("assemble() = %s, want %s", got, assembled)
+		}
+	}
+
+	for _, bad := range []string{`{"a": 1,}`, `{"a" 1}`, `{"a": 1} x`, `{"a": [1}`} {
+		if _, err := NewIndex([]byte(bad), 3); err == nil {
+			t.Errorf("NewIndex(%s) expecting non-nil error", bad)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package jsonq
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Resolver returns the value of a variable and whether it is defined.
+type Resolver func(name string) (string, bool)
+
+// Interpolate substitutes the ${NAME} placeholders of the string values of
+// v, in place, with their value given by resolve. Object keys are left as
+// they are. $${ writes a literal ${.
+//
+// A nil resolve reads the environment, so a configuration file can be
+// loaded with:
+//
+//	v, err := p.Parse(config)
+//	...
+//	err = v.Interpolate(nil)
+//
+// An undefined variable or an unclosed placeholder is reported by a
+// *PathError leading to its string; the strings before it in the document
+// are already substituted.
+func (v *Value) Interpolate(resolve Resolver) error {
+	if resolve == nil {
+		resolve = os.LookupEnv
+	}
+	return v.interpolate(resolve, Path{})
+}
+
+func (v *Value) interpolate(resolve Resolver, path Path) error {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			if err := kv.v.interpolate(resolve, path.child(kv.k)); err != nil {
+				return err
+			}
+		}
+	case TypeArray:
+		for i, e := range v.a {
+			if err := e.interpolate(resolve, path.child(strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	case TypeString:
+		if !strings.Contains(v.s, "${") {
+			return nil
+		}
+		s, err := interpolate(v.s, resolve)
+		if err != nil {
+			return &PathError{Path: path, Err: err}
+		}
+		v.s = s
+		v.Description = string(appendQuoted(nil, s))
+	}
+	return nil
+}
+
+func interpolate(s string, resolve Resolver) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed placeholder in %q", s)
+		}
+		name := s[i+2 : i+end]
+		value, ok := resolve(name)
+		if !ok {
+			return "", fmt.Errorf("undefined variable %q", name)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(value)
+		s = s[i+end+1:]
+	}
+}
@@ -0,0 +1,66 @@
+package jsonq
+
+import (
+	"os"
+	"testing"
+)
+
+func TestValueInterpolate(t *testing.T) {
+	vars := map[string]string{"HOST": "db.local", "PORT": "5432", "EMPTY": ""}
+	resolve := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+	var p Parser
+	v, err := p.Parse(`{"db": {"url": "pg://${HOST}:${PORT}/x${EMPTY}", "port": 1, "${HOST}": "key"}, "list": ["$${HOST}", "plain", "${PORT}"]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.Interpolate(resolve); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := string(v.GetStringBytes("db", "url")); got != "pg://db.local:5432/x" {
+		t.Errorf("db.url = %q", got)
+	}
+	if got := string(v.GetStringBytes("list", "0")); got != "${HOST}" {
+		t.Errorf("list.0 = %q", got)
+	}
+	if got := string(v.GetStringBytes("list", "2")); got != "5432" {
+		t.Errorf("list.2 = %q", got)
+	}
+	if got := string(v.GetStringBytes("db", "${HOST}")); got != "key" {
+		t.Errorf("db.${HOST} = %q", got)
+	}
+	got, err := v.Keep(*MustParseQuery("{db{url}}"))
+	if err != nil || got != `{"db":{"url":"pg://db.local:5432/x"}}` {
+		t.Errorf("Keep() = %s, %v", got, err)
+	}
+
+	for _, in := range []string{`{"a": ["${MISSING}"]}`, `{"a": "${HOST"}`} {
+		v, err := p.Parse(in)
+		if err != nil {
+			t.Fatalf("cannot parse json: %s", err)
+		}
+		if err := v.Interpolate(resolve); err == nil {
+			t.Errorf("Interpolate(%s) expecting non-nil error", in)
+		} else if pe, ok := err.(*PathError); !ok || pe.Path[0] != "a" {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	}
+}
+
+func TestValueInterpolateEnv(t *testing.T) {
+	os.Setenv("JSONQ_TEST_VAR", "on")
+	defer os.Unsetenv("JSONQ_TEST_VAR")
+	var p Parser
+	v, err := p.Parse(`["${JSONQ_TEST_VAR}"]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.Interpolate(nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := string(v.GetStringBytes("0")); got != "on" {
+		t.Errorf("0 = %q", got)
+	}
+}
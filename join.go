@@ -0,0 +1,109 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// join embeds in the elements of a level the records of another array
+// of the document that share a key with them.
+//
+// It is written join(customers.id = customer_id) as customer{name} in a
+// retrieve block: for every element of the level, the elements of the
+// customers array at the root of the document whose id equals the
+// customer_id of the element are projected with {name} and embedded as
+// an array under customer.
+type join struct {
+	array Path
+	field string
+	local string
+	as    string
+	q     *Query
+}
+
+func parseJoin(cmd string, strict bool) (*join, error) {
+	n, err := scanGroup(cmd[len("join"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	condition := cmd[len("join(") : len("join")+n-1]
+	rest := strings.TrimSpace(cmd[len("join")+n:])
+
+	sides := strings.Split(condition, "=")
+	if len(sides) != 2 {
+		return nil, fmt.Errorf("mal formated join condition : %q", condition)
+	}
+	foreign := strings.Split(strings.TrimSpace(sides[0]), ".")
+	local := strings.TrimSpace(sides[1])
+	if len(foreign) < 2 || len(local) == 0 {
+		return nil, fmt.Errorf("mal formated join condition : %q", condition)
+	}
+
+	if !strings.HasPrefix(rest, "as ") {
+		return nil, fmt.Errorf("missing 'as' in join : %q", cmd)
+	}
+	rest = strings.TrimSpace(rest[len("as "):])
+	i := 0
+	for i < len(rest) && isNameChar(rest[i]) {
+		i++
+	}
+	as := rest[:i]
+	if len(as) == 0 {
+		return nil, fmt.Errorf("missing name in join : %q", cmd)
+	}
+	q, _, err := parseQuery(rest[i:], strict)
+	if err != nil {
+		return nil, err
+	}
+	return &join{
+		array: Path(foreign[:len(foreign)-1]),
+		field: foreign[len(foreign)-1],
+		local: local,
+		as:    as,
+		q:     q,
+	}, nil
+}
+
+// index returns the elements of the joined array by key. It is built once
+// per execution.
+func (j *join) index(e *execution) map[string][]*Value {
+	if idx, ok := e.joins[j]; ok {
+		return idx
+	}
+	idx := map[string][]*Value{}
+	for _, record := range e.root.GetArray(j.array...) {
+		if key := record.Get(j.field); key != nil {
+			idx[key.raw()] = append(idx[key.raw()], record)
+		}
+	}
+	if e.joins == nil {
+		e.joins = map[*join]map[string][]*Value{}
+	}
+	e.joins[j] = idx
+	return idx
+}
+
+// keep returns the projected records matching o, found at path.
+func (j *join) keep(o *Object, path Path, e *execution) (string, error) {
+	var w bytes.Buffer
+	w.WriteRune('[')
+	if key := o.Get(j.local); key != nil {
+		first := true
+		for _, record := range j.index(e)[key.raw()] {
+			nValue, err := record.keep(*j.q, path.child(j.as), e)
+			if err != nil {
+				return "", err
+			}
+			if len(nValue) > 0 {
+				if !first {
+					w.WriteRune(',')
+				}
+				first = false
+				w.WriteString(nValue)
+			}
+		}
+	}
+	w.WriteRune(']')
+	return w.String(), nil
+}
@@ -0,0 +1,54 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"customers": [{"id": 1, "name": "Al"}, {"id": 2, "name": "Bo"}],
+		"orders": [{"id": 10, "customer_id": 2}, {"id": 11, "customer_id": 1}, {"id": 12, "customer_id": 3}, {"id": 13}]
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q, err := ParseQuery("{orders{id, join(customers.id = customer_id) as customer{name}}}")
+	if err != nil {
+		t.Fatalf("cannot parse query: %s", err)
+	}
+	got, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{"orders":[{"id":10,"customer":[{"name":"Bo"}]},{"id":11,"customer":[{"name":"Al"}]},{"id":12,"customer":[]},{"id":13,"customer":[]}]}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+
+	// The join of a level selected twice.
+	for _, cmd := range []string{
+		"{orders{id}, orders{join(customers.id = customer_id) as customer{name}}}",
+		"{orders{id, join(customers.id = customer_id) as customer{name}}, orders{id}}",
+	} {
+		q, err := ParseQuery(cmd)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		if got, err := v.Keep(*q); err != nil || got != want {
+			t.Errorf("Keep(%s) = %s, %v, want %s", cmd, got, err, want)
+		}
+	}
+
+	for _, cmd := range []string{
+		"{orders{join(customers.id) as customer{name}}}",
+		"{orders{join(id = customer_id) as customer{name}}}",
+		"{orders{join(customers.id = customer_id) customer{name}}}",
+		"{orders{join(customers.id = customer_id) as {name}}}",
+		"{orders{join(customers.id = customer_id as customer{name}}}",
+	} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting non-nil error", cmd)
+		}
+	}
+}
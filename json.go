@@ -3,25 +3,147 @@ package jsonq
 import (
 	"bytes"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
-func (v Value) check(filter Filter) bool {
+func (v Value) check(filter Filter, opts *Options) bool {
+	if len(filter.sub) > 0 && v.Type() != TypeArray {
+		return v.checkSub(filter, opts)
+	}
 	switch v.Type() {
 	case TypeString:
-		return filter.check(v.s)
+		return filter.check(v.s, opts)
 	case TypeNumber:
-		return filter.check(v.n)
+		if opts.Decimal {
+			if ok, handled := filter.checkDecimal(&v, opts); handled {
+				return ok
+			}
+		}
+		if filter.coercion(opts).StrictNumbers {
+			return filter.check(v.typedNumber(), opts)
+		}
+		return filter.check(v.n, opts)
 	case TypeTrue:
-		return filter.check(true)
+		return filter.check(true, opts)
 	case TypeFalse:
-		return filter.check(false)
+		return filter.check(false, opts)
 	case TypeNull:
-		return filter.check(nil)
+		return filter.check(nil, opts)
+	case TypeArray:
+		all := filter.quantifier() == quantAll
+		for _, uValue := range v.a {
+			if uValue.check(filter, opts) != all {
+				return !all
+			}
+		}
+		return all
 	default:
 		return false
 	}
 }
 
+// truth is the result of a filter in the three-valued logic.
+// Its values are ordered so that and is a min and or is a max.
+type truth int8
+
+const (
+	truthFalse truth = iota
+	truthUnknown
+	truthTrue
+)
+
+func (t truth) and(other truth) truth {
+	if other < t {
+		return other
+	}
+	return t
+}
+
+func (t truth) or(other truth) truth {
+	if other > t {
+		return other
+	}
+	return t
+}
+
+func (t truth) not() truth {
+	return truthTrue - t
+}
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// eval combines the filters of the request over o.
+//
+// Filters on keys missing from o are ignored, unless the request uses
+// the three-valued logic where they evaluate to unknown.
+func (request Query) eval(o *Object) truth {
+	e := request.condition()
+	if e == nil {
+		return truthTrue
+	}
+	result, present := e.eval(request.opts.ThreeValued, func(i int) (truth, bool) {
+		filter := request.filters[i]
+		nValue := o.Get(filter.key)
+		if result, ok := filter.presence(nValue != nil); ok {
+			return truthOf(result), true
+		}
+		if nValue == nil {
+			return truthUnknown, false
+		}
+		if filter.calc != nil {
+			val, ok := filter.calc.eval(o, request.opts.Decimal)
+			if !ok {
+				return truthUnknown, false
+			}
+			computed := *filter
+			computed.val, computed.calc = val, nil
+			filter = &computed
+		}
+		if request.opts.Diagnostics != nil {
+			request.opts.Diagnostics.observe(request.path.child(filter.key), filter.val, nValue)
+		}
+		return truthOf(nValue.check(*filter, &request.opts)), true
+	})
+	if !present {
+		return truthTrue
+	}
+	return result
+}
+
+// accept reports whether o passes the filters of the request.
+func (request Query) accept(o *Object) bool {
+	return request.eval(o) == truthTrue
+}
+
+// levelNames returns the names of the sub levels of the request in the
+// order they are emitted: sorted for deterministic requests, in map order
+// otherwise.
+func (request Query) levelNames() []string {
+	if request.opts.Deterministic {
+		return request.nextNames()
+	}
+	names := make([]string, 0, len(request.next))
+	for name := range request.next {
+		names = append(names, name)
+	}
+	return names
+}
+
+// typedNumber returns the number of v as an int64 when its JSON token is an
+// integer, and as a float64 otherwise.
+func (v Value) typedNumber() interface{} {
+	if i, err := strconv.ParseInt(v.s, 10, 64); err == nil {
+		return i
+	}
+	return v.n
+}
+
 // Search return an Array of interface values by the given keys path
 func (v Value) Search(keys ...string) ([]interface{}, error) {
 	var rValues []interface{}
@@ -45,7 +167,7 @@ func (v Value) Search(keys ...string) ([]interface{}, error) {
 		}
 		next := pValue.Get(keys[0])
 		if next == nil {
-			return nil, fmt.Errorf("key not found : %s", keys[0])
+			return nil, fmt.Errorf("%w : %s", ErrKeyNotFound, keys[0])
 		}
 		nValue, err := pValue.Get(keys[0]).Search(keys[1:]...)
 		if err != nil {
@@ -65,7 +187,7 @@ func (v Value) Search(keys ...string) ([]interface{}, error) {
 	case TypeTrue:
 		rValues = append(rValues, true)
 	default:
-		return nil, fmt.Errorf("Type not recognized")
+		return nil, ErrUnknownType
 	}
 	return rValues, nil
 }
@@ -84,22 +206,19 @@ func (v Value) Check(request Query) error {
 				return nil
 			}
 		}
-		return fmt.Errorf("No element found")
+		return fmt.Errorf("%w : no element found", ErrNoMatch)
 	case TypeObject:
 		pValue, err := v.Object()
 		if err != nil {
 			return err
 		}
 		if request.stillFilters {
-			for _, filter := range request.filters {
-				nValue := pValue.Get(filter.key)
-				if nValue != nil && nValue.check(*filter) == false {
-					return fmt.Errorf("")
-				}
+			if !request.accept(pValue) {
+				return ErrNoMatch
 			}
 			for name, next := range request.next {
 				nValue := pValue.Get(name)
-				if next != nil {
+				if nValue != nil && next != nil {
 					err := nValue.Check(Query(*next))
 					if err != nil {
 						return err
@@ -111,146 +230,321 @@ func (v Value) Check(request Query) error {
 	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
 		return nil
 	default:
-		return fmt.Errorf("Type not recognized")
+		return ErrUnknownType
 	}
 }
 
+// Keep returns the JSON of the parts of v selected by the request.
+//
+// In best effort mode, the parts of v that fail are left out of the output
+// and reported by a *PartialError along with it.
 func (v Value) Keep(request Query) (string, error) {
-	w := bytes.Buffer{}
+	e := newExecution(request, &v)
+	return e.result(v.keep(request, Path{}, e))
+}
+
+func (v Value) keep(request Query, path Path, e *execution) (string, error) {
 	switch v.Type() {
 	case TypeArray:
 		pValue, err := v.Array()
 		if err != nil {
 			return "", err
 		}
-		w.WriteRune('[')
-		for index, uValue := range pValue {
-			nValue, err := uValue.Keep(request)
-			if err != nil {
-				return "", err
-			}
-			if len(nValue) > 0 {
-				w.WriteString(nValue)
-				if index < len(pValue)-1 {
-					w.WriteRune(',')
-				}
-			}
-		}
-		w.WriteRune(']')
-		return w.String(), nil
+		return keepArray(request, pValue, 0, path, e)
 	case TypeObject:
 		pValue, err := v.Object()
 		if err != nil {
 			return "", err
 		}
-		for _, filter := range request.filters {
-			if nValue := pValue.Get(filter.key); nValue != nil {
-				if nValue.check(*filter) == false {
-					return "", nil
-				}
-			}
+		if !request.accept(pValue) {
+			return "", nil
 		}
-		w.WriteRune('{')
-		i := 0
-		for _, retrieve := range request.retrieve {
-			i++
-			w.WriteRune('"')
-			w.WriteString(retrieve)
-			w.WriteRune('"')
-			w.WriteRune(':')
-			w.WriteString(pValue.Get(retrieve).Description)
-			if i < len(request.next)+len(request.retrieve) {
-				w.WriteRune(',')
-			}
+		return request.writeLevel(&v, pValue, path, e, (*Value).raw, request.levels(pValue, path, e))
+	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
+		return v.Description, nil
+	default:
+		return "", e.fail(path, ErrUnknownType)
+	}
+}
+
+// keepArray returns the JSON array of the elements of a kept by the request,
+// after the directives of the level are applied. A level with aggregations
+// returns the object of their results instead, and one with group_by the
+// object of its groups. offset is the index of a[0]
+// in the array of the document.
+func keepArray(request Query, a []*Value, offset int, path Path, e *execution) (string, error) {
+	out := newArrayOutput(request, len(a), e)
+	for index, uValue := range a {
+		nValue, err := uValue.keep(request, path.child(strconv.Itoa(offset+index)), e)
+		if err != nil {
+			return "", err
 		}
-		for name, next := range request.next {
-			i++
-			nValue, err := pValue.Get(name).Keep(Query(*next))
-			if err != nil {
-				return "", err
-			}
-			w.WriteRune('"')
-			w.WriteString(name)
-			w.WriteRune('"')
-			w.WriteRune(':')
-			w.WriteString(nValue)
-			if i < len(request.next)+len(request.retrieve) {
-				w.WriteRune(',')
-			}
+		if err := out.add(nValue, uValue); err != nil {
+			return "", err
+		}
+	}
+	return out.result(path)
+}
+
+// arrayOutput collects the outputs of the elements of an array kept by the
+// level request.
+type arrayOutput struct {
+	request  Query
+	e        *execution
+	elements []string
+	values   []*Value
+	// reduced tells that directives drop elements, so the array is not at
+	// least as large as the elements kept so far.
+	reduced bool
+	size    int
+}
+
+func newArrayOutput(request Query, n int, e *execution) *arrayOutput {
+	return &arrayOutput{
+		request:  request,
+		e:        e,
+		elements: make([]string, 0, n),
+		values:   make([]*Value, 0, n),
+		reduced:  request.top != nil || request.sample != nil || request.pivot != nil || request.grouping != nil || len(request.aggregates) > 0,
+	}
+}
+
+// add adds nValue, the output of the element uValue.
+func (a *arrayOutput) add(nValue string, uValue *Value) error {
+	if len(nValue) == 0 || a.e.duplicate(nValue) {
+		return nil
+	}
+	a.elements = append(a.elements, nValue)
+	a.values = append(a.values, uValue)
+	if a.size += len(nValue) + 1; !a.reduced {
+		return a.e.limit(a.size)
+	}
+	return nil
+}
+
+// result returns the JSON of the array at path, once its elements are
+// added.
+func (a *arrayOutput) result(path Path) (string, error) {
+	request, e := a.request, a.e
+	elements, values := a.elements, a.values
+	if request.sorting != nil {
+		elements, values = pick(elements, values, request.sorting.sort(values, request.opts.Collator))
+	}
+	if request.top != nil {
+		elements, values = pick(elements, values, request.top.choose(values, request.opts.Collator))
+	}
+	if request.sample != nil {
+		elements, values = pick(elements, values, request.sample.choose(len(elements), e.rand()))
+	}
+	for _, r := range request.running {
+		r.apply(elements, values)
+	}
+	if request.grouping != nil {
+		return request.grouping.keep(values, path, e)
+	}
+	if request.pivot != nil && !request.pivot.reverse {
+		return request.pivot.object(values), nil
+	}
+	if len(request.aggregates) > 0 {
+		w := bytes.Buffer{}
+		w.WriteRune('{')
+		for i, agg := range request.aggregates {
+			writeField(&w, i == 0, agg.as, agg.fn.aggregate(values, &request.opts))
 		}
 		w.WriteRune('}')
 		return w.String(), nil
-	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
-		return v.Description, nil
-	default:
-		return "", fmt.Errorf("Type not recognized")
 	}
+	out := "[" + strings.Join(elements, ",") + "]"
+	return out, e.limit(len(out))
 }
 
+// pick returns the elements and values at indexes, in their order.
+func pick(elements []string, values []*Value, indexes []int) ([]string, []*Value) {
+	pElements := make([]string, len(indexes))
+	pValues := make([]*Value, len(indexes))
+	for i, index := range indexes {
+		pElements[i], pValues[i] = elements[index], values[index]
+	}
+	return pElements, pValues
+}
+
+// Retrieve is Keep without the filters of the root level of the request.
+// Missing keys are left out of its output.
 func (v Value) Retrieve(request Query) (string, error) {
-	w := bytes.Buffer{}
+	e := newExecution(request, &v)
+	return e.result(v.retrieve(request, Path{}, e))
+}
+
+func (v Value) retrieve(request Query, path Path, e *execution) (string, error) {
 	switch v.Type() {
 	case TypeArray:
 		pValue, err := v.Array()
 		if err != nil {
 			return "", err
 		}
-		w.WriteRune('[')
-		for index, uValue := range pValue {
-			nValue, err := uValue.Keep(request)
-			if err != nil {
-				return "", err
-			}
-			if len(nValue) > 0 {
-				w.WriteString(nValue)
-				if index < len(pValue)-1 {
-					w.WriteRune(',')
-				}
-			}
-		}
-		w.WriteRune(']')
-		return w.String(), nil
+		return keepArray(request, pValue, 0, path, e)
 	case TypeObject:
 		pValue, err := v.Object()
 		if err != nil {
 			return "", err
 		}
-		w.WriteRune('{')
-		i := 0
-		for _, retrieve := range request.retrieve {
-			i++
-			val := pValue.Get(retrieve)
-			if val != nil {
-				w.WriteRune('"')
-				w.WriteString(retrieve)
-				w.WriteRune('"')
-				w.WriteRune(':')
-				w.WriteString(val.String())
-				if i < len(request.next)+len(request.retrieve) {
-					w.WriteRune(',')
-				}
+		raw := func(val *Value) string {
+			if request.opts.Deterministic && val.Description != "" {
+				return val.Description
 			}
+			return val.String()
 		}
-		for name, next := range request.next {
-			i++
-			nValue, err := pValue.Get(name).Keep(Query(*next))
-			if err != nil {
+		return request.writeLevel(&v, pValue, path, e, raw, request.levels(pValue, path, e))
+	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
+		return v.Description, nil
+	default:
+		return "", e.fail(path, ErrUnknownType)
+	}
+}
+
+// writeLevel returns the JSON object of the level request for the object
+// pValue of v, found at path in the document. raw returns the JSON of the
+// values of the keys selected as they are, and level the output of the sub
+// level name, if any.
+func (request Query) writeLevel(v *Value, pValue *Object, path Path, e *execution, raw func(*Value) string, level func(name string) (string, bool, error)) (string, error) {
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	first := true
+	request.eachField(pValue, func(key string, val *Value) {
+		writeField(&w, first, key, raw(val))
+		first = false
+	})
+	for _, name := range request.levelNames() {
+		nValue, ok, err := level(name)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			writeField(&w, first, name, nValue)
+			first = false
+		}
+	}
+	for _, j := range request.joins {
+		nValue, err := j.keep(pValue, path, e)
+		if err != nil {
+			return "", err
+		}
+		writeField(&w, first, j.as, nValue)
+		first = false
+	}
+	for _, l := range request.lookups {
+		writeField(&w, first, l.as, l.keep(pValue, request.opts.Lookups))
+		first = false
+	}
+	for _, c := range request.computed {
+		nValue, err := c.keep(pValue)
+		if err != nil {
+			if err = e.fail(path.child(c.as), err); err != nil {
 				return "", err
 			}
-			w.WriteRune('"')
-			w.WriteString(name)
-			w.WriteRune('"')
-			w.WriteRune(':')
-			w.WriteString(nValue)
-			if i < len(request.next)+len(request.retrieve) {
-				w.WriteRune(',')
-			}
+			continue
 		}
-		w.WriteRune('}')
-		return w.String(), nil
-	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
-		return v.Description, nil
+		writeField(&w, first, c.as, nValue)
+		first = false
+	}
+	for _, c := range request.counts {
+		if nValue, ok := c.keep(pValue); ok {
+			writeField(&w, first, c.as, nValue)
+			first = false
+		}
+	}
+	for _, z := range request.zips {
+		nValue, ok, err := z.keep(pValue, path, e)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			writeField(&w, first, z.as, nValue)
+			first = false
+		}
+	}
+	if request.descent != nil {
+		if err := request.writeDescent(&w, first, v, path, e); err != nil {
+			return "", err
+		}
+	}
+	w.WriteRune('}')
+	if err := e.limit(w.Len()); err != nil {
+		return "", err
+	}
+	if request.pivot != nil && request.pivot.reverse {
+		return request.unpivot(pValue, w.String())
+	}
+	return w.String(), nil
+}
+
+// raw returns the JSON of v.
+func (v *Value) raw() string {
+	if len(v.Description) > 0 {
+		return v.Description
+	}
+	return v.String()
+}
+
+// writeField writes "name":value to w, preceded by a comma unless it is
+// the first field of its object.
+func writeField(w *bytes.Buffer, first bool, name, value string) {
+	if !first {
+		w.WriteRune(',')
+	}
+	w.WriteRune('"')
+	w.WriteString(name)
+	w.WriteRune('"')
+	w.WriteRune(':')
+	w.WriteString(value)
+}
+
+// levels returns the function evaluating the sub levels of the request in
+// the object o, found at path in the document.
+func (request Query) levels(o *Object, path Path, e *execution) func(name string) (string, bool, error) {
+	return func(name string) (string, bool, error) {
+		return request.keepLevel(o, name, path, e)
+	}
+}
+
+// keepLevel applies the sub level name of the request to its value in o,
+// found at path in the document.
+//
+// ok is false when nothing must be written for the level: the key is
+// missing, the value was filtered out, the scalar policy skips it or
+// it failed in best effort mode.
+func (request Query) keepLevel(o *Object, name string, path Path, e *execution) (value string, ok bool, err error) {
+	next := request.next[name]
+	path = path.child(name)
+	nValue := o.Get(name)
+	if nValue == nil {
+		if request.opts.Scalars == ScalarError {
+			return "", false, e.fail(path, fmt.Errorf("cannot apply level %q: %w", next.path.String(), ErrKeyNotFound))
+		}
+		return "", false, nil
+	}
+	if next.slice != nil {
+		if nValue.Type() == TypeArray {
+			return next.keepSlice(nValue.a, path, e)
+		}
+		if request.opts.Scalars == ScalarError {
+			return "", false, e.fail(path, fmt.Errorf("cannot index level %q, a %s", next.path.String(), nValue.Type()))
+		}
+		return "", false, nil
+	}
+	switch nValue.Type() {
+	case TypeObject, TypeArray:
+		value, err = nValue.keep(*next, path, e)
+		return value, len(value) > 0, err
+	}
+	switch request.opts.Scalars {
+	case ScalarError:
+		return "", false, e.fail(path, fmt.Errorf("cannot apply level %q to a %s", next.path.String(), nValue.Type()))
+	case ScalarSkip:
+		return "", false, nil
+	case ScalarWrap:
+		return "[" + nValue.Description + "]", true, nil
 	default:
-		return "", fmt.Errorf("Type not recognized")
+		return nValue.Description, true, nil
 	}
 }
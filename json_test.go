@@ -1,5 +1,9 @@
 package jsonq
 
+import (
+	"testing"
+)
+
 // var (
 // 	smallFixtureValue  *Value
 // 	mediumFixtureValue *Value
@@ -481,3 +485,264 @@ package jsonq
 // 		log.Fatalf("cannot Check json: %s", err)
 // 	}
 // }
+
+func TestKeepDeterministic(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"d": {"x": 1}, "b": {"x": 2}, "a": {"x": 3}, "c": {"x": 4e2}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{d{x}, b{x}, a{x}, c{x}}")
+	q.SetOptions(Options{Deterministic: true})
+
+	const want = `{"a":{"x":3},"b":{"x":2},"c":{"x":4e2},"d":{"x":1}}`
+	for i := 0; i < 10; i++ {
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != want {
+			t.Fatalf("Keep() = %s, want %s", got, want)
+		}
+		got, err = v.Retrieve(*q)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != want {
+			t.Fatalf("Retrieve() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestKeepScalarPolicy(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"id": 1, "name": "Bob", "tags": [{"id": 1}, {"id": 2}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		name    string
+		query   string
+		policy  ScalarPolicy
+		want    string
+		wantErr string
+	}{
+		{"keep", "{id, name{first}}", ScalarKeep, `{"id":1,"name":"Bob"}`, ""},
+		{"error", "{id, name{first}}", ScalarError, "", `cannot apply level "name" to a string`},
+		{"error on missing", "{id, other{first}}", ScalarError, "", `cannot apply level "other": key not found`},
+		{"skip", "{id, name{first}}", ScalarSkip, `{"id":1}`, ""},
+		{"wrap", "{id, name{first}}", ScalarWrap, `{"id":1,"name":["Bob"]}`, ""},
+		{"missing", "{id, other{first}}", ScalarKeep, `{"id":1}`, ""},
+		{"filtered out", "{tags(id = 2){id}}", ScalarKeep, `{"tags":[{"id":2}]}`, ""},
+		{"filtered out first", "{tags(id = 1){id}}", ScalarKeep, `{"tags":[{"id":1}]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := MustParseQuery(tt.query)
+			q.SetOptions(Options{Scalars: tt.policy})
+			got, err := v.Keep(*q)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Keep() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Keep() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepBestEffort(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"id": 1, "name": {"first": "Al"}}, {"id": 2, "name": "Bob"}, {"id": 3, "name": {"first": "Cy"}}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{users{id, name{first}}}")
+
+	q.SetOptions(Options{Scalars: ScalarError})
+	if _, err := v.Keep(*q); err == nil {
+		t.Fatalf("expecting non-nil error")
+	} else if pe, ok := err.(*PathError); !ok || pe.Path.String() != "users.1.name" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	q.SetOptions(Options{Scalars: ScalarError, BestEffort: true})
+	got, err := v.Keep(*q)
+	const want = `{"users":[{"id":1,"name":{"first":"Al"}},{"id":2},{"id":3,"name":{"first":"Cy"}}]}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+	pe, ok := err.(*PartialError)
+	if !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(pe.Errors) != 1 || pe.Errors[0].Path.String() != "users.1.name" {
+		t.Errorf("unexpected partial errors: %s", pe)
+	}
+}
+
+func TestKeepUnique(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": [{"id": 1, "x": 1}, {"x": 2, "id": 1.0}, {"id": 2}], "b": [{"id": 2}, {"id": 3}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{a{id}, b{id}}")
+	q.SetOptions(Options{Unique: true, Deterministic: true})
+	got, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{"a":[{"id":1},{"id":2}],"b":[{"id":3}]}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+}
+
+func TestKeepOr(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"user": [{"name": "Al", "age": 20, "role": "dev"}, {"name": "Bo", "age": 15, "role": "admin"}, {"name": "Cy", "age": 15, "role": "dev"}, {"name": "Di"}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{user(age > 18 || role = admin){name}}", `{"user":[{"name":"Al"},{"name":"Bo"},{"name":"Di"}]}`},
+		{"{user(age > 18 && role = admin || name = Cy){name}}", `{"user":[{"name":"Cy"}]}`},
+		{"{user(name = Cy || age > 18 && role = dev){name}}", `{"user":[{"name":"Al"},{"name":"Cy"}]}`},
+		{"{user(age > 18 || nickname = x){name}}", `{"user":[{"name":"Al"},{"name":"Di"}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.cmd))
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestKeepMergedLevels(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"user": [{"name": "Al", "age": 20, "role": "dev"}, {"name": "Bo", "age": 15, "role": "admin"}, {"name": "Cy", "age": 15, "role": "dev"}], "orders": [{"id": 1}, {"id": 2}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{user(role = admin){name}, user(name = Cy){age}}", `{"user":[{"name":"Bo","age":15},{"name":"Cy","age":15}]}`},
+		{"{user(role = admin){name}, user{age}}", `{"user":[{"name":"Al","age":20},{"name":"Bo","age":15},{"name":"Cy","age":15}]}`},
+		{"{user(age > 18){count() as n}, user(role = admin){count() as n}}", `{"user":{"n":2}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.cmd))
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestKeepExists(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"name": "Al", "phone": "x1"}, {"name": "Bo", "phone": null}, {"name": "Cy"}, {"name": "Di", "phone": []}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{users(phone?){name}}", `{"users":[{"name":"Al"},{"name":"Bo"},{"name":"Di"}]}`},
+		{"{users(!phone?){name}}", `{"users":[{"name":"Cy"}]}`},
+		{"{users(!phone? || phone = x1){name}}", `{"users":[{"name":"Al"},{"name":"Cy"}]}`},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.cmd)
+		for _, opts := range []Options{{}, {ThreeValued: true}} {
+			q.SetOptions(opts)
+			got, err := v.Keep(*q)
+			if err != nil {
+				t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+			}
+			if got != tt.want {
+				t.Errorf("Keep(%q) with %+v = %s, want %s", tt.cmd, opts, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestKeepNull(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"name": "Al", "phone": "x1"}, {"name": "Bo", "phone": null}, {"name": "Cy"}, {"name": "Di", "phone": 0}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		opts Options
+		want string
+	}{
+		{"{users(phone === null){name}}", Options{}, `{"users":[{"name":"Bo"},{"name":"Cy"}]}`},
+		{"{users(phone === null){name}}", Options{ThreeValued: true}, `{"users":[{"name":"Bo"}]}`},
+		{"{users(phone? && phone === null){name}}", Options{}, `{"users":[{"name":"Bo"}]}`},
+		{"{users(phone !== null){name}}", Options{ThreeValued: true}, `{"users":[{"name":"Al"},{"name":"Di"}]}`},
+		{"{users(phone = null){name}}", Options{ThreeValued: true}, `{"users":[]}`},
+		{"{users(phone !== x1){name}}", Options{ThreeValued: true}, `{"users":[{"name":"Bo"},{"name":"Di"}]}`},
+		{"{users(phone != x1){name}}", Options{ThreeValued: true}, `{"users":[]}`},
+		{"{users(phone === false){name}}", Options{ThreeValued: true, Coercion: CoercionPolicy{Bools: true}}, `{"users":[]}`},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.cmd)
+		q.SetOptions(tt.opts)
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) with %+v = %s, want %s", tt.cmd, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestKeepMaxResultSize(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"name": "Al"}, {"name": "Bo"}, {"name": "Cy"}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	const full = `{"users":[{"name":"Al"},{"name":"Bo"},{"name":"Cy"}]}`
+	tests := []struct {
+		query string
+		max   int
+		err   error
+	}{
+		{"{users{name}}", len(full), nil},
+		{"{users{name}}", len(full) - 1, ErrResultTooLarge},
+		{"{users{name}}", 20, ErrResultTooLarge},
+		{"{users{top(1, by: name), name}}", 30, nil},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		q.SetOptions(Options{MaxResultSize: tt.max, BestEffort: true})
+		if _, err := v.Keep(*q); err != tt.err {
+			t.Errorf("Keep(%s) with %d bytes: error = %v, want %v", tt.query, tt.max, err, tt.err)
+		}
+		if _, err := v.Retrieve(*q); err != tt.err {
+			t.Errorf("Retrieve(%s) with %d bytes: error = %v, want %v", tt.query, tt.max, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package jsonqtest helps testing services built on jsonq: it compares the
+// results of queries with golden files, in canonical form, and reports the
+// differences path by path.
+//
+// The golden files are written, instead of compared, when the tests run
+// with the -jsonqtest.update flag:
+//
+//	go test ./... -jsonqtest.update
+package jsonqtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qdequele/jsonq"
+)
+
+var update = flag.Bool("jsonqtest.update", false, "write the golden files of jsonqtest with the actual results")
+
+// AssertKeepEqual fails t unless the result of Keep of query on the JSON
+// doc equals the JSON of goldenFile. Whitespace, the order of object keys
+// and the formatting of numbers do not matter.
+func AssertKeepEqual(t testing.TB, doc []byte, query, goldenFile string) {
+	t.Helper()
+	assertGolden(t, doc, query, goldenFile, (*jsonq.Value).Keep)
+}
+
+// AssertRetrieveEqual is AssertKeepEqual for Retrieve.
+func AssertRetrieveEqual(t testing.TB, doc []byte, query, goldenFile string) {
+	t.Helper()
+	assertGolden(t, doc, query, goldenFile, (*jsonq.Value).Retrieve)
+}
+
+func assertGolden(t testing.TB, doc []byte, query, goldenFile string, run func(*jsonq.Value, jsonq.Query) (string, error)) {
+	t.Helper()
+	request, err := jsonq.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("cannot parse query %s: %s", query, err)
+		return
+	}
+	var p jsonq.Parser
+	v, err := p.ParseBytes(doc)
+	if err != nil {
+		t.Fatalf("cannot parse document: %s", err)
+		return
+	}
+	got, err := run(v, *request)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err)
+		return
+	}
+	if *update {
+		if err := writeGolden(goldenFile, got); err != nil {
+			t.Fatalf("cannot update %s: %s", goldenFile, err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %s; run the tests with -jsonqtest.update to create it", err)
+		return
+	}
+	diff, err := Diff(got, string(want))
+	if err != nil {
+		t.Fatalf("%s: %s", goldenFile, err)
+		return
+	}
+	if diff != "" {
+		t.Errorf("%s differs from %s:\n%s", query, goldenFile, diff)
+	}
+}
+
+// writeGolden writes the JSON result, indented, to the golden file.
+func writeGolden(file, result string) error {
+	var b bytes.Buffer
+	if err := json.Indent(&b, []byte(result), "", "  "); err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b.Bytes(), 0644)
+}
+
+// AssertJSONEqual fails t unless the JSON got and want hold the same data,
+// and reports their differences otherwise.
+func AssertJSONEqual(t testing.TB, got, want string) {
+	t.Helper()
+	diff, err := Diff(got, want)
+	if err != nil {
+		t.Fatalf("%s", err)
+		return
+	}
+	if diff != "" {
+		t.Errorf("JSON differs:\n%s", diff)
+	}
+}
+
+// Diff returns the differences between the JSON got and want, one per
+// line with its JSON pointer, such as:
+//
+//	/users/0/name: got "Bo", want "Al"
+//	/users/2: unexpected {"name":"Cy"}
+//	/total: missing, want 3
+//
+// It returns an empty string when they hold the same data.
+func Diff(got, want string) (string, error) {
+	var pg, pw jsonq.Parser
+	g, err := pg.Parse(got)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse the actual JSON: %s", err)
+	}
+	w, err := pw.Parse(want)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse the expected JSON: %s", err)
+	}
+	var lines []string
+	for _, op := range jsonq.CreatePatch(w, g).GetArray() {
+		ptr := string(op.GetStringBytes("path"))
+		line := ptr
+		if line == "" {
+			line = "(root)"
+		}
+		switch string(op.GetStringBytes("op")) {
+		case "add":
+			line += ": unexpected " + canonical(op.Get("value"))
+		case "remove":
+			expected, _ := w.Pointer(ptr)
+			line += ": missing, want " + canonical(expected)
+		case "replace":
+			expected, _ := w.Pointer(ptr)
+			line += ": got " + canonical(op.Get("value")) + ", want " + canonical(expected)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func canonical(v *jsonq.Value) string {
+	if v == nil {
+		return "nothing"
+	}
+	return string(v.AppendCanonical(nil))
+}
@@ -0,0 +1,109 @@
+package jsonqtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB recording the failures instead of reporting
+// them.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+const users = `{"users": [
+	{"name": "Al", "age": 3.0, "email": "al@example.com"},
+	{"name": "Bo", "age": 4e1, "email": "bo@example.com"}
+]}`
+
+func TestAssertKeepEqual(t *testing.T) {
+	AssertKeepEqual(t, []byte(users), "{users{name, age}}", "testdata/users.golden")
+	AssertRetrieveEqual(t, []byte(users), "{users{age, name}}", "testdata/users.golden")
+
+	r := &recorder{TB: t}
+	AssertKeepEqual(r, []byte(users), "{users(age > 10){name, age}}", "testdata/users.golden")
+	if len(r.errors) != 1 || r.fatal {
+		t.Fatalf("AssertKeepEqual reported %q, want a single error", r.errors)
+	}
+	if want := "/users/0/name: got \"Bo\", want \"Al\"\n/users/0/age: got 40, want 3\n/users/1: missing, want {\"age\":40,\"name\":\"Bo\"}"; !strings.HasSuffix(r.errors[0], want) {
+		t.Errorf("AssertKeepEqual reported %q, want it to end with %q", r.errors[0], want)
+	}
+
+	for _, tt := range []struct {
+		query, golden string
+	}{
+		{"{users{name", "testdata/users.golden"},
+		{"{users{name}}", "testdata/missing.golden"},
+	} {
+		r := &recorder{TB: t}
+		AssertKeepEqual(r, []byte(users), tt.query, tt.golden)
+		if !r.fatal {
+			t.Errorf("AssertKeepEqual(%s, %s) expecting a fatal error", tt.query, tt.golden)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "new", "users.golden")
+	*update = true
+	defer func() { *update = false }()
+	AssertKeepEqual(t, []byte(users), "{users{name}}", golden)
+	data, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("cannot read the golden file: %s", err)
+	}
+	want := "{\n  \"users\": [\n    {\n      \"name\": \"Al\"\n    },\n    {\n      \"name\": \"Bo\"\n    }\n  ]\n}\n"
+	if string(data) != want {
+		t.Errorf("golden file = %q, want %q", data, want)
+	}
+	*update = false
+	AssertKeepEqual(t, []byte(users), "{users{name}}", golden)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		got, want string
+		diff      string
+	}{
+		{`{"a":1,"b":[1,2]}`, `{"b":[1,2.0],"a":1e0}`, ""},
+		{`{"a":1,"c":true}`, `{"a":2,"b":{"x":null}}`, "/b: missing, want {\"x\":null}\n/a: got 1, want 2\n/c: unexpected true"},
+		{`[1,2,3]`, `[1]`, "/1: unexpected 2\n/2: unexpected 3"},
+		{`"x"`, `{"a/b":1}`, "(root): got \"x\", want {\"a/b\":1}"},
+		{`{"a/b":1}`, `{"a/b":2}`, "/a~1b: got 1, want 2"},
+	}
+	for _, tt := range tests {
+		diff, err := Diff(tt.got, tt.want)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if diff != tt.diff {
+			t.Errorf("Diff(%s, %s) = %q, want %q", tt.got, tt.want, diff, tt.diff)
+		}
+	}
+
+	if _, err := Diff(`{`, `{}`); err == nil {
+		t.Errorf("Diff expecting non-nil error")
+	}
+	r := &recorder{TB: t}
+	AssertJSONEqual(r, `{"a":1}`, `{"a":1}`)
+	AssertJSONEqual(r, `{"a":1}`, `{"a":2}`)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "/a: got 1, want 2") {
+		t.Errorf("AssertJSONEqual reported %q", r.errors)
+	}
+}
@@ -0,0 +1,238 @@
+package jsonqtest
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/qdequele/jsonq"
+)
+
+// Operator is the expected semantics of a filter operation.
+type Operator struct {
+	// Op is the operation, as written in filters: =, >=, :: ...
+	Op string
+
+	// All tells that the operation holds on an array when it holds on all
+	// its elements, like the negated operations, instead of on any.
+	All bool
+
+	// Want reports whether the operation holds between a value of a
+	// document, decoded by encoding/json, and the literal of the filter,
+	// typed like jsonq types it: bool, int64, float64, string or nil. The
+	// value is never an array.
+	Want func(value, literal interface{}) bool
+}
+
+// Operators are the semantics of the operations of jsonq, with the zero
+// Options, as documented by Operation, Quantifier and CoercionPolicy.
+var Operators = []Operator{
+	{Op: "=", Want: equal},
+	{Op: "!=", All: true, Want: func(v, l interface{}) bool { return alike(v, l) && !equal(v, l) }},
+	{Op: ">", Want: func(v, l interface{}) bool { c, ok := compare(v, l); return ok && c > 0 }},
+	{Op: ">=", Want: func(v, l interface{}) bool { c, ok := compare(v, l); return ok && c >= 0 }},
+	{Op: "<", Want: func(v, l interface{}) bool { c, ok := compare(v, l); return ok && c < 0 }},
+	{Op: "<=", Want: func(v, l interface{}) bool { c, ok := compare(v, l); return ok && c <= 0 }},
+	{Op: ":", Want: func(v, l interface{}) bool { in, ok := contains(v, l); return ok && in }},
+	{Op: "!:", All: true, Want: func(v, l interface{}) bool { in, ok := contains(v, l); return ok && !in }},
+	{Op: "::", Want: func(v, l interface{}) bool { m, ok := matches(v, l); return ok && m }},
+	{Op: "!::", All: true, Want: func(v, l interface{}) bool { m, ok := matches(v, l); return ok && !m }},
+	{Op: "===", Want: same},
+	{Op: "!==", All: true, Want: func(v, l interface{}) bool { return !same(v, l) }},
+}
+
+// values are the JSON values of the documents checked by CheckOperators.
+var values = []string{
+	`true`, `false`, `null`,
+	`0`, `3`, `3.0`, `-2.5`, `10`,
+	`""`, `"abc"`, `"ABCdef"`, `"b"`, `"3"`, `"true"`,
+	`[]`, `[3, "abc"]`, `[10, 10]`, `[null]`, `[[3], ["b"]]`,
+}
+
+// literals are the literals of the filters checked by CheckOperators, with
+// their typed values.
+var literals = []struct {
+	text  string
+	value interface{}
+}{
+	{`true`, true},
+	{`false`, false},
+	{`null`, nil},
+	{`0`, int64(0)},
+	{`3`, int64(3)},
+	{`-2.5`, -2.5},
+	{`10.0`, 10.0},
+	{`3f`, 3.0},
+	{`abc`, "abc"},
+	{`"AB"`, "AB"},
+	{`"b"`, "b"},
+	{`"^ab"`, "^ab"},
+	{`"3"`, "3"},
+}
+
+// CheckOperators fails t for each filter whose result differs from the
+// semantics of its operation in ops, when the query runs with opts. It
+// applies every operation, on every literal of a set mixing the types, to
+// documents holding booleans, numbers, strings, null and arrays, nested or
+// not.
+//
+// Operators checks the operations of jsonq with the zero Options; other
+// options, such as a CoercionPolicy or a Collator, come with their own
+// semantics.
+func CheckOperators(t testing.TB, opts jsonq.Options, ops []Operator) {
+	t.Helper()
+	for _, op := range ops {
+		for _, l := range literals {
+			query := "(a " + op.Op + " " + l.text + "){a}"
+			request, err := jsonq.ParseQuery(query)
+			if err != nil {
+				t.Fatalf("cannot parse query %s: %s", query, err)
+				return
+			}
+			request.SetOptions(opts)
+			for _, value := range values {
+				var decoded interface{}
+				if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+					t.Fatalf("cannot decode %s: %s", value, err)
+					return
+				}
+				var p jsonq.Parser
+				doc, err := p.Parse(`{"a": ` + value + `}`)
+				if err != nil {
+					t.Fatalf("cannot parse document: %s", err)
+					return
+				}
+				kept, err := doc.Keep(*request)
+				if err != nil {
+					t.Fatalf("%s: %s", query, err)
+					return
+				}
+				got, want := kept != "", op.holds(decoded, l.value)
+				if got != want {
+					t.Errorf("%s on %s: matched = %t, want %t", query, value, got, want)
+				}
+			}
+		}
+	}
+}
+
+// holds applies op to value, element by element for arrays.
+func (op Operator) holds(value, literal interface{}) bool {
+	a, ok := value.([]interface{})
+	if !ok {
+		return op.Want(value, literal)
+	}
+	for _, element := range a {
+		if op.holds(element, literal) != op.All {
+			return !op.All
+		}
+	}
+	return op.All
+}
+
+// number returns v as a float64 when it is a number.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// alike reports whether v and l have types that compare: numbers,
+// strings or booleans.
+func alike(v, l interface{}) bool {
+	if _, ok := number(v); ok {
+		_, ok = number(l)
+		return ok
+	}
+	switch v.(type) {
+	case string:
+		_, ok := l.(string)
+		return ok
+	case bool:
+		_, ok := l.(bool)
+		return ok
+	}
+	return false
+}
+
+func equal(v, l interface{}) bool {
+	if !alike(v, l) {
+		return false
+	}
+	if n, ok := number(v); ok {
+		m, _ := number(l)
+		return n == m
+	}
+	return v == l
+}
+
+func same(v, l interface{}) bool {
+	if v == nil || l == nil {
+		return v == l
+	}
+	return equal(v, l)
+}
+
+// compare orders the numbers and the strings v and l. ok is false for the
+// other types.
+func compare(v, l interface{}) (c int, ok bool) {
+	if n, ok := number(v); ok {
+		m, ok := number(l)
+		switch {
+		case !ok:
+			return 0, false
+		case n < m:
+			return -1, true
+		case n > m:
+			return 1, true
+		}
+		return 0, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	t, ok := l.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(s, t), true
+}
+
+// contains reports whether the string v contains the string l, ignoring
+// the case. ok is false for the other types.
+func contains(v, l interface{}) (in, ok bool) {
+	s, ok := v.(string)
+	if !ok {
+		return false, false
+	}
+	t, ok := l.(string)
+	if !ok {
+		return false, false
+	}
+	return strings.Contains(strings.ToLower(s), strings.ToLower(t)), true
+}
+
+// matches reports whether the regular expression l matches the string v,
+// ignoring the case. ok is false for the other types and invalid
+// expressions.
+func matches(v, l interface{}) (m, ok bool) {
+	s, ok := v.(string)
+	if !ok {
+		return false, false
+	}
+	t, ok := l.(string)
+	if !ok {
+		return false, false
+	}
+	re, err := regexp.Compile(strings.ToLower(t))
+	if err != nil {
+		return false, false
+	}
+	return re.MatchString(strings.ToLower(s)), true
+}
@@ -0,0 +1,56 @@
+package jsonqtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qdequele/jsonq"
+)
+
+func TestCheckOperators(t *testing.T) {
+	CheckOperators(t, jsonq.Options{}, Operators)
+
+	// With the coercion of booleans, = compares them to "true", 1 ...
+	toBool := func(v interface{}) (bool, bool) {
+		switch b := v.(type) {
+		case bool:
+			return b, true
+		case string:
+			if b == "true" || b == "1" || b == "false" || b == "0" {
+				return b == "true" || b == "1", true
+			}
+		case float64, int64:
+			if n, _ := number(b); n == 0 || n == 1 {
+				return n == 1, true
+			}
+		}
+		return false, false
+	}
+	bools := Operator{Op: "=", Want: func(v, l interface{}) bool {
+		_, vBool := v.(bool)
+		_, lBool := l.(bool)
+		if vBool || lBool {
+			b, ok := toBool(v)
+			c, ok2 := toBool(l)
+			return ok && ok2 && b == c
+		}
+		return equal(v, l)
+	}}
+	CheckOperators(t, jsonq.Options{Coercion: jsonq.CoercionPolicy{Bools: true}}, []Operator{bools})
+
+	r := &recorder{TB: t}
+	wrong := Operator{Op: ">", Want: func(v, l interface{}) bool { c, ok := compare(v, l); return ok && c >= 0 }}
+	CheckOperators(r, jsonq.Options{}, []Operator{wrong})
+	if len(r.errors) == 0 || r.fatal {
+		t.Fatalf("CheckOperators reported %q, want errors", r.errors)
+	}
+	if want := "(a > 3){a} on 3: matched = false, want true"; !strings.Contains(strings.Join(r.errors, "\n"), want) {
+		t.Errorf("CheckOperators reported %q, want %q", r.errors, want)
+	}
+
+	r = &recorder{TB: t}
+	CheckOperators(r, jsonq.Options{}, []Operator{{Op: "<>", Want: equal}})
+	if !r.fatal {
+		t.Errorf("CheckOperators expecting a fatal error")
+	}
+}
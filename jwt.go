@@ -0,0 +1,129 @@
+package jsonq
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformedToken is returned by ParseClaims for a token that isn't a
+	// JWT with a JSON object payload, and by Claims.Validate for time claims
+	// that aren't numbers.
+	ErrMalformedToken = errors.New("malformed token")
+	// ErrTokenExpired is returned by Claims.Validate past the exp claim.
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenNotYetValid is returned by Claims.Validate before the nbf
+	// claim.
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+)
+
+var jwtPool ParserPool
+
+// Claims are the claims of the payload of a JWT. The getters of Value read
+// them, such as c.GetStringBytes("sub").
+//
+// ParseClaims does not verify the signature of the token: the claims must
+// only be trusted once the token is verified.
+type Claims struct {
+	*Value
+	p *Parser
+}
+
+// ParseClaims splits the JWT token, decodes its payload and parses it with
+// a pooled Parser. The Claims must be released after use.
+func ParseClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expects 3 parts, got %d", ErrMalformedToken, len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode payload: %s", ErrMalformedToken, err)
+	}
+	p := jwtPool.Get()
+	v, err := p.ParseBytes(payload)
+	if err != nil {
+		jwtPool.Put(p)
+		return nil, fmt.Errorf("%w: cannot parse payload: %s", ErrMalformedToken, err)
+	}
+	if v.Type() != TypeObject {
+		jwtPool.Put(p)
+		return nil, fmt.Errorf("%w: payload is %s, not an object", ErrMalformedToken, v.Type())
+	}
+	return &Claims{Value: v, p: p}, nil
+}
+
+// Release returns the parser of c to the pool. c and the values it returned
+// cannot be used afterwards.
+func (c *Claims) Release() {
+	if c.p != nil {
+		jwtPool.Put(c.p)
+		c.p, c.Value = nil, nil
+	}
+}
+
+// ExpiresAt returns the exp claim. ok is false when it is missing or isn't
+// a number.
+func (c *Claims) ExpiresAt() (t time.Time, ok bool) {
+	t, ok, _ = c.numericDate("exp")
+	return t, ok
+}
+
+// NotBefore returns the nbf claim. ok is false when it is missing or isn't
+// a number.
+func (c *Claims) NotBefore() (t time.Time, ok bool) {
+	t, ok, _ = c.numericDate("nbf")
+	return t, ok
+}
+
+// IssuedAt returns the iat claim. ok is false when it is missing or isn't
+// a number.
+func (c *Claims) IssuedAt() (t time.Time, ok bool) {
+	t, ok, _ = c.numericDate("iat")
+	return t, ok
+}
+
+// Expired reports whether the token has expired at now. A token without
+// exp claim never expires.
+func (c *Claims) Expired(now time.Time) bool {
+	exp, ok := c.ExpiresAt()
+	return ok && !now.Before(exp)
+}
+
+// Validate checks the exp and nbf claims at now, allowing leeway for the
+// clock skew between the issuer and the caller. Missing claims pass.
+func (c *Claims) Validate(now time.Time, leeway time.Duration) error {
+	exp, ok, err := c.numericDate("exp")
+	if err != nil {
+		return err
+	}
+	if ok && !now.Before(exp.Add(leeway)) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, exp.UTC().Format(time.RFC3339))
+	}
+	nbf, ok, err := c.numericDate("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(leeway).Before(nbf) {
+		return fmt.Errorf("%w before %s", ErrTokenNotYetValid, nbf.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// numericDate returns the claim name, a number of seconds since the Unix
+// epoch. err is set when the claim isn't a number.
+func (c *Claims) numericDate(name string) (t time.Time, ok bool, err error) {
+	v := c.Get(name)
+	if v == nil {
+		return time.Time{}, false, nil
+	}
+	if v.Type() != TypeNumber {
+		return time.Time{}, false, fmt.Errorf("%w: %s is %s, not a number", ErrMalformedToken, name, v.Type())
+	}
+	sec, frac := math.Modf(v.n)
+	return time.Unix(int64(sec), int64(frac*1e9)), true, nil
+}
@@ -0,0 +1,134 @@
+package jsonq
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func token(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestParseClaims(t *testing.T) {
+	c, err := ParseClaims(token(`{"sub":"1234","roles":["admin","ops"],"exp":1700000000,"nbf":1699990000.5,"iat":1699990000}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer c.Release()
+	if sub := string(c.GetStringBytes("sub")); sub != "1234" {
+		t.Errorf("sub = %q, want %q", sub, "1234")
+	}
+	if role := string(c.GetStringBytes("roles", "1")); role != "ops" {
+		t.Errorf("roles.1 = %q, want %q", role, "ops")
+	}
+	if exp, ok := c.ExpiresAt(); !ok || !exp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ExpiresAt = %s, %t, want %s", exp, ok, time.Unix(1700000000, 0))
+	}
+	if nbf, ok := c.NotBefore(); !ok || !nbf.Equal(time.Unix(1699990000, 5e8)) {
+		t.Errorf("NotBefore = %s, %t, want %s", nbf, ok, time.Unix(1699990000, 5e8))
+	}
+	if iat, ok := c.IssuedAt(); !ok || !iat.Equal(time.Unix(1699990000, 0)) {
+		t.Errorf("IssuedAt = %s, %t, want %s", iat, ok, time.Unix(1699990000, 0))
+	}
+
+	got, err := c.Retrieve(*MustParseQuery("{sub, roles}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := `{"sub":"1234","roles":["admin","ops"]}`; got != want {
+		t.Errorf("Retrieve = %s, want %s", got, want)
+	}
+}
+
+func TestParseClaimsPadding(t *testing.T) {
+	payload := base64.URLEncoding.EncodeToString([]byte(`{"a":1}`))
+	c, err := ParseClaims("e30." + payload + ".")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer c.Release()
+	if a := c.GetInt("a"); a != 1 {
+		t.Errorf("a = %d, want 1", a)
+	}
+}
+
+func TestParseClaimsErrors(t *testing.T) {
+	for _, tok := range []string{
+		"",
+		"a.b",
+		"a.b.c.d",
+		"e30.!!!.sig",
+		token(`{"sub":`),
+		token(`["sub"]`),
+	} {
+		if _, err := ParseClaims(tok); !errors.Is(err, ErrMalformedToken) {
+			t.Errorf("ParseClaims(%q) = %v, want ErrMalformedToken", tok, err)
+		}
+	}
+}
+
+func TestClaimsValidate(t *testing.T) {
+	exp, nbf := time.Unix(1700000000, 0), time.Unix(1699990000, 0)
+	c, err := ParseClaims(token(`{"exp":1700000000,"nbf":1699990000}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer c.Release()
+	tests := []struct {
+		now    time.Time
+		leeway time.Duration
+		want   error
+	}{
+		{exp.Add(-time.Second), 0, nil},
+		{exp, 0, ErrTokenExpired},
+		{exp.Add(30 * time.Second), time.Minute, nil},
+		{exp.Add(2 * time.Minute), time.Minute, ErrTokenExpired},
+		{nbf, 0, nil},
+		{nbf.Add(-time.Second), 0, ErrTokenNotYetValid},
+		{nbf.Add(-30 * time.Second), time.Minute, nil},
+	}
+	for _, tt := range tests {
+		if err := c.Validate(tt.now, tt.leeway); !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+			t.Errorf("Validate(%s, %s) = %v, want %v", tt.now.UTC(), tt.leeway, err, tt.want)
+		}
+	}
+	if c.Expired(exp.Add(-time.Second)) || !c.Expired(exp) {
+		t.Errorf("Expired expected to switch at %s", exp.UTC())
+	}
+
+	forever, err := ParseClaims(token(`{"sub":"x"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer forever.Release()
+	if forever.Expired(time.Now()) || forever.Validate(time.Now(), 0) != nil {
+		t.Errorf("a token without exp nor nbf expected to be valid")
+	}
+
+	bad, err := ParseClaims(token(`{"exp":"tomorrow"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer bad.Release()
+	if err := bad.Validate(time.Now(), 0); !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("Validate = %v, want ErrMalformedToken", err)
+	}
+	if _, ok := bad.ExpiresAt(); ok {
+		t.Errorf("ExpiresAt expected to fail on a string exp")
+	}
+}
+
+func TestClaimsRelease(t *testing.T) {
+	c, err := ParseClaims(token(`{"sub":"x"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c.Release()
+	c.Release()
+	if c.Value != nil {
+		t.Errorf("Release expected to drop the claims")
+	}
+}
@@ -0,0 +1,89 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookup enriches the elements of a level with an entry of a companion
+// document passed in Options.Lookups.
+//
+// It is written lookup(countries, country_code).name as country in a
+// retrieve block: the entry of the countries document for the
+// country_code of the element is found, and its name is emitted under
+// country. The path after the parentheses and the as clause are optional;
+// without as, the field is named after the companion document.
+//
+// Companion documents are objects keyed by the looked up values. A missing
+// document, entry or path is emitted as null.
+type lookup struct {
+	table string
+	local string
+	path  Path
+	as    string
+}
+
+func parseLookup(cmd string) (*lookup, error) {
+	n, err := scanGroup(cmd[len("lookup"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Split(cmd[len("lookup("):len("lookup")+n-1], ",")
+	if len(args) != 2 {
+		return nil, fmt.Errorf("lookup expects a document and a key : %q", cmd)
+	}
+	l := &lookup{
+		table: strings.TrimSpace(args[0]),
+		local: strings.TrimSpace(args[1]),
+	}
+	if !isName(l.table) || !isName(l.local) {
+		return nil, fmt.Errorf("lookup expects a document and a key : %q", cmd)
+	}
+	l.as = l.table
+
+	rest := strings.TrimSpace(cmd[len("lookup")+n:])
+	if i := strings.Index(rest, " as "); i >= 0 {
+		l.as = strings.TrimSpace(rest[i+len(" as "):])
+		rest = strings.TrimSpace(rest[:i])
+	} else if strings.HasPrefix(rest, "as ") {
+		l.as = strings.TrimSpace(rest[len("as "):])
+		rest = ""
+	}
+	if len(rest) > 0 {
+		if rest[0] != '.' || len(rest) == 1 {
+			return nil, fmt.Errorf("mal formated lookup path : %q", rest)
+		}
+		l.path = Path(strings.Split(rest[1:], "."))
+		for _, key := range l.path {
+			if !isName(key) {
+				return nil, fmt.Errorf("mal formated lookup path : %q", rest)
+			}
+		}
+	}
+	if !isName(l.as) {
+		return nil, fmt.Errorf("mal formated lookup name : %q", l.as)
+	}
+	return l, nil
+}
+
+// keep returns the JSON of the entry looked up for o.
+func (l *lookup) keep(o *Object, tables map[string]*Value) string {
+	key := o.Get(l.local)
+	if key == nil {
+		return "null"
+	}
+	var k string
+	switch key.Type() {
+	case TypeString:
+		k = key.s
+	case TypeObject, TypeArray:
+		return "null"
+	default:
+		k = key.raw()
+	}
+	entry := tables[l.table].Get(k).Get(l.path...)
+	if entry == nil {
+		return "null"
+	}
+	return entry.raw()
+}
@@ -0,0 +1,60 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var pc, p Parser
+	countries, err := pc.Parse(`{"FR": {"name": "France", "eu": true}, "JP": {"name": "Japan", "eu": false}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	v, err := p.Parse(`{"users": [{"id": 1, "country": "FR"}, {"id": 2, "country": "JP"}, {"id": 3, "country": "XX"}, {"id": 4}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q, err := ParseQuery("{users{id, lookup(countries, country).name as country_name, lookup(countries, country)}}")
+	if err != nil {
+		t.Fatalf("cannot parse query: %s", err)
+	}
+	q.SetOptions(Options{Lookups: map[string]*Value{"countries": countries}})
+	got, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{"users":[` +
+		`{"id":1,"country_name":"France","countries":{"name":"France","eu":true}},` +
+		`{"id":2,"country_name":"Japan","countries":{"name":"Japan","eu":false}},` +
+		`{"id":3,"country_name":null,"countries":null},` +
+		`{"id":4,"country_name":null,"countries":null}]}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+
+	// The lookups of a level selected twice.
+	merged := MustParseQuery("{users{id, lookup(countries, country).name as country_name}, users{lookup(countries, country)}}")
+	merged.SetOptions(Options{Lookups: map[string]*Value{"countries": countries}})
+	if got, err := v.Keep(*merged); err != nil || got != want {
+		t.Errorf("Keep() of a level selected twice = %s, %v, want %s", got, err, want)
+	}
+
+	q.SetOptions(Options{})
+	got, err = v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != `{"users":[{"id":1,"country_name":null,"countries":null},{"id":2,"country_name":null,"countries":null},{"id":3,"country_name":null,"countries":null},{"id":4,"country_name":null,"countries":null}]}` {
+		t.Errorf("Keep() without lookups = %s", got)
+	}
+
+	for _, cmd := range []string{
+		"{users{lookup(countries).name}}",
+		"{users{lookup(countries, country)name}}",
+		"{users{lookup(countries, country).name as}}",
+	} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting non-nil error", cmd)
+		}
+	}
+}
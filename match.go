@@ -0,0 +1,150 @@
+package jsonq
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Match returns the paths of every object accepted by a filtered level of q.
+//
+// Match behaves like a dry run of Keep: it walks v with the same levels,
+// slices and descents, and the same filter semantics, but never builds the
+// projected output. Levels without filters are only traversed. The paths
+// come in document order, and an empty result means nothing matched.
+func (q *Query) Match(v *Value) []Path {
+	var matches []match
+	q.matchValue(v, Path{}, nil, &matches)
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return comparePositions(matches[i].position, matches[j].position) < 0
+	})
+	// An object accepted by a level and by a descent is matched once.
+	paths := []Path{matches[0].path}
+	for i := 1; i < len(matches); i++ {
+		if comparePositions(matches[i].position, matches[i-1].position) != 0 {
+			paths = append(paths, matches[i].path)
+		}
+	}
+	return paths
+}
+
+// match is an object accepted by a filtered level, at path in the document.
+// Its position holds the indexes of the members and elements leading to it,
+// to order the matches as the document does.
+type match struct {
+	path     Path
+	position []int
+}
+
+// matchValue records the matches of the level q in v.
+func (q *Query) matchValue(v *Value, path Path, position []int, matches *[]match) {
+	switch v.Type() {
+	case TypeArray:
+		q.matchElements(v.a, 0, path, position, matches)
+	case TypeObject:
+		if !q.accept(&v.o) {
+			return
+		}
+		q.matchLevels(v, path, position, matches)
+		if q.descent != nil {
+			q.descent.matchDescent(v, path, position, matches)
+		}
+	}
+}
+
+// matchElements records the matches of the level q in the elements of an
+// array from offset on.
+func (q *Query) matchElements(a []*Value, offset int, path Path, position []int, matches *[]match) {
+	for i, uValue := range a {
+		index := offset + i
+		q.matchValue(uValue, path.child(strconv.Itoa(index)), childPosition(position, index), matches)
+	}
+}
+
+// matchLevels records the object v, accepted by q, and the matches of the
+// sub levels of q in its members.
+func (q *Query) matchLevels(v *Value, path Path, position []int, matches *[]match) {
+	if len(q.filters) > 0 {
+		*matches = append(*matches, match{path: path, position: position})
+	}
+	o := &v.o
+	o.unescapeKeys()
+	for i, kv := range o.kvs {
+		next := q.next[kv.k]
+		// A level applies to the first member of its name only, as Get
+		// returns it.
+		if next == nil || o.Get(kv.k) != kv.v {
+			continue
+		}
+		next.matchLevel(kv.v, path.child(kv.k), childPosition(position, i), matches)
+	}
+}
+
+// matchLevel records the matches of the level q in v, the value of its key,
+// with the slice of q.
+func (q *Query) matchLevel(v *Value, path Path, position []int, matches *[]match) {
+	if q.slice == nil {
+		q.matchValue(v, path, position, matches)
+		return
+	}
+	if v.Type() != TypeArray {
+		return
+	}
+	from, to := q.slice.bounds(len(v.a))
+	if q.slice.element {
+		if from < to {
+			q.matchValue(v.a[from], path.child(strconv.Itoa(from)), childPosition(position, from), matches)
+		}
+		return
+	}
+	q.matchElements(v.a[from:to], from, path, position, matches)
+}
+
+// matchDescent records the matches of the descent level d in v and all its
+// descendants.
+func (d *Query) matchDescent(v *Value, path Path, position []int, matches *[]match) {
+	switch v.Type() {
+	case TypeArray:
+		for index, uValue := range v.a {
+			d.matchDescent(uValue, path.child(strconv.Itoa(index)), childPosition(position, index), matches)
+		}
+	case TypeObject:
+		if d.accept(&v.o) {
+			d.matchLevels(v, path, position, matches)
+		}
+		v.o.unescapeKeys()
+		for i, kv := range v.o.kvs {
+			d.matchDescent(kv.v, path.child(kv.k), childPosition(position, i), matches)
+		}
+	}
+}
+
+// childPosition returns the position of the child at index of the value at
+// position.
+func childPosition(position []int, index int) []int {
+	return append(position[:len(position):len(position)], index)
+}
+
+// comparePositions orders the positions a and b in document order.
+func comparePositions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// nextNames returns the names of the sub levels of q in sorted order.
+func (q Query) nextNames() []string {
+	names := make([]string, 0, len(q.next))
+	for name, next := range q.next {
+		if next != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
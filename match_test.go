@@ -0,0 +1,76 @@
+package jsonq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryMatch(t *testing.T) {
+	const doc = `{
+		"name": "shop",
+		"products": [
+			{"name": "a", "price": 5, "tags": [{"id": 1}, {"id": 2}]},
+			{"name": "b", "price": 50, "tags": [{"id": 2}]},
+			{"name": "c", "price": 500}
+		]
+	}`
+	var p Parser
+	v, err := p.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []Path
+	}{
+		{"no filters", "{name, products{name}}", nil},
+		{"root filter", "(name = shop){name}", []Path{{}}},
+		{"root filter fails", "(name = other){name}", nil},
+		{"array filter", "{products(price > 10){name}}", []Path{{"products", "1"}, {"products", "2"}}},
+		{"nested filter", "{products(price < 100){tags(id = 2){id}}}", []Path{
+			{"products", "0"},
+			{"products", "0", "tags", "1"},
+			{"products", "1"},
+			{"products", "1", "tags", "0"},
+		}},
+		{"slice", "{products[1:](price < 100){name}}", []Path{{"products", "1"}}},
+		{"element", "{products[-1](price > 10){name}}", []Path{{"products", "2"}}},
+		{"slice out of the array", "{products[5:](price > 0){name}}", nil},
+		{"descent", "{**(id? && id = 2){id}}", []Path{{"products", "0", "tags", "1"}, {"products", "1", "tags", "0"}}},
+		{"descent and level", "{products(price < 10){name}, **(price? && price < 100){name}}", []Path{{"products", "0"}, {"products", "1"}}},
+		{"indexes in document order", "{products{tags(id > 0){id}}}", []Path{
+			{"products", "0", "tags", "0"},
+			{"products", "0", "tags", "1"},
+			{"products", "1", "tags", "0"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("cannot parse query %q: %s", tt.query, err)
+			}
+			if got := q.Match(v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatchKeyOrder(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"zebra": {"x": 1}, "apple": [{"x": 2}], "mango": {"zebra": {"x": 3}, "apple": {"x": 4}}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q, err := ParseQuery("{zebra(x > 0){x}, apple(x > 0){x}, **(x? && x > 2){x}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []Path{{"zebra"}, {"apple", "0"}, {"mango", "zebra"}, {"mango", "apple"}}
+	if got := q.Match(v); !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
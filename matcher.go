@@ -0,0 +1,239 @@
+package jsonq
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Matcher tells whether JSON objects pass a set of filters.
+//
+// A Matcher only parses the values of the filtered keys, and stops reading
+// the document as soon as the verdict is known. The rest of the document
+// is not validated. It suits event routing, where most of the payload is
+// irrelevant to the decision.
+//
+// Matcher may be used from concurrent goroutines.
+type Matcher struct {
+	q      Query
+	states sync.Pool
+}
+
+type matchState struct {
+	b      []byte
+	c      cache
+	done   []bool
+	values []truth
+}
+
+// NewMatcher compiles the filters part of a query, such as
+// (level = error && status >= 500), into a Matcher. The surrounding
+// parentheses are optional.
+func NewMatcher(filters string) (*Matcher, error) {
+	if n, err := scanGroup(filters, '(', ')'); err == nil && n == len(filters) && filters[0] == '(' {
+		filters = filters[1 : len(filters)-1]
+	}
+	q, err := ParseQuery("(" + filters + ")")
+	if err != nil {
+		return nil, err
+	}
+	if len(q.filters) == 0 || len(q.next) > 0 || len(q.retrieve) > 0 || q.all || q.descent != nil {
+		return nil, fmt.Errorf("a matcher only accepts filters : %q", filters)
+	}
+	for _, filter := range q.filters {
+		if filter.calc != nil {
+			return nil, fmt.Errorf("a matcher does not compute filter values from fields : %q", filters)
+		}
+	}
+	return &Matcher{q: *q}, nil
+}
+
+// SetOptions changes the evaluation settings of m.
+//
+// It must not be called while m is in use.
+func (m *Matcher) SetOptions(opts Options) {
+	m.q.SetOptions(opts)
+}
+
+// MatchBytes reports whether the JSON object in data passes the filters of m.
+//
+// false is returned for documents that are not objects or that are
+// malformed up to the point where the verdict is known.
+func (m *Matcher) MatchBytes(data []byte) bool {
+	return m.Match(b2s(data))
+}
+
+// Match is MatchBytes for a string.
+func (m *Matcher) Match(s string) bool {
+	st, _ := m.states.Get().(*matchState)
+	if st == nil {
+		st = &matchState{}
+	}
+	ok := m.match(s, st)
+	m.states.Put(st)
+	return ok
+}
+
+func (m *Matcher) match(s string, st *matchState) bool {
+	filters := m.q.filters
+	if cap(st.done) < len(filters) {
+		st.done = make([]bool, len(filters))
+		st.values = make([]truth, len(filters))
+	}
+	st.done = st.done[:len(filters)]
+	st.values = st.values[:len(filters)]
+	for i := range st.done {
+		st.done[i] = false
+	}
+	pending := len(filters)
+
+	s = skipWS(s)
+	if len(s) == 0 || s[0] != '{' {
+		return false
+	}
+	s = skipWS(s[1:])
+	if len(s) > 0 && s[0] == '}' {
+		s = ""
+	}
+	for pending > 0 && len(s) > 0 {
+		if s[0] != '"' {
+			return false
+		}
+		key, tail, err := parseRawKey(s[1:])
+		if err != nil {
+			return false
+		}
+		if hasBackslash(key) {
+			key = unescapeStringBestEffort(string(append([]byte(nil), key...)))
+		}
+		s = skipWS(tail)
+		if len(s) == 0 || s[0] != ':' {
+			return false
+		}
+		s = skipWS(s[1:])
+
+		wanted := false
+		for i, filter := range filters {
+			if !st.done[i] && filter.key == key {
+				wanted = true
+				break
+			}
+		}
+		if wanted {
+			tail, err = skipValue(s)
+			if err != nil {
+				return false
+			}
+			// The value is copied, since unescaping its strings
+			// modifies them in place.
+			st.b = append(st.b[:0], s[:len(s)-len(tail)]...)
+			st.c.reset()
+			v, _, err := parseValue(b2s(st.b), &st.c)
+			if err != nil {
+				return false
+			}
+			for i, filter := range filters {
+				if !st.done[i] && filter.key == key {
+					st.done[i] = true
+					if result, ok := filter.presence(true); ok {
+						st.values[i] = truthOf(result)
+					} else {
+						st.values[i] = truthOf(v.check(*filter, &m.q.opts))
+					}
+					pending--
+				}
+			}
+			// Filters not read yet are unknown : the verdict is known
+			// when it does not depend on them.
+			if result, _ := m.q.condition().eval(true, func(i int) (truth, bool) {
+				if !st.done[i] {
+					return truthUnknown, true
+				}
+				return st.values[i], true
+			}); result != truthUnknown {
+				return result == truthTrue
+			}
+		} else {
+			tail, err = skipValue(s)
+			if err != nil {
+				return false
+			}
+		}
+
+		s = skipWS(tail)
+		if len(s) == 0 {
+			return false
+		}
+		if s[0] == '}' {
+			break
+		}
+		if s[0] != ',' {
+			return false
+		}
+		s = skipWS(s[1:])
+	}
+	result, present := m.q.condition().eval(m.q.opts.ThreeValued, func(i int) (truth, bool) {
+		if result, ok := filters[i].presence(st.done[i]); ok {
+			return truthOf(result), true
+		}
+		return st.values[i], st.done[i]
+	})
+	return result == truthTrue || !present
+}
+
+func hasBackslash(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+// skipValue returns the tail of s following the JSON value starting s,
+// without allocating anything for the value.
+func skipValue(s string) (string, error) {
+	if len(s) == 0 {
+		return s, fmt.Errorf("cannot parse empty string")
+	}
+	switch s[0] {
+	case '"':
+		_, tail, err := parseRawString(s[1:])
+		return tail, err
+	case '{', '[':
+		depth := 0
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				_, tail, err := parseRawString(s[i+1:])
+				if err != nil {
+					return tail, err
+				}
+				i = len(s) - len(tail) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return s[i+1:], nil
+				}
+			}
+		}
+		return "", fmt.Errorf("unexpected end of %q", s[0])
+	case 't':
+		return skipLiteral(s, "true")
+	case 'f':
+		return skipLiteral(s, "false")
+	case 'n':
+		return skipLiteral(s, "null")
+	default:
+		_, tail, err := parseRawNumber(s)
+		return tail, err
+	}
+}
+
+func skipLiteral(s, literal string) (string, error) {
+	if len(s) < len(literal) || s[:len(literal)] != literal {
+		return s, fmt.Errorf("unexpected value found: %q", s)
+	}
+	return s[len(literal):], nil
+}
@@ -0,0 +1,71 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		filters string
+		doc     string
+		want    bool
+	}{
+		{"level = error", `{"level": "error"}`, true},
+		{"(level = error)", `{"level": "error"}`, true},
+		{"level = error", `{"level": "info"}`, false},
+		{"level = error && status >= 500", `{"status": 503, "payload": {"a": [1, "}"]}, "level": "error"}`, true},
+		{"level = error && status >= 500", `{"status": 404, "level": "error"}`, false},
+		{"level = error", `{"payload": {"a": [1, "\"}"]}, "level": "error"}`, true},
+		{"level = error", `{"level": "error", "tail": this is not json`, true},
+		{"level = error", `{"level": "info", "tail": this is not json`, false},
+		{"level = error", `{"level": "error"}`, true},
+		{"msg : oops", `{"msg": "Oops \"here\""}`, true},
+		{"status > 1", `{}`, true},
+		{"status > 1", `[]`, false},
+		{"status > 1", `{"status": }`, false},
+		{"tags = a", `{"tags": ["b", "a"]}`, true},
+		{"level = error || status >= 500", `{"level": "info", "status": 503}`, true},
+		{"level = error || status >= 500", `{"level": "info", "status": 200}`, false},
+		{"level = error || status >= 500", `{"level": "error", "tail": this is not json`, true},
+		{"level = error && status >= 500 || debug = true", `{"level": "error", "status": 200, "debug": true}`, true},
+		{"level = error && status >= 500 || debug = true", `{"level": "info", "status": 503}`, false},
+		{"level = error || status >= 500", `{"level": "info"}`, false},
+		{"(level = error && status >= 500) || debug = true", `{"level": "error", "debug": false, "status": 503}`, true},
+		{"(level = error || level = warn) && status >= 500", `{"level": "warn", "status": 200}`, false},
+		{"(level = error || level = warn) && status >= 500", `{"status": 500, "level": "warn", "tail": this is not json`, true},
+		{"(level = error) || (status >= 500)", `{"status": 500}`, true},
+		{"trace?", `{"level": "error", "trace": null}`, true},
+		{"trace?", `{"level": "error"}`, false},
+		{"!trace?", `{"level": "error"}`, true},
+		{"!trace? && level = error", `{"level": "error", "trace": []}`, false},
+		{"trace? || level = error", `{"trace": {}, "tail": this is not json`, true},
+	}
+	for _, tt := range tests {
+		m, err := NewMatcher(tt.filters)
+		if err != nil {
+			t.Fatalf("cannot compile %q: %s", tt.filters, err)
+		}
+		data := []byte(tt.doc)
+		if got := m.MatchBytes(data); got != tt.want {
+			t.Errorf("%q on %s = %v, want %v", tt.filters, tt.doc, got, tt.want)
+		}
+		if string(data) != tt.doc {
+			t.Errorf("%q modified the document: %s", tt.filters, data)
+		}
+	}
+
+	m, err := NewMatcher("status > 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m.SetOptions(Options{ThreeValued: true})
+	if m.Match(`{"level": "error"}`) {
+		t.Errorf("three-valued matcher matched a document without the filtered key")
+	}
+
+	for _, filters := range []string{"", "{a}", "a = 1){b}"} {
+		if _, err := NewMatcher(filters); err == nil {
+			t.Errorf("NewMatcher(%q) expecting non-nil error", filters)
+		}
+	}
+}
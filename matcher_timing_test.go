@@ -0,0 +1,22 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func BenchmarkMatcher(b *testing.B) {
+	m, err := NewMatcher("type = PushEvent && public = true")
+	if err != nil {
+		b.Fatalf("cannot compile matcher: %s", err)
+	}
+	data := []byte(`{"id": "2489651045", "type": "PushEvent", "public": true, "payload": ` + mediumFixture + `}`)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if !m.MatchBytes(data) {
+				panic("BUG: unexpected mismatch")
+			}
+		}
+	})
+}
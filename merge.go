@@ -0,0 +1,107 @@
+package jsonq
+
+// ArrayStrategy is the way Merge combines two arrays.
+type ArrayStrategy int
+
+const (
+	// ArrayReplace keeps the overlay array.
+	ArrayReplace ArrayStrategy = iota
+	// ArrayAppend appends the overlay elements to the base ones.
+	ArrayAppend
+	// ArrayMergeByKey merges the overlay objects into the base objects with
+	// the same value for MergeStrategy.Key. The other overlay elements are
+	// appended.
+	ArrayMergeByKey
+)
+
+// MergeStrategy configures Merge.
+type MergeStrategy struct {
+	Arrays ArrayStrategy
+	// Key identifies the elements of arrays merged by key.
+	Key string
+}
+
+// Merge returns the deep merge of overlay into base, so that layered
+// configuration, such as defaults, then environment, then flags, resolves
+// in one call per layer.
+//
+// Objects are merged key by key, base keys first. Arrays are combined
+// according to strategy. Otherwise, including a null overlay, the overlay
+// value wins. A nil value is an absent layer: the other one is returned.
+//
+// Neither base nor overlay is modified, but the result shares values with
+// them, so it is valid as long as they are.
+func Merge(base, overlay *Value, strategy MergeStrategy) *Value {
+	if base == nil {
+		return overlay
+	}
+	if overlay == nil {
+		return base
+	}
+	switch {
+	case base.Type() == TypeObject && overlay.Type() == TypeObject:
+		return mergeObjects(&base.o, &overlay.o, strategy)
+	case base.Type() == TypeArray && overlay.Type() == TypeArray:
+		switch strategy.Arrays {
+		case ArrayAppend:
+			a := make([]*Value, 0, len(base.a)+len(overlay.a))
+			a = append(append(a, base.a...), overlay.a...)
+			return &Value{t: TypeArray, a: a}
+		case ArrayMergeByKey:
+			return mergeByKey(base.a, overlay.a, strategy)
+		}
+	}
+	return overlay
+}
+
+func mergeObjects(base, overlay *Object, strategy MergeStrategy) *Value {
+	base.unescapeKeys()
+	overlay.unescapeKeys()
+	v := &Value{t: TypeObject}
+	v.o.keysUnescaped = true
+	v.o.kvs = make([]kv, 0, len(base.kvs)+len(overlay.kvs))
+	for _, bkv := range base.kvs {
+		v.o.kvs = append(v.o.kvs, kv{k: bkv.k, v: Merge(bkv.v, overlay.Get(bkv.k), strategy)})
+	}
+	for _, okv := range overlay.kvs {
+		if base.Get(okv.k) == nil {
+			v.o.kvs = append(v.o.kvs, okv)
+		}
+	}
+	return v
+}
+
+func mergeByKey(base, overlay []*Value, strategy MergeStrategy) *Value {
+	a := make([]*Value, len(base), len(base)+len(overlay))
+	copy(a, base)
+	index := map[string]int{}
+	for i, element := range base {
+		if k := elementKey(element, strategy.Key); k != "" {
+			if _, ok := index[k]; !ok {
+				index[k] = i
+			}
+		}
+	}
+	for _, element := range overlay {
+		k := elementKey(element, strategy.Key)
+		if i, ok := index[k]; ok && k != "" {
+			a[i] = Merge(a[i], element, strategy)
+			continue
+		}
+		a = append(a, element)
+	}
+	return &Value{t: TypeArray, a: a}
+}
+
+// elementKey returns the canonical JSON of the key of an array element,
+// or an empty string when it has none.
+func elementKey(element *Value, key string) string {
+	if element.Type() != TypeObject {
+		return ""
+	}
+	k := element.o.Get(key)
+	if k == nil {
+		return ""
+	}
+	return string(k.AppendCanonical(nil))
+}
@@ -0,0 +1,46 @@
+package jsonq
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	const base = `{"name": "app", "db": {"host": "localhost", "port": 5432}, "tags": ["a"], "users": [{"id": 1, "role": "admin"}, {"id": 2, "role": "dev"}], "debug": true}`
+	const overlay = `{"db": {"host": "db.prod"}, "tags": ["b"], "users": [{"id": 2, "role": "ops"}, {"id": 3}, "x"], "debug": null, "extra": 1}`
+	tests := []struct {
+		strategy MergeStrategy
+		want     string
+	}{
+		{
+			MergeStrategy{},
+			`{"name":"app","db":{"host":"db.prod","port":5432},"tags":["b"],"users":[{"id":2,"role":"ops"},{"id":3},"x"],"debug":null,"extra":1}`,
+		},
+		{
+			MergeStrategy{Arrays: ArrayAppend},
+			`{"name":"app","db":{"host":"db.prod","port":5432},"tags":["a","b"],"users":[{"id":1,"role":"admin"},{"id":2,"role":"dev"},{"id":2,"role":"ops"},{"id":3},"x"],"debug":null,"extra":1}`,
+		},
+		{
+			MergeStrategy{Arrays: ArrayMergeByKey, Key: "id"},
+			`{"name":"app","db":{"host":"db.prod","port":5432},"tags":["a","b"],"users":[{"id":1,"role":"admin"},{"id":2,"role":"ops"},{"id":3},"x"],"debug":null,"extra":1}`,
+		},
+	}
+	var pb, po Parser
+	for _, tt := range tests {
+		b, err := pb.Parse(base)
+		if err != nil {
+			t.Fatalf("cannot parse base: %s", err)
+		}
+		o, err := po.Parse(overlay)
+		if err != nil {
+			t.Fatalf("cannot parse overlay: %s", err)
+		}
+		got := Merge(b, o, tt.strategy)
+		if s := got.String(); s != tt.want {
+			t.Errorf("Merge(%+v) = %s, want %s", tt.strategy, s, tt.want)
+		}
+		if host := string(b.GetStringBytes("db", "host")); host != "localhost" {
+			t.Errorf("Merge modified base: db.host = %s", host)
+		}
+	}
+	if got := Merge(nil, nil, MergeStrategy{}); got != nil {
+		t.Errorf("Merge(nil, nil) = %s", got)
+	}
+}
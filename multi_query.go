@@ -0,0 +1,207 @@
+package jsonq
+
+import (
+	"sort"
+	"strconv"
+)
+
+// MultiQuery evaluates several queries against the same document, as when
+// many subscribers select different fields of one event.
+//
+// The document is parsed once, and walked once: the levels of the queries
+// are combined into one tree, and each value is given to every query
+// selecting it. Only the levels with a slice are walked for their query
+// alone.
+//
+// MultiQuery may be used from concurrent goroutines.
+type MultiQuery struct {
+	queries []*Query
+	root    *multiLevel
+	pp      ParserPool
+}
+
+// multiLevel combines the levels of the queries of a MultiQuery applying to
+// the same values of the document.
+type multiLevel struct {
+	// levels holds the levels, by index of their query.
+	levels map[int]*Query
+	next   map[string]*multiLevel
+	// names are the names of the sub levels of the levels, sorted.
+	names []string
+}
+
+// kept is the output of a level for a value.
+type kept struct {
+	value string
+	err   error
+}
+
+// Result is the output of a query of a MultiQuery.
+type Result struct {
+	Output string
+	Err    error
+}
+
+// NewMultiQuery returns a MultiQuery evaluating the queries.
+func NewMultiQuery(queries ...*Query) *MultiQuery {
+	root := newMultiLevel()
+	for i, q := range queries {
+		root.add(i, q)
+	}
+	return &MultiQuery{
+		queries: queries,
+		root:    root,
+	}
+}
+
+func newMultiLevel() *multiLevel {
+	return &multiLevel{levels: map[int]*Query{}, next: map[string]*multiLevel{}}
+}
+
+// add adds level, of the query i, to m.
+func (m *multiLevel) add(i int, level *Query) {
+	m.levels[i] = level
+	for name, next := range level.next {
+		if _, ok := m.next[name]; !ok {
+			m.next[name] = newMultiLevel()
+			m.names = append(m.names, name)
+			sort.Strings(m.names)
+		}
+		if next != nil && next.slice == nil {
+			m.next[name].add(i, next)
+		}
+	}
+}
+
+// Len returns the number of queries in m.
+func (m *MultiQuery) Len() int {
+	return len(m.queries)
+}
+
+// Keep returns the result of Value.Keep for every query of m on v,
+// in the order the queries were given.
+func (m *MultiQuery) Keep(v *Value) []Result {
+	es := make([]*execution, len(m.queries))
+	ids := make([]int, len(m.queries))
+	for i, q := range m.queries {
+		es[i] = newExecution(*q, v)
+		ids[i] = i
+	}
+	outputs := m.root.keep(v, Path{}, ids, es)
+	results := make([]Result, len(m.queries))
+	for i, e := range es {
+		results[i].Output, results[i].Err = e.result(outputs[i].value, outputs[i].err)
+	}
+	return results
+}
+
+// keep returns the outputs of the levels of the queries ids for v, found
+// at path in the document, as Value.keep does for each of them.
+func (m *multiLevel) keep(v *Value, path Path, ids []int, es []*execution) map[int]kept {
+	outputs := make(map[int]kept, len(ids))
+	switch v.Type() {
+	case TypeArray:
+		arrays := make(map[int]*arrayOutput, len(ids))
+		for _, id := range ids {
+			arrays[id] = newArrayOutput(*m.levels[id], len(v.a), es[id])
+		}
+		for index, uValue := range v.a {
+			elements := m.keep(uValue, path.child(strconv.Itoa(index)), ids, es)
+			// The queries failing on an element are done with the array.
+			live := ids[:0:0]
+			for _, id := range ids {
+				err := elements[id].err
+				if err == nil {
+					err = arrays[id].add(elements[id].value, uValue)
+				}
+				if err != nil {
+					outputs[id] = kept{err: err}
+					continue
+				}
+				live = append(live, id)
+			}
+			ids = live
+		}
+		for _, id := range ids {
+			value, err := arrays[id].result(path)
+			outputs[id] = kept{value: value, err: err}
+		}
+	case TypeObject:
+		o := &v.o
+		accepted := ids[:0:0]
+		for _, id := range ids {
+			if m.levels[id].accept(o) {
+				accepted = append(accepted, id)
+			} else {
+				outputs[id] = kept{}
+			}
+		}
+		levels := m.keepLevels(o, path, accepted, es)
+		for _, id := range accepted {
+			level := func(name string) (string, bool, error) {
+				l := levels[id][name]
+				return l.value, len(l.value) > 0, l.err
+			}
+			value, err := m.levels[id].writeLevel(v, o, path, es[id], (*Value).raw, level)
+			outputs[id] = kept{value: value, err: err}
+		}
+	case TypeString, TypeNumber, TypeFalse, TypeTrue, TypeNull:
+		for _, id := range ids {
+			outputs[id] = kept{value: v.Description}
+		}
+	default:
+		for _, id := range ids {
+			outputs[id] = kept{err: es[id].fail(path, ErrUnknownType)}
+		}
+	}
+	return outputs
+}
+
+// keepLevels returns the outputs of the sub levels of the levels of the
+// queries ids in the object o, by query and name. The objects and arrays
+// are walked once for the queries sharing a level, the other values are
+// given to Query.keepLevel.
+func (m *multiLevel) keepLevels(o *Object, path Path, ids []int, es []*execution) map[int]map[string]kept {
+	levels := make(map[int]map[string]kept, len(ids))
+	for _, id := range ids {
+		levels[id] = map[string]kept{}
+	}
+	for _, name := range m.names {
+		next := m.next[name]
+		nValue := o.Get(name)
+		var shared []int
+		for _, id := range ids {
+			if _, ok := m.levels[id].next[name]; !ok {
+				continue
+			}
+			if _, ok := next.levels[id]; ok && nValue != nil && (nValue.Type() == TypeObject || nValue.Type() == TypeArray) {
+				shared = append(shared, id)
+				continue
+			}
+			value, ok, err := m.levels[id].keepLevel(o, name, path, es[id])
+			if !ok {
+				value = ""
+			}
+			levels[id][name] = kept{value: value, err: err}
+		}
+		if len(shared) > 0 {
+			for id, l := range next.keep(nValue, path.child(name), shared, es) {
+				levels[id][name] = l
+			}
+		}
+	}
+	return levels
+}
+
+// KeepBytes parses data and returns the result of every query of m on it.
+func (m *MultiQuery) KeepBytes(data []byte) ([]Result, error) {
+	p := m.pp.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		m.pp.Put(p)
+		return nil, err
+	}
+	results := m.Keep(v)
+	m.pp.Put(p)
+	return results, nil
+}
@@ -0,0 +1,84 @@
+package jsonq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMultiQuery(t *testing.T) {
+	m := NewMultiQuery(
+		MustParseQuery("{id}"),
+		MustParseQuery("(type = push){id, type}"),
+		MustParseQuery("(type = pull){id}"),
+		MustParseQuery("{repo{name}}"),
+	)
+	if m.Len() != 4 {
+		t.Fatalf("unexpected len; got %d; want 4", m.Len())
+	}
+	results, err := m.KeepBytes([]byte(`{"id": 1, "type": "push", "repo": {"name": "jsonq", "stars": 3}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{
+		`{"id":1}`,
+		`{"id":1,"type":"push"}`,
+		``,
+		`{"repo":{"name":"jsonq"}}`,
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Errorf("query %d: unexpected error: %s", i, result.Err)
+		}
+		if result.Output != want[i] {
+			t.Errorf("query %d: got %s; want %s", i, result.Output, want[i])
+		}
+	}
+
+	if _, err := m.KeepBytes([]byte(`{"id": `)); err == nil {
+		t.Errorf("expecting non-nil error on invalid json")
+	}
+}
+
+func TestMultiQueryKeep(t *testing.T) {
+	queries := []string{
+		"{users{name}}",
+		"{users(age > 20){name, age}}",
+		"{users{name, tags}, total}",
+		"{users[0]{name}, total}",
+		"{users[1:]{name}}",
+		"{users(age > 20){name}, total}",
+		"{*, users{*, !tags}}",
+		"{users{name, count(tags) as n}}",
+		"{total{name}}",
+		"{meta{version}, users{name}}",
+		"{users(name = Bo){**{id}}}",
+		"(total > 5){total}",
+	}
+	var p Parser
+	v, err := p.Parse(`{"total": 2, "users": [{"name": "Al", "age": 30, "tags": [{"id": 1}]}, {"name": "Bo", "age": 12, "tags": [{"id": 2}, {"id": 3}]}, 3]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	for _, bestEffort := range []bool{false, true} {
+		var m []*Query
+		for _, query := range queries {
+			q, err := ParseQuery(query)
+			if err != nil {
+				t.Fatalf("cannot parse %s: %s", query, err)
+			}
+			q.SetOptions(Options{Deterministic: true, Scalars: ScalarError, BestEffort: bestEffort})
+			m = append(m, q)
+		}
+		mq := NewMultiQuery(m...)
+		// The levels without a slice share the walk of the users.
+		if n := len(mq.root.next["users"].levels); n != 8 {
+			t.Errorf("%d queries share the users level, want 8", n)
+		}
+		for i, result := range mq.Keep(v) {
+			want, err := v.Keep(*m[i])
+			if result.Output != want || fmt.Sprint(result.Err) != fmt.Sprint(err) {
+				t.Errorf("%s, best effort %t: got %s, %v; want %s, %v", queries[i], bestEffort, result.Output, result.Err, want, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Set sets the value of key in o, replacing the current one or appending
+// a new member.
+func (o *Object) Set(key string, value *Value) {
+	o.unescapeKeys()
+	for i := range o.kvs {
+		if o.kvs[i].k == key {
+			o.kvs[i].v = value
+			return
+		}
+	}
+	o.kvs = append(o.kvs, kv{k: key, v: value})
+}
+
+// Del deletes key from o and reports whether it was there.
+func (o *Object) Del(key string) bool {
+	o.unescapeKeys()
+	for i := range o.kvs {
+		if o.kvs[i].k == key {
+			o.kvs = append(o.kvs[:i], o.kvs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetPath sets the value at path in v. The objects missing along path are
+// created. An array index must be an existing element, or the length of
+// the array to append value.
+//
+// v keeps a reference to value, so it is valid as long as value is.
+func (v *Value) SetPath(path Path, value *Value) error {
+	if len(path) == 0 {
+		return fmt.Errorf("cannot set the root value")
+	}
+	parent := v
+	for i, key := range path[:len(path)-1] {
+		next := parent.step(key)
+		if next == nil {
+			if parent.Type() != TypeObject {
+				return fmt.Errorf("%w : %s", ErrKeyNotFound, path[:i+1])
+			}
+			next = newObject()
+			parent.o.Set(key, next)
+		}
+		parent = next
+	}
+	return parent.setChild(path, value, false)
+}
+
+// setChild sets the value at path, of a key of the object or array v. For
+// arrays, insert shifts the elements from the index on instead of replacing
+// one, and "-" appends.
+func (v *Value) setChild(path Path, value *Value, insert bool) error {
+	key := path[len(path)-1]
+	switch v.Type() {
+	case TypeObject:
+		v.o.Set(key, value)
+		return nil
+	case TypeArray:
+		n := len(v.a)
+		if key != "-" {
+			var err error
+			if n, err = arrayIndex(key, len(v.a)); err != nil {
+				return err
+			}
+		}
+		switch {
+		case n == len(v.a):
+			v.a = append(v.a, value)
+		case insert:
+			v.a = append(v.a, nil)
+			copy(v.a[n+1:], v.a[n:])
+			v.a[n] = value
+		default:
+			v.a[n] = value
+		}
+		return nil
+	}
+	return &ErrWrongType{Path: path[:len(path)-1], Want: TypeObject, Got: v.Type()}
+}
+
+// arrayIndex parses the index of an element of an array of length n, or n
+// itself.
+func arrayIndex(key string, n int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i > n || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("invalid index %q for an array of %d elements", key, n)
+	}
+	return i, nil
+}
+
+// clone returns a deep copy of v.
+func (v *Value) clone() *Value {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		c := newObject()
+		c.o.kvs = make([]kv, len(v.o.kvs))
+		for i, kv := range v.o.kvs {
+			c.o.kvs[i].k = kv.k
+			c.o.kvs[i].v = kv.v.clone()
+		}
+		return c
+	case TypeArray:
+		c := &Value{t: TypeArray, a: make([]*Value, len(v.a))}
+		for i, e := range v.a {
+			c.a[i] = e.clone()
+		}
+		return c
+	}
+	c := *v
+	return &c
+}
@@ -0,0 +1,58 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestObjectSetDel(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b!":2}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	o := v.GetObject()
+	o.Set("a", v.Get("b!"))
+	o.Set("c", valueNull)
+	if v.String() != `{"a":2,"b!":2,"c":null}` {
+		t.Fatalf("unexpected object after Set: %s", v)
+	}
+	if !o.Del("b!") || o.Del("missing") {
+		t.Fatalf("unexpected Del results")
+	}
+	if v.String() != `{"a":2,"c":null}` {
+		t.Fatalf("unexpected object after Del: %s", v)
+	}
+}
+
+func TestValueSetPath(t *testing.T) {
+	f := func(s string, path Path, expected string) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if err := v.SetPath(path, &Value{t: TypeString, s: "x"}); err != nil {
+			t.Fatalf("cannot set %q in %q: %s", path, s, err)
+		}
+		if v.String() != expected {
+			t.Fatalf("unexpected value after setting %q in %q; got %s; want %s", path, s, v, expected)
+		}
+	}
+	f(`{}`, Path{"a"}, `{"a":"x"}`)
+	f(`{"a":{"b":1}}`, Path{"a", "b"}, `{"a":{"b":"x"}}`)
+	f(`{}`, Path{"a", "b", "c"}, `{"a":{"b":{"c":"x"}}}`)
+	f(`{"a":[1,2]}`, Path{"a", "0"}, `{"a":["x",2]}`)
+	f(`{"a":[1,2]}`, Path{"a", "2"}, `{"a":[1,2,"x"]}`)
+
+	var p Parser
+	v, err := p.Parse(`{"a":[1],"n":1}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	for _, path := range []Path{{}, {"a", "3"}, {"a", "x"}, {"a", "5", "b"}, {"n", "b"}} {
+		if err := v.SetPath(path, valueNull); err == nil {
+			t.Fatalf("expecting error when setting %q", path)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package jsonq
+
+// Normalizer returns a normalized form of a string.
+//
+// User generated content may hold the same text in several unicode forms:
+// "é" may be a single code point or an "e" followed by a combining accent.
+// Normalizing both sides of a filter makes them compare equal.
+//
+// The forms of golang.org/x/text/unicode/norm satisfy Normalizer:
+//
+//	q.SetOptions(jsonq.Options{Normalizer: norm.NFC})
+type Normalizer interface {
+	String(s string) string
+}
+
+func normalize(n Normalizer, v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return n.String(s)
+	}
+	return v
+}
@@ -0,0 +1,41 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+// composer composes the decomposed "é" only.
+type composer struct{}
+
+func (composer) String(s string) string {
+	return strings.Replace(s, "e\u0301", "é", -1)
+}
+
+func TestNormalizer(t *testing.T) {
+	var p Parser
+	v, err := p.Parse("[{\"name\": \"Renée\"}, {\"name\": \"Rene\u0301e\"}, {\"name\": \"Renee\"}]")
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query      string
+		want       int
+		wantNoNorm int
+	}{
+		{"(name = Renée){name}", 2, 1},
+		{"(name = Rene\u0301e){name}", 2, 1},
+		{"(name != Renée){name}", 1, 2},
+		{"(name : né){name}", 2, 1},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		if got := len(q.Match(v)); got != tt.wantNoNorm {
+			t.Errorf("%+q without normalizer matched %d names, want %d", tt.query, got, tt.wantNoNorm)
+		}
+		q.SetOptions(Options{Normalizer: composer{}})
+		if got := len(q.Match(v)); got != tt.want {
+			t.Errorf("%+q with normalizer matched %d names, want %d", tt.query, got, tt.want)
+		}
+	}
+}
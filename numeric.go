@@ -0,0 +1,112 @@
+package jsonq
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// numeric is one of the aggregations of the numbers of a field: sum(total),
+// avg(total), min(total) and max(total). Elements missing the field, or
+// whose field is not a number, are left out. Without numbers, the sum is 0
+// and the others are null. With the Decimal option, the numbers are summed
+// and compared as decimals, leaving out those too large for them.
+type numeric struct {
+	field Path
+	fn    string
+}
+
+func parseNumeric(fn string) func(args []string) (aggregator, error) {
+	return func(args []string) (aggregator, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects a field", fn)
+		}
+		field, err := parseField(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &numeric{field: field, fn: fn}, nil
+	}
+}
+
+func (n *numeric) aggregate(elements []*Value, opts *Options) string {
+	if opts.Decimal {
+		return n.decimal(elements)
+	}
+	var sum, min, max float64
+	count := 0
+	for _, element := range elements {
+		v := element.Get(n.field...)
+		if v == nil || v.Type() != TypeNumber {
+			continue
+		}
+		if count == 0 || v.n < min {
+			min = v.n
+		}
+		if count == 0 || v.n > max {
+			max = v.n
+		}
+		sum += v.n
+		count++
+	}
+	if count == 0 && n.fn != "sum" {
+		return "null"
+	}
+	var result float64
+	switch n.fn {
+	case "sum":
+		result = sum
+	case "avg":
+		result = sum / float64(count)
+	case "min":
+		result = min
+	case "max":
+		result = max
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64)
+}
+
+// decimal is aggregate with the Decimal option.
+func (n *numeric) decimal(elements []*Value) string {
+	var sum, min, max *big.Rat
+	count := 0
+	for _, element := range elements {
+		v := element.Get(n.field...)
+		if v == nil || v.Type() != TypeNumber {
+			continue
+		}
+		d, ok := v.decimal()
+		if !ok {
+			continue
+		}
+		if count == 0 {
+			sum, min, max = new(big.Rat), d, d
+		}
+		if d.Cmp(min) < 0 {
+			min = d
+		}
+		if d.Cmp(max) > 0 {
+			max = d
+		}
+		sum.Add(sum, d)
+		count++
+	}
+	if count == 0 {
+		if n.fn == "sum" {
+			return "0"
+		}
+		return "null"
+	}
+	var result *big.Rat
+	switch n.fn {
+	case "sum":
+		result = sum
+	case "avg":
+		result = sum.Quo(sum, new(big.Rat).SetInt64(int64(count)))
+	case "min":
+		result = min
+	case "max":
+		result = max
+	}
+	return formatDecimal(result)
+}
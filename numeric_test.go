@@ -0,0 +1,49 @@
+package jsonq
+
+import "testing"
+
+func TestKeepNumeric(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"orders": [
+		{"status": "paid", "total": 10, "shipping": {"cost": 2.5}},
+		{"status": "paid", "total": 25.5, "shipping": {"cost": 0}},
+		{"status": "open", "total": 7},
+		{"status": "paid", "total": "unknown"},
+		{"status": "paid"}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{orders(status === "paid"){sum(total), avg(total)}}`, `{"orders":{"sum(total)":35.5,"avg(total)":17.75}}`},
+		{`{orders{min(total), max(total) as highest, count()}}`, `{"orders":{"min(total)":7,"highest":25.5,"count()":5}}`},
+		{`{orders{sum(shipping.cost) as shipping, avg(shipping.cost)}}`, `{"orders":{"shipping":2.5,"avg(shipping.cost)":1.25}}`},
+		{`{orders(status = gone){sum(total), avg(total), min(total), max(total)}}`, `{"orders":{"sum(total)":0,"avg(total)":null,"min(total)":null,"max(total)":null}}`},
+		{`{orders(status = paid && total > 0){top(1, by: total), sum(total)}}`, `{"orders":{"sum(total)":25.5}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumericErrors(t *testing.T) {
+	for _, query := range []string{
+		"{orders{sum()}}",
+		"{orders{avg(a, b)}}",
+		"{orders{min(a..b)}}",
+		"{orders{max(total) as}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package jsonq
+
+// Options holds the evaluation settings of a Query.
+//
+// The settings are shared by every level of the query.
+// Use Query.SetOptions to change them.
+type Options struct {
+	// ThreeValued makes filters on missing keys evaluate to unknown instead
+	// of being ignored. Unknown propagates like SQL NULLs: false && unknown
+	// is false, true && unknown is unknown. An element is only kept when its
+	// filters evaluate to true.
+	ThreeValued bool
+
+	// Diagnostics, when set, records the filters comparing a number
+	// to a string during executions of the query.
+	Diagnostics *Diagnostics
+
+	// Collator, when set, orders strings for the >, >=, < and <= filters
+	// instead of the raw byte ordering.
+	Collator Collator
+
+	// Normalizer, when set, normalizes both sides of string filters
+	// before comparing them.
+	Normalizer Normalizer
+
+	// Strings, when set, compares the strings of the filters in place of
+	// DefaultStringComparer.
+	Strings StringComparer
+
+	// Coercion controls the implicit conversions between filter values
+	// and document values.
+	Coercion CoercionPolicy
+
+	// Decimal compares the numbers of the documents with the numbers of
+	// the filters as exact decimals, read from their JSON tokens, and
+	// computes sum, avg, min and max with decimals as well, so that
+	// 0.1 + 0.2 is 0.3. Averages which are not decimals are rounded to 16
+	// places.
+	Decimal bool
+
+	// Deterministic guarantees that repeated executions of the query on the
+	// same document produce byte-identical output: sub levels are emitted
+	// sorted by name and numbers keep their original JSON token.
+	Deterministic bool
+
+	// Scalars tells what to do when a level of the query targets a value
+	// that is neither an object nor an array.
+	Scalars ScalarPolicy
+
+	// BestEffort makes Keep and Retrieve leave out the parts of the
+	// document that fail instead of failing entirely. The output is then
+	// returned along with a *PartialError listing the failures.
+	BestEffort bool
+
+	// Lookups are the companion documents referenced by the lookup
+	// projections of the query, by name.
+	Lookups map[string]*Value
+
+	// Unique drops the array elements whose projection has the same
+	// canonical form as an element already emitted anywhere in the result.
+	Unique bool
+
+	// Seed seeds the random choices of the sample directives, so that a
+	// query returns the same sample every time. Zero picks a new seed for
+	// every execution.
+	Seed int64
+
+	// Stats makes Keep and Retrieve return a summary of the selected
+	// fields instead of their values.
+	Stats bool
+
+	// MaxResultSize aborts Keep and Retrieve with ErrResultTooLarge as soon
+	// as their output would be larger than this many bytes, so that small
+	// queries cannot amplify into huge responses. Zero means no limit.
+	MaxResultSize int
+
+	// Audit, when set, is called with the record of every execution of
+	// Keep and Retrieve, and Caller is the identity of whoever runs the
+	// query, such as a user or an API key name, copied to the records.
+	Audit  func(AuditRecord)
+	Caller string
+}
+
+// ScalarPolicy is the behavior of a level of a query applied to a scalar,
+// like name{first} on {"name": "Bob"}.
+type ScalarPolicy int
+
+const (
+	// ScalarKeep emits the scalar as is.
+	ScalarKeep ScalarPolicy = iota
+	// ScalarError fails the execution with the path of the level.
+	// Missing keys fail as well.
+	ScalarError
+	// ScalarSkip leaves the level out of the output.
+	ScalarSkip
+	// ScalarWrap emits the scalar as a single-element array.
+	ScalarWrap
+)
+
+// Options returns the evaluation settings of q.
+func (q Query) Options() Options {
+	return q.opts
+}
+
+// SetOptions changes the evaluation settings of q and of all its sub levels.
+func (q *Query) SetOptions(opts Options) {
+	q.opts = opts
+	for _, next := range q.next {
+		if next != nil {
+			next.SetOptions(opts)
+		}
+	}
+	for _, j := range q.joins {
+		j.q.SetOptions(opts)
+	}
+	for _, c := range q.counts {
+		c.q.SetOptions(opts)
+	}
+	if q.grouping != nil {
+		q.grouping.q.SetOptions(opts)
+	}
+	if q.descent != nil {
+		q.descent.SetOptions(opts)
+	}
+}
@@ -0,0 +1,72 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestTruth(t *testing.T) {
+	values := []truth{truthFalse, truthUnknown, truthTrue}
+	and := [][]truth{
+		{truthFalse, truthFalse, truthFalse},
+		{truthFalse, truthUnknown, truthUnknown},
+		{truthFalse, truthUnknown, truthTrue},
+	}
+	or := [][]truth{
+		{truthFalse, truthUnknown, truthTrue},
+		{truthUnknown, truthUnknown, truthTrue},
+		{truthTrue, truthTrue, truthTrue},
+	}
+	for i, a := range values {
+		for j, b := range values {
+			if got := a.and(b); got != and[i][j] {
+				t.Errorf("%d and %d = %d, want %d", a, b, got, and[i][j])
+			}
+			if got := a.or(b); got != or[i][j] {
+				t.Errorf("%d or %d = %d, want %d", a, b, got, or[i][j])
+			}
+		}
+	}
+	if truthUnknown.not() != truthUnknown || truthTrue.not() != truthFalse {
+		t.Errorf("unexpected negation")
+	}
+}
+
+func TestOptionsThreeValued(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"a": 1, "b": 2}, {"a": 1}, {"a": 2}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{items(a = 1 && b = 2){a}}")
+	items := q.next["items"]
+
+	if got := len(items.Match(v)); got != 2 {
+		t.Errorf("default options matched %d elements, want 2", got)
+	}
+	q.SetOptions(Options{ThreeValued: true})
+	if !items.Options().ThreeValued {
+		t.Fatalf("options were not propagated to sub levels")
+	}
+	if got := len(items.Match(v)); got != 1 {
+		t.Errorf("three-valued options matched %d elements, want 1", got)
+	}
+	a := v.GetArray()
+	if got := items.eval(&a[1].o); got != truthUnknown {
+		t.Errorf("eval with missing key = %d, want unknown", got)
+	}
+	if got := items.eval(&a[2].o); got != truthFalse {
+		t.Errorf("eval with a failing filter and a missing key = %d, want false", got)
+	}
+
+	or := MustParseQuery("{items(b = 2 || a = 2){a}}").next["items"]
+	if got := len(or.Match(v)); got != 2 {
+		t.Errorf("default options matched %d elements with ||, want 2", got)
+	}
+	or.SetOptions(Options{ThreeValued: true})
+	if got := or.eval(&a[1].o); got != truthUnknown {
+		t.Errorf("eval of || with a failing filter and a missing key = %d, want unknown", got)
+	}
+	if got := or.eval(&a[2].o); got != truthTrue {
+		t.Errorf("eval of || with a passing filter and a missing key = %d, want true", got)
+	}
+}
@@ -0,0 +1,154 @@
+package jsonq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ordering orders the elements of an array by one of their fields, written
+// score or score desc.
+type ordering struct {
+	field Path
+	desc  bool
+}
+
+func parseOrdering(cmd string) (*ordering, error) {
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, fmt.Errorf("mal formated ordering %q", cmd)
+	}
+	field, err := parseField(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	o := &ordering{field: field}
+	if len(parts) == 2 {
+		switch strings.ToLower(parts[1]) {
+		case "asc":
+		case "desc":
+			o.desc = true
+		default:
+			return nil, fmt.Errorf("unknown ordering direction %q", parts[1])
+		}
+	}
+	return o, nil
+}
+
+// sort returns the indexes of elements in the order of o. Elements that
+// compare equal keep their document order, and elements missing the field
+// come last whatever the direction.
+func (o *ordering) sort(elements []*Value, c Collator) []int {
+	keys := make([]*Value, len(elements))
+	for i, element := range elements {
+		keys[i] = element.Get(o.field...)
+	}
+	indexes := make([]int, len(elements))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := keys[indexes[i]], keys[indexes[j]]
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if o.desc {
+			return compareValues(b, a, c) < 0
+		}
+		return compareValues(a, b, c) < 0
+	})
+	return indexes
+}
+
+// compareValues orders two values: numbers first, by value, then strings,
+// with c when not nil, then booleans, false first, then the other values,
+// which compare equal.
+func compareValues(a, b *Value, c Collator) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch a.Type() {
+	case TypeNumber:
+		switch {
+		case a.n < b.n:
+			return -1
+		case a.n > b.n:
+			return 1
+		}
+	case TypeString:
+		if c != nil {
+			return c.CompareString(a.s, b.s)
+		}
+		return strings.Compare(a.s, b.s)
+	case TypeFalse:
+		if b.Type() == TypeTrue {
+			return -1
+		}
+	case TypeTrue:
+		if b.Type() == TypeFalse {
+			return 1
+		}
+	}
+	return 0
+}
+
+func typeRank(v *Value) int {
+	switch v.Type() {
+	case TypeNumber:
+		return 0
+	case TypeString:
+		return 1
+	case TypeFalse, TypeTrue:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Order is the direction of SortArrayByKeys.
+type Order int
+
+const (
+	// Asc sorts the smallest values first.
+	Asc Order = iota
+	// Desc sorts the largest values first.
+	Desc
+)
+
+// SortArray sorts the elements of the array v in place with less, keeping
+// the document order of equal elements. It does nothing when v is not an
+// array.
+func (v *Value) SortArray(less func(a, b *Value) bool) {
+	if v == nil || v.t != TypeArray {
+		return
+	}
+	sort.SliceStable(v.a, func(i, j int) bool {
+		return less(v.a[i], v.a[j])
+	})
+}
+
+// SortArrayByKeys sorts the elements of the array v in place by the field
+// at keys path, written as for ParsePath, as the top directive does:
+// numbers, then strings, then booleans, elements missing the field last
+// whatever the order. Empty keys sort the elements themselves.
+func (v *Value) SortArrayByKeys(keys string, order Order) error {
+	if v == nil {
+		return &ErrWrongType{Want: TypeArray, Got: TypeNull}
+	}
+	if v.t != TypeArray {
+		return &ErrWrongType{Want: TypeArray, Got: v.Type()}
+	}
+	field, err := ParsePath(keys)
+	if err != nil {
+		return err
+	}
+	o := ordering{field: field, desc: order == Desc}
+	indexes := o.sort(v.a, nil)
+	sorted := make([]*Value, len(v.a))
+	for i, index := range indexes {
+		sorted[i] = v.a[index]
+	}
+	copy(v.a, sorted)
+	return nil
+}
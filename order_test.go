@@ -0,0 +1,57 @@
+package jsonq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValueSortArray(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[3,1,2]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	v.SortArray(func(a, b *Value) bool { return a.GetFloat64() < b.GetFloat64() })
+	if v.String() != `[1,2,3]` {
+		t.Fatalf("unexpected sorted array: %s", v)
+	}
+}
+
+func TestValueSortArrayByKeys(t *testing.T) {
+	f := func(s, keys string, order Order, expected string) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if err := v.SortArrayByKeys(keys, order); err != nil {
+			t.Fatalf("cannot sort %q by %q: %s", s, keys, err)
+		}
+		if v.String() != expected {
+			t.Fatalf("unexpected sorted array for %q by %q; got %s; want %s", s, keys, v, expected)
+		}
+	}
+	items := `[{"id":1,"price":5},{"id":2},{"id":3,"price":2},{"id":4,"price":5}]`
+	f(items, "price", Asc, `[{"id":3,"price":2},{"id":1,"price":5},{"id":4,"price":5},{"id":2}]`)
+	f(items, "price", Desc, `[{"id":1,"price":5},{"id":4,"price":5},{"id":3,"price":2},{"id":2}]`)
+	f(`[{"a":{"b":"y"}},{"a":{"b":"x"}}]`, "a.b", Asc, `[{"a":{"b":"x"}},{"a":{"b":"y"}}]`)
+	f(`["b",2,"a",1]`, "", Asc, `[1,2,"a","b"]`)
+
+	var p Parser
+	v, err := p.Parse(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	var wrongType *ErrWrongType
+	if err := v.SortArrayByKeys("a", Asc); !errors.As(err, &wrongType) {
+		t.Fatalf("expecting ErrWrongType when sorting an object; got %v", err)
+	}
+	v, err = p.Parse(`[1]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.SortArrayByKeys("a..b", Asc); err == nil {
+		t.Fatalf("expecting error for an invalid keys path")
+	}
+}
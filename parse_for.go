@@ -0,0 +1,222 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFor parses data keeping only the parts needed by q: the values of
+// the keys neither selected nor filtered by q are skipped, without
+// allocating a Value for them. Projecting a few fields out of wide objects
+// gets much faster.
+//
+// The returned Value gives the same result as the whole document to Keep,
+// Retrieve and Check with q, but not necessarily with other queries. It is
+// valid until the next call to Parse*.
+//
+// Levels using directives, such as lookups, computed fields or
+// aggregations, are parsed whole, and so is the document when q joins
+// arrays.
+func (p *Parser) ParseFor(q *Query, data []byte) (*Value, error) {
+	n := q.needs()
+	if n.all || q.hasJoins() {
+		return p.ParseBytes(data)
+	}
+	s := skipWS(b2s(data))
+	p.b = append(p.b[:0], s...)
+	p.c.reset()
+
+	v, tail, err := parseValueFor(b2s(p.b), &p.c, n)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse JSON: %w; unparsed tail: %q", err, tail)
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedTail, tail)
+	}
+	return v, nil
+}
+
+// need tells the parts of a value needed by a query: the whole value, or
+// the values of some keys of its objects, including those of the objects
+// of its arrays.
+type need struct {
+	all  bool
+	keys map[string]*need
+}
+
+var needAll = &need{all: true}
+
+// needs returns what q needs of the values of its level.
+func (q *Query) needs() *need {
+	if len(q.joins)+len(q.lookups)+len(q.zips)+len(q.computed)+len(q.counts)+len(q.aggregates)+len(q.running) > 0 ||
+		q.top != nil || q.sorting != nil || q.pivot != nil || q.grouping != nil || q.all || q.descent != nil {
+		return needAll
+	}
+	n := &need{keys: map[string]*need{}}
+	for _, filter := range q.filters {
+		n.keys[filter.key] = needAll
+		if filter.calc != nil {
+			for _, field := range filter.calc.fields() {
+				n.keys[field[0]] = needAll
+			}
+		}
+	}
+	for _, retrieve := range q.retrieve {
+		n.keys[retrieve] = needAll
+	}
+	for name, next := range q.next {
+		if next == nil {
+			n.keys[name] = needAll
+			continue
+		}
+		if _, ok := n.keys[name]; !ok {
+			n.keys[name] = next.needs()
+		}
+	}
+	return n
+}
+
+// hasJoins reports whether q or one of its levels joins arrays, which may be
+// anywhere in the document.
+func (q *Query) hasJoins() bool {
+	if len(q.joins) > 0 {
+		return true
+	}
+	for _, next := range q.next {
+		if next != nil && next.hasJoins() {
+			return true
+		}
+	}
+	return false
+}
+
+func parseValueFor(s string, c *cache, n *need) (*Value, string, error) {
+	if n.all || len(s) == 0 {
+		return parseValue(s, c)
+	}
+	switch s[0] {
+	case '{':
+		v, tail, err := parseObjectFor(s[1:], c, n)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse object: %w", err)
+		}
+		return v, tail, nil
+	case '[':
+		v, tail, err := parseArrayFor(s[1:], c, n)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse array: %w", err)
+		}
+		return v, tail, nil
+	default:
+		return parseValue(s, c)
+	}
+}
+
+func parseArrayFor(s string, c *cache, n *need) (*Value, string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return nil, s, fmt.Errorf("missing ']'")
+	}
+
+	if s[0] == ']' {
+		// Empty arrays are not shared, so they can be modified.
+		a := c.getValue()
+		a.t = TypeArray
+		return a, s[1:], nil
+	}
+
+	a := c.getValue()
+	a.t = TypeArray
+	for {
+		var v *Value
+		var err error
+
+		s = skipWS(s)
+		v, s, err = parseValueFor(s, c, n)
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse array value: %w", err)
+		}
+		a.a = append(a.a, v)
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, fmt.Errorf("%w of array", ErrUnexpectedEnd)
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			s = s[1:]
+			return a, s, nil
+		}
+		return nil, s, fmt.Errorf("%w after array value", ErrMissingComma)
+	}
+}
+
+func parseObjectFor(s string, c *cache, n *need) (*Value, string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return nil, s, fmt.Errorf("missing '}'")
+	}
+
+	if s[0] == '}' {
+		// Empty objects are not shared, so they can be modified.
+		o := c.getValue()
+		o.t = TypeObject
+		return o, s[1:], nil
+	}
+
+	o := c.getValue()
+	o.t = TypeObject
+	for {
+		var err error
+		var k string
+
+		// Parse key.
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != '"' {
+			return nil, s, fmt.Errorf(`cannot find opening '"" for object key`)
+		}
+		k, s, err = parseRawKey(s[1:])
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return nil, s, ErrMissingColon
+		}
+		s = s[1:]
+
+		// Parse the value when needed. Escaped keys are compared once
+		// unescaped, so their values are always kept.
+		s = skipWS(s)
+		sub, ok := n.keys[k]
+		if strings.IndexByte(k, '\\') >= 0 {
+			sub, ok = needAll, true
+		}
+		if ok {
+			kv := o.o.getKV()
+			kv.k = k
+			kv.v, s, err = parseValueFor(s, c, sub)
+		} else {
+			s, err = skipValue(s)
+		}
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse object value: %w", err)
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, fmt.Errorf("%w of object", ErrUnexpectedEnd)
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			return o, s[1:], nil
+		}
+		return nil, s, fmt.Errorf("%w after object value", ErrMissingComma)
+	}
+}
@@ -0,0 +1,62 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParserParseFor(t *testing.T) {
+	const doc = `{
+		"meta": {"count": 3, "big": [1, 2, {"x": "}"}]},
+		"users": [
+			{"id": 1, "name": "Al", "age": 30, "bio": "long \"text\"", "address": {"city": "Paris", "zip": "75000"}, "tags": ["a"]},
+			{"id": 2, "name": "Bo", "age": 15, "address": {"city": "Lyon"}, "tags": []},
+			{"id": 3, "name": "Cy", "age": 40, "escapedA": true}
+		]
+	}`
+	tests := []struct {
+		query   string
+		skipped []string
+	}{
+		{"{users(age > 18){name, address{city}}}", []string{"meta", "users.0.bio", "users.0.address.zip", "users.0.tags"}},
+		{"{users{tags}, meta{count}}", []string{"users.0.name", "meta.big"}},
+		{"{users{lookup(t, id), name}}", []string{"meta"}},
+		{"{users{name, n: format(\"{bio}\")}}", []string{"meta"}},
+		{"{meta}", []string{"users"}},
+	}
+	var whole, p Parser
+	all, err := whole.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		q.SetOptions(Options{Deterministic: true})
+		v, err := p.ParseFor(q, []byte(doc))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		want, _ := all.Keep(*q)
+		if got, err := v.Keep(*q); err != nil || got != want {
+			t.Errorf("%s: Keep() = %s, %v, want %s", tt.query, got, err, want)
+		}
+		for _, path := range tt.skipped {
+			if v.Get(strings.Split(path, ".")...) != nil {
+				t.Errorf("%s: %s was not skipped", tt.query, path)
+			}
+		}
+	}
+
+	q := MustParseQuery("{users{join(users.id = id) as same{name}, id}}")
+	v, err := p.ParseFor(q, []byte(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.Get("meta", "count") == nil {
+		t.Errorf("a joined document must be parsed whole")
+	}
+
+	if _, err := p.ParseFor(MustParseQuery("{users{id}}"), []byte(`{"users": [{"id": 1, "x": [}]}`)); err == nil {
+		t.Errorf("expecting non-nil error for invalid skipped values")
+	}
+}
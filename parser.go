@@ -33,15 +33,33 @@ func (p *Parser) Parse(s string) (*Value, error) {
 
 	v, tail, err := parseValue(b2s(p.b), &p.c)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse JSON: %s; unparsed tail: %q", err, tail)
+		return nil, fmt.Errorf("cannot parse JSON: %w; unparsed tail: %q", err, tail)
 	}
 	tail = skipWS(tail)
 	if len(tail) > 0 {
-		return nil, fmt.Errorf("unexpected tail: %q", tail)
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedTail, tail)
 	}
 	return v, nil
 }
 
+// ParseFirst parses the first JSON value of s and returns it along with
+// the rest of s, leading whitespace skipped, so concatenated values such
+// as `{"a":1}{"a":2}` are parsed one call at a time.
+//
+// The returned value is valid until the next call to Parse*. The tail is
+// a substring of s.
+func (p *Parser) ParseFirst(s string) (*Value, string, error) {
+	s = skipWS(s)
+	p.b = append(p.b[:0], s...)
+	p.c.reset()
+
+	v, tail, err := parseValue(b2s(p.b), &p.c)
+	if err != nil {
+		return nil, s, fmt.Errorf("cannot parse JSON: %w; unparsed tail: %q", err, tail)
+	}
+	return v, skipWS(s[len(s)-len(tail):]), nil
+}
+
 // ParseBytes parses b containing JSON.
 //
 // The returned Value is valid until the next call to Parse*.
@@ -101,21 +119,21 @@ func parseValue(s string, c *cache) (*Value, string, error) {
 	if s[0] == '{' {
 		v, tail, err := parseObject(s[1:], c)
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse object: %s", err)
+			return nil, tail, fmt.Errorf("cannot parse object: %w", err)
 		}
 		return v, tail, nil
 	}
 	if s[0] == '[' {
 		v, tail, err := parseArray(s[1:], c)
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse array: %s", err)
+			return nil, tail, fmt.Errorf("cannot parse array: %w", err)
 		}
 		return v, tail, nil
 	}
 	if s[0] == '"' {
 		ss, tail, err := parseRawString(s[1:])
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse string: %s", err)
+			return nil, tail, fmt.Errorf("cannot parse string: %w", err)
 		}
 		v := c.getValue()
 		v.t = typeRawString
@@ -125,26 +143,26 @@ func parseValue(s string, c *cache) (*Value, string, error) {
 	}
 	if s[0] == 't' {
 		if len(s) < len("true") || s[:len("true")] != "true" {
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("%w: %q", ErrUnexpectedValue, s)
 		}
 		return valueTrue, s[len("true"):], nil
 	}
 	if s[0] == 'f' {
 		if len(s) < len("false") || s[:len("false")] != "false" {
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("%w: %q", ErrUnexpectedValue, s)
 		}
 		return valueFalse, s[len("false"):], nil
 	}
 	if s[0] == 'n' {
 		if len(s) < len("null") || s[:len("null")] != "null" {
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("%w: %q", ErrUnexpectedValue, s)
 		}
 		return valueNull, s[len("null"):], nil
 	}
 
 	ns, tail, err := parseRawNumber(s)
 	if err != nil {
-		return nil, tail, fmt.Errorf("cannot parse number: %s", err)
+		return nil, tail, fmt.Errorf("cannot parse number: %w", err)
 	}
 	v := c.getValue()
 	v.t = typeRawNumber
@@ -160,7 +178,10 @@ func parseArray(s string, c *cache) (*Value, string, error) {
 	}
 
 	if s[0] == ']' {
-		return emptyArray, s[1:], nil
+		// Empty arrays are not shared, so they can be modified.
+		a := c.getValue()
+		a.t = TypeArray
+		return a, s[1:], nil
 	}
 
 	a := c.getValue()
@@ -172,13 +193,13 @@ func parseArray(s string, c *cache) (*Value, string, error) {
 		s = skipWS(s)
 		v, s, err = parseValue(s, c)
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse array value: %s", err)
+			return nil, s, fmt.Errorf("cannot parse array value: %w", err)
 		}
 		a.a = append(a.a, v)
 
 		s = skipWS(s)
 		if len(s) == 0 {
-			return nil, s, fmt.Errorf("unexpected end of array")
+			return nil, s, fmt.Errorf("%w of array", ErrUnexpectedEnd)
 		}
 		if s[0] == ',' {
 			s = s[1:]
@@ -188,7 +209,7 @@ func parseArray(s string, c *cache) (*Value, string, error) {
 			s = s[1:]
 			return a, s, nil
 		}
-		return nil, s, fmt.Errorf("missing ',' after array value")
+		return nil, s, fmt.Errorf("%w after array value", ErrMissingComma)
 	}
 }
 
@@ -199,7 +220,10 @@ func parseObject(s string, c *cache) (*Value, string, error) {
 	}
 
 	if s[0] == '}' {
-		return emptyObject, s[1:], nil
+		// Empty objects are not shared, so they can be modified.
+		o := c.getValue()
+		o.t = TypeObject
+		return o, s[1:], nil
 	}
 
 	o := c.getValue()
@@ -215,11 +239,11 @@ func parseObject(s string, c *cache) (*Value, string, error) {
 		}
 		kv.k, s, err = parseRawKey(s[1:])
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse object key: %s", err)
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
 		}
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
-			return nil, s, fmt.Errorf("missing ':' after object key")
+			return nil, s, ErrMissingColon
 		}
 		s = s[1:]
 
@@ -227,11 +251,11 @@ func parseObject(s string, c *cache) (*Value, string, error) {
 		s = skipWS(s)
 		kv.v, s, err = parseValue(s, c)
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse object value: %s", err)
+			return nil, s, fmt.Errorf("cannot parse object value: %w", err)
 		}
 		s = skipWS(s)
 		if len(s) == 0 {
-			return nil, s, fmt.Errorf("unexpected end of object")
+			return nil, s, fmt.Errorf("%w of object", ErrUnexpectedEnd)
 		}
 		if s[0] == ',' {
 			s = s[1:]
@@ -240,7 +264,7 @@ func parseObject(s string, c *cache) (*Value, string, error) {
 		if s[0] == '}' {
 			return o, s[1:], nil
 		}
-		return nil, s, fmt.Errorf("missing ',' after object value")
+		return nil, s, fmt.Errorf("%w after object value", ErrMissingComma)
 	}
 }
 
@@ -457,6 +481,13 @@ func (o *Object) Get(key string) *Value {
 	return nil
 }
 
+// Has reports whether o contains the key.
+//
+// Unlike Get followed by a type check, it never decodes the value.
+func (o *Object) Has(key string) bool {
+	return o.Get(key) != nil
+}
+
 // Visit calls f for each item in the o in the original order
 // of the parsed JSON.
 //
@@ -523,10 +554,12 @@ func (v *Value) String() string {
 	case TypeString:
 		return fmt.Sprintf("%q", v.s)
 	case TypeNumber:
-		if float64(int(v.n)) == v.n {
-			return fmt.Sprintf("%d", int(v.n))
+		if len(v.s) > 0 {
+			// The number is unmodified since it was parsed,
+			// so emit its original token verbatim.
+			return v.s
 		}
-		return fmt.Sprintf("%f", v.n)
+		return strconv.FormatFloat(v.n, 'g', -1, 64)
 	case TypeTrue:
 		return "true"
 	case TypeFalse:
@@ -604,6 +637,48 @@ func (v *Value) Type() Type {
 	return v.t
 }
 
+// IsObject reports whether v is an object.
+func (v *Value) IsObject() bool {
+	return v.t == TypeObject
+}
+
+// IsArray reports whether v is an array.
+func (v *Value) IsArray() bool {
+	return v.t == TypeArray
+}
+
+// IsString reports whether v is a string.
+func (v *Value) IsString() bool {
+	return v.t == TypeString || v.t == typeRawString
+}
+
+// IsNumber reports whether v is a number.
+func (v *Value) IsNumber() bool {
+	return v.t == TypeNumber || v.t == typeRawNumber
+}
+
+// IsBool reports whether v is true or false.
+func (v *Value) IsBool() bool {
+	return v.t == TypeTrue || v.t == TypeFalse
+}
+
+// IsNull reports whether v is null.
+func (v *Value) IsNull() bool {
+	return v.t == TypeNull
+}
+
+// TypeOf returns the type of the value at the given keys path. ok is false
+// for non-existing keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (v *Value) TypeOf(keys ...string) (t Type, ok bool) {
+	v = v.Get(keys...)
+	if v == nil {
+		return TypeNull, false
+	}
+	return v.Type(), true
+}
+
 // Exists returns true if the field exists for the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -612,6 +687,16 @@ func (v *Value) Exists(keys ...string) bool {
 	return v != nil
 }
 
+// HasKey reports whether a value exists at the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// The strings and numbers on the path are not decoded, so it is a cheap
+// presence check for hot paths.
+func (v *Value) HasKey(keys ...string) bool {
+	return v.Get(keys...) != nil
+}
+
 // Get returns value by the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -624,24 +709,64 @@ func (v *Value) Get(keys ...string) *Value {
 		return nil
 	}
 	for _, key := range keys {
-		if v.t == TypeObject {
-			v = v.o.Get(key)
-			if v == nil {
-				return nil
-			}
-		} else if v.t == TypeArray {
-			n, err := strconv.Atoi(key)
-			if err != nil || n < 0 || n >= len(v.a) {
-				return nil
-			}
-			v = v.a[n]
-		} else {
+		if v = v.step(key); v == nil {
 			return nil
 		}
 	}
 	return v
 }
 
+// step returns the value under key in the object or array v.
+func (v *Value) step(key string) *Value {
+	switch v.t {
+	case TypeObject:
+		return v.o.Get(key)
+	case TypeArray:
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 0 || n >= len(v.a) {
+			return nil
+		}
+		return v.a[n]
+	}
+	return nil
+}
+
+// GetMany returns the values by the given keys paths, in the order of
+// paths, with nil for non-existing keys paths.
+//
+// The prefix a path shares with the previous one is resolved only once,
+// so listing paths with common prefixes next to each other is cheaper
+// than calling Get for every path.
+//
+// The returned values are valid until Parse is called on the Parser returned v.
+func (v *Value) GetMany(paths ...Path) []*Value {
+	values := make([]*Value, len(paths))
+	if v == nil {
+		return values
+	}
+	// walked[i] is the value at the first i keys of the previous path.
+	var buf [8]*Value
+	walked := append(buf[:0], v)
+	var prev Path
+	for i, p := range paths {
+		n := 0
+		for n < len(p) && n < len(prev) && n+1 < len(walked) && p[n] == prev[n] {
+			n++
+		}
+		walked = walked[:n+1]
+		cur := walked[n]
+		for _, key := range p[n:] {
+			if cur = cur.step(key); cur == nil {
+				break
+			}
+			walked = append(walked, cur)
+		}
+		values[i] = cur
+		prev = p
+	}
+	return values
+}
+
 // GetObject returns object value by the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -690,12 +815,17 @@ func (v *Value) GetFloat64(keys ...string) float64 {
 // Array indexes may be represented as decimal numbers in keys.
 //
 // 0 is returned for non-existing keys path or for invalid value type.
+//
+// The fractional part of the number is dropped and numbers out of the
+// int range saturate to the closest bound. Use Int or IntStrict to detect
+// these cases.
 func (v *Value) GetInt(keys ...string) int {
 	v = v.Get(keys...)
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
-	return int(v.n)
+	n, _ := v.toInt()
+	return n
 }
 
 // GetStringBytes returns string value by the given keys path.
@@ -726,6 +856,66 @@ func (v *Value) GetBool(keys ...string) bool {
 	return false
 }
 
+// GetStringOr returns the string value by the given keys path, or def for
+// non-existing keys path or for invalid value type.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (v *Value) GetStringOr(def string, keys ...string) string {
+	v = v.Get(keys...)
+	if v == nil || v.Type() != TypeString {
+		return def
+	}
+	return v.s
+}
+
+// GetIntOr returns the int value by the given keys path, or def for
+// non-existing keys path, for invalid value type and for numbers that
+// aren't integers or overflow int.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (v *Value) GetIntOr(def int, keys ...string) int {
+	v = v.Get(keys...)
+	if v == nil {
+		return def
+	}
+	n, err := v.IntStrict()
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// GetFloat64Or returns the float64 value by the given keys path, or def
+// for non-existing keys path or for invalid value type.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (v *Value) GetFloat64Or(def float64, keys ...string) float64 {
+	v = v.Get(keys...)
+	if v == nil || v.Type() != TypeNumber {
+		return def
+	}
+	return v.n
+}
+
+// GetBoolOr returns the bool value by the given keys path, or def for
+// non-existing keys path or for invalid value type.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (v *Value) GetBoolOr(def bool, keys ...string) bool {
+	v = v.Get(keys...)
+	if v == nil {
+		return def
+	}
+	switch v.t {
+	case TypeTrue:
+		return true
+	case TypeFalse:
+		return false
+	default:
+		return def
+	}
+}
+
 // Object returns the underlying JSON object for the v.
 //
 // The returned object is valid until Parse is called on the Parser returned v.
@@ -733,7 +923,7 @@ func (v *Value) GetBool(keys ...string) bool {
 // Use GetObject if you don't need error handling.
 func (v *Value) Object() (*Object, error) {
 	if v.t != TypeObject {
-		return nil, fmt.Errorf("value doesn't contain object; it contains %s", v.Type())
+		return nil, &ErrWrongType{Want: TypeObject, Got: v.Type()}
 	}
 	return &v.o, nil
 }
@@ -745,7 +935,7 @@ func (v *Value) Object() (*Object, error) {
 // Use GetArray if you don't need error handling.
 func (v *Value) Array() ([]*Value, error) {
 	if v.t != TypeArray {
-		return nil, fmt.Errorf("value doesn't contain array; it contains %s", v.Type())
+		return nil, &ErrWrongType{Want: TypeArray, Got: v.Type()}
 	}
 	return v.a, nil
 }
@@ -757,7 +947,7 @@ func (v *Value) Array() ([]*Value, error) {
 // Use GetStringBytes if you don't need error handling.
 func (v *Value) StringBytes() ([]byte, error) {
 	if v.Type() != TypeString {
-		return nil, fmt.Errorf("value doesn't contain string; it contains %s", v.Type())
+		return nil, &ErrWrongType{Want: TypeString, Got: v.Type()}
 	}
 	return s2b(v.s), nil
 }
@@ -767,17 +957,73 @@ func (v *Value) StringBytes() ([]byte, error) {
 // Use GetFloat64 if you don't need error handling.
 func (v *Value) Float64() (float64, error) {
 	if v.Type() != TypeNumber {
-		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+		return 0, &ErrWrongType{Want: TypeNumber, Got: v.Type()}
 	}
 	return v.n, nil
 }
 
 // Int returns the underlying JSON int for the v.
 //
+// The fractional part of the number is dropped. An error is returned
+// if the number is out of the int range.
+//
 // Use GetInt if you don't need error handling.
 func (v *Value) Int() (int, error) {
-	f, err := v.Float64()
-	return int(f), err
+	if v.Type() != TypeNumber {
+		return 0, &ErrWrongType{Want: TypeNumber, Got: v.Type()}
+	}
+	n, ok := v.toInt()
+	if !ok {
+		return n, fmt.Errorf("number %s %w", v.s, ErrOverflow)
+	}
+	return n, nil
+}
+
+// IntStrict returns the underlying JSON int for the v.
+//
+// Unlike Int, an error is also returned if the number has a fractional part.
+func (v *Value) IntStrict() (int, error) {
+	n, err := v.Int()
+	if err != nil {
+		return n, err
+	}
+	if float64(n) != v.n {
+		if _, err := strconv.ParseInt(v.s, 10, 0); err != nil {
+			return n, fmt.Errorf("number %s %w", v.s, ErrNotInteger)
+		}
+	}
+	return n, nil
+}
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// toInt converts the number of v to an int, truncating its fractional part.
+// ok is false if the number overflows, in which case n saturates to the
+// closest bound.
+//
+// The caller must ensure v is a number.
+func (v *Value) toInt() (n int, ok bool) {
+	// Integers are parsed from their token, so they don't suffer from
+	// the float64 precision loss above 2^53.
+	i, err := strconv.ParseInt(v.s, 10, 0)
+	if err == nil {
+		return int(i), true
+	}
+	if ne, isNumErr := err.(*strconv.NumError); isNumErr && ne.Err == strconv.ErrRange {
+		return int(i), false
+	}
+	switch {
+	case v.n != v.n:
+		return 0, false
+	case v.n >= float64(maxInt):
+		return maxInt, false
+	case v.n < float64(minInt):
+		return minInt, false
+	}
+	return int(v.n), true
 }
 
 // Bool returns the underlying JSON bool for the v.
@@ -790,13 +1036,11 @@ func (v *Value) Bool() (bool, error) {
 	if v.t == TypeFalse {
 		return false, nil
 	}
-	return false, fmt.Errorf("value doesn't contain bool; it contains %s", v.Type())
+	return false, &ErrWrongType{Want: TypeTrue, Got: v.Type()}
 }
 
 var (
-	valueTrue   = &Value{t: TypeTrue, Description: "true"}
-	valueFalse  = &Value{t: TypeFalse, Description: "false"}
-	valueNull   = &Value{t: TypeNull, Description: "null"}
-	emptyObject = &Value{t: TypeObject}
-	emptyArray  = &Value{t: TypeArray}
+	valueTrue  = &Value{t: TypeTrue, Description: "true"}
+	valueFalse = &Value{t: TypeFalse, Description: "false"}
+	valueNull  = &Value{t: TypeNull, Description: "null"}
 )
@@ -2,7 +2,6 @@ package jsonq
 
 import (
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 )
@@ -18,12 +17,16 @@ const (
 	notContain Operation = "!:"
 	like       Operation = "::"
 	notLike    Operation = "!::"
+	exists     Operation = "?"
+	notExists  Operation = "!?"
+	same       Operation = "==="
+	notSame    Operation = "!=="
 )
 
-var cmdRegex = regexp.MustCompile(`^([a-z_]+)?(?:\(([^{\}\)\(]*)\))?(?:{(.*)})?$`)
-var filterRegex = regexp.MustCompile(`(?:([a-zA-Z_-]+)\s*([><!:=]+)\s*((?:[^&\(\)\{}\s\")]+|(?:\"[^&\(\)\{}]*\")))\s*)+`)
-
 // Operation is common possible operations in filters (=, !=, >, <, >=, <=, :).
+//
+// === and !== compare strictly: (a === null) matches the null values of a
+// and (a !== null) all the others.
 type Operation string
 
 func (o Operation) check(base, compared interface{}) bool {
@@ -48,6 +51,10 @@ func (o Operation) check(base, compared interface{}) bool {
 		return checkLike(base, compared)
 	case notLike:
 		return checkNotLike(base, compared)
+	case same:
+		return checkSame(base, compared)
+	case notSame:
+		return !checkSame(base, compared)
 	default:
 		return false
 	}
@@ -75,6 +82,10 @@ func findOperation(line string) (Operation, error) {
 		return like, nil
 	case "!::":
 		return notLike, nil
+	case "===":
+		return same, nil
+	case "!==":
+		return notSame, nil
 	default:
 		return "error", fmt.Errorf("operation %s does not exist", line)
 	}
@@ -106,13 +117,6 @@ func checkEq(base, compared interface{}) bool {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkEq(key, compared) == false {
-				return false
-			}
-		}
-		return true
 	}
 	return false
 }
@@ -143,21 +147,12 @@ func checkDiff(base, compared interface{}) bool {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkDiff(key, compared) != false {
-				return false
-			}
-		}
-		return true
 	}
 	return false
 }
 
 func checkSup(base, compared interface{}) bool {
 	switch v := base.(type) {
-	case bool, nil:
-		return false
 	case int64:
 		if comp, ok := compared.(int64); ok == true && comp > v {
 			return true
@@ -177,22 +172,12 @@ func checkSup(base, compared interface{}) bool {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkSup(key, compared) == false {
-				return false
-			}
-		}
-		return true
-	default:
-		return false
 	}
+	return false
 }
 
 func checkSupEq(base, compared interface{}) bool {
 	switch v := base.(type) {
-	case bool, nil:
-		return false
 	case int64:
 		if comp, ok := compared.(int64); ok == true && comp >= v {
 			return true
@@ -208,25 +193,16 @@ func checkSupEq(base, compared interface{}) bool {
 		}
 		return false
 	case string:
-		if comp, ok := compared.(string); ok == true && comp > v {
+		if comp, ok := compared.(string); ok == true && comp >= v {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkSupEq(key, compared) == true {
-				return true
-			}
-		}
-		return false
 	}
 	return false
 }
 
 func checkInf(base, compared interface{}) bool {
 	switch v := base.(type) {
-	case bool, nil:
-		return false
 	case int64:
 		if comp, ok := compared.(int64); ok == true && comp < v {
 			return true
@@ -246,22 +222,12 @@ func checkInf(base, compared interface{}) bool {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkSup(key, compared) == false {
-				return false
-			}
-		}
-		return true
-	default:
-		return false
 	}
+	return false
 }
 
 func checkInfEq(base, compared interface{}) bool {
 	switch v := base.(type) {
-	case bool, nil:
-		return false
 	case int64:
 		if comp, ok := compared.(int64); ok == true && comp <= v {
 			return true
@@ -277,98 +243,179 @@ func checkInfEq(base, compared interface{}) bool {
 		}
 		return false
 	case string:
-		if comp, ok := compared.(string); ok == true && v < comp {
+		if comp, ok := compared.(string); ok == true && comp <= v {
 			return true
 		}
 		return false
-	case []interface{}:
-		for _, key := range v {
-			if checkInfEq(key, compared) == true {
-				return true
-			}
-		}
-		return false
 	}
 	return false
 }
 
 // In this case we check if the compare string is contained int the base string
 func checkContain(base, compared interface{}) bool {
-	if b, ok := base.(string); ok == true {
-		if c, ok := compared.(string); ok == true {
-			b = strings.ToLower(b)
-			b = strings.TrimLeft(b, `"`)
-			b = strings.TrimRight(b, `"`)
-			c = strings.ToLower(c)
-			return strings.Contains(c, b)
-		}
-	}
-	return false
+	ok, _ := contain.compareStrings(defaultStrings, base, compared)
+	return ok
 }
 
 func checkNotContain(base, compared interface{}) bool {
-	if b, ok := base.(string); ok == true {
-		if c, ok := compared.(string); ok == true {
-			b = strings.ToLower(b)
-			b = strings.TrimLeft(b, `"`)
-			b = strings.TrimRight(b, `"`)
-			c = strings.ToLower(c)
-			return !strings.Contains(c, b)
-		}
-	}
-	return false
+	ok, _ := notContain.compareStrings(defaultStrings, base, compared)
+	return ok
 }
 
 // In this function base was the json value, compared the string used for the regex. Both should be strings
 func checkLike(base, compared interface{}) bool {
-	if b, ok := base.(string); ok == true {
-		if c, ok := compared.(string); ok == true {
-			b = strings.ToLower(b)
-			b = strings.TrimLeft(b, `"`)
-			b = strings.TrimRight(b, `"`)
-			c = strings.ToLower(c)
-			if ok, err := regexp.MatchString(b, c); err == nil {
-				return ok
-			}
-		}
-	}
-	return false
+	ok, _ := like.compareStrings(defaultStrings, base, compared)
+	return ok
 }
 
 func checkNotLike(base, compared interface{}) bool {
-	if b, ok := base.(string); ok == true {
-		if c, ok := compared.(string); ok == true {
-			b = strings.ToLower(b)
-			b = strings.TrimLeft(b, `"`)
-			b = strings.TrimRight(b, `"`)
-			c = strings.ToLower(c)
-			if ok, err := regexp.MatchString(b, c); err == nil {
-				return !ok
-			}
-		}
+	ok, _ := notLike.compareStrings(defaultStrings, base, compared)
+	return ok
+}
+
+// checkSame is the strict equality of === and !== : null is only the same
+// as null, and values of different types are never the same, so unlike !=,
+// (a !== null) holds for every value but null.
+func checkSame(base, compared interface{}) bool {
+	if base == nil || compared == nil {
+		return base == compared
 	}
-	return false
+	return checkEq(base, compared)
 }
 
+// Quantifier tells how a filter applies when the filtered key holds an array.
+//
+// With any, the filter holds if at least one element satisfies the operation.
+// With all, it holds if every element does, so an empty array always matches.
+// Nested arrays are checked element by element with the same quantifier.
+//
+// A filter can choose its quantifier with a prefix : (any tags = a) or
+// (all scores > 10). Without prefix, the positive operations (=, >, >=, <,
+// <=, :, ::, ===) use any and the negated ones (!=, !:, !::, !==) use all, so
+// (tags != a) keeps the arrays in which no element equals a.
+//
+// The function forms any(items.price > 100) and all(items.price > 100)
+// compare a path of the objects of an array, see parseQuantified.
+type Quantifier string
+
+const (
+	quantAny Quantifier = "any"
+	quantAll Quantifier = "all"
+)
+
 //Filter is the type used for describe a operation of filtering
 type Filter struct {
-	key string
-	op  Operation
-	val interface{}
+	key   string
+	op    Operation
+	val   interface{}
+	quant Quantifier
+	// sub is the path of the compared values in the objects of the key,
+	// for the quantified filters such as any(items.price > 100).
+	sub Path
+	// calc computes the value of the filter from the fields of each
+	// object, in place of val.
+	calc *arithmetic
+	// strict turns off the conversions of the compared values, for the
+	// queries parsed by ParseQueryStrict.
+	strict bool
 }
 
 func (f Filter) eq(other Filter) bool {
-	bkey := f.key == other.key
+	bkey := f.key == other.key && f.sub.String() == other.sub.String()
 	bop := f.op == other.op
-	bval := fmt.Sprintln(f.val) == fmt.Sprintln(other.val)
-	return bkey && bop && bval
+	bval := fmt.Sprintln(f.val) == fmt.Sprintln(other.val) && f.calc.String() == other.calc.String()
+	bquant := f.quantifier() == other.quantifier()
+	return bkey && bop && bval && bquant
 }
 
-func (f Filter) check(compareTo interface{}) bool {
-	return f.op.check(f.val, compareTo)
+func (f Filter) check(compareTo interface{}, opts *Options) bool {
+	if f.op == same || f.op == notSame {
+		// The strict operations ignore the coercions, the normalizer and
+		// the collator, but not the distinction between integers and
+		// floats.
+		_, _, ok := CoercionPolicy{StrictNumbers: f.coercion(opts).StrictNumbers}.apply(f.val, compareTo)
+		return (ok && checkSame(f.val, compareTo)) == (f.op == same)
+	}
+	base, compareTo, ok := f.coercion(opts).apply(f.val, compareTo)
+	if !ok {
+		return false
+	}
+	if opts.Normalizer != nil {
+		base, compareTo = normalize(opts.Normalizer, base), normalize(opts.Normalizer, compareTo)
+	}
+	if opts.Collator != nil {
+		if ok, handled := f.op.collate(opts.Collator, base, compareTo); handled {
+			return ok
+		}
+	}
+	if opts.Strings != nil {
+		if ok, handled := f.op.compareStrings(opts.Strings, base, compareTo); handled {
+			return ok
+		}
+	}
+	return f.op.check(base, compareTo)
+}
+
+// coercion returns the conversions applied by f with opts.
+func (f Filter) coercion(opts *Options) CoercionPolicy {
+	if f.strict {
+		return CoercionPolicy{StrictNumbers: true}
+	}
+	return opts.Coercion
+}
+
+// presence returns the result of the existence filter f, such as phone?
+// or !phone?, given whether its key is present. ok is false for the other
+// filters.
+func (f Filter) presence(present bool) (result, ok bool) {
+	switch f.op {
+	case exists:
+		return present, true
+	case notExists:
+		return !present, true
+	}
+	return false, false
+}
+
+// quantifier returns the quantifier of f, resolving the default one from the operation.
+func (f Filter) quantifier() Quantifier {
+	if f.quant != "" {
+		return f.quant
+	}
+	switch f.op {
+	case diff, notContain, notLike, notSame:
+		return quantAll
+	default:
+		return quantAny
+	}
 }
 
+// typed converts a filter literal into the value used for comparisons.
+//
+// A double quoted literal is always a string, once its escape sequences
+// are interpreted, so "0123" never becomes the number 123.
+//
+// The suffixes i and f force a number type: 3i is an int64 and 3f a float64.
+// They only apply to plain decimals, so 0x1f and 1e3f stay strings.
 func typed(v string) interface{} {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		if u, err := strconv.Unquote(v); err == nil {
+			return u
+		}
+		return v[1 : len(v)-1]
+	}
+	if n := len(v) - 1; n > 0 && decimal(v[:n], v[n] == 'f') {
+		switch v[n] {
+		case 'i':
+			if i, err := strconv.ParseInt(v[:n], 10, 64); err == nil {
+				return i
+			}
+		case 'f':
+			if f, err := strconv.ParseFloat(v[:n], 64); err == nil {
+				return f
+			}
+		}
+	}
 	switch v {
 	case "true":
 		return true
@@ -388,47 +435,66 @@ func typed(v string) interface{} {
 	return v
 }
 
-func newFilter(cmd string) ([]*Filter, error) {
-	filters := make([]*Filter, 0, len(strings.Split(cmd, "&&")))
-	if strings.ContainsAny(cmd, "|") {
-		return nil, fmt.Errorf("Format error in filters : %q", cmd)
+// decimal reports whether v is a plain decimal number, such as -12, or
+// 1.5 when fraction is set: the only literals taking an i or f suffix.
+func decimal(v string, fraction bool) bool {
+	if v != "" && (v[0] == '-' || v[0] == '+') {
+		v = v[1:]
 	}
-	for _, match := range filterRegex.FindAllStringSubmatch(cmd, -1) {
-
-		if len(match[1]) > 0 && len(match[2]) > 0 && len(match[3]) > 0 {
-			op, err := findOperation(match[2])
-			if err != nil {
-				return nil, err
-			}
-			filters = append(filters, &Filter{
-				match[1],
-				op,
-				typed(match[3]),
-			})
-		} else {
-			return nil, fmt.Errorf("Format error in filters : %q", match[0])
+	digits, dot := false, false
+	for _, r := range v {
+		switch {
+		case '0' <= r && r <= '9':
+			digits = true
+		case r == '.' && fraction && !dot:
+			dot = true
+		default:
+			return false
 		}
 	}
-	if len(filters) == 0 {
-		return nil, fmt.Errorf("Format error in filters : %q", cmd)
-	}
-	return filters, nil
+	return digits
 }
 
 // Query is a description of a Query in a graphql like request
 type Query struct {
 	filters      []*Filter
+	expr         *filterExpr
 	next         map[string]*Query
 	retrieve     []string
+	all          bool
+	excluded     []string
+	descent      *Query
+	slice        *slice
 	stillFilters bool
+	opts         Options
+	path         Path
+	joins        []*join
+	lookups      []*lookup
+	sample       *sample
+	top          *top
+	sorting      *ordering
+	grouping     *group
+	running      []*running
+	pivot        *pivot
+	zips         []*zip
+	computed     []*computed
+	counts       []*count
+	aggregates   []*aggregate
+	source       string
 }
 
 func (q Query) eq(other Query) bool {
+	if len(q.filters) != len(other.filters) || len(q.next) != len(other.next) || len(q.retrieve) != len(other.retrieve) {
+		return false
+	}
 	for index, filter := range q.filters {
 		if other.filters[index] == nil || !filter.eq(*other.filters[index]) {
 			return false
 		}
 	}
+	if len(q.filters) > 0 && q.condition().String() != other.condition().String() {
+		return false
+	}
 	for key, query := range q.next {
 		if other.next[key] == nil || !query.eq(*other.next[key]) {
 			return false
@@ -439,15 +505,42 @@ func (q Query) eq(other Query) bool {
 			return false
 		}
 	}
+	if q.all != other.all || strings.Join(q.excluded, ",") != strings.Join(other.excluded, ",") {
+		return false
+	}
+	if q.slice.String() != other.slice.String() {
+		return false
+	}
+	if !q.sorting.eq(other.sorting) {
+		return false
+	}
+	if (q.descent == nil) != (other.descent == nil) || q.descent != nil && !q.descent.eq(*other.descent) {
+		return false
+	}
 	return true
 }
 
 func newQuery() Query {
 	return Query{
-		[]*Filter{},
-		map[string]*Query{},
-		[]string{},
-		false,
+		filters:  []*Filter{},
+		next:     map[string]*Query{},
+		retrieve: []string{},
+	}
+}
+
+// setPath records the position of every level of q from the root of the query.
+func (q *Query) setPath(path Path) {
+	q.path = path
+	for name, next := range q.next {
+		if next != nil {
+			next.setPath(path.child(name))
+		}
+	}
+	for _, j := range q.joins {
+		j.q.setPath(path.child(j.as))
+	}
+	if q.descent != nil {
+		q.descent.setPath(path.child("**"))
 	}
 }
 
@@ -457,15 +550,25 @@ func (l Query) print(Query int) {
 		fmt.Printf("%s - %s %s %q \n", strings.Repeat("\t", Query), (*filter).key, filter.op, filter.val)
 	}
 	fmt.Printf("%s Retrieve :\n", strings.Repeat("\t", Query))
+	if l.all {
+		fmt.Printf("%s - *\n", strings.Repeat("\t", Query))
+	}
 	for _, retrieve := range l.retrieve {
 		if len(retrieve) > 0 {
 			fmt.Printf("%s - %s\n", strings.Repeat("\t", Query), retrieve)
 		}
 	}
+	for _, excluded := range l.excluded {
+		fmt.Printf("%s - !%s\n", strings.Repeat("\t", Query), excluded)
+	}
 	fmt.Printf("%s Next :\n", strings.Repeat("\t", Query))
 	for _, next := range l.next {
 		next.print(Query + 1)
 	}
+	if l.descent != nil {
+		fmt.Printf("%s Descent :\n", strings.Repeat("\t", Query))
+		l.descent.print(Query + 1)
+	}
 }
 
 // Print will recursively show the content of Querys.
@@ -473,83 +576,323 @@ func (l Query) Print() {
 	l.print(0)
 }
 
-func parseQuery(cmd string) (Query *Query, QueryName string, err error) {
-	matches := cmdRegex.FindStringSubmatch(cmd)
+func parseQuery(cmd string, strict bool) (Query *Query, QueryName string, err error) {
+	name, index, filtersCmd, retrieveCmd, err := splitLevel(cmd)
+	if err != nil {
+		return nil, "", err
+	}
 	lvl := newQuery()
-	if len(matches) == 0 {
-		return nil, "", fmt.Errorf("mal formated")
+	if len(index) > 0 {
+		if lvl.slice, err = parseSlice(index); err != nil {
+			return nil, "", err
+		}
+		// items[0] keeps the whole elements, like items[0]{*}.
+		lvl.all = len(retrieveCmd) == 0
 	}
-	if len(matches) > 2 && len(matches[2]) > 0 {
-		filters, err := newFilter(matches[2])
+	if len(filtersCmd) > 0 {
+		filters, expr, err := newFilter(filtersCmd, strict)
 		if err != nil {
 			return nil, "", err
 		}
-		for _, filter := range filters {
-			if filter != nil {
-				lvl.filters = append(lvl.filters, filter)
-			}
-		}
+		lvl.filters, lvl.expr = filters, expr
 		if len(lvl.filters) > 0 {
 			lvl.stillFilters = true
 		}
 	}
-	if len(matches) > 3 && len(matches[3]) > 0 {
-		for _, attr := range splitComa(matches[3]) {
-			if strings.ContainsAny(attr, "(){}") {
-				newQuery, QueryName, _ := parseQuery(attr)
+	if len(retrieveCmd) > 0 {
+		for _, attr := range splitComa(retrieveCmd) {
+			if ok, err := lvl.parseWildcard(attr); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+			} else if ok, err := lvl.parseDescent(attr, strict); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+			} else if strings.HasPrefix(attr, "join(") {
+				j, err := parseJoin(attr, strict)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.joins = append(lvl.joins, j)
+			} else if strings.HasPrefix(attr, "lookup(") {
+				l, err := parseLookup(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.lookups = append(lvl.lookups, l)
+			} else if strings.HasPrefix(attr, "zip(") {
+				z, err := parseZip(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.zips = append(lvl.zips, z)
+			} else if strings.HasPrefix(attr, "sample(") {
+				s, err := parseSample(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.sample = s
+			} else if strings.HasPrefix(attr, "top(") {
+				t, err := parseTop(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.top = t
+			} else if strings.HasPrefix(attr, "sort(") {
+				o, err := parseSort(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.sorting = o
+			} else if g, ok, err := parseGroup(attr, strict); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.grouping = g
+			} else if strings.HasPrefix(attr, "pivot(") || strings.HasPrefix(attr, "unpivot(") {
+				p, err := parsePivot(attr)
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.pivot = p
+			} else if r, ok, err := parseRunning(attr); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.running = append(lvl.running, r)
+			} else if c, ok, err := parseComputed(attr); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.computed = append(lvl.computed, c)
+			} else if c, ok, err := parseCount(attr, strict); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.counts = append(lvl.counts, c)
+			} else if agg, ok, err := parseAggregate(attr); ok || err != nil {
+				if err != nil {
+					return nil, "", err
+				}
+				lvl.aggregates = append(lvl.aggregates, agg)
+			} else if strings.ContainsAny(attr, "(){}[") {
+				newQuery, QueryName, err := parseQuery(attr, strict)
+				if err != nil {
+					return nil, "", err
+				}
 				if newQuery.stillFilters == true {
 					lvl.stillFilters = true
 				}
-				lvl.next[QueryName] = newQuery
-			} else {
+				if previous := lvl.next[QueryName]; previous != nil {
+					if previous.slice.String() != newQuery.slice.String() {
+						return nil, "", fmt.Errorf("level %q selected with different indexes", QueryName)
+					}
+					previous.merge(newQuery)
+				} else {
+					lvl.next[QueryName] = newQuery
+				}
+			} else if !lvl.retrieves(attr) {
 				lvl.retrieve = append(lvl.retrieve, attr)
 			}
 		}
+		for _, retrieve := range lvl.retrieve {
+			delete(lvl.next, retrieve)
+		}
+		if len(lvl.excluded) > 0 && !lvl.all {
+			return nil, "", fmt.Errorf("exclusions without wildcard : %q", retrieveCmd)
+		}
+		if lvl.grouping != nil && (lvl.pivot != nil || len(lvl.aggregates) > 0) {
+			return nil, "", fmt.Errorf("group_by with a pivot or aggregations : %q", retrieveCmd)
+		}
+		if lvl.grouping != nil {
+			lvl.grouping.resolve(&lvl)
+		}
+		for _, retrieve := range lvl.retrieve {
+			if lvl.descent != nil && lvl.descent.collects(retrieve) {
+				return nil, "", fmt.Errorf("%q is selected by both the level and its descent", retrieve)
+			}
+		}
+	}
+	return &lvl, name, nil
+}
+
+// retrieves reports whether q already retrieves the key.
+func (q *Query) retrieves(key string) bool {
+	for _, retrieve := range q.retrieve {
+		if retrieve == key {
+			return true
+		}
+	}
+	return false
+}
+
+// merge adds the selections of other to q.
+//
+// A key listed several times in a level is selected once. When a level
+// appears several times, as in {user{name}, user{age}}, the occurrences
+// are merged: their selections are united and all their filters apply.
+// A plain key selects the whole value, so it wins over a level of the same
+// name: {user, user{name}} keeps the whole user.
+func (q *Query) merge(other *Query) {
+	// The level keeps the values selected by either of them, so a level
+	// without filters keeps them all.
+	switch {
+	case len(q.filters) == 0:
+	case len(other.filters) == 0:
+		q.filters, q.expr = q.filters[:0], nil
+	default:
+		q.expr = orExpr(q.condition(), other.condition().shift(len(q.filters)))
+		q.filters = append(q.filters, other.filters...)
+	}
+	q.stillFilters = q.stillFilters || other.stillFilters
+	if other.sample != nil {
+		q.sample = other.sample
+	}
+	if other.top != nil {
+		q.top = other.top
+	}
+	if other.sorting != nil {
+		q.sorting = other.sorting
+	}
+	if other.pivot != nil {
+		q.pivot = other.pivot
+	}
+	if other.grouping != nil {
+		q.grouping = other.grouping
+	}
+	// The fields repeated in both are only written once.
+	for _, j := range other.joins {
+		if !q.produces(j.as) {
+			q.joins = append(q.joins, j)
+		}
+	}
+	for _, l := range other.lookups {
+		if !q.produces(l.as) {
+			q.lookups = append(q.lookups, l)
+		}
+	}
+	for _, z := range other.zips {
+		if !q.produces(z.as) {
+			q.zips = append(q.zips, z)
+		}
+	}
+	for _, c := range other.computed {
+		if !q.produces(c.as) {
+			q.computed = append(q.computed, c)
+		}
+	}
+	if q.grouping != nil {
+		q.grouping.resolve(q)
+	}
+	for _, c := range other.counts {
+		if !q.produces(c.as) {
+			q.counts = append(q.counts, c)
+		}
+	}
+aggregates:
+	for _, agg := range other.aggregates {
+		for _, previous := range q.aggregates {
+			if previous.as == agg.as {
+				continue aggregates
+			}
+		}
+		q.aggregates = append(q.aggregates, agg)
+	}
+running:
+	for _, r := range other.running {
+		for _, previous := range q.running {
+			if previous.as == r.as {
+				continue running
+			}
+		}
+		q.running = append(q.running, r)
+	}
+	q.mergeWildcard(other)
+	if other.descent != nil {
+		if q.descent != nil {
+			q.descent.merge(other.descent)
+		} else {
+			q.descent = other.descent
+		}
+	}
+	for _, retrieve := range other.retrieve {
+		if !q.retrieves(retrieve) {
+			q.retrieve = append(q.retrieve, retrieve)
+		}
+	}
+	for name, next := range other.next {
+		if previous := q.next[name]; previous != nil {
+			previous.merge(next)
+		} else {
+			q.next[name] = next
+		}
+	}
+	for _, retrieve := range q.retrieve {
+		delete(q.next, retrieve)
 	}
-	return &lvl, matches[1], nil
 }
 
 // ParseQuery create a easy traversable structure from a graphql like query.
 func ParseQuery(cmd string) (parser *Query, err error) {
-	parser, _, err = parseQuery(cmd)
-	return parser, err
+	return parseRootQuery(cmd, false)
+}
+
+// ParseQueryStrict is ParseQuery where the filters compare the values of the
+// document as they are, whatever the CoercionPolicy of the query: booleans
+// only match booleans, and integers only match integers, so (n = 1)
+// doesn't match 1.0.
+//
+// Quoted literals, such as (id = "0123"), are strings with ParseQuery too,
+// so strict parsing is not about them: it applies no coercion at all, as
+// CoercionPolicy{StrictNumbers: true} does, even to unquoted literals and
+// even when the query options ask for Bools.
+func ParseQueryStrict(cmd string) (parser *Query, err error) {
+	return parseRootQuery(cmd, true)
 }
 
 // MustParseQuery is parseQuery without error return. You should be sure of your query syntax !
 func MustParseQuery(cmd string) (parser *Query) {
-	parser, _, err := parseQuery(cmd)
+	parser, err := parseRootQuery(cmd, false)
 	if err != nil {
 		panic(err)
 	}
 	return parser
 }
 
+func parseRootQuery(cmd string, strict bool) (*Query, error) {
+	parser, _, err := parseQuery(cmd, strict)
+	if err != nil {
+		return nil, err
+	}
+	parser.setPath(Path{})
+	parser.source = cmd
+	return parser, nil
+}
+
 func splitComa(line string) []string {
 	array := []string{}
-	runes := []rune(string(line))
 	count := 0
 	firstIndex := 0
-	for index, char := range line {
-		switch char {
-		case '{':
+	for index := 0; index < len(line); index++ {
+		switch line[index] {
+		case '"':
+			if n, err := scanQuoted(line[index:]); err == nil {
+				index += n - 1
+			}
+		case '{', '(', '[':
 			count++
-		case '}':
+		case '}', ')', ']':
 			count--
-		case ' ', '\n', '\t':
-			if count == 0 {
-				firstIndex++
-			}
 		case ',':
 			if count == 0 {
-				array = append(array, string(runes[firstIndex:index]))
+				array = append(array, strings.TrimSpace(line[firstIndex:index]))
 				firstIndex = index + 1
 			}
-		default:
-			continue
 		}
 	}
-	if firstIndex < len(line) {
-		array = append(array, string(runes[firstIndex:len(line)]))
+	if rest := strings.TrimSpace(line[firstIndex:]); len(rest) > 0 {
+		array = append(array, rest)
 	}
 	return array
 }
@@ -13,23 +13,23 @@ func TestMustParseQuery(t *testing.T) {
 		args       args
 		wantParser *Query
 	}{
-		{"retrieve only", args{"{}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{}, false}},
-		{"retrieve only", args{"{a,b,c}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{"a", "b", "c"}, false}},
-		{"retrieve only", args{"{a, b, c}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{"a", "b", "c"}, false}},
-		{"filter only", args{"(a : 1){}"}, &Query{[]*Filter{&Filter{"a", ":", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a:1){}"}, &Query{[]*Filter{&Filter{"a", ":", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a :: 1){}"}, &Query{[]*Filter{&Filter{"a", "::", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a::1){}"}, &Query{[]*Filter{&Filter{"a", "::", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a>1){}"}, &Query{[]*Filter{&Filter{"a", ">", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a > 1){}"}, &Query{[]*Filter{&Filter{"a", ">", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a<1){}"}, &Query{[]*Filter{&Filter{"a", "<", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a < 1){}"}, &Query{[]*Filter{&Filter{"a", "<", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a=1){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a = 1){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a!=1){}"}, &Query{[]*Filter{&Filter{"a", "!=", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter only", args{"(a != 1){}"}, &Query{[]*Filter{&Filter{"a", "!=", 1}}, map[string]*Query{}, []string{}, false}},
-		{"filter twice", args{"(a = 1 && b > 0){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}, &Filter{"b", ">", 0}}, map[string]*Query{}, []string{}, false}},
-		{"filter  and retrieve", args{"(a = 1 && b > 0){a,b,c{x,y,z}}"}, &Query{[]*Filter{&Filter{"a", "=", 1}, &Filter{"b", ">", 0}}, map[string]*Query{"c": &Query{[]*Filter{}, map[string]*Query{}, []string{"x", "y", "z"}, false}}, []string{"a", "b"}, false}},
+		{"retrieve only", args{"{}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"retrieve only", args{"{a,b,c}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a", "b", "c"}, stillFilters: false}},
+		{"retrieve only", args{"{a, b, c}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a", "b", "c"}, stillFilters: false}},
+		{"filter only", args{"(a : 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ":", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a:1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ":", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a :: 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "::", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a::1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "::", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a>1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ">", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a > 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ">", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a<1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "<", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a < 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "<", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a=1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a = 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a!=1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "!=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter only", args{"(a != 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "!=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter twice", args{"(a = 1 && b > 0){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}},
+		{"filter  and retrieve", args{"(a = 1 && b > 0){a,b,c{x,y,z}}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}}, next: map[string]*Query{"c": &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"x", "y", "z"}, stillFilters: false}}, retrieve: []string{"a", "b"}, stillFilters: false}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -50,34 +50,50 @@ func TestParseQuery(t *testing.T) {
 		wantParser *Query
 		wantErr    bool
 	}{
-		{"retrieve only", args{"{}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{}, false}, false},
-		{"retrieve only", args{"{a,b,c}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{"a", "b", "c"}, false}, false},
-		{"retrieve only", args{"{a, b, c}"}, &Query{[]*Filter{}, map[string]*Query{}, []string{"a", "b", "c"}, false}, false},
-		{"filter only", args{"(a : 1){}"}, &Query{[]*Filter{&Filter{"a", ":", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a:1){}"}, &Query{[]*Filter{&Filter{"a", ":", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a :: 1){}"}, &Query{[]*Filter{&Filter{"a", "::", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a::1){}"}, &Query{[]*Filter{&Filter{"a", "::", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a>1){}"}, &Query{[]*Filter{&Filter{"a", ">", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a > 1){}"}, &Query{[]*Filter{&Filter{"a", ">", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a<1){}"}, &Query{[]*Filter{&Filter{"a", "<", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a < 1){}"}, &Query{[]*Filter{&Filter{"a", "<", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a=1){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a = 1){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a!=1){}"}, &Query{[]*Filter{&Filter{"a", "!=", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter only", args{"(a != 1){}"}, &Query{[]*Filter{&Filter{"a", "!=", 1}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter twice", args{"(a = 1 && b > 0){}"}, &Query{[]*Filter{&Filter{"a", "=", 1}, &Filter{"b", ">", 0}}, map[string]*Query{}, []string{}, false}, false},
-		{"filter  and retrieve", args{"(a = 1 && b > 0){a,b,c{x,y,z}}"}, &Query{[]*Filter{&Filter{"a", "=", 1}, &Filter{"b", ">", 0}}, map[string]*Query{"c": &Query{[]*Filter{}, map[string]*Query{}, []string{"x", "y", "z"}, false}}, []string{"a", "b"}, false}, false},
+		{"retrieve only", args{"{}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"retrieve only", args{"{a,b,c}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a", "b", "c"}, stillFilters: false}, false},
+		{"retrieve only", args{"{a, b, c}"}, &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a", "b", "c"}, stillFilters: false}, false},
+		{"filter only", args{"(a : 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ":", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a:1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ":", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a :: 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "::", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a::1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "::", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a>1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ">", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a > 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ">", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a<1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "<", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a < 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "<", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a=1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a = 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a!=1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "!=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter only", args{"(a != 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "!=", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter twice", args{"(a = 1 && b > 0){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter exists", args{"(phone? && ! fax ?){}"}, &Query{filters: []*Filter{&Filter{key: "phone", op: "?"}, &Filter{key: "fax", op: "!?"}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter same", args{"(a === null && b !== 1){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "==="}, &Filter{key: "b", op: "!==", val: 1}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter exists", args{"(a = why?){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: "why?"}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter  and retrieve", args{"(a = 1 && b > 0){a,b,c{x,y,z}}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}}, next: map[string]*Query{"c": &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"x", "y", "z"}, stillFilters: false}}, retrieve: []string{"a", "b"}, stillFilters: false}, false},
+		{"filter or", args{"(a>1 || b < c){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: ">", val: 1}, &Filter{key: "b", op: "<", val: "c"}}, expr: anyOf(leaf(0), leaf(1)), next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter group", args{"((a = 1 || b > 0) && c = 2){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}, &Filter{key: "c", op: "=", val: 2}}, expr: allOf(anyOf(leaf(0), leaf(1)), leaf(2)), next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter group", args{"((a = 1) && (b > 0 && (c = 2))){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}, &Filter{key: "c", op: "=", val: 2}}, next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
+		{"filter or", args{"(a = 1 && b > 0 || c = 2){}"}, &Query{filters: []*Filter{&Filter{key: "a", op: "=", val: 1}, &Filter{key: "b", op: ">", val: 0}, &Filter{key: "c", op: "=", val: 2}}, expr: anyOf(allOf(leaf(0), leaf(1)), leaf(2)), next: map[string]*Query{}, retrieve: []string{}, stillFilters: false}, false},
 		{"retrieve only", args{"{"}, nil, true},
 		{"retrieve only", args{"{a,b,c"}, nil, true},
 		{"filter only", args{"( : 1){}"}, nil, true},
 		{"filter only", args{"(a:){}"}, nil, true},
 		{"filter only", args{"(a ::: 1){}"}, nil, true},
-		{"filter only", args{"(a>1 || b < c){}"}, nil, true},
+		{"filter only", args{"(a>1 | b < c){}"}, nil, true},
+		{"filter only", args{"(a>1 || ){}"}, nil, true},
+		{"filter group", args{"((a>1 || b < c){}"}, nil, true},
+		{"filter group", args{"((a>1) b < c){}"}, nil, true},
+		{"filter group", args{"(a>1 && ()){}"}, nil, true},
+		{"filter group", args{"(a = (1)){}"}, nil, true},
 		{"filter only", args{"(a > 1{}"}, nil, true},
 		{"filter only", args{"a<1){}"}, nil, true},
 		{"filter only", args{"(a){}"}, nil, true},
-		{"filter only", args{"(a === 1){}"}, nil, true},
+		{"filter only", args{"(a ==== 1){}"}, nil, true},
+		{"filter only", args{"(a !=== 1){}"}, nil, true},
 		{"filter only", args{"(a?1){}"}, nil, true},
+		{"filter exists", args{"(?){}"}, nil, true},
+		{"filter exists", args{"(!?){}"}, nil, true},
+		{"filter exists", args{"(a.b?){}"}, nil, true},
 		{"filter only", args{"(ac 1){}"}, nil, true},
 	}
 	for _, tt := range tests {
@@ -94,3 +110,295 @@ func TestParseQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestTyped(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  interface{}
+	}{
+		{"int", "0123", int64(123)},
+		{"float", "1e3", float64(1000)},
+		{"bool", "true", true},
+		{"null", "null", nil},
+		{"string", "abc", "abc"},
+		{"quoted string", `"abc"`, "abc"},
+		{"quoted int", `"0123"`, "0123"},
+		{"quoted float", `"1e3"`, "1e3"},
+		{"quoted bool", `"true"`, "true"},
+		{"quoted null", `"null"`, "null"},
+		{"int suffix", "1i", int64(1)},
+		{"float suffix", "1f", float64(1)},
+		{"negative int suffix", "-2i", int64(-2)},
+		{"float suffix fraction", "1.5f", float64(1.5)},
+		{"float suffix exponent", "1e3f", "1e3f"},
+		{"int suffix on fraction", "1.5i", "1.5i"},
+		{"suffix on hexadecimal", "0x1f", "0x1f"},
+		{"suffix on infinity", "Inff", "Inff"},
+		{"suffix alone", "f", "f"},
+		{"suffix on word", "hi", "hi"},
+		{"suffix on quoted", `"1i"`, "1i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typed(tt.value); got != tt.want {
+				t.Errorf("typed(%q) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryStrict(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"id": "0123", "n": 123, "f": 2.0, "b": true}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query           string
+		lenient, strict int
+	}{
+		{`(id = "0123"){id}`, 1, 1},
+		{`(id = 0123){id}`, 0, 0},
+		{`(n = "0123"){n}`, 0, 0},
+		{`(n = 123){n}`, 1, 1},
+		{`(n = 123.0){n}`, 1, 0},
+		{`(f = 2){f}`, 1, 0},
+		{`(f !== 2){f}`, 0, 1},
+	}
+	for _, tt := range tests {
+		q := MustParseQuery(tt.query)
+		if got := q.Match(v); len(got) != tt.lenient {
+			t.Errorf("%s matched %v, want %d match", tt.query, got, tt.lenient)
+		}
+		q, err := ParseQueryStrict(tt.query)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		if got := q.Match(v); len(got) != tt.strict {
+			t.Errorf("strict %s matched %v, want %d match", tt.query, got, tt.strict)
+		}
+	}
+
+	// The quoted literals are strings in both modes: strict parsing turns off
+	// every coercion, even those asked by the options.
+	for _, query := range []string{`(b = 1){b}`, `(b = "true"){b}`} {
+		opts := Options{Coercion: CoercionPolicy{Bools: true}}
+		q := MustParseQuery(query)
+		q.SetOptions(opts)
+		if got := q.Match(v); len(got) != 1 {
+			t.Errorf("%s with coerced booleans matched %v, want 1 match", query, got)
+		}
+		q, err := ParseQueryStrict(query)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		q.SetOptions(opts)
+		if got := q.Match(v); len(got) != 0 {
+			t.Errorf("strict %s with coerced booleans matched %v, want no match", query, got)
+		}
+	}
+}
+
+func TestOperationCheck(t *testing.T) {
+	ops := []Operation{eq, diff, sup, supEq, inf, infEq}
+	tests := []struct {
+		name     string
+		base     interface{}
+		compared interface{}
+		want     []bool // eq, diff, sup, supEq, inf, infEq
+	}{
+		{"int lower", int64(2), int64(1), []bool{false, true, false, false, true, true}},
+		{"int equal", int64(2), int64(2), []bool{true, false, false, true, false, true}},
+		{"int greater", int64(2), int64(3), []bool{false, true, true, true, false, false}},
+		{"int float", int64(2), float64(2.5), []bool{false, true, true, true, false, false}},
+		{"float int", float64(2.5), int64(2), []bool{false, true, false, false, true, true}},
+		{"string lower", "b", "a", []bool{false, true, false, false, true, true}},
+		{"string equal", "b", "b", []bool{true, false, false, true, false, true}},
+		{"string greater", "b", "c", []bool{false, true, true, true, false, false}},
+		{"bool equal", true, true, []bool{true, false, false, false, false, false}},
+		{"bool diff", true, false, []bool{false, true, false, false, false, false}},
+		{"string int", "1", int64(1), []bool{false, false, false, false, false, false}},
+		{"int string", int64(1), "1", []bool{false, false, false, false, false, false}},
+		{"null", nil, nil, []bool{false, false, false, false, false, false}},
+	}
+	for _, tt := range tests {
+		for i, op := range ops {
+			if got := op.check(tt.base, tt.compared); got != tt.want[i] {
+				t.Errorf("%s: %v %s %v = %v, want %v", tt.name, tt.compared, op, tt.base, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestOperationCheckSame(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     interface{}
+		compared interface{}
+		want     bool
+	}{
+		{"null", nil, nil, true},
+		{"null value", nil, "null", false},
+		{"value null", int64(0), nil, false},
+		{"int float", int64(2), float64(2), true},
+		{"string", "b", "b", true},
+		{"string int", "1", int64(1), false},
+		{"bool", false, false, true},
+	}
+	for _, tt := range tests {
+		if got := same.check(tt.base, tt.compared); got != tt.want {
+			t.Errorf("%s: %v === %v = %v, want %v", tt.name, tt.compared, tt.base, got, tt.want)
+		}
+		if got := notSame.check(tt.base, tt.compared); got == tt.want {
+			t.Errorf("%s: %v !== %v = %v, want %v", tt.name, tt.compared, tt.base, got, !tt.want)
+		}
+	}
+}
+
+func TestFilterArrayQuantifier(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"empty": [], "tags": ["a", "b"], "scores": [5, 15], "nested": [[5], [15, 25]]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"tags = a", true},
+		{"any tags = a", true},
+		{"all tags = a", false},
+		{"tags != c", true},
+		{"tags != a", false},
+		{"any tags != a", true},
+		{"tags : b", true},
+		{"tags !: b", false},
+		{"scores > 10", true},
+		{"all scores > 10", false},
+		{"all scores > 1", true},
+		{"scores >= 15", true},
+		{"scores < 10", true},
+		{"all scores <= 15", true},
+		{"all scores < 15", false},
+		{"nested > 20", true},
+		{"all nested > 1", true},
+		{"all nested > 10", false},
+		{"empty = a", false},
+		{"all empty = a", true},
+	}
+	for _, tt := range tests {
+		filters, _, err := newFilter(tt.filter, false)
+		if err != nil {
+			t.Fatalf("cannot parse filter %q: %s", tt.filter, err)
+		}
+		f := filters[0]
+		if got := v.Get(f.key).check(*f, &Options{}); got != tt.want {
+			t.Errorf("filter %q = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryOverlapping(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		want *Query
+	}{
+		{"duplicate keys", "{a, b, a}", &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a", "b"}}},
+		{"key and level", "{a{x}, a}", &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a"}}},
+		{"level and key", "{a, a{x}}", &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"a"}}},
+		{"duplicate levels", "{a{x, y}, a(n > 1){y, z, b{c}}, a{b{d}}}", &Query{
+			filters: []*Filter{},
+			next: map[string]*Query{"a": &Query{
+				filters:  []*Filter{},
+				next:     map[string]*Query{"b": &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"c", "d"}}},
+				retrieve: []string{"x", "y", "z"},
+			}},
+			retrieve: []string{},
+		}},
+		{"duplicate levels with or", "{a(n = 1 || n = 2){x}, a(m = 3){y}}", &Query{
+			filters: []*Filter{},
+			next: map[string]*Query{"a": &Query{
+				filters:  []*Filter{&Filter{key: "n", op: "=", val: 1}, &Filter{key: "n", op: "=", val: 2}, &Filter{key: "m", op: "=", val: 3}},
+				expr:     anyOf(leaf(0), leaf(1), leaf(2)),
+				next:     map[string]*Query{},
+				retrieve: []string{"x", "y"},
+			}},
+			retrieve: []string{},
+		}},
+		{"duplicate filtered levels", "{a(n = 1 && m = 2){x}, a(m = 3){y}}", &Query{
+			filters: []*Filter{},
+			next: map[string]*Query{"a": &Query{
+				filters:  []*Filter{&Filter{key: "n", op: "=", val: 1}, &Filter{key: "m", op: "=", val: 2}, &Filter{key: "m", op: "=", val: 3}},
+				expr:     anyOf(allOf(leaf(0), leaf(1)), leaf(2)),
+				next:     map[string]*Query{},
+				retrieve: []string{"x", "y"},
+			}},
+			retrieve: []string{},
+		}},
+		{"level and nested key", "{a{b{c}}, a{b}}", &Query{
+			filters:  []*Filter{},
+			next:     map[string]*Query{"a": &Query{filters: []*Filter{}, next: map[string]*Query{}, retrieve: []string{"b"}}},
+			retrieve: []string{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParseQuery(tt.cmd)
+			if !got.eq(*tt.want) {
+				t.Errorf("MustParseQuery(%q) = %+v, want %+v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryQuotedLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		want interface{}
+	}{
+		{"escaped quote", `(a = "say \"hi\""){a}`, `say "hi"`},
+		{"escaped backslash", `(a = "c:\\dir"){a}`, `c:\dir`},
+		{"newline", `(a = "l1\nl2"){a}`, "l1\nl2"},
+		{"unicode", `(a = "\u0041"){a}`, "A"},
+		{"and operator", `(a = "x && y"){a}`, "x && y"},
+		{"or operator", `(a = "x || y"){a}`, "x || y"},
+		{"braces", `(a = "{x}"){a}`, "{x}"},
+		{"parentheses", `(a = "f(x)"){a}`, "f(x)"},
+		{"comma", `(a = "x, y"){a, b}`, "x, y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQueryStrict(tt.cmd)
+			if err != nil {
+				t.Fatalf("ParseQueryStrict(%q) unexpected error: %s", tt.cmd, err)
+			}
+			if len(q.filters) != 1 || q.filters[0].val != tt.want {
+				t.Errorf("ParseQueryStrict(%q) filters = %v, want value %q", tt.cmd, q.filters, tt.want)
+			}
+		})
+	}
+
+	for _, cmd := range []string{
+		`(a = "unclosed){a}`,
+		`(a = "bad \q escape"){a}`,
+		`(a = "x" y){a}`,
+		`{b(a = "x}`,
+	} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting non-nil error", cmd)
+		}
+	}
+
+	var p Parser
+	v, err := p.Parse(`[{"a": "f(x) && {y}"}, {"a": "other"}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery(`{items(a = "f(x) && {y}"){a}}`)
+	if got := q.next["items"].Match(v); len(got) != 1 {
+		t.Errorf("Match() = %v, want one match", got)
+	}
+}
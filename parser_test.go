@@ -1,6 +1,7 @@
 package jsonq
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -240,6 +241,43 @@ func TestValueInvalidTypeConversion(t *testing.T) {
 	}
 }
 
+func TestValueIntOverflow(t *testing.T) {
+	var p Parser
+
+	v, err := p.Parse(`[9223372036854775807, 9223372036854775808, -9223372036854775809, 1e300, -1e300, 12.7, -12.7, 1e3]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	a := v.GetArray()
+
+	f := func(v *Value, expectedGet int, intErr, strictErr bool) {
+		t.Helper()
+
+		if n := v.GetInt(); n != expectedGet {
+			t.Fatalf("unexpected GetInt for %s; got %d; want %d", v, n, expectedGet)
+		}
+		n, err := v.Int()
+		if (err != nil) != intErr {
+			t.Fatalf("unexpected Int error for %s: %v", v, err)
+		}
+		if n != expectedGet {
+			t.Fatalf("unexpected Int for %s; got %d; want %d", v, n, expectedGet)
+		}
+		if _, err := v.IntStrict(); (err != nil) != strictErr {
+			t.Fatalf("unexpected IntStrict error for %s: %v", v, err)
+		}
+	}
+
+	f(a[0], maxInt, false, false)
+	f(a[1], maxInt, true, true)
+	f(a[2], minInt, true, true)
+	f(a[3], maxInt, true, true)
+	f(a[4], minInt, true, true)
+	f(a[5], 12, false, true)
+	f(a[6], -12, false, true)
+	f(a[7], 1000, false, false)
+}
+
 func TestValueGetTyped(t *testing.T) {
 	var p Parser
 
@@ -325,6 +363,103 @@ func TestValueGetTyped(t *testing.T) {
 	}
 }
 
+func TestValueTypePredicates(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"o": {}, "a": [], "s": "x", "n": 1.5, "t": true, "f": false, "z": null}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		key  string
+		want Type
+		is   func(*Value) bool
+	}{
+		{"o", TypeObject, (*Value).IsObject},
+		{"a", TypeArray, (*Value).IsArray},
+		{"s", TypeString, (*Value).IsString},
+		{"n", TypeNumber, (*Value).IsNumber},
+		{"t", TypeTrue, (*Value).IsBool},
+		{"f", TypeFalse, (*Value).IsBool},
+		{"z", TypeNull, (*Value).IsNull},
+	}
+	preds := []func(*Value) bool{(*Value).IsObject, (*Value).IsArray, (*Value).IsString, (*Value).IsNumber, (*Value).IsBool, (*Value).IsNull}
+	for _, tt := range tests {
+		val := v.Get(tt.key)
+		matches := 0
+		for _, pred := range preds {
+			if pred(val) {
+				matches++
+			}
+		}
+		if !tt.is(val) || matches != 1 {
+			t.Errorf("%s: %d predicates hold, want only Is%s", tt.key, matches, tt.want)
+		}
+		if typ, ok := v.TypeOf(tt.key); !ok || typ != tt.want {
+			t.Errorf("TypeOf(%q) = %s, %v, want %s", tt.key, typ, ok, tt.want)
+		}
+	}
+	if _, ok := v.TypeOf("o", "missing"); ok {
+		t.Errorf("TypeOf() of a missing key must not be ok")
+	}
+}
+
+func TestValueHasKey(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": {"b\\c": "x\\ny", "n": 12}, "l": [1, {"k": null}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	for _, keys := range [][]string{{"a"}, {"a", "n"}, {"a", `b\c`}, {"l", "1", "k"}} {
+		if !v.HasKey(keys...) {
+			t.Errorf("HasKey(%q) = false", keys)
+		}
+	}
+	for _, keys := range [][]string{{"b"}, {"a", "n", "x"}, {"l", "2"}, {"l", "k"}} {
+		if v.HasKey(keys...) {
+			t.Errorf("HasKey(%q) = true", keys)
+		}
+	}
+	o := v.GetObject("a")
+	if !o.Has("n") || o.Has("m") {
+		t.Errorf("Has() mismatch")
+	}
+	if v.Get("a", "n").t != typeRawNumber {
+		t.Errorf("HasKey decoded a number")
+	}
+}
+
+func TestValueGetOr(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"s": "x", "i": 3, "f": 1.5, "big": 1e30, "b": false, "z": null}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if got := v.GetStringOr("def", "s"); got != "x" {
+		t.Errorf("GetStringOr(s) = %q", got)
+	}
+	if got := v.GetStringOr("def", "i"); got != "def" {
+		t.Errorf("GetStringOr(i) = %q", got)
+	}
+	if got := v.GetStringOr("def", "missing"); got != "def" {
+		t.Errorf("GetStringOr(missing) = %q", got)
+	}
+	for key, want := range map[string]int{"i": 3, "f": 7, "big": 7, "s": 7, "missing": 7} {
+		if got := v.GetIntOr(7, key); got != want {
+			t.Errorf("GetIntOr(%s) = %d, want %d", key, got, want)
+		}
+	}
+	for key, want := range map[string]float64{"i": 3, "f": 1.5, "z": -1, "missing": -1} {
+		if got := v.GetFloat64Or(-1, key); got != want {
+			t.Errorf("GetFloat64Or(%s) = %v, want %v", key, got, want)
+		}
+	}
+	for key, want := range map[string]bool{"b": false, "z": true, "s": true, "missing": true} {
+		if got := v.GetBoolOr(true, key); got != want {
+			t.Errorf("GetBoolOr(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
 func TestVisitNil(t *testing.T) {
 	var p Parser
 	v, err := p.Parse(`{}`)
@@ -340,6 +475,39 @@ func TestVisitNil(t *testing.T) {
 	})
 }
 
+func TestValueGetMany(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"xx":33.33,"foo":[123,{"bar":["baz"],"x":"y"}],"": "empty-key"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	paths := []Path{
+		{"foo", "1", "x"},
+		{"xx"},
+		{"foo", "1", "bar", "0"},
+		{"missing", "a"},
+		{"foo", "0", "nested"},
+		{"foo", "1", "x"},
+		{""},
+		{},
+		{"foo", "7"},
+	}
+	values := v.GetMany(paths...)
+	if len(values) != len(paths) {
+		t.Fatalf("unexpected number of values; got %d; want %d", len(values), len(paths))
+	}
+	for i, p := range paths {
+		if values[i] != v.Get(p...) {
+			t.Fatalf("unexpected value for %q; got %s; want %s", p, values[i], v.Get(p...))
+		}
+	}
+
+	var nilValue *Value
+	if values := nilValue.GetMany(Path{"a"}); len(values) != 1 || values[0] != nil {
+		t.Fatalf("expecting a nil value on a nil receiver; got %v", values)
+	}
+}
+
 func TestValueGet(t *testing.T) {
 	var pp ParserPool
 
@@ -423,6 +591,34 @@ func TestValueGet(t *testing.T) {
 	pp.Put(p)
 }
 
+func TestParserParseFirst(t *testing.T) {
+	var p Parser
+	var values []string
+	tail := " {\"a\":1}{\"a\":2}\n[3] \"x\"\n"
+	for tail != "" {
+		v, rest, err := p.ParseFirst(tail)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", tail, err)
+		}
+		values = append(values, v.String())
+		tail = rest
+	}
+	if s := strings.Join(values, " "); s != `{"a":1} {"a":2} [3] "x"` {
+		t.Fatalf("unexpected values: %s", s)
+	}
+
+	v, tail, err := p.ParseFirst(`123 trailing`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.GetInt() != 123 || tail != "trailing" {
+		t.Fatalf("unexpected value %s and tail %q", v, tail)
+	}
+	if _, _, err := p.ParseFirst(`[1 2]`); !errors.Is(err, ErrMissingComma) {
+		t.Fatalf("expecting %q; got %v", ErrMissingComma, err)
+	}
+}
+
 func TestParserParse(t *testing.T) {
 	var p Parser
 
@@ -759,8 +955,23 @@ func TestParserParse(t *testing.T) {
 			t.Fatalf("unexpected value obtained for integer; got %f; want %f", n, -12.345)
 		}
 		s := v.String()
-		if s != "-12.345000" {
-			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "-12.345000")
+		if s != "-12.345" {
+			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "-12.345")
+		}
+	})
+
+	t.Run("raw number", func(t *testing.T) {
+		for _, raw := range []string{"1e22", "-1.5E-7", "0.10", "123456789012345678901234567890"} {
+			v, err := p.Parse(raw)
+			if err != nil {
+				t.Fatalf("cannot parse number %q: %s", raw, err)
+			}
+			if v.Type() != TypeNumber {
+				t.Fatalf("unexpected type obtained for number: %#v", v)
+			}
+			if s := v.String(); s != raw {
+				t.Fatalf("unexpected string representation of number; got %q; want %q", s, raw)
+			}
 		}
 	})
 
@@ -868,8 +1079,8 @@ func TestParserParse(t *testing.T) {
 		}
 
 		s := v.String()
-		if s != `{"foo":[1,2,3],"bar":{},"baz":123.456000}` {
-			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":[1,2,3],"bar":{},"baz":123.456000}`)
+		if s != `{"foo":[1,2,3],"bar":{},"baz":123.456}` {
+			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":[1,2,3],"bar":{},"baz":123.456}`)
 		}
 	})
 
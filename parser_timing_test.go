@@ -275,3 +275,64 @@ func benchmarkStdJSONParseEmptyStruct(b *testing.B, s string) {
 		}
 	})
 }
+
+func BenchmarkParseFor(b *testing.B) {
+	q := MustParseQuery("{statuses{id, user{screen_name}}}")
+	b.Run("parse", func(b *testing.B) {
+		benchmarkParseFor(b, twitterFixture, nil)
+	})
+	b.Run("parse-for", func(b *testing.B) {
+		benchmarkParseFor(b, twitterFixture, q)
+	})
+}
+
+func benchmarkParseFor(b *testing.B, s string, q *Query) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	data := []byte(s)
+	b.RunParallel(func(pb *testing.PB) {
+		p := benchPool.Get()
+		for pb.Next() {
+			var err error
+			if q == nil {
+				_, err = p.ParseBytes(data)
+			} else {
+				_, err = p.ParseFor(q, data)
+			}
+			if err != nil {
+				panic(fmt.Errorf("unexpected error: %s", err))
+			}
+		}
+		benchPool.Put(p)
+	})
+}
+
+func BenchmarkValueGetMany(b *testing.B) {
+	var p Parser
+	v, err := p.Parse(twitterFixture)
+	if err != nil {
+		panic(fmt.Errorf("unexpected error: %s", err))
+	}
+	paths := []Path{
+		{"statuses", "0", "user", "screen_name"},
+		{"statuses", "0", "user", "followers_count"},
+		{"statuses", "0", "user", "lang"},
+		{"statuses", "0", "text"},
+		{"statuses", "0", "id_str"},
+		{"search_metadata", "count"},
+	}
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, path := range paths {
+				v.Get(path...)
+			}
+		}
+	})
+	b.Run("GetMany", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v.GetMany(paths...)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CreateMergePatch returns the JSON merge patch (RFC 7386) turning a into
+// b: an object holding the changed members of b, recursively, and null for
+// the members of a missing from b. It is b itself when a or b is not an
+// object.
+//
+// Merge patches cannot set a member to null, since null removes it; use
+// CreatePatch when b may hold null members.
+//
+// The patch shares its values with b, so it is valid as long as b is.
+func CreateMergePatch(a, b *Value) *Value {
+	if a == nil || b == nil || a.Type() != TypeObject || b.Type() != TypeObject {
+		return b
+	}
+	a.o.unescapeKeys()
+	b.o.unescapeKeys()
+	patch := newObject()
+	for _, akv := range a.o.kvs {
+		if b.o.Get(akv.k) == nil {
+			patch.o.kvs = append(patch.o.kvs, kv{k: akv.k, v: valueNull})
+		}
+	}
+	for _, bkv := range b.o.kvs {
+		av := a.o.Get(bkv.k)
+		if av != nil && equalValues(av, bkv.v) {
+			continue
+		}
+		patch.o.kvs = append(patch.o.kvs, kv{k: bkv.k, v: CreateMergePatch(av, bkv.v)})
+	}
+	return patch
+}
+
+// CreatePatch returns the JSON patch (RFC 6902) turning a into b: an array
+// of add, remove and replace operations. Objects and arrays are compared
+// member by member and element by element, so a change deep in a document
+// is a single operation.
+//
+// The patch shares its values with b, so it is valid as long as b is.
+func CreatePatch(a, b *Value) *Value {
+	patch := &Value{t: TypeArray, a: []*Value{}}
+	diffValues(patch, "", a, b)
+	return patch
+}
+
+func diffValues(patch *Value, ptr string, a, b *Value) {
+	if equalValues(a, b) {
+		return
+	}
+	switch {
+	case a.Type() == TypeObject && b.Type() == TypeObject:
+		a.o.unescapeKeys()
+		b.o.unescapeKeys()
+		for _, akv := range a.o.kvs {
+			if b.o.Get(akv.k) == nil {
+				patch.a = append(patch.a, patchOperation("remove", ptr+"/"+escapePointer(akv.k), nil))
+			}
+		}
+		for _, bkv := range b.o.kvs {
+			child := ptr + "/" + escapePointer(bkv.k)
+			if av := a.o.Get(bkv.k); av != nil {
+				diffValues(patch, child, av, bkv.v)
+			} else {
+				patch.a = append(patch.a, patchOperation("add", child, bkv.v))
+			}
+		}
+	case a.Type() == TypeArray && b.Type() == TypeArray:
+		n := len(a.a)
+		if len(b.a) < n {
+			n = len(b.a)
+		}
+		for i := 0; i < n; i++ {
+			diffValues(patch, ptr+"/"+strconv.Itoa(i), a.a[i], b.a[i])
+		}
+		// Remove from the end so that the indexes stay valid.
+		for i := len(a.a) - 1; i >= n; i-- {
+			patch.a = append(patch.a, patchOperation("remove", ptr+"/"+strconv.Itoa(i), nil))
+		}
+		for i := n; i < len(b.a); i++ {
+			patch.a = append(patch.a, patchOperation("add", ptr+"/"+strconv.Itoa(i), b.a[i]))
+		}
+	default:
+		patch.a = append(patch.a, patchOperation("replace", ptr, b))
+	}
+}
+
+func patchOperation(op, path string, value *Value) *Value {
+	o := newObject()
+	o.o.kvs = append(o.o.kvs,
+		kv{k: "op", v: &Value{t: TypeString, s: op}},
+		kv{k: "path", v: &Value{t: TypeString, s: path}},
+	)
+	if value != nil {
+		o.o.kvs = append(o.o.kvs, kv{k: "value", v: value})
+	}
+	return o
+}
+
+func newObject() *Value {
+	v := &Value{t: TypeObject}
+	v.o.keysUnescaped = true
+	return v
+}
+
+// escapePointer escapes key as a JSON pointer (RFC 6901) reference token.
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+func equalValues(a, b *Value) bool {
+	return bytes.Equal(a.AppendCanonical(nil), b.AppendCanonical(nil))
+}
+
+// ApplyMergePatch returns doc with the JSON merge patch (RFC 7386) applied:
+// the members of patch replace those of doc, recursively for objects, and
+// its null members remove them. doc is not modified.
+//
+// The result shares values with doc and patch, so it is valid as long as
+// they are.
+func ApplyMergePatch(doc, patch *Value) *Value {
+	if patch == nil || patch.Type() != TypeObject {
+		return patch
+	}
+	patch.o.unescapeKeys()
+	result := newObject()
+	if doc != nil && doc.Type() == TypeObject {
+		doc.o.unescapeKeys()
+		result.o.kvs = append(result.o.kvs, doc.o.kvs...)
+	}
+	for _, pkv := range patch.o.kvs {
+		if pkv.v.Type() == TypeNull {
+			result.o.Del(pkv.k)
+			continue
+		}
+		result.o.Set(pkv.k, ApplyMergePatch(result.o.Get(pkv.k), pkv.v))
+	}
+	return result
+}
+
+// ApplyPatch returns doc with the JSON patch (RFC 6902) applied: an array
+// of add, remove, replace, move, copy and test operations. doc is not
+// modified, and no change is made when an operation fails.
+//
+// The result shares values with patch, so it is valid as long as patch is.
+func ApplyPatch(doc, patch *Value) (*Value, error) {
+	if patch.Type() != TypeArray {
+		return nil, &ErrWrongType{Want: TypeArray, Got: patch.Type()}
+	}
+	doc = doc.clone()
+	for i, op := range patch.a {
+		if op.Type() != TypeObject {
+			return nil, &ErrWrongType{Path: Path{strconv.Itoa(i)}, Want: TypeObject, Got: op.Type()}
+		}
+		var err error
+		if doc, err = applyOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc, op *Value) (*Value, error) {
+	name := string(op.GetStringBytes("op"))
+	path, err := operationPath(op, "path")
+	if err != nil {
+		return nil, err
+	}
+	value := op.Get("value")
+	switch name {
+	case "add", "replace", "test":
+		if value == nil {
+			return nil, fmt.Errorf("missing value for %s", name)
+		}
+	case "move", "copy":
+		from, err := operationPath(op, "from")
+		if err != nil {
+			return nil, err
+		}
+		if value = doc.Get(from...); value == nil {
+			return nil, fmt.Errorf("%w : %s", ErrKeyNotFound, op.GetStringBytes("from"))
+		}
+		if name == "move" {
+			if doc, err = removeAt(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = value.clone()
+		}
+		name = "add"
+	}
+
+	switch name {
+	case "add":
+		return addAt(doc, path, value)
+	case "remove":
+		return removeAt(doc, path)
+	case "replace":
+		if doc.Get(path...) == nil {
+			return nil, fmt.Errorf("%w : %s", ErrKeyNotFound, op.GetStringBytes("path"))
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		return doc, doc.Get(path[:len(path)-1]...).setChild(path, value, false)
+	case "test":
+		if current := doc.Get(path...); current == nil || !equalValues(current, value) {
+			return nil, fmt.Errorf("test failed at %q", op.GetStringBytes("path"))
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func operationPath(op *Value, key string) (Path, error) {
+	ptr := op.Get(key)
+	if ptr == nil || ptr.Type() != TypeString {
+		return nil, fmt.Errorf("missing %s", key)
+	}
+	return parsePointer(ptr.s)
+}
+
+func addAt(doc *Value, path Path, value *Value) (*Value, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent := doc.Get(path[:len(path)-1]...)
+	if parent == nil {
+		return nil, fmt.Errorf("%w : %s", ErrKeyNotFound, path[:len(path)-1])
+	}
+	return doc, parent.setChild(path, value, true)
+}
+
+func removeAt(doc *Value, path Path) (*Value, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the root value")
+	}
+	parent := doc.Get(path[:len(path)-1]...)
+	if parent == nil || parent.step(path[len(path)-1]) == nil {
+		return nil, fmt.Errorf("%w : %s", ErrKeyNotFound, path)
+	}
+	key := path[len(path)-1]
+	if parent.Type() == TypeObject {
+		parent.o.Del(key)
+		return doc, nil
+	}
+	i, _ := strconv.Atoi(key)
+	parent.a = append(parent.a[:i], parent.a[i+1:]...)
+	return doc, nil
+}
@@ -0,0 +1,147 @@
+package jsonq
+
+import (
+	"errors"
+	"testing"
+)
+
+func parsePair(t *testing.T, a, b string) (*Value, *Value) {
+	t.Helper()
+	var pa, pb Parser
+	va, err := pa.Parse(a)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", a, err)
+	}
+	vb, err := pb.Parse(b)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", b, err)
+	}
+	return va, vb
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	f := func(a, b, expected string) {
+		t.Helper()
+		va, vb := parsePair(t, a, b)
+		if patch := CreateMergePatch(va, vb); patch.String() != expected {
+			t.Fatalf("unexpected merge patch from %s to %s; got %s; want %s", a, b, patch, expected)
+		}
+	}
+	f(`{"a":1,"b":2}`, `{"a":1,"b":2}`, `{}`)
+	f(`{"a":1,"b":2}`, `{"b":3,"c":4}`, `{"a":null,"b":3,"c":4}`)
+	f(`{"a":{"b":1,"c":2}}`, `{"a":{"b":1,"c":3}}`, `{"a":{"c":3}}`)
+	f(`{"a":[1,2]}`, `{"a":[1]}`, `{"a":[1]}`)
+	f(`{"a":1}`, `[1]`, `[1]`)
+}
+
+func TestCreatePatch(t *testing.T) {
+	f := func(a, b, expected string) {
+		t.Helper()
+		va, vb := parsePair(t, a, b)
+		if patch := CreatePatch(va, vb); patch.String() != expected {
+			t.Fatalf("unexpected patch from %s to %s; got %s; want %s", a, b, patch, expected)
+		}
+	}
+	f(`{"a":1}`, `{"a":1}`, `[]`)
+	f(`{"a":1,"b":2}`, `{"b":3,"c":null}`,
+		`[{"op":"remove","path":"/a"},{"op":"replace","path":"/b","value":3},{"op":"add","path":"/c","value":null}]`)
+	f(`{"a/b":{"c~d":1}}`, `{"a/b":{"c~d":2}}`, `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`)
+	f(`[1,2,3]`, `[1,5]`, `[{"op":"replace","path":"/1","value":5},{"op":"remove","path":"/2"}]`)
+	f(`[1]`, `[1,2,3]`, `[{"op":"add","path":"/1","value":2},{"op":"add","path":"/2","value":3}]`)
+	f(`{"a":1}`, `"x"`, `[{"op":"replace","path":"","value":"x"}]`)
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	f := func(doc, patch, expected string) {
+		t.Helper()
+		vd, vp := parsePair(t, doc, patch)
+		if result := ApplyMergePatch(vd, vp); result.String() != expected {
+			t.Fatalf("unexpected result of %s on %s; got %s; want %s", patch, doc, result, expected)
+		}
+		if vd.String() != doc {
+			t.Fatalf("the document was modified: %s", vd)
+		}
+	}
+	f(`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`)
+	f(`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`)
+	f(`{"a":"b"}`, `{"a":null}`, `{}`)
+	f(`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`)
+	f(`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`)
+	f(`["a"]`, `{"a":"c"}`, `{"a":"c"}`)
+	f(`{"a":"foo"}`, `"bar"`, `"bar"`)
+	f(`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`)
+}
+
+func TestApplyPatch(t *testing.T) {
+	f := func(doc, patch, expected string) {
+		t.Helper()
+		vd, vp := parsePair(t, doc, patch)
+		result, err := ApplyPatch(vd, vp)
+		if err != nil {
+			t.Fatalf("cannot apply %s on %s: %s", patch, doc, err)
+		}
+		if result.String() != expected {
+			t.Fatalf("unexpected result of %s on %s; got %s; want %s", patch, doc, result, expected)
+		}
+		if vd.String() != doc {
+			t.Fatalf("the document was modified: %s", vd)
+		}
+	}
+	f(`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`)
+	f(`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`)
+	f(`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`)
+	f(`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`)
+	f(`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`)
+	f(`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`)
+	f(`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+		`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`)
+	f(`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`)
+	f(`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`)
+	f(`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`)
+	f(`{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`)
+	f(`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`)
+
+	for _, patch := range []string{
+		`[{"op":"test","path":"/baz","value":"bar"}]`,
+		`[{"op":"add","path":"/a/b","value":1}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/foo/5","value":1}]`,
+		`[{"op":"add","path":"/baz"}]`,
+		`[{"op":"jump","path":"/baz"}]`,
+		`[{"op":"add","value":1}]`,
+		`{"op":"add","path":"/x","value":1}`,
+	} {
+		vd, vp := parsePair(t, `{"baz":"qux","foo":[1]}`, patch)
+		if _, err := ApplyPatch(vd, vp); err == nil {
+			t.Fatalf("expecting error when applying %s", patch)
+		}
+		if vd.String() != `{"baz":"qux","foo":[1]}` {
+			t.Fatalf("the document was modified by the failing %s: %s", patch, vd)
+		}
+	}
+	vd, vp := parsePair(t, `{}`, `[{"op":"remove","path":"/missing"}]`)
+	if _, err := ApplyPatch(vd, vp); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expecting %q; got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestCreatePatchRoundTrip(t *testing.T) {
+	f := func(a, b string) {
+		t.Helper()
+		va, vb := parsePair(t, a, b)
+		patched, err := ApplyPatch(va, CreatePatch(va, vb))
+		if err != nil {
+			t.Fatalf("cannot apply the patch from %s to %s: %s", a, b, err)
+		}
+		if !equalValues(patched, vb) {
+			t.Fatalf("unexpected patched value from %s to %s: %s", a, b, patched)
+		}
+		if merged := ApplyMergePatch(va, CreateMergePatch(va, vb)); !equalValues(merged, vb) {
+			t.Fatalf("unexpected merged value from %s to %s: %s", a, b, merged)
+		}
+	}
+	f(`{"a":1,"b":[1,2,3],"c":{"d":"e"}}`, `{"b":[1,4],"c":{"d":"f","g":true},"h":[]}`)
+	f(`[1,{"a":2}]`, `[1,{"a":3},4]`)
+	f(`{"a":1}`, `"x"`)
+}
@@ -0,0 +1,85 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is a keys path leading to a value inside a JSON document.
+//
+// Array indexes are represented as decimal numbers, so a Path may be
+// passed directly to Value.Get, Exists or Search: v.Get(path...).
+type Path []string
+
+// ParsePath parses a path written as in a configuration file, such as
+// "a.b[3].c": keys are separated by dots and array indexes are written in
+// brackets. A backslash escapes the next character, so "a\.b" is the
+// single key "a.b".
+func ParsePath(s string) (Path, error) {
+	p := Path{}
+	if s == "" {
+		return p, nil
+	}
+	var key strings.Builder
+	// expectKey is true when a key must follow, after a dot or at the
+	// start, and false after an index.
+	expectKey := s[0] != '['
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("path %q ends with an escape", s)
+			}
+			i++
+			key.WriteByte(s[i])
+		case '.', '[':
+			if expectKey {
+				if key.Len() == 0 {
+					return nil, fmt.Errorf("empty key in path %q", s)
+				}
+				p = append(p, key.String())
+				key.Reset()
+			}
+			expectKey = c == '.'
+			if c == '.' {
+				continue
+			}
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ']' in path %q", s)
+			}
+			index := s[i+1 : i+end]
+			if index == "" || strings.Trim(index, "0123456789") != "" {
+				return nil, fmt.Errorf("invalid index %q in path %q", index, s)
+			}
+			p = append(p, index)
+			i += end
+			if i+1 < len(s) && s[i+1] != '.' && s[i+1] != '[' {
+				return nil, fmt.Errorf("unexpected %q after index in path %q", s[i+1], s)
+			}
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("unexpected %q after index in path %q", c, s)
+			}
+			key.WriteByte(c)
+		}
+	}
+	if expectKey {
+		if key.Len() == 0 {
+			return nil, fmt.Errorf("empty key in path %q", s)
+		}
+		p = append(p, key.String())
+	}
+	return p, nil
+}
+
+// String returns the dot separated representation of p.
+func (p Path) String() string {
+	return strings.Join(p, ".")
+}
+
+func (p Path) child(key string) Path {
+	np := make(Path, len(p), len(p)+1)
+	copy(np, p)
+	return append(np, key)
+}
@@ -0,0 +1,44 @@
+package jsonq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	f := func(s string, expected Path) {
+		t.Helper()
+		p, err := ParsePath(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if !reflect.DeepEqual(p, expected) {
+			t.Fatalf("unexpected path for %q; got %q; want %q", s, p, expected)
+		}
+	}
+	f("", Path{})
+	f("a", Path{"a"})
+	f("a.b[3].c", Path{"a", "b", "3", "c"})
+	f("[0][1]", Path{"0", "1"})
+	f(`a\.b.c`, Path{"a.b", "c"})
+	f(`a\[0\]`, Path{"a[0]"})
+	f(`a\\b`, Path{`a\b`})
+
+	for _, s := range []string{".", "a.", ".a", "a..b", "a[", "a[]", "a[x]", "a[0]b", `a\`} {
+		if _, err := ParsePath(s); err == nil {
+			t.Fatalf("expecting error when parsing %q", s)
+		}
+	}
+
+	v, err := (&Parser{}).Parse(`{"a.b":{"c":[1,{"d":true}]}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	p, err := ParsePath(`a\.b.c[1].d`)
+	if err != nil {
+		t.Fatalf("cannot parse path: %s", err)
+	}
+	if !v.GetBool(p...) {
+		t.Fatalf("expecting true at %q", p)
+	}
+}
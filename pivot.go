@@ -0,0 +1,126 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pivot turns an array level of {key, value} objects into a single object,
+// and unpivot turns an object level back into such an array. Both are
+// written in the retrieve block of the level with the names of the key and
+// value fields:
+//
+//	{attributes{pivot(name, value)}}
+//	{settings{unpivot(name, value)}}
+//
+// pivot maps the key of each element matching the filters of the level to
+// its value; elements whose key is not a string or a number are left out,
+// and the last element wins when keys repeat. unpivot returns an element
+// per field selected by the level, or per field of the object when the
+// level selects none.
+type pivot struct {
+	key     string
+	value   string
+	reverse bool
+}
+
+func parsePivot(cmd string) (*pivot, error) {
+	p := &pivot{}
+	name := "pivot"
+	if strings.HasPrefix(cmd, "unpivot(") {
+		p.reverse = true
+		name = "unpivot"
+	}
+	n, err := scanGroup(cmd[len(name):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	if len(name)+n != len(cmd) {
+		return nil, fmt.Errorf("mal formated %s : %q", name, cmd)
+	}
+	args := splitComa(cmd[len(name)+1 : len(cmd)-1])
+	if len(args) != 2 || !isName(args[0]) || !isName(args[1]) {
+		return nil, fmt.Errorf("%s expects a key and a value field : %q", name, cmd)
+	}
+	p.key, p.value = args[0], args[1]
+	return p, nil
+}
+
+// object returns the JSON object pivoted from elements.
+func (p *pivot) object(elements []*Value) string {
+	var keys []string
+	values := map[string]string{}
+	for _, element := range elements {
+		k := element.Get(p.key)
+		if k == nil {
+			continue
+		}
+		var key string
+		switch k.Type() {
+		case TypeString:
+			key = k.s
+		case TypeNumber:
+			key = k.raw()
+		default:
+			continue
+		}
+		value := "null"
+		if v := element.Get(p.value); v != nil {
+			value = v.raw()
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('{')
+	for i, key := range keys {
+		if i > 0 {
+			w.WriteRune(',')
+		}
+		w.WriteString(strconv.Quote(key))
+		w.WriteRune(':')
+		w.WriteString(values[key])
+	}
+	w.WriteRune('}')
+	return w.String()
+}
+
+// array returns the JSON array unpivoted from o.
+func (p *pivot) array(o *Object) string {
+	w := bytes.Buffer{}
+	w.WriteRune('[')
+	o.Visit(func(key []byte, v *Value) {
+		if w.Len() > 1 {
+			w.WriteRune(',')
+		}
+		w.WriteRune('{')
+		writeField(&w, true, p.key, strconv.Quote(string(key)))
+		writeField(&w, false, p.value, v.raw())
+		w.WriteRune('}')
+	})
+	w.WriteRune(']')
+	return w.String()
+}
+
+// selects reports whether the level selects any field.
+func (request Query) selects() bool {
+	return request.all || request.descent != nil || len(request.retrieve)+len(request.next)+len(request.joins)+len(request.lookups)+len(request.zips)+len(request.computed)+len(request.counts) > 0
+}
+
+// unpivot returns the array unpivoted from the object kept by the level
+// from o.
+func (request Query) unpivot(o *Object, kept string) (string, error) {
+	if request.selects() {
+		var parser Parser
+		v, err := parser.Parse(kept)
+		if err != nil {
+			return "", err
+		}
+		o = &v.o
+	}
+	return request.pivot.array(o), nil
+}
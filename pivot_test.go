@@ -0,0 +1,44 @@
+package jsonq
+
+import "testing"
+
+func TestKeepPivot(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"attributes": [{"name": "color", "value": "red"}, {"name": "size", "value": 42}, {"name": 3, "value": [1]}, {"value": "x"}, {"name": "color", "value": "blue"}],
+		"settings": {"theme": "dark", "font": {"size": 12}, "beta": true}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"{attributes{pivot(name, value)}}", `{"attributes":{"color":"blue","size":42,"3":[1]}}`},
+		{"{attributes(name = color){pivot(name, value)}}", `{"attributes":{"color":"blue"}}`},
+		{"{settings{unpivot(key, val)}}", `{"settings":[{"key":"theme","val":"dark"},{"key":"font","val":{"size":12}},{"key":"beta","val":true}]}`},
+		{"{settings{unpivot(key, val), beta}}", `{"settings":[{"key":"beta","val":true}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParsePivotErrors(t *testing.T) {
+	for _, query := range []string{
+		"{attributes{pivot(name)}}",
+		"{attributes{pivot(name, a.b)}}",
+		"{attributes{unpivot(name, value) as x}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
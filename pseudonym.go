@@ -0,0 +1,32 @@
+package jsonq
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Pseudonymize returns a Replace function replacing identifiers by stable
+// pseudonyms, such as "ps_5b2d…": the same identifier always gets the same
+// pseudonym under the same key, so the output can still be grouped and
+// joined on it, while the identifier can't be recovered without the key.
+//
+// The pseudonym is the HMAC-SHA256 of the canonical form of the value, as
+// by AppendCanonical, so "A" and "\u0041", or 1 and 1.0, get the same one.
+// Its first 16 bytes are written in hexadecimal after prefix. null values
+// are left as is.
+func Pseudonymize(key []byte, prefix string) func(raw []byte) []byte {
+	return func(raw []byte) []byte {
+		var p Parser
+		data := raw
+		if v, err := p.ParseBytes(raw); err == nil {
+			if v.Type() == TypeNull {
+				return raw
+			}
+			data = v.AppendCanonical(nil)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return appendQuoted(nil, fmt.Sprintf("%s%x", prefix, mac.Sum(nil)[:16]))
+	}
+}
@@ -0,0 +1,40 @@
+package jsonq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymize(t *testing.T) {
+	pseudonym := Pseudonymize([]byte("k1"), "ps_")
+	same := [][2]string{{`"A"`, `"\u0041"`}, {`1`, `1.0`}, {`{"a":1,"b":2}`, `{"b":2, "a":1}`}}
+	for _, pair := range same {
+		a, b := pseudonym([]byte(pair[0])), pseudonym([]byte(pair[1]))
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s and %s got different pseudonyms %s and %s", pair[0], pair[1], a, b)
+		}
+		if !bytes.HasPrefix(a, []byte(`"ps_`)) || len(a) != len(`"ps_"`)+32 {
+			t.Errorf("unexpected pseudonym %s", a)
+		}
+	}
+	if a, b := pseudonym([]byte(`"A"`)), pseudonym([]byte(`"B"`)); bytes.Equal(a, b) {
+		t.Errorf("different values got the same pseudonym %s", a)
+	}
+	if a, b := pseudonym([]byte(`"A"`)), Pseudonymize([]byte("k2"), "ps_")([]byte(`"A"`)); bytes.Equal(a, b) {
+		t.Errorf("different keys gave the same pseudonym %s", a)
+	}
+	if got := pseudonym([]byte(`null`)); string(got) != `null` {
+		t.Errorf("null pseudonymized to %s", got)
+	}
+
+	var out bytes.Buffer
+	rules := []RewriteRule{{Path: Path{"users", "*", "email"}, Replace: pseudonym}}
+	if err := Transform(&out, strings.NewReader(`{"users":[{"email":"a@x.io","n":1},{"email":"a@x.io"},{"email":null}]}`), rules...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	token := string(pseudonym([]byte(`"a@x.io"`)))
+	if want := `{"users":[{"email":` + token + `,"n":1},{"email":` + token + `},{"email":null}]}`; out.String() != want {
+		t.Errorf("Transform = %s, want %s", out.String(), want)
+	}
+}
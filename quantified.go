@@ -0,0 +1,49 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseQuantified parses the condition of the quantified filters
+// any(items.price > 100) and all(items.price > 100), which apply to the
+// arrays of objects: the first holds when at least one item is priced over
+// 100, the second when every item is. The key of the condition is a path,
+// whose arrays, nested or not, are checked element by element with the
+// quantifier. Elements without the path fail the condition.
+func parseQuantified(quant Quantifier, cmd string, strict bool) (*Filter, error) {
+	s := strings.TrimSpace(cmd)
+	i := 0
+	for i < len(s) && (isNameChar(s[i]) || s[i] == '.') {
+		i++
+	}
+	path := Path(strings.Split(s[:i], "."))
+	for _, key := range path {
+		if key == "" {
+			return nil, fmt.Errorf("Format error in filters : %s(%s)", quant, cmd)
+		}
+	}
+	f, err := parseCondition(path[len(path)-1]+s[i:], strict)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.presence(true); ok || f.quant != "" {
+		return nil, fmt.Errorf("%s expects a comparison : %s(%s)", quant, quant, cmd)
+	}
+	f.key, f.sub, f.quant = path[0], path[1:], quant
+	return f, nil
+}
+
+// checkSub checks the filter on the value at the sub path of the filter
+// in the object v. Other values fail.
+func (v Value) checkSub(filter Filter, opts *Options) bool {
+	if v.Type() != TypeObject {
+		return false
+	}
+	nValue := v.o.Get(filter.sub[0])
+	if nValue == nil {
+		return false
+	}
+	filter.sub = filter.sub[1:]
+	return nValue.check(filter, opts)
+}
@@ -0,0 +1,59 @@
+package jsonq
+
+import "testing"
+
+func TestKeepQuantified(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"orders": [
+		{"id": 1, "items": [{"price": 150}, {"price": 20}]},
+		{"id": 2, "items": [{"price": 120}, {"price": 300, "tags": [{"name": "promo"}]}]},
+		{"id": 3, "items": [{"price": 5}, {"sku": "x"}]},
+		{"id": 4, "items": []},
+		{"id": 5, "items": {"price": 200}},
+		{"id": 6}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{orders(any(items.price > 100)){id}}`, `{"orders":[{"id":1},{"id":2},{"id":5},{"id":6}]}`},
+		{`{orders(all(items.price > 100)){id}}`, `{"orders":[{"id":2},{"id":4},{"id":5},{"id":6}]}`},
+		{`{orders(id < 6 && all( items.price >= 5 )){id}}`, `{"orders":[{"id":1},{"id":2},{"id":4},{"id":5}]}`},
+		{`{orders(any(items.tags.name = promo)){id}}`, `{"orders":[{"id":2},{"id":6}]}`},
+		{`{orders(id < 4 && (all(items.price != 20) || id = 1)){id}}`, `{"orders":[{"id":1},{"id":2}]}`},
+		{`{orders(any(items = x)){id}}`, `{"orders":[{"id":6}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	q := MustParseQuery(`{orders(any(items.price > 100)){id}}`)
+	q.SetOptions(Options{ThreeValued: true})
+	if got, err := v.Keep(*q); err != nil || got != `{"orders":[{"id":1},{"id":2},{"id":5}]}` {
+		t.Errorf("Keep with the three-valued logic = %s, %v", got, err)
+	}
+}
+
+func TestParseQuantifiedErrors(t *testing.T) {
+	for _, query := range []string{
+		"{orders(any(items.price > 100){id}}",
+		"{orders(any(items..price > 100)){id}}",
+		"{orders(all(.price > 100)){id}}",
+		"{orders(any(items.sku?)){id}}",
+		"{orders(any(all items.price > 100)){id}}",
+		"{orders(any(items.price >)){id}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isNameChar reports whether c may be part of a level name or a filter key.
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// isName reports whether s is a valid level name or filter key.
+func isName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// scanQuoted returns the length of the double quoted literal starting s,
+// quotes included. Backslashes escape the following character.
+func scanQuoted(s string) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("missing closing '\"' in %q", s)
+}
+
+// scanGroup returns the length of the group opened by s[0] and closed by
+// the matching close byte, ignoring the bytes of quoted literals.
+func scanGroup(s string, open, close byte) (int, error) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			n, err := scanQuoted(s[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n - 1
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("missing closing %q in %q", close, s)
+}
+
+// splitLevel splits a level of a query, name[index](filters){retrieve},
+// into its name, its index, its filters and its retrieve block. Every part
+// is optional, but an index requires a name.
+func splitLevel(cmd string) (name, index, filters, retrieve string, err error) {
+	i := 0
+	for i < len(cmd) && isNameChar(cmd[i]) {
+		i++
+	}
+	name, cmd = cmd[:i], cmd[i:]
+	if len(cmd) > 0 && cmd[0] == '[' && len(name) > 0 {
+		n := strings.IndexByte(cmd, ']')
+		if n < 0 {
+			return "", "", "", "", fmt.Errorf("missing closing ']' in %q", cmd)
+		}
+		if n == 1 {
+			return "", "", "", "", fmt.Errorf("empty index in %q", name+cmd)
+		}
+		index, cmd = cmd[1:n], cmd[n+1:]
+	}
+	if len(cmd) > 0 && cmd[0] == '(' {
+		n, err := scanGroup(cmd, '(', ')')
+		if err != nil {
+			return "", "", "", "", err
+		}
+		filters, cmd = cmd[1:n-1], cmd[n:]
+		if strings.IndexAny(stripQuoted(filters), "{}") >= 0 {
+			return "", "", "", "", fmt.Errorf("mal formated filters : %q", filters)
+		}
+	}
+	if len(cmd) > 0 && cmd[0] == '{' {
+		n, err := scanGroup(cmd, '{', '}')
+		if err != nil {
+			return "", "", "", "", err
+		}
+		retrieve, cmd = cmd[1:n-1], cmd[n:]
+	}
+	if len(cmd) > 0 {
+		return "", "", "", "", fmt.Errorf("mal formated")
+	}
+	return name, index, filters, retrieve, nil
+}
+
+// stripQuoted returns s without its quoted literals. s must have balanced quotes.
+func stripQuoted(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			n, _ := scanQuoted(s[i:])
+			i += n - 1
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseCondition parses a single filter : [any|all] key op value, or the
+// existence filters key? and !key?.
+//
+// The value is either a bare word, a double quoted literal, which may
+// contain any character and the escape sequences of Go strings, such as
+// \", \\, \n or \u0041, or an arithmetic expression, such as
+// price * quantity.
+func parseCondition(cmd string, strict bool) (*Filter, error) {
+	s := strings.TrimSpace(cmd)
+	if key := strings.TrimSuffix(s, "?"); key != s {
+		op := exists
+		if strings.HasPrefix(key, "!") {
+			op, key = notExists, key[1:]
+		}
+		if key = strings.TrimSpace(key); isName(key) {
+			return &Filter{key: key, op: op}, nil
+		}
+	}
+	var quant Quantifier
+	for _, q := range []Quantifier{quantAny, quantAll} {
+		if strings.HasPrefix(s, string(q)) && len(s) > len(q) && (s[len(q)] == ' ' || s[len(q)] == '\t') {
+			quant, s = q, strings.TrimLeft(s[len(q):], " \t")
+			break
+		}
+	}
+
+	i := 0
+	for i < len(s) && isNameChar(s[i]) {
+		i++
+	}
+	key := s[:i]
+	s = strings.TrimLeft(s[i:], " \t\n")
+
+	i = 0
+	for i < len(s) && strings.IndexByte("><!:=", s[i]) >= 0 {
+		i++
+	}
+	opStr := s[:i]
+	s = strings.TrimLeft(s[i:], " \t\n")
+
+	if len(key) > 0 && len(opStr) > 0 && !strings.HasPrefix(s, `"`) {
+		if calc, ok, err := parseArithmetic(s); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			op, err := findOperation(opStr)
+			if err != nil {
+				return nil, err
+			}
+			f := &Filter{key: key, op: op, quant: quant, calc: calc, strict: strict}
+			if v, ok := calc.constant(); ok {
+				f.val, f.calc = v, nil
+			}
+			return f, nil
+		}
+	}
+
+	var raw string
+	if len(s) > 0 && s[0] == '"' {
+		n, err := scanQuoted(s)
+		if err != nil {
+			return nil, err
+		}
+		raw, s = s[:n], s[n:]
+		if _, err := strconv.Unquote(raw); err != nil {
+			return nil, fmt.Errorf("invalid literal %s in filters : %s", raw, err)
+		}
+	} else {
+		i = 0
+		for i < len(s) && strings.IndexByte("&(){}\" \t\n", s[i]) < 0 {
+			i++
+		}
+		raw, s = s[:i], s[i:]
+	}
+	if len(key) == 0 || len(opStr) == 0 || len(raw) == 0 || len(strings.TrimSpace(s)) > 0 {
+		return nil, fmt.Errorf("Format error in filters : %q", cmd)
+	}
+	op, err := findOperation(opStr)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{key: key, op: op, val: typed(raw), quant: quant, strict: strict}, nil
+}
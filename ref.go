@@ -0,0 +1,169 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RefLoader returns the document at uri, for the $ref pointing to other
+// documents, such as "common.json#/definitions/id".
+type RefLoader func(uri string) (*Value, error)
+
+// ResolveRefs replaces, in place, the {"$ref": "#/definitions/x"} objects
+// of v with the value they point to, as found in OpenAPI or JSON Schema
+// documents. The keys next to $ref are dropped.
+//
+// A ref starting with # is a JSON pointer into v. Other refs are of the
+// form uri#pointer, the document at uri being loaded once by load. The
+// refs of a loaded document are resolved against it. A nil load only
+// allows the refs into v.
+//
+// A ref that cannot be resolved, or that points back to a value it is
+// part of, is reported by a *PathError leading to it. RefResolver can
+// leave such cycles unexpanded instead.
+func (v *Value) ResolveRefs(load RefLoader) error {
+	return RefResolver{Load: load}.Resolve(v)
+}
+
+// DefaultMaxRefDepth is the number of refs a RefResolver follows through
+// by default before giving up.
+const DefaultMaxRefDepth = 64
+
+// RefResolver resolves the $ref of documents. See Value.ResolveRefs.
+type RefResolver struct {
+	Load RefLoader
+	// Truncate leaves in place the refs closing a cycle, or going deeper
+	// than MaxDepth, instead of failing. Recursive schemas, such as a tree
+	// node referring to itself, are expanded once.
+	Truncate bool
+	// MaxDepth is the number of nested refs followed while expanding a
+	// value. Zero means DefaultMaxRefDepth.
+	MaxDepth int
+}
+
+// Resolve replaces, in place, the refs of v with the value they point to.
+func (rr RefResolver) Resolve(v *Value) error {
+	r := &refResolver{
+		RefResolver: rr,
+		docs:        map[string]*Value{},
+		active:      map[*Value]bool{},
+		done:        map[*Value]bool{},
+	}
+	if r.MaxDepth == 0 {
+		r.MaxDepth = DefaultMaxRefDepth
+	}
+	return r.resolve(v, v, Path{}, 0)
+}
+
+type refResolver struct {
+	RefResolver
+	docs map[string]*Value
+	// active are the values being expanded, and done the ones expanded.
+	// Expanding an active value means a cycle, and a done one is not
+	// walked again so shared definitions cost once.
+	active map[*Value]bool
+	done   map[*Value]bool
+}
+
+func (r *refResolver) resolve(v, doc *Value, path Path, depth int) error {
+	if r.done[v] {
+		return nil
+	}
+	r.active[v] = true
+	defer delete(r.active, v)
+	switch v.Type() {
+	case TypeObject:
+		if ref := v.o.Get("$ref"); ref != nil && ref.Type() == TypeString {
+			target, targetDoc, err := r.target(ref.s, doc)
+			if err != nil {
+				return &PathError{Path: path, Err: err}
+			}
+			if r.active[target] || depth >= r.MaxDepth {
+				if r.Truncate {
+					return nil
+				}
+				if depth >= r.MaxDepth {
+					return &PathError{Path: path, Err: fmt.Errorf("refs nested deeper than %d", r.MaxDepth)}
+				}
+				return &PathError{Path: path, Err: fmt.Errorf("cyclic ref %q", ref.s)}
+			}
+			if err := r.resolve(target, targetDoc, path, depth+1); err != nil {
+				return err
+			}
+			*v = *target
+			return nil
+		}
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			if err := r.resolve(kv.v, doc, path.child(kv.k), depth); err != nil {
+				return err
+			}
+		}
+	case TypeArray:
+		for i, e := range v.a {
+			if err := r.resolve(e, doc, path.child(strconv.Itoa(i)), depth); err != nil {
+				return err
+			}
+		}
+	}
+	r.done[v] = true
+	return nil
+}
+
+// target returns the value ref points to from doc, and the document
+// holding it.
+func (r *refResolver) target(ref string, doc *Value) (*Value, *Value, error) {
+	uri, pointer := ref, ""
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		uri, pointer = ref[:i], ref[i+1:]
+	}
+	if len(uri) > 0 {
+		loaded, ok := r.docs[uri]
+		if !ok {
+			if r.Load == nil {
+				return nil, nil, fmt.Errorf("cannot load %q: no loader", uri)
+			}
+			var err error
+			if loaded, err = r.Load(uri); err != nil {
+				return nil, nil, fmt.Errorf("cannot load %q: %s", uri, err)
+			}
+			r.docs[uri] = loaded
+		}
+		doc = loaded
+	}
+	target, err := doc.Pointer(pointer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot resolve %q: %s", ref, err)
+	}
+	return target, doc, nil
+}
+
+// Pointer returns the value at the JSON pointer ptr (RFC 6901), such as
+// "/definitions/x" or "/items/0". The empty pointer is v itself.
+func (v *Value) Pointer(ptr string) (*Value, error) {
+	keys, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	target := v.Get(keys...)
+	if target == nil {
+		return nil, fmt.Errorf("nothing at %q", ptr)
+	}
+	return target, nil
+}
+
+// parsePointer returns the keys of the JSON pointer ptr.
+func parsePointer(ptr string) (Path, error) {
+	if ptr == "" {
+		return Path{}, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+	keys := strings.Split(ptr[1:], "/")
+	for i, key := range keys {
+		keys[i] = strings.Replace(strings.Replace(key, "~1", "/", -1), "~0", "~", -1)
+	}
+	return keys, nil
+}
@@ -0,0 +1,124 @@
+package jsonq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValueResolveRefs(t *testing.T) {
+	var p, pc Parser
+	common, err := pc.Parse(`{"definitions": {"id": {"type": "integer"}, "name": {"$ref": "#/definitions/str"}, "str": {"type": "string"}}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	loads := 0
+	load := func(uri string) (*Value, error) {
+		loads++
+		if uri != "common.json" {
+			return nil, fmt.Errorf("not found")
+		}
+		return common, nil
+	}
+	v, err := p.Parse(`{
+		"definitions": {"user": {"properties": {"id": {"$ref": "common.json#/definitions/id"}, "name": {"$ref": "common.json#/definitions/name"}}}, "a/b": {"x": 1}},
+		"items": [{"$ref": "#/definitions/user", "description": "dropped"}, {"$ref": "#/definitions/a~1b"}]
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.ResolveRefs(load); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `[{"properties":{"id":{"type":"integer"},"name":{"type":"string"}}},{"x":1}]`
+	if got := v.Get("items").String(); got != want {
+		t.Errorf("items = %s, want %s", got, want)
+	}
+	if loads != 1 {
+		t.Errorf("loaded %d times, want once", loads)
+	}
+
+	for _, in := range []string{
+		`{"a": [{"$ref": "#/missing"}]}`,
+		`{"a": [{"$ref": "other.json#/x"}]}`,
+		`{"a": [{"$ref": "#missing"}]}`,
+	} {
+		v, err := p.Parse(in)
+		if err != nil {
+			t.Fatalf("cannot parse json: %s", err)
+		}
+		if err := v.ResolveRefs(load); err == nil {
+			t.Errorf("ResolveRefs(%s) expecting non-nil error", in)
+		} else if pe, ok := err.(*PathError); !ok || pe.Path.String() != "a.0" {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	}
+	v, err = p.Parse(`{"$ref": "common.json#/definitions/id"}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.ResolveRefs(nil); err == nil {
+		t.Errorf("expecting non-nil error without loader")
+	}
+}
+
+func TestRefResolverCycles(t *testing.T) {
+	const doc = `{
+		"definitions": {"node": {"properties": {"value": {"type": "integer"}, "next": {"$ref": "#/definitions/node"}}}},
+		"root": {"$ref": "#/definitions/node"}
+	}`
+	var p Parser
+	v, err := p.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := v.ResolveRefs(nil); err == nil {
+		t.Fatalf("expecting non-nil error")
+	} else if pe, ok := err.(*PathError); !ok || pe.Path.String() != "definitions.node.properties.next" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	v, err = p.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := (RefResolver{Truncate: true}).Resolve(v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{"properties":{"value":{"type":"integer"},"next":{"$ref":"#/definitions/node"}}}`
+	if got := v.Get("root").String(); got != want {
+		t.Errorf("root = %s, want %s", got, want)
+	}
+
+	v, err = p.Parse(`{"a": {"$ref": "#/b"}, "b": {"$ref": "#/c"}, "c": {"$ref": "#/d"}, "d": 1}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := (RefResolver{MaxDepth: 2}).Resolve(v); err == nil {
+		t.Errorf("expecting non-nil error")
+	}
+}
+
+func TestRefResolverShared(t *testing.T) {
+	// Every level doubles the references to the next one; expanding each
+	// of them separately would walk 2^40 values.
+	doc := `{"l0": [1, 2]`
+	for i := 1; i <= 40; i++ {
+		doc += fmt.Sprintf(`, "l%d": [{"$ref": "#/l%d"}, {"$ref": "#/l%d"}]`, i, i-1, i-1)
+	}
+	doc += "}"
+	var p Parser
+	v, err := p.Parse(doc)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if err := (RefResolver{MaxDepth: 100}).Resolve(v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	keys := []string{"l40"}
+	for i := 0; i < 41; i++ {
+		keys = append(keys, "1")
+	}
+	if got := v.Get(keys...).String(); got != "2" {
+		t.Errorf("l40.1.1...1 = %s, want 2", got)
+	}
+}
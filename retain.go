@@ -0,0 +1,65 @@
+package jsonq
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Lease keeps the values parsed by a Parser valid past its next Parse,
+// without copying them.
+//
+// A Lease starts with a single reference. Values shared with other
+// goroutines may take more with Retain; each reference is dropped with
+// Release. When the last one is dropped, the memory of the values goes
+// back to the parsers, so they must not be used anymore.
+type Lease struct {
+	refs int32
+	b    []byte
+	vs   []Value
+}
+
+// leased holds the memory of released leases, for the next parsers.
+var leased sync.Pool
+
+// Retain transfers the memory of the values last parsed by p to the
+// returned Lease. p allocates new memory for its next Parse, or reuses the
+// memory of a released lease.
+//
+//	v, err := p.Parse(s)
+//	...
+//	l := p.Retain()
+//	go func() {
+//		defer l.Release()
+//		use(v)
+//	}()
+//	pool.Put(p)
+func (p *Parser) Retain() *Lease {
+	l := &Lease{refs: 1, b: p.b, vs: p.c.vs}
+	p.b, p.c.vs = nil, nil
+	if r, ok := leased.Get().(*Lease); ok {
+		p.b, p.c.vs = r.b[:0], r.vs[:0]
+	}
+	return l
+}
+
+// Retain adds a reference to l.
+func (l *Lease) Retain() {
+	if atomic.AddInt32(&l.refs, 1) <= 1 {
+		panic("BUG: Retain of a released Lease")
+	}
+}
+
+// Release drops a reference to l. The values of l must not be used after
+// their last reference is dropped.
+func (l *Lease) Release() {
+	switch refs := atomic.AddInt32(&l.refs, -1); {
+	case refs == 0:
+		for i := range l.vs {
+			l.vs[i].reset()
+		}
+		leased.Put(&Lease{b: l.b, vs: l.vs})
+		l.b, l.vs = nil, nil
+	case refs < 0:
+		panic("BUG: Release of a released Lease")
+	}
+}
@@ -0,0 +1,44 @@
+package jsonq
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestParserRetain(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"name": "first", "n": [1, 2, 3]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	l := p.Retain()
+	l.Retain()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer l.Release()
+		if got := string(v.GetStringBytes("name")); got != "first" {
+			t.Errorf("name = %q, want first", got)
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		if _, err := p.Parse(`{"name": "second", "n": [4, 5, 6, 7, 8]}`); err != nil {
+			t.Fatalf("cannot parse json: %s", err)
+		}
+	}
+	if got := v.Get("n").String(); got != "[1,2,3]" {
+		t.Errorf("n = %s, want [1,2,3]", got)
+	}
+	wg.Wait()
+	l.Release()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expecting a panic on extra Release")
+		}
+	}()
+	l.Release()
+}
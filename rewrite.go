@@ -0,0 +1,167 @@
+package jsonq
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// RewriteRule rewrites the member or element at Path in Transform.
+type RewriteRule struct {
+	// Path leads to the rewritten member or element. A "*" key matches
+	// any object key or array index.
+	Path Path
+	// Rename, when not empty, is the new key of the member at Path.
+	Rename string
+	// Replace, when not nil, returns the JSON replacing the value at Path
+	// given its original bytes.
+	Replace func(raw []byte) []byte
+}
+
+// Mask returns a Replace function replacing values by the JSON string s,
+// such as "***".
+func Mask(s string) func(raw []byte) []byte {
+	masked := appendQuoted(nil, s)
+	return func(raw []byte) []byte { return masked }
+}
+
+// Transform copies the JSON stream src to dst byte for byte, whitespace
+// included, except for the keys and values rewritten by rules, so proxies
+// can rename a key or mask a field without perturbing the rest of the
+// payload. The stream is processed token by token, so it is never held in
+// memory as a whole.
+//
+// src may hold several whitespace separated JSON values, such as newline
+// delimited JSON; the rules apply to each of them.
+func Transform(dst io.Writer, src io.Reader, rules ...RewriteRule) error {
+	w := bufio.NewWriter(dst)
+	t := NewTokenizer(src)
+	t.keep = true
+	tr := transform{t: t, w: w, rules: rules}
+	if err := tr.run(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// transformFrame is an object or array open in a Transform.
+type transformFrame struct {
+	object bool
+	// key is the current key of an object and index the current index of
+	// an array, -1 before the first element.
+	key   string
+	index int
+}
+
+type transform struct {
+	t      *Tokenizer
+	w      *bufio.Writer
+	rules  []RewriteRule
+	frames []transformFrame
+	path   Path
+}
+
+func (tr *transform) run() error {
+	t := tr.t
+	for {
+		if tr.expectsValue() {
+			if rule := tr.match(true); rule != nil {
+				raw, err := t.rawValue()
+				if err != nil {
+					return err
+				}
+				tr.copyTo(t.start)
+				tr.w.Write(rule.Replace(raw))
+				t.written = t.end
+				continue
+			}
+		}
+		tok, err := t.Next()
+		if err == io.EOF {
+			tr.copyTo(t.pos)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok.Kind {
+		case Key:
+			tr.frames[len(tr.frames)-1].key = tok.Value
+			if rule := tr.match(false); rule != nil {
+				tr.copyTo(t.start)
+				tr.w.Write(appendQuoted(nil, rule.Rename))
+				t.written = t.end
+			}
+		case ObjectStart, ArrayStart:
+			tr.frames = append(tr.frames, transformFrame{object: tok.Kind == ObjectStart, index: -1})
+		case ObjectEnd, ArrayEnd:
+			tr.frames = tr.frames[:len(tr.frames)-1]
+		}
+		tr.copyTo(t.pos)
+	}
+}
+
+// expectsValue reports whether a value comes next, and if so moves the
+// current array, if any, to its next index.
+func (tr *transform) expectsValue() bool {
+	t := tr.t
+	switch t.state {
+	case expectValue:
+		if len(tr.frames) == 0 {
+			return t.More()
+		}
+	case expectFirstValue, expectComma:
+		if t.top() != '[' || !t.More() {
+			return false
+		}
+	default:
+		return false
+	}
+	if f := &tr.frames[len(tr.frames)-1]; !f.object {
+		f.index++
+	}
+	return true
+}
+
+// match returns the first rule replacing the current value, or renaming
+// the current key.
+func (tr *transform) match(replace bool) *RewriteRule {
+	tr.path = tr.path[:0]
+	for _, f := range tr.frames {
+		if f.object {
+			tr.path = append(tr.path, f.key)
+		} else {
+			tr.path = append(tr.path, strconv.Itoa(f.index))
+		}
+	}
+	for i := range tr.rules {
+		rule := &tr.rules[i]
+		if replace && rule.Replace == nil || !replace && rule.Rename == "" {
+			continue
+		}
+		if len(rule.Path) != len(tr.path) {
+			continue
+		}
+		matched := true
+		for j, key := range rule.Path {
+			if key != "*" && key != tr.path[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+// copyTo writes the input bytes not yet written up to offset end of the
+// tokenizer buffer.
+func (tr *transform) copyTo(end int) {
+	t := tr.t
+	if end > t.written {
+		tr.w.Write(t.buf[t.written:end])
+		t.written = end
+	}
+}
@@ -0,0 +1,44 @@
+package jsonq
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTransform(t *testing.T) {
+	f := func(s string, rules []RewriteRule, expected string) {
+		t.Helper()
+		for _, r := range []io.Reader{strings.NewReader(s), iotest.OneByteReader(strings.NewReader(s))} {
+			var out bytes.Buffer
+			if err := Transform(&out, r, rules...); err != nil {
+				t.Fatalf("unexpected error when transforming %q: %s", s, err)
+			}
+			if out.String() != expected {
+				t.Fatalf("unexpected output for %q;\ngot\n%s\nwant\n%s", s, out.String(), expected)
+			}
+		}
+	}
+	doc := "{\n  \"user\" : {\"name\": \"Al\",  \"password\":\"s3cr\\\"et\"},\n  \"items\": [ 1.50, {\"id\":2}, [3] ]\n}\n"
+	f(doc, nil, doc)
+	f(doc, []RewriteRule{{Path: Path{"user", "password"}, Replace: Mask("***")}},
+		"{\n  \"user\" : {\"name\": \"Al\",  \"password\":\"***\"},\n  \"items\": [ 1.50, {\"id\":2}, [3] ]\n}\n")
+	f(doc, []RewriteRule{{Path: Path{"user", "name"}, Rename: "login"}, {Path: Path{"items"}, Rename: "products"}},
+		"{\n  \"user\" : {\"login\": \"Al\",  \"password\":\"s3cr\\\"et\"},\n  \"products\": [ 1.50, {\"id\":2}, [3] ]\n}\n")
+	f(doc, []RewriteRule{{Path: Path{"items", "1"}, Replace: Mask("x")}, {Path: Path{"items", "2", "0"}, Replace: Mask("y")}},
+		"{\n  \"user\" : {\"name\": \"Al\",  \"password\":\"s3cr\\\"et\"},\n  \"items\": [ 1.50, \"x\", [\"y\"] ]\n}\n")
+	f(doc, []RewriteRule{{Path: Path{"*", "id"}, Replace: Mask("no")}, {Path: Path{"items", "*", "id"}, Rename: "ID"}},
+		"{\n  \"user\" : {\"name\": \"Al\",  \"password\":\"s3cr\\\"et\"},\n  \"items\": [ 1.50, {\"ID\":2}, [3] ]\n}\n")
+	f(doc, []RewriteRule{{Path: Path{"user"}, Replace: func(raw []byte) []byte { return []byte(`null`) }}},
+		"{\n  \"user\" : null,\n  \"items\": [ 1.50, {\"id\":2}, [3] ]\n}\n")
+	f(`{"a":1} {"a":2}`, []RewriteRule{{Path: Path{"a"}, Replace: Mask("*")}}, `{"a":"*"} {"a":"*"}`)
+	f(` 7 `, []RewriteRule{{Path: Path{}, Replace: Mask("root")}}, ` "root" `)
+
+	var out bytes.Buffer
+	if err := Transform(&out, strings.NewReader(`{"a": [1, 2}`)); !errors.Is(err, ErrUnexpectedValue) {
+		t.Fatalf("expecting %q for invalid JSON; got %v", ErrUnexpectedValue, err)
+	}
+}
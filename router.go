@@ -0,0 +1,169 @@
+package jsonq
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Destination is a target of a Router.
+type Destination struct {
+	// Name identifies the destination in its dead letters.
+	Name string
+	// Match holds the filters of the events sent to the destination, in
+	// the syntax of NewMatcher. Filters on missing keys are unknown, as
+	// with Options.ThreeValued, so (level = error) only matches the events
+	// with a level. Empty, every event is sent.
+	Match string
+	// Projection, when set, is the query whose Value.Keep result is sent
+	// instead of the event, such as {id,user{email}}. The events its root
+	// filters reject are not sent; as in Match, filters on missing keys are
+	// unknown.
+	Projection string
+	// Deliver sends a payload to the destination, such as an HTTP POST to
+	// a webhook. It may be called from concurrent goroutines.
+	Deliver func(payload []byte) error
+	// Retries is the number of times a failed delivery is retried. The
+	// first retry waits Backoff, and each following one twice as long.
+	Retries int
+	Backoff time.Duration
+}
+
+// DeadLetter is an event a destination failed to receive.
+type DeadLetter struct {
+	Destination string
+	Payload     []byte
+	// Attempts is the number of deliveries tried, and Err the error of the
+	// last one.
+	Attempts int
+	Err      error
+}
+
+type destination struct {
+	Destination
+	matcher    *Matcher
+	projection *Query
+}
+
+// Router dispatches JSON events to the destinations whose filters they
+// pass. Matching uses a Matcher, so the events are only parsed when a
+// matched destination has a projection.
+//
+// Router may be used from concurrent goroutines.
+type Router struct {
+	destinations []*destination
+	deadLetter   func(DeadLetter)
+	pp           ParserPool
+}
+
+// NewRouter compiles the filters and projections of destinations into a
+// Router. The deliveries failing after their retries are passed to
+// deadLetter, which may be nil to drop them.
+func NewRouter(destinations []Destination, deadLetter func(DeadLetter)) (*Router, error) {
+	r := &Router{deadLetter: deadLetter}
+	for _, d := range destinations {
+		if d.Deliver == nil {
+			return nil, fmt.Errorf("destination %q: no Deliver function", d.Name)
+		}
+		dst := &destination{Destination: d}
+		if d.Match != "" {
+			m, err := NewMatcher(d.Match)
+			if err != nil {
+				return nil, fmt.Errorf("destination %q: %w", d.Name, err)
+			}
+			m.SetOptions(Options{ThreeValued: true})
+			dst.matcher = m
+		}
+		if d.Projection != "" {
+			q, err := ParseQuery(d.Projection)
+			if err != nil {
+				return nil, fmt.Errorf("destination %q: %w", d.Name, err)
+			}
+			q.SetOptions(Options{ThreeValued: true})
+			dst.projection = q
+		}
+		r.destinations = append(r.destinations, dst)
+	}
+	return r, nil
+}
+
+// Route sends the JSON event in data to the matching destinations and
+// returns their number. The deliveries run concurrently, and Route
+// returns once they all succeeded or went to the dead letter handler, so
+// data may be reused afterwards.
+//
+// An error is returned when a projection can't parse the event, in which
+// case nothing is sent.
+func (r *Router) Route(data []byte) (int, error) {
+	var matched []*destination
+	projected := false
+	for _, d := range r.destinations {
+		if d.matcher == nil || d.matcher.MatchBytes(data) {
+			matched = append(matched, d)
+			projected = projected || d.projection != nil
+		}
+	}
+	if len(matched) == 0 {
+		return 0, nil
+	}
+
+	payloads := make([][]byte, 0, len(matched))
+	targets := make([]*destination, 0, len(matched))
+	if projected {
+		p := r.pp.Get()
+		defer r.pp.Put(p)
+		v, err := p.ParseBytes(data)
+		if err != nil {
+			return 0, err
+		}
+		for _, d := range matched {
+			if d.projection == nil {
+				payloads, targets = append(payloads, data), append(targets, d)
+				continue
+			}
+			output, err := v.Keep(*d.projection)
+			if err != nil {
+				return 0, fmt.Errorf("destination %q: %w", d.Name, err)
+			}
+			if len(output) > 0 {
+				payloads, targets = append(payloads, []byte(output)), append(targets, d)
+			}
+		}
+	} else {
+		for _, d := range matched {
+			payloads, targets = append(payloads, data), append(targets, d)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, d := range targets {
+		wg.Add(1)
+		go func(d *destination, payload []byte) {
+			defer wg.Done()
+			r.deliver(d, payload)
+		}(d, payloads[i])
+	}
+	wg.Wait()
+	return len(targets), nil
+}
+
+// deliver sends payload to d, retrying on failure, and passes it to the
+// dead letter handler when every attempt failed.
+func (r *Router) deliver(d *destination, payload []byte) {
+	wait := d.Backoff
+	var err error
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		if err = d.Deliver(payload); err == nil {
+			return
+		}
+	}
+	if r.deadLetter != nil {
+		// payload may be the event of the caller, which outlives Route.
+		payload = append([]byte(nil), payload...)
+		r.deadLetter(DeadLetter{Destination: d.Name, Payload: payload, Attempts: d.Retries + 1, Err: err})
+	}
+}
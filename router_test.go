@@ -0,0 +1,93 @@
+package jsonq
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]string{}
+	deliver := func(name string, failures int) func([]byte) error {
+		return func(payload []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], string(payload))
+			if len(got[name]) <= failures {
+				return errors.New("unavailable")
+			}
+			return nil
+		}
+	}
+	var dead []string
+	r, err := NewRouter([]Destination{
+		{Name: "all", Deliver: deliver("all", 0)},
+		{Name: "errors", Match: "level = error", Projection: "{msg}", Deliver: deliver("errors", 0)},
+		{Name: "flaky", Match: "(type = push)", Deliver: deliver("flaky", 1), Retries: 1},
+		{Name: "down", Match: "type = push && id > 1", Deliver: deliver("down", 5), Retries: 2},
+		{Name: "rejected", Projection: "(id > 1){id}", Deliver: deliver("rejected", 0)},
+	}, func(d DeadLetter) {
+		mu.Lock()
+		defer mu.Unlock()
+		dead = append(dead, fmt.Sprintf("%s %s %d %s", d.Destination, d.Payload, d.Attempts, d.Err))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		event string
+		want  int
+	}{
+		{`{"level":"error","msg":"a"}`, 2},
+		{`{"type":"push","id":1}`, 2},
+		{`{"type":"push","id":2}`, 4},
+		{`{"level":"error"}`, 2},
+	}
+	for _, tt := range tests {
+		n, err := r.Route([]byte(tt.event))
+		if err != nil {
+			t.Fatalf("Route(%s) unexpected error: %s", tt.event, err)
+		}
+		if n != tt.want {
+			t.Errorf("Route(%s) = %d destinations, want %d", tt.event, n, tt.want)
+		}
+	}
+
+	want := map[string]string{
+		"all":      `[{"level":"error","msg":"a"} {"type":"push","id":1} {"type":"push","id":2} {"level":"error"}]`,
+		"errors":   `[{"msg":"a"} {}]`,
+		"flaky":    `[{"type":"push","id":1} {"type":"push","id":1} {"type":"push","id":2}]`,
+		"down":     `[{"type":"push","id":2} {"type":"push","id":2} {"type":"push","id":2}]`,
+		"rejected": `[{"id":2}]`,
+	}
+	for name, w := range want {
+		if g := fmt.Sprint(got[name]); g != w {
+			t.Errorf("%s received %s, want %s", name, g, w)
+		}
+	}
+	sort.Strings(dead)
+	if g := fmt.Sprint(dead); g != `[down {"type":"push","id":2} 3 unavailable]` {
+		t.Errorf("unexpected dead letters: %s", g)
+	}
+
+	if _, err := r.Route([]byte(`{"level":"error","msg":`)); err == nil {
+		t.Errorf("expecting an error routing invalid json to a projection")
+	}
+}
+
+func TestNewRouterErrors(t *testing.T) {
+	deliver := func([]byte) error { return nil }
+	for _, d := range []Destination{
+		{Name: "no deliver"},
+		{Name: "bad match", Match: "a ==== 1", Deliver: deliver},
+		{Name: "bad projection", Projection: "{a", Deliver: deliver},
+	} {
+		if _, err := NewRouter([]Destination{d}, nil); err == nil {
+			t.Errorf("NewRouter(%s) expecting an error", d.Name)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// running is a field computed over the elements of an array level up to
+// the current one, in the order they are returned. It is written
+// name: fn(field) in the retrieve block of the level:
+//
+//	transactions{date, amount, running_total: cumsum(amount)}
+//
+// cumsum sums the field, cumavg averages it. Elements whose field is not
+// a number do not contribute.
+type running struct {
+	as    string
+	avg   bool
+	field Path
+}
+
+// parseRunning parses cmd as a running field. It reports false when cmd
+// does not define one, computed fields included.
+func parseRunning(cmd string) (*running, bool, error) {
+	i := strings.IndexByte(cmd, ':')
+	if i < 0 || !isName(strings.TrimSpace(cmd[:i])) {
+		return nil, false, nil
+	}
+	r := &running{as: strings.TrimSpace(cmd[:i])}
+	call := strings.TrimSpace(cmd[i+1:])
+	switch {
+	case strings.HasPrefix(call, "cumsum("):
+		call = call[len("cumsum"):]
+	case strings.HasPrefix(call, "cumavg("):
+		r.avg = true
+		call = call[len("cumavg"):]
+	default:
+		return nil, false, nil
+	}
+	if call[len(call)-1] != ')' {
+		return nil, true, fmt.Errorf("mal formated running field : %q", cmd)
+	}
+	field, err := parseField(strings.TrimSpace(call[1 : len(call)-1]))
+	if err != nil {
+		return nil, true, err
+	}
+	r.field = field
+	return r, true, nil
+}
+
+// apply adds the running field to elements, the JSON objects kept from
+// values.
+func (r *running) apply(elements []string, values []*Value) {
+	sum := 0.0
+	count := 0
+	for i, element := range elements {
+		if v := values[i].Get(r.field...); v != nil && v.Type() == TypeNumber {
+			sum += v.n
+			count++
+		}
+		total := sum
+		if r.avg && count > 0 {
+			total /= float64(count)
+		}
+		elements[i] = addField(element, r.as, strconv.FormatFloat(total, 'g', -1, 64))
+	}
+}
+
+// addField adds a field to the JSON object element. Elements that are not
+// objects are returned unchanged.
+func addField(element, name, value string) string {
+	if len(element) < 2 || element[0] != '{' {
+		return element
+	}
+	sep := ","
+	if element == "{}" {
+		sep = ""
+	}
+	return element[:len(element)-1] + sep + strconv.Quote(name) + ":" + value + "}"
+}
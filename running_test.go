@@ -0,0 +1,54 @@
+package jsonq
+
+import "testing"
+
+func TestKeepRunning(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"transactions": [
+		{"day": 1, "amount": 10},
+		{"day": 2, "amount": -4},
+		{"day": 3},
+		{"day": 4, "amount": 12.5}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			"{transactions{day, total: cumsum(amount)}}",
+			`{"transactions":[{"day":1,"total":10},{"day":2,"total":6},{"day":3,"total":6},{"day":4,"total":18.5}]}`,
+		},
+		{
+			"{transactions(day > 1){avg: cumavg(amount)}}",
+			`{"transactions":[{"avg":-4},{"avg":-4},{"avg":4.25}]}`,
+		},
+		{
+			"{transactions{top(2, by: day desc), day, total: cumsum(amount)}}",
+			`{"transactions":[{"day":4,"total":12.5},{"day":3,"total":12.5}]}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseRunningErrors(t *testing.T) {
+	for _, query := range []string{
+		"{transactions{total: cumprod(amount)}}",
+		"{transactions{total: cumsum(amount}}",
+		"{transactions{total: cumsum(a b)}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
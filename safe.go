@@ -0,0 +1,42 @@
+package jsonq
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// The functions of the package taking untrusted input, such as Parse,
+// ParseQuery, Keep and Retrieve, return errors instead of panicking, and
+// are fuzzed to keep it so. MustParseQuery, which panics by design, is the
+// exception.
+
+// PanicError is a panic recovered by Safe.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("recovered panic: %v", e.Value)
+}
+
+// Safe calls fn and returns its error, or a *PanicError if it panics, as a
+// last line of defense for the code handling untrusted documents and
+// queries:
+//
+//	err := jsonq.Safe(func() error {
+//		q, err := jsonq.ParseQuery(query)
+//		if err != nil {
+//			return err
+//		}
+//		out, err = v.Keep(*q)
+//		return err
+//	})
+func Safe(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
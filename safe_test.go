@@ -0,0 +1,72 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestSafe(t *testing.T) {
+	err := Safe(func() error {
+		MustParseQuery("{a(}")
+		return nil
+	})
+	pe, ok := err.(*PanicError)
+	if !ok || len(pe.Stack) == 0 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if err := Safe(func() error { return nil }); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+const fuzzDocument = `{"users": [{"id": 1, "name": "Al", "tags": ["a", "b"], "address": {"city": "Paris"}, "at": "2024-01-02T03:04:05Z"},
+	{"id": 2, "name": "Bo", "score": 1.5, "address": null}], "meta": {"count": 2}}`
+
+func FuzzParseQuery(f *testing.F) {
+	for _, seed := range []string{
+		"{users(id > 1){name, address{city}}}",
+		`{users(name = "Al" || id = 2){top(1, by: id), tags}}`,
+		"{users{sample(0.5), bucket(id, [0, 1, 2]) as b}}",
+		`{users{x: format("{} {name}", id), d: datetrunc(at, "day"), t: cumsum(score)}}`,
+		"{users{zip(tags, tags) as z{a, b}, pivot(name, id)}, meta{unpivot(k, v)}}",
+		"{users{join(users.id = id) as same{name}, lookup(t, id).x as y}}",
+		"{users(tags :: a){id}}",
+		"{}",
+	} {
+		f.Add(seed)
+	}
+	var p Parser
+	v, err := p.Parse(fuzzDocument)
+	if err != nil {
+		f.Fatalf("cannot parse json: %s", err)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		q, err := ParseQuery(query)
+		if err != nil {
+			return
+		}
+		q.SetOptions(Options{Seed: 1, BestEffort: true})
+		v.Keep(*q)
+		v.Retrieve(*q)
+		v.Check(*q)
+		q.Match(v)
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{fuzzDocument, `[1, "a", true, null, {"b": [{}]}]`, `"é\n"`, `-1.5e-3`, `{"a\"b": 1}`} {
+		f.Add(seed)
+	}
+	q := MustParseQuery("{users(id > 1){name, address{city}}, meta}")
+	f.Fuzz(func(t *testing.T, data string) {
+		var p Parser
+		v, err := p.Parse(data)
+		if err != nil {
+			return
+		}
+		v.Keep(*q)
+		v.AppendCanonical(nil)
+		if _, err := p.ParseFor(q, []byte(data)); err != nil {
+			t.Errorf("ParseFor(%q) error = %s while Parse succeeds", data, err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package jsonq
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sample keeps a uniform random subset of the elements of an array level.
+//
+// It is written in the retrieve block of the level: users{sample(1000), name}
+// keeps 1000 of the matching users, users{sample(0.01), name} keeps about
+// one percent of them. The kept elements stay in document order. Options.Seed
+// makes the choice reproducible.
+type sample struct {
+	ratio float64
+	size  int
+}
+
+func parseSample(cmd string) (*sample, error) {
+	n, err := scanGroup(cmd[len("sample"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	if len("sample")+n != len(cmd) {
+		return nil, fmt.Errorf("mal formated sample : %q", cmd)
+	}
+	arg := strings.TrimSpace(cmd[len("sample(") : len(cmd)-1])
+	if size, err := strconv.Atoi(arg); err == nil && size >= 0 {
+		return &sample{size: size}, nil
+	}
+	ratio, err := strconv.ParseFloat(arg, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("sample expects a count or a ratio between 0 and 1 : %q", cmd)
+	}
+	return &sample{ratio: ratio}, nil
+}
+
+// choose returns the indexes, in increasing order, of the elements kept by
+// s among n.
+func (s *sample) choose(n int, r *rand.Rand) []int {
+	var indexes []int
+	if s.ratio > 0 {
+		for i := 0; i < n; i++ {
+			if r.Float64() < s.ratio {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+	if s.size >= n {
+		indexes = make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	indexes = r.Perm(n)[:s.size]
+	sort.Ints(indexes)
+	return indexes
+}
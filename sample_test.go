@@ -0,0 +1,60 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want *sample
+		err  bool
+	}{
+		{"sample(10)", &sample{size: 10}, false},
+		{"sample( 0.25 )", &sample{ratio: 0.25}, false},
+		{"sample(1.5)", nil, true},
+		{"sample(-3)", nil, true},
+		{"sample(ten)", nil, true},
+		{"sample(3) as x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSample(tt.cmd)
+		if (err != nil) != tt.err {
+			t.Errorf("parseSample(%q) error = %v", tt.cmd, err)
+			continue
+		}
+		if !tt.err && *got != *tt.want {
+			t.Errorf("parseSample(%q) = %+v, want %+v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestKeepSample(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"id": 0}, {"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}, {"id": 5}, {"id": 6}, {"id": 7}]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{users(id > 1){sample(3), id}}")
+	q.SetOptions(Options{Seed: 42})
+	got, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := strings.Count(got, `"id"`); n != 3 {
+		t.Fatalf("Keep() = %s, want 3 users", got)
+	}
+	if strings.Contains(got, `"id":0`) || strings.Contains(got, `"id":1}`) {
+		t.Fatalf("Keep() = %s, sampled a filtered out user", got)
+	}
+	again, _ := v.Keep(*q)
+	if again != got {
+		t.Errorf("Keep() = %s then %s with the same seed", got, again)
+	}
+
+	q = MustParseQuery("{users{sample(1.0), id}}")
+	if got, _ := v.Keep(*q); strings.Count(got, `"id"`) != 8 {
+		t.Errorf("Keep() = %s, want every user", got)
+	}
+}
@@ -0,0 +1,358 @@
+package jsonq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaIssue is a part of a query that does not fit the JSON Schema of
+// the documents it runs on.
+type SchemaIssue struct {
+	// Path is the key of the query at fault, from its root.
+	Path    Path
+	Message string
+}
+
+func (i SchemaIssue) String() string {
+	return i.Path.String() + ": " + i.Message
+}
+
+// CheckSchema reports the keys filtered, selected or ordered by q that the
+// JSON Schema schema does not define, and the filters whose value can never
+// match the type the schema gives to their key. The local refs of schema,
+// such as #/$defs/user, are followed, and the variants of allOf, anyOf and
+// oneOf are merged.
+//
+// Objects declaring no properties, such as free-form maps, accept any key.
+// The joins, lookups and descents of q are not checked.
+func (q *Query) CheckSchema(schema *Value) []SchemaIssue {
+	c := &schemaChecker{root: schema}
+	c.level(q, c.flatten(schema, nil, 0), Path{})
+	return c.issues
+}
+
+// CheckOpenAPI is CheckSchema against the JSON response schema of an
+// operation of the OpenAPI document doc, such as GET /users/{id}. An empty
+// status picks the first 2xx response, or else the default one. Swagger
+// 2.0 documents are supported as well.
+func (q *Query) CheckOpenAPI(doc *Value, method, path, status string) ([]SchemaIssue, error) {
+	c := &schemaChecker{root: doc}
+	op := c.resolve(doc.Get("paths", path, strings.ToLower(method)))
+	if op == nil {
+		return nil, fmt.Errorf("no operation %s %s", strings.ToUpper(method), path)
+	}
+	responses := c.resolve(op.Get("responses"))
+	if responses == nil || responses.Type() != TypeObject {
+		return nil, fmt.Errorf("no responses for %s %s", strings.ToUpper(method), path)
+	}
+	if status == "" {
+		status = defaultStatus(responses.GetObject())
+	}
+	response := c.resolve(responses.Get(status))
+	if response == nil {
+		return nil, fmt.Errorf("no %s response for %s %s", status, strings.ToUpper(method), path)
+	}
+	schema := response.Get("schema")
+	if schema == nil {
+		schema = jsonSchema(response.GetObject("content"))
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("no JSON schema for the %s response of %s %s", status, strings.ToUpper(method), path)
+	}
+	c.level(q, c.flatten(schema, nil, 0), Path{})
+	return c.issues, nil
+}
+
+// defaultStatus returns the first 2xx status of responses, or default.
+func defaultStatus(responses *Object) string {
+	var statuses []string
+	responses.Visit(func(key []byte, v *Value) {
+		if len(key) == 3 && key[0] == '2' {
+			statuses = append(statuses, string(key))
+		}
+	})
+	if len(statuses) == 0 {
+		return "default"
+	}
+	sort.Strings(statuses)
+	return statuses[0]
+}
+
+// jsonSchema returns the schema of the JSON media type of an OpenAPI
+// content, such as application/json or application/problem+json.
+func jsonSchema(content *Object) *Value {
+	if content == nil {
+		return nil
+	}
+	if schema := content.Get("application/json").Get("schema"); schema != nil {
+		return schema
+	}
+	var schema *Value
+	content.Visit(func(key []byte, v *Value) {
+		if schema == nil && strings.HasSuffix(string(key), "json") {
+			schema = v.Get("schema")
+		}
+	})
+	return schema
+}
+
+// schemaChecker checks queries against a schema. Its sets of schemas hold
+// the schemas a value follows at once, nil standing for an unknown one.
+type schemaChecker struct {
+	root   *Value
+	issues []SchemaIssue
+}
+
+func (c *schemaChecker) report(path Path, format string, args ...interface{}) {
+	c.issues = append(c.issues, SchemaIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolve follows the local refs of s. It returns nil for refs it cannot
+// follow.
+func (c *schemaChecker) resolve(s *Value) *Value {
+	for depth := 0; s != nil; depth++ {
+		ref := s.Get("$ref")
+		if ref == nil || ref.Type() != TypeString {
+			return s
+		}
+		target := string(ref.GetStringBytes())
+		if depth == DefaultMaxRefDepth || !strings.HasPrefix(target, "#") {
+			return nil
+		}
+		s, _ = c.root.Pointer(target[1:])
+	}
+	return nil
+}
+
+// flatten adds s and the schemas of its allOf, anyOf and oneOf to set.
+func (c *schemaChecker) flatten(s *Value, set []*Value, depth int) []*Value {
+	if s = c.resolve(s); s == nil || depth == DefaultMaxRefDepth {
+		return append(set, nil)
+	}
+	set = append(set, s)
+	for _, variants := range []string{"allOf", "anyOf", "oneOf"} {
+		for _, variant := range s.GetArray(variants) {
+			set = c.flatten(variant, set, depth+1)
+		}
+	}
+	return set
+}
+
+// schemaTypes returns the JSON Schema types allowed by set, such as object
+// or integer, or nil when they are unknown.
+func schemaTypes(set []*Value) map[string]bool {
+	types := map[string]bool{}
+	for _, s := range set {
+		if s == nil {
+			return nil
+		}
+		t := s.Get("type")
+		switch {
+		case t != nil && t.Type() == TypeString:
+			types[string(t.GetStringBytes())] = true
+		case t != nil && t.Type() == TypeArray:
+			for _, name := range t.a {
+				types[string(name.GetStringBytes())] = true
+			}
+		case s.Exists("properties"):
+			types["object"] = true
+		case s.Exists("items"):
+			types["array"] = true
+		case !s.Exists("allOf") && !s.Exists("anyOf") && !s.Exists("oneOf"):
+			// An untyped schema accepts anything.
+			return nil
+		}
+		if s.GetBool("nullable") {
+			types["null"] = true
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	return types
+}
+
+func typeList(types map[string]bool) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " or ")
+}
+
+// elements returns the schemas of the elements of the arrays following
+// set, through nested arrays, as a level of a query applies to each of
+// them. It returns set for the other values.
+func (c *schemaChecker) elements(set []*Value) []*Value {
+	for depth := 0; depth < DefaultMaxRefDepth; depth++ {
+		types := schemaTypes(set)
+		if types == nil || !types["array"] || types["object"] {
+			return set
+		}
+		var items []*Value
+		for _, s := range set {
+			if s.Exists("items") {
+				items = c.flatten(s.Get("items"), items, 0)
+			}
+		}
+		if len(items) == 0 {
+			return []*Value{nil}
+		}
+		set = items
+	}
+	return []*Value{nil}
+}
+
+// property returns the schemas of the key of the objects following set.
+// ok is false when set defines properties but not this one.
+func (c *schemaChecker) property(set []*Value, key string) (sub []*Value, ok bool) {
+	declared := false
+	var additional []*Value
+	for _, s := range set {
+		if s == nil || s.Exists("patternProperties") {
+			return []*Value{nil}, true
+		}
+		if properties := s.GetObject("properties"); properties != nil {
+			declared = true
+			if p := properties.Get(key); p != nil {
+				sub = c.flatten(p, sub, 0)
+			}
+		}
+		if a := s.Get("additionalProperties"); a != nil && a.Type() == TypeObject {
+			additional = append(additional, a)
+		}
+	}
+	if len(sub) > 0 {
+		return sub, true
+	}
+	for _, a := range additional {
+		sub = c.flatten(a, sub, 0)
+	}
+	if len(sub) > 0 {
+		return sub, true
+	}
+	if !declared {
+		return []*Value{nil}, true
+	}
+	return nil, false
+}
+
+// level checks the level q, applied to the values following set.
+func (c *schemaChecker) level(q *Query, set []*Value, path Path) {
+	set = c.elements(set)
+	for _, f := range q.filters {
+		c.filter(f, set, path)
+		if f.calc != nil {
+			for _, field := range f.calc.fields() {
+				c.field(field, set, path)
+			}
+		}
+	}
+	for _, key := range q.retrieve {
+		if _, ok := c.property(set, key); !ok {
+			c.report(path.child(key), "not in the schema")
+		}
+	}
+	if q.sorting != nil {
+		c.field(q.sorting.field, set, path)
+	}
+	if q.top != nil {
+		c.field(q.top.by.field, set, path)
+	}
+	if q.grouping != nil {
+		for _, field := range q.grouping.fields() {
+			c.field(field, set, path)
+		}
+		c.level(q.grouping.q, set, path)
+	}
+	for _, n := range q.counts {
+		sub, ok := c.property(set, n.name)
+		if !ok {
+			c.report(path.child(n.name), "not in the schema")
+			continue
+		}
+		c.level(n.q, sub, path.child(n.name))
+	}
+	names := make([]string, 0, len(q.next))
+	for name := range q.next {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		next := q.next[name]
+		sub, ok := c.property(set, name)
+		if !ok {
+			c.report(path.child(name), "not in the schema")
+			continue
+		}
+		if next == nil {
+			continue
+		}
+		if types := schemaTypes(sub); next.slice != nil && types != nil && !types["array"] {
+			c.report(path.child(name), "indexed, but the schema gives %s", typeList(types))
+			continue
+		}
+		if types := schemaTypes(c.elements(sub)); types != nil && !types["object"] &&
+			len(next.filters)+len(next.retrieve)+len(next.next) > 0 {
+			c.report(path.child(name), "level on %s", typeList(types))
+			continue
+		}
+		c.level(next, sub, path.child(name))
+	}
+}
+
+// field checks the path of a field of the objects following set.
+func (c *schemaChecker) field(field Path, set []*Value, path Path) {
+	for i, key := range field {
+		sub, ok := c.property(c.elements(set), key)
+		if !ok {
+			c.report(append(append(Path{}, path...), field[:i+1]...), "not in the schema")
+			return
+		}
+		set = sub
+	}
+}
+
+// filter checks that the key of f is defined by set, and that the value of
+// f may match its type.
+func (c *schemaChecker) filter(f *Filter, set []*Value, path Path) {
+	sub, ok := c.property(set, f.key)
+	if !ok {
+		c.report(path.child(f.key), "not in the schema")
+		return
+	}
+	path = path.child(f.key)
+	for _, key := range f.sub {
+		if sub, ok = c.property(c.elements(sub), key); !ok {
+			c.report(path.child(key), "not in the schema")
+			return
+		}
+		path = path.child(key)
+	}
+	var want string
+	switch f.val.(type) {
+	case int64, float64:
+		want = "number"
+	case string:
+		want = "string"
+	case bool:
+		want = "boolean"
+	}
+	if f.calc != nil {
+		want = "number"
+	}
+	switch f.op {
+	case exists, notExists, contain, notContain:
+		return
+	case like, notLike:
+		want = "string"
+	}
+	// A filter on an array applies to its elements.
+	types := schemaTypes(c.elements(sub))
+	if want == "" || types == nil || types[want] || want == "number" && types["integer"] {
+		return
+	}
+	c.report(path, "filter %s compares a %s to %s", f.op, want, typeList(types))
+}
@@ -0,0 +1,162 @@
+package jsonq
+
+import (
+	"strings"
+	"testing"
+)
+
+const petstore = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/pets": {
+			"get": {
+				"responses": {
+					"default": {"$ref": "#/components/responses/Error"},
+					"200": {
+						"description": "pets",
+						"content": {"application/json": {"schema": {
+							"type": "object",
+							"properties": {
+								"total": {"type": "integer"},
+								"pets": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}
+							}
+						}}}
+					}
+				}
+			}
+		},
+		"/pets/{id}": {
+			"get": {
+				"responses": {
+					"200": {"content": {"application/vnd.api+json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}
+				}
+			}
+		}
+	},
+	"components": {
+		"responses": {
+			"Error": {"content": {"application/problem+json": {"schema": {
+				"type": "object",
+				"properties": {"title": {"type": "string"}, "status": {"type": "integer"}}
+			}}}}
+		},
+		"schemas": {
+			"Pet": {
+				"allOf": [
+					{"$ref": "#/components/schemas/Named"},
+					{
+						"type": "object",
+						"properties": {
+							"age": {"type": "number", "nullable": true},
+							"tags": {"type": "array", "items": {"type": "string"}},
+							"owner": {"oneOf": [{"$ref": "#/components/schemas/Named"}, {"type": "string"}]},
+							"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+							"extra": {"type": "object"},
+							"toys": {"type": "array", "items": {"type": "array", "items": {"$ref": "#/components/schemas/Named"}}}
+						}
+					}
+				]
+			},
+			"Named": {"type": "object", "properties": {"name": {"type": "string"}, "id": {"type": "integer"}}}
+		}
+	}
+}`
+
+func TestCheckOpenAPI(t *testing.T) {
+	var p Parser
+	doc, err := p.Parse(petstore)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query  string
+		path   string
+		status string
+		want   []string
+	}{
+		{`{total, pets(age > 2 && name :: "^r"){name, tags, owner{name}, labels{color}, extra{anything}, toys{id}}}`, "/pets", "", nil},
+		{`{pets(tags = cat){id, sort(age desc), top(2, by: owner.name)}}`, "/pets", "", nil},
+		{`{count, pets{nickname, owner{email}}}`, "/pets", "", []string{
+			"count: not in the schema",
+			"pets.nickname: not in the schema",
+			"pets.owner.email: not in the schema",
+		}},
+		{`{pets(age = old && name > 3 && tags :: x && id ? ){name}}`, "/pets", "", []string{
+			"pets.age: filter = compares a string to null or number",
+			"pets.name: filter > compares a number to string",
+		}},
+		{`{pets(id = 1){sort(weight), top(1, by: owner.age)}}`, "/pets", "", []string{
+			"pets.weight: not in the schema",
+			"pets.owner.age: not in the schema",
+		}},
+		{`{pets{name{first}, tags[0], total[0]}}`, "/pets", "", []string{
+			"pets.name: level on string",
+			"pets.total: not in the schema",
+		}},
+		{`{total[0:2]}`, "/pets", "", []string{"total: indexed, but the schema gives integer"}},
+		{`{name, age, breed}`, "/pets/{id}", "", []string{"breed: not in the schema"}},
+		{`{title, detail}`, "/pets", "default", []string{"detail: not in the schema"}},
+		{`{toys{name, color}}`, "/pets/{id}", "200", []string{"toys.color: not in the schema"}},
+	}
+	for _, tt := range tests {
+		issues, err := MustParseQuery(tt.query).CheckOpenAPI(doc, "GET", tt.path, tt.status)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		got := make([]string, len(issues))
+		for i, issue := range issues {
+			got[i] = issue.String()
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("CheckOpenAPI(%s) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, op := range [][3]string{
+		{"POST", "/pets", ""},
+		{"GET", "/owners", ""},
+		{"GET", "/pets", "404"},
+	} {
+		if _, err := MustParseQuery("{total}").CheckOpenAPI(doc, op[0], op[1], op[2]); err == nil {
+			t.Errorf("CheckOpenAPI(%s %s %s) expecting non-nil error", op[0], op[1], op[2])
+		}
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	var p Parser
+	schema, err := p.Parse(`{
+		"$defs": {"item": {"type": "object", "properties": {"sku": {"type": "string"}, "qty": {"type": "integer"}}}},
+		"type": "object",
+		"properties": {"orders": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": ["integer", "null"]},
+					"items": {"type": "array", "items": {"$ref": "#/$defs/item"}},
+					"meta": {"type": "object", "patternProperties": {"^x-": {"type": "string"}}},
+					"ref": {"$ref": "other.json#/defs/x"}
+				}
+			}
+		}}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	issues := MustParseQuery(`{orders(id = "x" && any(items.qty > x) && all(items.size < 3)){items(qty >= 2){sku, price}, meta{x-a}, ref{y}, name}}`).CheckSchema(schema)
+	want := []string{
+		"orders.id: filter = compares a string to integer or null",
+		"orders.items.qty: filter > compares a string to integer",
+		"orders.items.size: not in the schema",
+		"orders.name: not in the schema",
+		"orders.items.price: not in the schema",
+	}
+	got := make([]string, len(issues))
+	for i, issue := range issues {
+		got[i] = issue.String()
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckSchema = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package jsonq
+
+import (
+	"sort"
+)
+
+// BinarySearchArray searches the array v, sorted in ascending order by the
+// key field of its elements, for the element whose field equals target.
+// An empty key searches an array of scalars by the elements themselves.
+//
+// target is a string, a bool, an int, an int64, a float64 or a *Value.
+// Values are ordered as by the top and sort directives: numbers, then
+// strings, then booleans; elements missing the key come last.
+//
+// BinarySearchArray returns the index of the first matching element and
+// the element itself. When no element matches, it returns the index where
+// target would be inserted and nil. It returns -1 and nil when v is not an
+// array or target has an unsupported type.
+func (v *Value) BinarySearchArray(key string, target interface{}) (int, *Value) {
+	if v == nil || v.t != TypeArray {
+		return -1, nil
+	}
+	t := scalarValue(target)
+	if t == nil {
+		return -1, nil
+	}
+	i := sort.Search(len(v.a), func(i int) bool {
+		e := v.a[i]
+		if key != "" {
+			if e = e.Get(key); e == nil {
+				return true
+			}
+		}
+		return compareValues(e, t, nil) >= 0
+	})
+	if i < len(v.a) {
+		e := v.a[i]
+		if key != "" {
+			e = e.Get(key)
+		}
+		if e != nil && typeRank(e) == typeRank(t) && compareValues(e, t, nil) == 0 {
+			return i, v.a[i]
+		}
+	}
+	return i, nil
+}
+
+// scalarValue returns x as a Value, or nil when x has an unsupported type.
+func scalarValue(x interface{}) *Value {
+	switch x := x.(type) {
+	case *Value:
+		return x
+	case string:
+		return &Value{t: TypeString, s: x}
+	case bool:
+		if x {
+			return valueTrue
+		}
+		return valueFalse
+	case int:
+		return &Value{t: TypeNumber, n: float64(x)}
+	case int64:
+		return &Value{t: TypeNumber, n: float64(x)}
+	case float64:
+		return &Value{t: TypeNumber, n: x}
+	}
+	return nil
+}
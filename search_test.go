@@ -0,0 +1,52 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestValueBinarySearchArray(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`[{"t":1,"v":"a"},{"t":3,"v":"b"},{"t":3,"v":"c"},{"t":7,"v":"d"},{"v":"e"}]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	f := func(target interface{}, expectedIndex int, expected string) {
+		t.Helper()
+		i, found := v.BinarySearchArray("t", target)
+		if i != expectedIndex {
+			t.Fatalf("unexpected index for %v; got %d; want %d", target, i, expectedIndex)
+		}
+		if expected == "" {
+			if found != nil {
+				t.Fatalf("unexpected element for %v: %s", target, found)
+			}
+			return
+		}
+		if found == nil || string(found.GetStringBytes("v")) != expected {
+			t.Fatalf("unexpected element for %v; got %s; want v=%q", target, found, expected)
+		}
+	}
+	f(1, 0, "a")
+	f(3, 1, "b")
+	f(int64(7), 3, "d")
+	f(7.0, 3, "d")
+	f(0, 0, "")
+	f(5, 3, "")
+	f(8, 4, "")
+	f("3", 4, "")
+	f([]int{}, -1, "")
+
+	v, err = p.Parse(`["apple","kiwi","pear"]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	if i, found := v.BinarySearchArray("", "kiwi"); i != 1 || found == nil {
+		t.Fatalf("cannot find kiwi; got %d, %s", i, found)
+	}
+	if i, _ := v.BinarySearchArray("", "banana"); i != 1 {
+		t.Fatalf("unexpected insertion index for banana; got %d; want 1", i)
+	}
+	if i, found := v.Get("0").BinarySearchArray("", "apple"); i != -1 || found != nil {
+		t.Fatalf("expecting no result on a non array; got %d, %s", i, found)
+	}
+}
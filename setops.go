@@ -0,0 +1,93 @@
+package jsonq
+
+// Union, Intersect and Except compare the elements of two arrays as sets.
+// With an empty key, elements are identified by their canonical JSON, so
+// {"a":1,"b":2} and {"b":2,"a":1} are the same element. Otherwise the
+// elements are objects identified by their key field, and the elements
+// missing it are never equal to another one.
+//
+// The results keep the first occurrence of every element, in the order of
+// a then b, and share their values with a and b, so they are valid as long
+// as a and b are. A nil array is an empty one.
+
+// Union returns the elements of a or b.
+func Union(a, b *Value, key string) (*Value, error) {
+	return setOperation(a, b, key, func(inA, inB bool) bool { return true })
+}
+
+// Intersect returns the elements of a that are in b.
+func Intersect(a, b *Value, key string) (*Value, error) {
+	return setOperation(a, b, key, func(inA, inB bool) bool { return inA && inB })
+}
+
+// Except returns the elements of a that are not in b, such as the IDs
+// present in a but not in b.
+func Except(a, b *Value, key string) (*Value, error) {
+	return setOperation(a, b, key, func(inA, inB bool) bool { return inA && !inB })
+}
+
+// setOperation returns the elements of a and b, deduplicated, for which
+// keep returns true given whether they are in a and b.
+func setOperation(a, b *Value, key string, keep func(inA, inB bool) bool) (*Value, error) {
+	ea, err := setElements(a)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := setElements(b)
+	if err != nil {
+		return nil, err
+	}
+	ids := func(elements []*Value) (map[string]bool, []string) {
+		set := map[string]bool{}
+		list := make([]string, len(elements))
+		for i, element := range elements {
+			list[i] = setIdentity(element, key)
+			set[list[i]] = true
+		}
+		return set, list
+	}
+	setA, idsA := ids(ea)
+	setB, idsB := ids(eb)
+
+	result := &Value{t: TypeArray, a: []*Value{}}
+	seen := map[string]bool{}
+	add := func(elements []*Value, ids []string, fromA bool) {
+		for i, element := range elements {
+			id := ids[i]
+			switch {
+			case id == "":
+				// Elements without identity only belong to their array.
+				if keep(fromA, !fromA) {
+					result.a = append(result.a, element)
+				}
+			case !seen[id]:
+				seen[id] = true
+				if keep(setA[id], setB[id]) {
+					result.a = append(result.a, element)
+				}
+			}
+		}
+	}
+	add(ea, idsA, true)
+	add(eb, idsB, false)
+	return result, nil
+}
+
+func setElements(v *Value) ([]*Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if v.Type() != TypeArray {
+		return nil, &ErrWrongType{Want: TypeArray, Got: v.Type()}
+	}
+	return v.a, nil
+}
+
+// setIdentity returns the identity of an array element, or an empty string
+// when it has none.
+func setIdentity(element *Value, key string) string {
+	if key == "" {
+		return string(element.AppendCanonical(nil))
+	}
+	return elementKey(element, key)
+}
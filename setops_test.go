@@ -0,0 +1,53 @@
+package jsonq
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetOperations(t *testing.T) {
+	type operation func(a, b *Value, key string) (*Value, error)
+	f := func(op operation, a, b, key, expected string) {
+		t.Helper()
+		var pa, pb Parser
+		va, err := pa.Parse(a)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", a, err)
+		}
+		vb, err := pb.Parse(b)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", b, err)
+		}
+		result, err := op(va, vb, key)
+		if err != nil {
+			t.Fatalf("unexpected error for %s and %s: %s", a, b, err)
+		}
+		if result.String() != expected {
+			t.Fatalf("unexpected result for %s and %s; got %s; want %s", a, b, result, expected)
+		}
+	}
+	f(Union, `[1,2,2,"a"]`, `[3,2,"a"]`, "", `[1,2,"a",3]`)
+	f(Intersect, `[1,2,2,"a"]`, `[3,2,"a"]`, "", `[2,"a"]`)
+	f(Except, `[1,2,2,"a"]`, `[3,2,"a"]`, "", `[1]`)
+	f(Union, `[{"a":1,"b":2}]`, `[{"b":2,"a":1},{"a":2}]`, "", `[{"a":1,"b":2},{"a":2}]`)
+
+	a := `[{"id":1,"v":"a"},{"id":2,"v":"b"},{"v":"c"}]`
+	b := `[{"id":2,"v":"B"},{"id":3,"v":"C"},{"v":"c"}]`
+	f(Union, a, b, "id", `[{"id":1,"v":"a"},{"id":2,"v":"b"},{"v":"c"},{"id":3,"v":"C"},{"v":"c"}]`)
+	f(Intersect, a, b, "id", `[{"id":2,"v":"b"}]`)
+	f(Except, a, b, "id", `[{"id":1,"v":"a"},{"v":"c"}]`)
+
+	var p Parser
+	v, err := p.Parse(`[1,2]`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	result, err := Except(v, nil, "")
+	if err != nil || result.String() != `[1,2]` {
+		t.Fatalf("unexpected result with a nil array; got %s, %v", result, err)
+	}
+	var wrongType *ErrWrongType
+	if _, err := Union(v, v.Get("0"), ""); !errors.As(err, &wrongType) {
+		t.Fatalf("expecting ErrWrongType for a non array; got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package jsonq
+
+import (
+	"strconv"
+)
+
+// SizeBytes estimates the size of the compact JSON serialization of v
+// without serializing it. The estimate is exact for values that were not
+// modified since they were parsed, escapes aside: strings are counted with
+// their escape sequences as found in the input, or with one extra byte per
+// escaped character once decoded.
+//
+// SizeBytes neither decodes the strings nor the numbers of v, so it is
+// cheap enough for quota checks on hot paths.
+func (v *Value) SizeBytes() int {
+	if v == nil {
+		return 0
+	}
+	switch v.t {
+	case TypeObject:
+		n := 2
+		for i, kv := range v.o.kvs {
+			if i > 0 {
+				n++
+			}
+			n += stringSize(kv.k, v.o.keysUnescaped) + 1 + kv.v.SizeBytes()
+		}
+		return n
+	case TypeArray:
+		n := 2
+		for i, e := range v.a {
+			if i > 0 {
+				n++
+			}
+			n += e.SizeBytes()
+		}
+		return n
+	case typeRawString:
+		return len(v.s) + 2
+	case TypeString:
+		return stringSize(v.s, true)
+	case typeRawNumber:
+		return len(v.s)
+	case TypeNumber:
+		if len(v.s) > 0 {
+			return len(v.s)
+		}
+		var buf [32]byte
+		return len(strconv.AppendFloat(buf[:0], v.n, 'g', -1, 64))
+	case TypeTrue:
+		return len("true")
+	case TypeFalse:
+		return len("false")
+	default:
+		return len("null")
+	}
+}
+
+// stringSize returns the size of s once quoted. Decoded strings count one
+// extra byte for every character needing an escape sequence.
+func stringSize(s string, decoded bool) int {
+	n := len(s) + 2
+	if decoded {
+		for i := 0; i < len(s); i++ {
+			if c := s[i]; c < 0x20 || c == '"' || c == '\\' {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// NodeCount returns the number of values in v: v itself plus every object
+// member and array element it contains, at any depth.
+func (v *Value) NodeCount() int {
+	if v == nil {
+		return 0
+	}
+	n := 1
+	switch v.t {
+	case TypeObject:
+		for _, kv := range v.o.kvs {
+			n += kv.v.NodeCount()
+		}
+	case TypeArray:
+		for _, e := range v.a {
+			n += e.NodeCount()
+		}
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestValueSizeBytes(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		expected := len(v.String())
+		if n := v.SizeBytes(); n != expected {
+			t.Fatalf("unexpected size for %q; got %d; want %d", s, n, expected)
+		}
+	}
+	f(`null`)
+	f(`true`)
+	f(`false`)
+	f(`1.5e3`)
+	f(`"foo"`)
+	f(`[]`)
+	f(`{}`)
+	f(`[1, "a", {"b": [true, false, null]}]`)
+	f(`{"users":[{"name":"a","age":20},{"name":"b","tags":["x","y"]}],"total":3}`)
+	f(`{"a\"b":"c\"d\\e\n"}`)
+
+	var nilValue *Value
+	if n := nilValue.SizeBytes(); n != 0 {
+		t.Fatalf("unexpected size for a nil value: %d", n)
+	}
+}
+
+func TestValueNodeCount(t *testing.T) {
+	f := func(s string, expected int) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if n := v.NodeCount(); n != expected {
+			t.Fatalf("unexpected node count for %q; got %d; want %d", s, n, expected)
+		}
+	}
+	f(`1`, 1)
+	f(`[]`, 1)
+	f(`[1,2,3]`, 4)
+	f(`{"a":{"b":[1,{"c":null}]},"d":"e"}`, 7)
+}
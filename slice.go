@@ -0,0 +1,125 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// slice selects elements of the array of a level : items[i] selects an
+// element, and items[start:end] the elements from start up to end
+// excluded. Negative indexes count from the end of the array, and the
+// bounds of a range are optional, as in items[-3:].
+type slice struct {
+	start, end       int
+	hasStart, hasEnd bool
+	// element is true for items[i], which selects the element itself
+	// rather than an array.
+	element bool
+}
+
+func parseSlice(s string) (*slice, error) {
+	bound := func(b string) (int, bool, error) {
+		if b = strings.TrimSpace(b); b == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(b)
+		if err != nil {
+			return 0, false, fmt.Errorf("mal formated index %q", s)
+		}
+		return n, true, nil
+	}
+	sl := &slice{}
+	var err error
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		sl.element = true
+		if sl.start, sl.hasStart, err = bound(s); err != nil {
+			return nil, err
+		}
+		if !sl.hasStart {
+			return nil, fmt.Errorf("mal formated index %q", s)
+		}
+		return sl, nil
+	}
+	if sl.start, sl.hasStart, err = bound(s[:i]); err != nil {
+		return nil, err
+	}
+	if sl.end, sl.hasEnd, err = bound(s[i+1:]); err != nil {
+		return nil, err
+	}
+	return sl, nil
+}
+
+// String returns sl as written in a query, such as [0:5].
+func (sl *slice) String() string {
+	if sl == nil {
+		return ""
+	}
+	if sl.element {
+		return "[" + strconv.Itoa(sl.start) + "]"
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	if sl.hasStart {
+		b.WriteString(strconv.Itoa(sl.start))
+	}
+	b.WriteByte(':')
+	if sl.hasEnd {
+		b.WriteString(strconv.Itoa(sl.end))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// bounds returns the range of the elements selected by sl in an array of
+// n elements, empty when they are out of the array.
+func (sl *slice) bounds(n int) (from, to int) {
+	index := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > n {
+			return n
+		}
+		return i
+	}
+	if sl.element {
+		i := sl.start
+		if i < 0 {
+			i += n
+		}
+		if i < 0 || i >= n {
+			return 0, 0
+		}
+		return i, i + 1
+	}
+	from, to = 0, n
+	if sl.hasStart {
+		from = index(sl.start)
+	}
+	if sl.hasEnd {
+		to = index(sl.end)
+	}
+	if from > to {
+		return from, from
+	}
+	return from, to
+}
+
+// keepSlice applies the level request, which has a slice, to the array a.
+func (request Query) keepSlice(a []*Value, path Path, e *execution) (string, bool, error) {
+	from, to := request.slice.bounds(len(a))
+	if !request.slice.element {
+		value, err := keepArray(request, a[from:to], from, path, e)
+		return value, len(value) > 0, err
+	}
+	if from == to {
+		return "", false, nil
+	}
+	value, err := a[from].keep(request, path.child(strconv.Itoa(from)), e)
+	return value, len(value) > 0, err
+}
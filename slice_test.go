@@ -0,0 +1,54 @@
+package jsonq
+
+import "testing"
+
+func TestKeepSlice(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"items": [{"name": "a", "price": 1}, {"name": "b", "price": 2}, {"name": "c", "price": 3}, {"name": "d", "price": 4}], "tags": ["x", "y"], "id": 7}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{items[0]{name}}", `{"items":{"name":"a"}}`},
+		{"{items[-1]{name}}", `{"items":{"name":"d"}}`},
+		{"{items[1]}", `{"items":{"name":"b","price":2}}`},
+		{"{items[0:2]{name}}", `{"items":[{"name":"a"},{"name":"b"}]}`},
+		{"{items[2:]{name}}", `{"items":[{"name":"c"},{"name":"d"}]}`},
+		{"{items[:-3]{name}}", `{"items":[{"name":"a"}]}`},
+		{"{items[1:3](price > 2){name}}", `{"items":[{"name":"c"}]}`},
+		{"{items[0](price > 2){name}}", `{}`},
+		{"{items[3:1]{name}}", `{"items":[]}`},
+		{"{items[9]{name}, id}", `{"id":7}`},
+		{"{tags[1], id[0]}", `{"tags":"y"}`},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.cmd)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) unexpected error: %s", tt.cmd, err)
+		}
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+
+	q := MustParseQuery("{items[0]{name}, id[0]}")
+	q.SetOptions(Options{Scalars: ScalarError})
+	if _, err := v.Keep(*q); err == nil {
+		t.Errorf("expecting an error indexing a number with ScalarError")
+	}
+}
+
+func TestParseSliceErrors(t *testing.T) {
+	for _, cmd := range []string{"{items[]{name}}", "{items[a]{name}}", "{items[0{name}}", "{items[1:x]}", "{items[0]{a}, items[1]{b}}"} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting an error", cmd)
+		}
+	}
+}
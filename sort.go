@@ -0,0 +1,32 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSort parses the sort directive of an array level, written in its
+// retrieve block: players{sort(score desc), name} keeps the players from
+// the highest score to the lowest. The direction defaults to asc.
+func parseSort(cmd string) (*ordering, error) {
+	n, err := scanGroup(cmd[len("sort"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	if len("sort")+n != len(cmd) {
+		return nil, fmt.Errorf("mal formated sort : %q", cmd)
+	}
+	by := strings.TrimSpace(cmd[len("sort(") : len(cmd)-1])
+	if by == "" {
+		return nil, fmt.Errorf("sort expects an ordering : %q", cmd)
+	}
+	return parseOrdering(by)
+}
+
+// eq reports whether o and other order by the same field and direction.
+func (o *ordering) eq(other *ordering) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.desc == other.desc && o.field.String() == other.field.String()
+}
@@ -0,0 +1,62 @@
+package jsonq
+
+import "testing"
+
+func TestKeepSort(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"players": [
+		{"name": "a", "score": 10, "team": "x"},
+		{"name": "b", "score": 30, "team": "y"},
+		{"name": "c", "team": "x"},
+		{"name": "d", "score": 20, "team": "x"},
+		{"name": "e", "score": 30, "team": "x"}
+	], "team": {"name": "x", "score": 1}}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"{players{sort(score), name}}", `{"players":[{"name":"a"},{"name":"d"},{"name":"b"},{"name":"e"},{"name":"c"}]}`},
+		{"{players{sort(score desc), name}}", `{"players":[{"name":"b"},{"name":"e"},{"name":"d"},{"name":"a"},{"name":"c"}]}`},
+		{"{players{sort(name DESC), name}}", `{"players":[{"name":"e"},{"name":"d"},{"name":"c"},{"name":"b"},{"name":"a"}]}`},
+		{"{players(team = x){sort(score desc), name}}", `{"players":[{"name":"e"},{"name":"d"},{"name":"a"},{"name":"c"}]}`},
+		{"{players[0:3]{sort(score desc), name}}", `{"players":[{"name":"b"},{"name":"a"},{"name":"c"}]}`},
+		{"{players{sort(score asc), top(2, by: name), name}}", `{"players":[{"name":"e"},{"name":"d"}]}`},
+		{"{team{sort(score), name}}", `{"team":{"name":"x"}}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	a := MustParseQuery("{players{sort(score desc), name}}")
+	b := MustParseQuery("{players{name, sort(score DESC)}}")
+	c := MustParseQuery("{players{sort(score), name}}")
+	if !a.eq(*b) {
+		t.Errorf("%s and %s expected to be equal", "sort(score desc)", "sort(score DESC)")
+	}
+	if a.eq(*c) {
+		t.Errorf("%s and %s expected to differ", "sort(score desc)", "sort(score)")
+	}
+
+	for _, query := range []string{
+		"{players{sort()}}",
+		"{players{sort(score up)}}",
+		"{players{sort(score desc name)}}",
+		"{players{sort(score) as best}}",
+		"{**{sort(score), name}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package jsonq
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// In stats mode (Options.Stats), Keep and Retrieve return a summary of
+// every selected field instead of the selected data. The summary is an
+// object keyed by the dot separated path of the fields, array indexes left
+// out:
+//
+//	{"users.age":{"count":3,"nulls":1,"distinct":2,"min":20,"max":31,"mean":25.5}}
+//
+// count is the number of values of the field, nulls included. min and max
+// are those of the numbers of the field, or of its strings when it holds
+// no number. mean is only given for numbers. distinct is an estimate of
+// the number of different values, within a few percents.
+
+// fieldStats summarizes the values of a field.
+type fieldStats struct {
+	count    int
+	nulls    int
+	numbers  int
+	sum      float64
+	min, max float64
+	strings  int
+	minS     string
+	maxS     string
+	distinct hyperLogLog
+	buf      []byte
+}
+
+func (s *fieldStats) add(v *Value) {
+	s.count++
+	switch v.Type() {
+	case TypeNull:
+		s.nulls++
+	case TypeNumber:
+		if s.numbers == 0 || v.n < s.min {
+			s.min = v.n
+		}
+		if s.numbers == 0 || v.n > s.max {
+			s.max = v.n
+		}
+		s.numbers++
+		s.sum += v.n
+	case TypeString:
+		if s.strings == 0 || v.s < s.minS {
+			s.minS = v.s
+		}
+		if s.strings == 0 || v.s > s.maxS {
+			s.maxS = v.s
+		}
+		s.strings++
+	}
+	s.buf = v.AppendCanonical(s.buf[:0])
+	h := fnv.New64a()
+	h.Write(s.buf)
+	s.distinct.add(h.Sum64())
+}
+
+func (s *fieldStats) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"count":`...)
+	dst = strconv.AppendInt(dst, int64(s.count), 10)
+	dst = append(dst, `,"nulls":`...)
+	dst = strconv.AppendInt(dst, int64(s.nulls), 10)
+	dst = append(dst, `,"distinct":`...)
+	dst = strconv.AppendUint(dst, s.distinct.count(), 10)
+	switch {
+	case s.numbers > 0:
+		dst = append(dst, `,"min":`...)
+		dst = strconv.AppendFloat(dst, s.min, 'g', -1, 64)
+		dst = append(dst, `,"max":`...)
+		dst = strconv.AppendFloat(dst, s.max, 'g', -1, 64)
+		dst = append(dst, `,"mean":`...)
+		dst = strconv.AppendFloat(dst, s.sum/float64(s.numbers), 'g', -1, 64)
+	case s.strings > 0:
+		dst = append(dst, `,"min":`...)
+		dst = appendQuoted(dst, s.minS)
+		dst = append(dst, `,"max":`...)
+		dst = appendQuoted(dst, s.maxS)
+	}
+	return append(dst, '}')
+}
+
+// summarize returns the stats of the fields of output, the JSON kept by
+// the request.
+func summarize(request Query, output string) (string, error) {
+	if len(output) == 0 {
+		return "{}", nil
+	}
+	var p Parser
+	v, err := p.Parse(output)
+	if err != nil {
+		return "", err
+	}
+	fields := map[string]*fieldStats{}
+	collectStats(request, v, Path{}, fields)
+
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	dst := []byte{'{'}
+	for i, path := range paths {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendQuoted(dst, path)
+		dst = append(dst, ':')
+		dst = fields[path].appendJSON(dst)
+	}
+	return string(append(dst, '}')), nil
+}
+
+func collectStats(q Query, v *Value, path Path, fields map[string]*fieldStats) {
+	switch v.Type() {
+	case TypeArray:
+		for _, uValue := range v.a {
+			collectStats(q, uValue, path, fields)
+		}
+	case TypeObject:
+		q.eachField(&v.o, func(key string, val *Value) {
+			field(fields, path.child(key)).add(val)
+		})
+		for name, next := range q.next {
+			if val := v.o.Get(name); val != nil && next != nil {
+				collectStats(*next, val, path.child(name), fields)
+			}
+		}
+		for _, j := range q.joins {
+			if val := v.o.Get(j.as); val != nil {
+				collectStats(*j.q, val, path.child(j.as), fields)
+			}
+		}
+		for _, l := range q.lookups {
+			if val := v.o.Get(l.as); val != nil {
+				field(fields, path.child(l.as)).add(val)
+			}
+		}
+	default:
+		field(fields, path).add(v)
+	}
+}
+
+func field(fields map[string]*fieldStats, path Path) *fieldStats {
+	key := path.String()
+	s := fields[key]
+	if s == nil {
+		s = &fieldStats{}
+		fields[key] = s
+	}
+	return s
+}
+
+const hllBits = 12
+
+// hyperLogLog estimates the number of distinct hashes added to it.
+type hyperLogLog struct {
+	registers [1 << hllBits]uint8
+}
+
+func (h *hyperLogLog) add(x uint64) {
+	// fnv spreads short inputs poorly over the high bits; mix them.
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	index := x >> (64 - hllBits)
+	rank := uint8(bits.LeadingZeros64(x<<hllBits|1<<(hllBits-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+func (h *hyperLogLog) count() uint64 {
+	const m = float64(1 << hllBits)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,45 @@
+package jsonq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeepStats(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [
+		{"name": "Bob", "age": 31, "tags": ["a"], "address": {"city": "Paris"}},
+		{"name": "Al", "age": 20, "address": {"city": "Paris"}},
+		{"name": "Cy", "age": null, "address": {"city": "Lyon"}},
+		{"name": "Al", "age": 20.0}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	q := MustParseQuery("{users{name, age, address{city}}}")
+	q.SetOptions(Options{Stats: true})
+	got, err := v.Keep(*q)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{` +
+		`"users.address.city":{"count":3,"nulls":0,"distinct":2,"min":"Lyon","max":"Paris"},` +
+		`"users.age":{"count":4,"nulls":1,"distinct":3,"min":20,"max":31,"mean":23.666666666666668},` +
+		`"users.name":{"count":4,"nulls":0,"distinct":3,"min":"Al","max":"Cy"}}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		s := &fieldStats{}
+		for i := 0; i < n; i++ {
+			s.add(&Value{t: TypeString, s: fmt.Sprint(i)})
+		}
+		got := float64(s.distinct.count())
+		if got < float64(n)*0.95 || got > float64(n)*1.05 {
+			t.Errorf("count() = %v, want about %d", got, n)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package jsonq
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Backpressure tells what an Engine does when a subscription can't keep
+// up with the published documents and its buffer is full.
+type Backpressure int
+
+const (
+	// Block makes Publish wait until the subscription has room, or is
+	// removed from the engine, which drops the result.
+	Block Backpressure = iota
+	// DropNewest discards the result that doesn't fit.
+	DropNewest
+	// DropOldest discards the oldest buffered result to make room.
+	DropOldest
+)
+
+// SubscriptionStats are the counters of a subscription.
+type SubscriptionStats struct {
+	// Evaluated is the number of documents the query ran on.
+	Evaluated uint64
+	// Matched is the number of documents accepted by the query.
+	Matched uint64
+	// Delivered is the number of results passed to the handler.
+	Delivered uint64
+	// Dropped is the number of results discarded by the backpressure policy.
+	Dropped uint64
+	// Errors is the number of documents on which the query failed.
+	Errors uint64
+}
+
+// Subscription is a query registered on an Engine.
+type Subscription struct {
+	// The counters come first so they are 64-bit aligned for atomic
+	// operations on 32-bit platforms.
+	stats SubscriptionStats
+
+	q       *Query
+	handler func(output string)
+	policy  Backpressure
+	results chan string
+	done    chan struct{}
+
+	// mu guards the closing of results against the pushes in progress,
+	// which quit interrupts.
+	mu     sync.RWMutex
+	closed bool
+	quit   chan struct{}
+}
+
+// Stats returns a snapshot of the counters of s.
+func (s *Subscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Evaluated: atomic.LoadUint64(&s.stats.Evaluated),
+		Matched:   atomic.LoadUint64(&s.stats.Matched),
+		Delivered: atomic.LoadUint64(&s.stats.Delivered),
+		Dropped:   atomic.LoadUint64(&s.stats.Dropped),
+		Errors:    atomic.LoadUint64(&s.stats.Errors),
+	}
+}
+
+func (s *Subscription) run() {
+	for output := range s.results {
+		s.handler(output)
+		atomic.AddUint64(&s.stats.Delivered, 1)
+	}
+	close(s.done)
+}
+
+func (s *Subscription) push(output string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.results <- output:
+		default:
+			atomic.AddUint64(&s.stats.Dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.results <- output:
+				return
+			default:
+			}
+			select {
+			case <-s.results:
+				atomic.AddUint64(&s.stats.Dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.results <- output:
+		case <-s.quit:
+			atomic.AddUint64(&s.stats.Dropped, 1)
+		}
+	}
+}
+
+// stop interrupts the pushes blocked on s, closes its buffer and waits for
+// the buffered results to be handled.
+func (s *Subscription) stop() {
+	close(s.quit)
+	s.mu.Lock()
+	s.closed = true
+	close(s.results)
+	s.mu.Unlock()
+	<-s.done
+}
+
+// Engine runs registered queries on a stream of documents and passes
+// the projected results of matching documents to their handlers.
+//
+// Every subscription has its own goroutine and buffer, so a slow handler
+// only delays its own results, within the limits of its backpressure
+// policy.
+//
+// Engine may be used from concurrent goroutines.
+type Engine struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	pp     ParserPool
+	closed bool
+}
+
+// NewEngine returns an Engine without subscriptions.
+func NewEngine() *Engine {
+	return &Engine{
+		subs: map[*Subscription]struct{}{},
+	}
+}
+
+// Subscribe registers q on e. handler is called with the result of
+// Value.Keep for every published document accepted by q, in publication
+// order. Up to bufferSize results wait for the handler before the
+// backpressure policy applies.
+func (e *Engine) Subscribe(q *Query, handler func(output string), bufferSize int, policy Backpressure) (*Subscription, error) {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	s := &Subscription{
+		q:       q,
+		handler: handler,
+		policy:  policy,
+		results: make(chan string, bufferSize),
+		done:    make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, fmt.Errorf("engine is closed")
+	}
+	e.subs[s] = struct{}{}
+	go s.run()
+	return s, nil
+}
+
+// Unsubscribe removes s from e. It waits for the buffered results of s
+// to be handled.
+func (e *Engine) Unsubscribe(s *Subscription) {
+	e.mu.Lock()
+	_, ok := e.subs[s]
+	delete(e.subs, s)
+	e.mu.Unlock()
+	if ok {
+		s.stop()
+	}
+}
+
+// Publish runs every subscription on the JSON document in data.
+func (e *Engine) Publish(data []byte) error {
+	p := e.pp.Get()
+	defer e.pp.Put(p)
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	// The subscriptions are pushed to without the lock, so that a blocked
+	// push doesn't hold up Unsubscribe, Close and the other publications.
+	e.mu.RLock()
+	if e.closed {
+		e.mu.RUnlock()
+		return fmt.Errorf("engine is closed")
+	}
+	subs := make([]*Subscription, 0, len(e.subs))
+	for s := range e.subs {
+		subs = append(subs, s)
+	}
+	e.mu.RUnlock()
+	for _, s := range subs {
+		atomic.AddUint64(&s.stats.Evaluated, 1)
+		if v.Type() == TypeObject && !s.q.accept(&v.o) {
+			continue
+		}
+		output, err := v.Keep(*s.q)
+		if err != nil {
+			atomic.AddUint64(&s.stats.Errors, 1)
+			continue
+		}
+		if len(output) == 0 {
+			continue
+		}
+		atomic.AddUint64(&s.stats.Matched, 1)
+		s.push(output)
+	}
+	return nil
+}
+
+// Close removes every subscription from e, waiting for their buffered
+// results to be handled. e can't be used afterwards.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	e.closed = true
+	subs := e.subs
+	e.subs = nil
+	e.mu.Unlock()
+	for s := range subs {
+		s.stop()
+	}
+}
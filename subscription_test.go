@@ -0,0 +1,149 @@
+package jsonq
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngine(t *testing.T) {
+	e := NewEngine()
+	q := func(cmd string) *Query {
+		q := MustParseQuery(cmd)
+		q.SetOptions(Options{ThreeValued: true})
+		return q
+	}
+
+	var mu sync.Mutex
+	var errors, pushes []string
+	collect := func(dst *[]string) func(string) {
+		return func(output string) {
+			mu.Lock()
+			*dst = append(*dst, output)
+			mu.Unlock()
+		}
+	}
+	se, err := e.Subscribe(q("(level = error){msg}"), collect(&errors), 10, Block)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sp, err := e.Subscribe(q("(type = push){id}"), collect(&pushes), 10, Block)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	docs := []string{
+		`{"level": "error", "msg": "a"}`,
+		`{"type": "push", "id": 1}`,
+		`{"level": "info", "msg": "b"}`,
+		`{"level": "error", "msg": "c", "type": "push", "id": 2}`,
+	}
+	for _, doc := range docs {
+		if err := e.Publish([]byte(doc)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := e.Publish([]byte(`{"level": `)); err == nil {
+		t.Fatalf("expecting non-nil error on invalid json")
+	}
+	e.Close()
+
+	if got := fmt.Sprint(errors); got != `[{"msg":"a"} {"msg":"c"}]` {
+		t.Errorf("unexpected error results: %s", got)
+	}
+	if got := fmt.Sprint(pushes); got != `[{"id":1} {"id":2}]` {
+		t.Errorf("unexpected push results: %s", got)
+	}
+	want := SubscriptionStats{Evaluated: 4, Matched: 2, Delivered: 2}
+	if got := se.Stats(); got != want {
+		t.Errorf("unexpected stats: %+v, want %+v", got, want)
+	}
+	if got := sp.Stats(); got != want {
+		t.Errorf("unexpected stats: %+v, want %+v", got, want)
+	}
+	if _, err := e.Subscribe(MustParseQuery("{id}"), func(string) {}, 1, Block); err == nil {
+		t.Errorf("expecting non-nil error on closed engine")
+	}
+}
+
+func TestEngineBackpressure(t *testing.T) {
+	for _, policy := range []Backpressure{DropNewest, DropOldest} {
+		e := NewEngine()
+		release := make(chan struct{})
+		var got []string
+		s, err := e.Subscribe(MustParseQuery("{id}"), func(output string) {
+			<-release
+			got = append(got, output)
+		}, 1, policy)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for i := 0; i < 10; i++ {
+			if err := e.Publish([]byte(fmt.Sprintf(`{"id": %d}`, i))); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		close(release)
+		e.Unsubscribe(s)
+
+		stats := s.Stats()
+		if stats.Matched != 10 || stats.Delivered+stats.Dropped != 10 || stats.Dropped == 0 {
+			t.Errorf("policy %d: unexpected stats %+v", policy, stats)
+		}
+		if policy == DropOldest && got[len(got)-1] != `{"id":9}` {
+			t.Errorf("policy %d: the last result was dropped: %v", policy, got)
+		}
+		if policy == DropNewest && got[0] != `{"id":0}` {
+			t.Errorf("policy %d: the first result was dropped: %v", policy, got)
+		}
+	}
+}
+
+func TestEngineCloseBlocked(t *testing.T) {
+	e := NewEngine()
+	release := make(chan struct{})
+	s, err := e.Subscribe(MustParseQuery("{id}"), func(string) { <-release }, 1, Block)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The handler holds the first result and the buffer the second, so
+	// the third publication blocks.
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 3; i++ {
+			e.Publish([]byte(fmt.Sprintf(`{"id": %d}`, i)))
+		}
+	}()
+	for deadline := time.Now().Add(time.Second); s.Stats().Matched < 3; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the publications didn't reach the subscription: %+v", s.Stats())
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		e.Close()
+		close(closed)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("Close didn't interrupt the blocked publication")
+	}
+	if err := e.Publish([]byte(`{"id": 3}`)); err == nil {
+		t.Errorf("expecting non-nil error on closed engine")
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Close didn't return once the handler was released")
+	}
+	if got, want := s.Stats(), (SubscriptionStats{Evaluated: 3, Matched: 3, Delivered: 2, Dropped: 1}); got != want {
+		t.Errorf("unexpected stats: %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,519 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+// The kinds of tokens emitted by a Tokenizer.
+const (
+	ObjectStart TokenKind = iota + 1
+	ObjectEnd
+	ArrayStart
+	ArrayEnd
+	Key
+	String
+	Number
+	True
+	False
+	Null
+)
+
+// String returns a string representation of k.
+func (k TokenKind) String() string {
+	switch k {
+	case ObjectStart:
+		return "ObjectStart"
+	case ObjectEnd:
+		return "ObjectEnd"
+	case ArrayStart:
+		return "ArrayStart"
+	case ArrayEnd:
+		return "ArrayEnd"
+	case Key:
+		return "Key"
+	case String:
+		return "String"
+	case Number:
+		return "Number"
+	case True:
+		return "True"
+	case False:
+		return "False"
+	case Null:
+		return "Null"
+	default:
+		return fmt.Sprintf("TokenKind(%d)", int(k))
+	}
+}
+
+// Token is an event emitted by a Tokenizer.
+type Token struct {
+	Kind TokenKind
+	// Value is the unescaped string of Key and String tokens, and the
+	// original text of Number tokens.
+	Value string
+}
+
+// The states of a Tokenizer, i.e. what it expects next.
+const (
+	expectValue = iota
+	expectFirstValue
+	expectKey
+	expectFirstKey
+	expectComma
+)
+
+// Tokenizer reads JSON from an io.Reader and emits its tokens one by one,
+// so single-pass extractors can process documents too large even for the
+// lazy DOM, holding only the current token in memory.
+//
+// A stream may hold several whitespace separated JSON values, such as
+// newline delimited JSON.
+type Tokenizer struct {
+	r   io.Reader
+	err error
+
+	buf []byte
+	pos int
+	// base is the offset of buf in the stream.
+	base int
+
+	// start and end delimit the bytes of the last token in buf.
+	start, end int
+	// When keep is set, the bytes from written on are kept in buf when
+	// more input is read, so Transform can copy them to its output.
+	keep    bool
+	written int
+
+	// containers holds the '{' and '[' of the open objects and arrays.
+	containers []byte
+	state      int
+}
+
+// NewTokenizer returns a Tokenizer reading from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{r: r, buf: make([]byte, 0, 4096)}
+}
+
+// Depth returns the number of objects and arrays open at the current
+// token.
+func (t *Tokenizer) Depth() int {
+	return len(t.containers)
+}
+
+// Next returns the next token. It returns io.EOF at the end of the stream,
+// once the last value is complete.
+func (t *Tokenizer) Next() (Token, error) {
+	tok, err := t.next()
+	if err == nil && tok.Kind != Key {
+		t.end = t.pos
+	}
+	return tok, err
+}
+
+func (t *Tokenizer) next() (Token, error) {
+	c, err := t.peek()
+	if err != nil {
+		if err == io.EOF && (len(t.containers) > 0 || t.state != expectValue) {
+			return Token{}, fmt.Errorf("%w of stream", ErrUnexpectedEnd)
+		}
+		return Token{}, err
+	}
+	t.start = t.pos
+
+	switch t.state {
+	case expectComma:
+		if c == ',' {
+			t.pos++
+			if t.top() == '{' {
+				t.state = expectKey
+			} else {
+				t.state = expectValue
+			}
+			return t.next()
+		}
+		if c != '}' && c != ']' {
+			return Token{}, fmt.Errorf("%w after %s value", ErrMissingComma, t.containerName())
+		}
+		return t.close(c)
+	case expectFirstKey, expectKey:
+		if c == '}' && t.state == expectFirstKey {
+			return t.close(c)
+		}
+		if c != '"' {
+			return Token{}, fmt.Errorf("%w: object key starting with %q", ErrUnexpectedValue, c)
+		}
+		key, err := t.readString()
+		if err != nil {
+			return Token{}, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		t.end = t.pos
+		if c, err = t.peek(); err != nil || c != ':' {
+			return Token{}, ErrMissingColon
+		}
+		t.pos++
+		t.state = expectValue
+		return Token{Kind: Key, Value: key}, nil
+	case expectFirstValue:
+		if c == ']' {
+			return t.close(c)
+		}
+	}
+
+	switch c {
+	case '{':
+		t.pos++
+		t.containers = append(t.containers, '{')
+		t.state = expectFirstKey
+		return Token{Kind: ObjectStart}, nil
+	case '[':
+		t.pos++
+		t.containers = append(t.containers, '[')
+		t.state = expectFirstValue
+		return Token{Kind: ArrayStart}, nil
+	case '"':
+		s, err := t.readString()
+		if err != nil {
+			return Token{}, fmt.Errorf("cannot parse string: %w", err)
+		}
+		t.afterValue()
+		return Token{Kind: String, Value: s}, nil
+	case 't':
+		return t.literal("true", True)
+	case 'f':
+		return t.literal("false", False)
+	case 'n':
+		return t.literal("null", Null)
+	}
+	if c == '-' || (c >= '0' && c <= '9') {
+		n, err := t.readNumber()
+		if err != nil {
+			return Token{}, fmt.Errorf("cannot parse number: %w", err)
+		}
+		t.afterValue()
+		return Token{Kind: Number, Value: n}, nil
+	}
+	return Token{}, fmt.Errorf("%w: %q", ErrUnexpectedValue, c)
+}
+
+// Offset returns the offset in the stream of the first byte of the last
+// token returned by Next.
+func (t *Tokenizer) Offset() int {
+	return t.base + t.start
+}
+
+// More reports whether the current array or object has another element,
+// or, outside of any, whether the stream has another value.
+func (t *Tokenizer) More() bool {
+	c, err := t.peek()
+	return err == nil && c != ']' && c != '}'
+}
+
+// Value reads the next value as a whole and returns it parsed with p,
+// then streaming resumes after it. Value is called where a value is
+// expected: at the start of the stream, after a Key token, or between
+// array elements, so only the interesting sub-trees of a giant document
+// are materialized:
+//
+//	for t.More() {
+//		tok, err := t.Next() // Key
+//		...
+//		if tok.Value == "payload" {
+//			v, err := t.Value(&p)
+//			...
+//		}
+//	}
+//
+// The returned value is valid until Parse is called on p.
+func (t *Tokenizer) Value(p *Parser) (*Value, error) {
+	raw, err := t.rawValue()
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(string(raw))
+}
+
+// rawValue reads the next value as a whole and returns its bytes, which
+// are valid until more input is read.
+func (t *Tokenizer) rawValue() ([]byte, error) {
+	c, err := t.peek()
+	if err == io.EOF && len(t.containers) > 0 {
+		return nil, fmt.Errorf("%w of stream", ErrUnexpectedEnd)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch t.state {
+	case expectKey, expectFirstKey:
+		return nil, fmt.Errorf("%w: expecting an object key", ErrUnexpectedValue)
+	case expectFirstValue:
+		if c == ']' {
+			return nil, fmt.Errorf("%w: no value left in array", ErrUnexpectedValue)
+		}
+	case expectComma:
+		if c != ',' {
+			return nil, fmt.Errorf("%w: no value left in %s", ErrUnexpectedValue, t.containerName())
+		}
+		t.pos++
+		t.state = expectValue
+	}
+	n, err := t.valueLen()
+	if err != nil {
+		return nil, err
+	}
+	t.start = t.pos
+	t.pos += n
+	t.end = t.pos
+	t.afterValue()
+	return t.buf[t.start:t.end], nil
+}
+
+// valueLen skips whitespace and returns the length of the value at the
+// current position, reading more input as needed. It only matches brackets
+// and strings: the value itself is checked when parsed.
+func (t *Tokenizer) valueLen() (int, error) {
+	if _, err := t.peek(); err != nil {
+		return 0, ErrUnexpectedEnd
+	}
+	switch c := t.buf[t.pos]; {
+	case c == '"':
+		return t.stringLen(t.pos)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return t.numberLen(t.pos)
+	case c != '{' && c != '[':
+		n := 0
+		for {
+			if t.pos+n == len(t.buf) {
+				if err := t.more(); err != nil {
+					return n, nil
+				}
+			}
+			if c := t.buf[t.pos+n]; c < 'a' || c > 'z' {
+				return n, nil
+			}
+			n++
+		}
+	}
+	n, depth := 0, 0
+	for {
+		if t.pos+n == len(t.buf) {
+			if err := t.more(); err != nil {
+				return 0, ErrUnexpectedEnd
+			}
+		}
+		switch t.buf[t.pos+n] {
+		case '"':
+			l, err := t.stringLen(t.pos + n)
+			if err != nil {
+				return 0, err
+			}
+			n += l
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+		n++
+		if depth == 0 {
+			return n, nil
+		}
+	}
+}
+
+// close closes the current container with c.
+func (t *Tokenizer) close(c byte) (Token, error) {
+	open := t.top()
+	if (open == '{') != (c == '}') {
+		return Token{}, fmt.Errorf("%w: %q closing %s", ErrUnexpectedValue, c, t.containerName())
+	}
+	t.pos++
+	t.containers = t.containers[:len(t.containers)-1]
+	t.afterValue()
+	if c == '}' {
+		return Token{Kind: ObjectEnd}, nil
+	}
+	return Token{Kind: ArrayEnd}, nil
+}
+
+func (t *Tokenizer) afterValue() {
+	if len(t.containers) == 0 {
+		t.state = expectValue
+	} else {
+		t.state = expectComma
+	}
+}
+
+func (t *Tokenizer) top() byte {
+	if len(t.containers) == 0 {
+		return 0
+	}
+	return t.containers[len(t.containers)-1]
+}
+
+func (t *Tokenizer) containerName() string {
+	if t.top() == '{' {
+		return "object"
+	}
+	return "array"
+}
+
+func (t *Tokenizer) literal(s string, kind TokenKind) (Token, error) {
+	for len(t.buf)-t.pos < len(s) {
+		if err := t.more(); err != nil {
+			break
+		}
+	}
+	if !bytes.HasPrefix(t.buf[t.pos:], []byte(s)) {
+		end := t.pos + len(s)
+		if end > len(t.buf) {
+			end = len(t.buf)
+		}
+		return Token{}, fmt.Errorf("%w: %q", ErrUnexpectedValue, t.buf[t.pos:end])
+	}
+	t.pos += len(s)
+	t.afterValue()
+	return Token{Kind: kind}, nil
+}
+
+// readString reads the string starting at the current '"' and returns it
+// unescaped.
+func (t *Tokenizer) readString() (string, error) {
+	n, err := t.stringLen(t.pos)
+	if err != nil {
+		return "", err
+	}
+	s := string(t.buf[t.pos+1 : t.pos+n-1])
+	t.pos += n
+	return unescapeStringBestEffort(s), nil
+}
+
+// stringLen returns the length of the string starting with the '"' at
+// offset i of the buffer, quotes included, reading more input as needed.
+func (t *Tokenizer) stringLen(i int) (int, error) {
+	start := i - t.pos
+	for j := i + 1; ; j++ {
+		for j >= len(t.buf) {
+			if err := t.more(); err != nil {
+				return 0, ErrUnexpectedEnd
+			}
+			// more may move the buffer contents.
+			j = t.pos + start + (j - i)
+			i = t.pos + start
+		}
+		switch t.buf[j] {
+		case '\\':
+			j++
+			if j >= len(t.buf) {
+				if err := t.more(); err != nil {
+					return 0, ErrUnexpectedEnd
+				}
+				j = t.pos + start + (j - i)
+				i = t.pos + start
+			}
+		case '"':
+			return j - i + 1, nil
+		}
+	}
+}
+
+// readNumber reads the number at the current position and returns its
+// text.
+func (t *Tokenizer) readNumber() (string, error) {
+	n, err := t.numberLen(t.pos)
+	if err != nil {
+		return "", err
+	}
+	s := string(t.buf[t.pos : t.pos+n])
+	t.pos += n
+	return s, nil
+}
+
+// numberLen returns the length of the number starting at offset i of the
+// buffer, reading more input as needed.
+func (t *Tokenizer) numberLen(i int) (int, error) {
+	n := 0
+	for {
+		for i+n >= len(t.buf) {
+			start := i - t.pos
+			err := t.more()
+			i = t.pos + start
+			if err != nil {
+				if n == 0 || t.buf[i+n-1] == '-' || t.buf[i+n-1] == '.' {
+					return 0, fmt.Errorf("%w: %q", ErrUnexpectedValue, t.buf[i:i+n])
+				}
+				return n, nil
+			}
+		}
+		c := t.buf[i+n]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			if n == 0 || t.buf[i+n-1] == '-' || t.buf[i+n-1] == '.' {
+				return 0, fmt.Errorf("%w: %q", ErrUnexpectedValue, t.buf[i:i+n+1])
+			}
+			return n, nil
+		}
+		n++
+	}
+}
+
+// peek skips whitespace and returns the next byte, without consuming it.
+func (t *Tokenizer) peek() (byte, error) {
+	for {
+		for t.pos < len(t.buf) {
+			switch c := t.buf[t.pos]; c {
+			case ' ', '\t', '\n', '\r':
+				t.pos++
+			default:
+				return c, nil
+			}
+		}
+		if err := t.more(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// more reads more input into the buffer, dropping the consumed bytes. It
+// may move the unconsumed bytes to the start of the buffer.
+func (t *Tokenizer) more() error {
+	if t.err != nil {
+		return t.err
+	}
+	drop := t.pos
+	if t.keep {
+		drop = t.written
+	}
+	if drop > 0 {
+		n := copy(t.buf, t.buf[drop:])
+		t.buf = t.buf[:n]
+		t.base += drop
+		t.pos -= drop
+		t.start -= drop
+		t.end -= drop
+		t.written -= drop
+	}
+	if len(t.buf) == cap(t.buf) {
+		buf := make([]byte, len(t.buf), 2*cap(t.buf))
+		copy(buf, t.buf)
+		t.buf = buf
+	}
+	for {
+		n, err := t.r.Read(t.buf[len(t.buf):cap(t.buf)])
+		t.buf = t.buf[:len(t.buf)+n]
+		if err != nil {
+			t.err = err
+			if n > 0 {
+				return nil
+			}
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package jsonq
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func tokenize(r io.Reader) (string, error) {
+	var sb strings.Builder
+	t := NewTokenizer(r)
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.Kind.String())
+		if tok.Value != "" {
+			sb.WriteString("(" + tok.Value + ")")
+		}
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	f := func(s, expected string) {
+		t.Helper()
+		for _, r := range []io.Reader{strings.NewReader(s), iotest.OneByteReader(strings.NewReader(s))} {
+			tokens, err := tokenize(r)
+			if err != nil {
+				t.Fatalf("unexpected error when tokenizing %q: %s", s, err)
+			}
+			if tokens != expected {
+				t.Fatalf("unexpected tokens for %q; got %s; want %s", s, tokens, expected)
+			}
+		}
+	}
+	f(``, ``)
+	f(`1`, `Number(1)`)
+	f(` -12.5e+3 `, `Number(-12.5e+3)`)
+	f(`"a\"bé"`, `String(a"bé)`)
+	f(`true false null`, `True False Null`)
+	f(`[]`, `ArrayStart ArrayEnd`)
+	f(`{}`, `ObjectStart ObjectEnd`)
+	f(`{"a": [1, {"b": null}, "c"], "d\n": true}`,
+		`ObjectStart Key(a) ArrayStart Number(1) ObjectStart Key(b) Null ObjectEnd String(c) ArrayEnd Key(d
+) True ObjectEnd`)
+	f("{\"a\":1}\n{\"a\":2}\n", `ObjectStart Key(a) Number(1) ObjectEnd ObjectStart Key(a) Number(2) ObjectEnd`)
+	f(`"`+strings.Repeat("x", 10000)+`"`, `String(`+strings.Repeat("x", 10000)+`)`)
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	f := func(s string, expected error) {
+		t.Helper()
+		for _, r := range []io.Reader{strings.NewReader(s), iotest.OneByteReader(strings.NewReader(s))} {
+			_, err := tokenize(r)
+			if !errors.Is(err, expected) {
+				t.Fatalf("expecting %q when tokenizing %q; got %v", expected, s, err)
+			}
+		}
+	}
+	f(`[1`, ErrUnexpectedEnd)
+	f(`{"a":1`, ErrUnexpectedEnd)
+	f(`"abc`, ErrUnexpectedEnd)
+	f(`{"a":`, ErrUnexpectedEnd)
+	f(`[1 2]`, ErrMissingComma)
+	f(`{"a" 1}`, ErrMissingColon)
+	f(`{"a":1,}`, ErrUnexpectedValue)
+	f(`[1,]`, ErrUnexpectedValue)
+	f(`[1}`, ErrUnexpectedValue)
+	f(`{1:2}`, ErrUnexpectedValue)
+	f(`tru`, ErrUnexpectedValue)
+	f(`nul]`, ErrUnexpectedValue)
+	f(`-`, ErrUnexpectedValue)
+	f(`]`, ErrUnexpectedValue)
+
+	readErr := errors.New("read error")
+	if _, err := tokenize(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(`[1,2]`)))); err == nil {
+		t.Fatalf("expecting the reader error to be returned")
+	}
+	if _, err := tokenize(iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Fatalf("expecting %q; got %v", readErr, err)
+	}
+}
+
+func TestTokenizerValue(t *testing.T) {
+	s := `{"meta": {"page": 1}, "items": [{"id": 1, "tags": ["a]", "{"]}, 2, "three", true, null, -4.5], "after": "x"}`
+	for _, r := range []io.Reader{strings.NewReader(s), iotest.OneByteReader(strings.NewReader(s))} {
+		tz := NewTokenizer(r)
+		var p Parser
+		// The values are only valid until the next call to Value, so
+		// they are copied.
+		var items strings.Builder
+		var after string
+		if tok, err := tz.Next(); err != nil || tok.Kind != ObjectStart {
+			t.Fatalf("unexpected first token %v: %v", tok, err)
+		}
+		for tz.More() {
+			key, err := tz.Next()
+			if err != nil {
+				t.Fatalf("cannot read key: %s", err)
+			}
+			switch key.Value {
+			case "items":
+				if tok, err := tz.Next(); err != nil || tok.Kind != ArrayStart {
+					t.Fatalf("unexpected token %v: %v", tok, err)
+				}
+				for tz.More() {
+					v, err := tz.Value(&p)
+					if err != nil {
+						t.Fatalf("cannot materialize item: %s", err)
+					}
+					if items.Len() > 0 {
+						items.WriteByte('|')
+					}
+					items.WriteString(v.String())
+				}
+				if tok, err := tz.Next(); err != nil || tok.Kind != ArrayEnd {
+					t.Fatalf("unexpected token %v: %v", tok, err)
+				}
+			case "after":
+				tok, err := tz.Next()
+				if err != nil {
+					t.Fatalf("cannot read after: %s", err)
+				}
+				after = tok.Value
+			default:
+				if _, err := tz.Value(&p); err != nil {
+					t.Fatalf("cannot skip %q: %s", key.Value, err)
+				}
+			}
+		}
+		if tok, err := tz.Next(); err != nil || tok.Kind != ObjectEnd {
+			t.Fatalf("unexpected last token %v: %v", tok, err)
+		}
+		if _, err := tz.Next(); err != io.EOF {
+			t.Fatalf("expecting io.EOF; got %v", err)
+		}
+		expected := `{"id":1,"tags":["a]","{"]}|2|"three"|true|null|-4.5`
+		if got := items.String(); got != expected {
+			t.Fatalf("unexpected items; got %s; want %s", got, expected)
+		}
+		if after != "x" {
+			t.Fatalf("unexpected after; got %q; want %q", after, "x")
+		}
+	}
+
+	f := func(s string, expected error) {
+		t.Helper()
+		tz := NewTokenizer(strings.NewReader(s))
+		if _, err := tz.Next(); err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		var p Parser
+		if _, err := tz.Value(&p); !errors.Is(err, expected) {
+			t.Fatalf("expecting %q for %q; got %v", expected, s, err)
+		}
+	}
+	f(`{"a":1}`, ErrUnexpectedValue)
+	f(`[]`, ErrUnexpectedValue)
+	f(`[{"a":`, ErrUnexpectedEnd)
+
+	tz := NewTokenizer(strings.NewReader(`[{"a":1,}]`))
+	if _, err := tz.Next(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var p Parser
+	if _, err := tz.Value(&p); err == nil {
+		t.Fatalf("expecting error for an invalid value")
+	}
+}
@@ -0,0 +1,51 @@
+package jsonq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// top keeps the n best elements of an array level, written in its retrieve
+// block: players{top(3, by: score desc), name} keeps the three players
+// with the highest score, best first. The direction defaults to desc.
+type top struct {
+	n  int
+	by *ordering
+}
+
+func parseTop(cmd string) (*top, error) {
+	n, err := scanGroup(cmd[len("top"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	if len("top")+n != len(cmd) {
+		return nil, fmt.Errorf("mal formated top : %q", cmd)
+	}
+	args := splitComa(cmd[len("top(") : len(cmd)-1])
+	if len(args) != 2 || !strings.HasPrefix(args[1], "by:") {
+		return nil, fmt.Errorf("top expects a count and an ordering : %q", cmd)
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("top expects a count and an ordering : %q", cmd)
+	}
+	by := strings.TrimSpace(args[1][len("by:"):])
+	if len(strings.Fields(by)) == 1 {
+		by += " desc"
+	}
+	o, err := parseOrdering(by)
+	if err != nil {
+		return nil, err
+	}
+	return &top{n: count, by: o}, nil
+}
+
+// choose returns the indexes of the elements kept by t, best first.
+func (t *top) choose(elements []*Value, c Collator) []int {
+	indexes := t.by.sort(elements, c)
+	if len(indexes) > t.n {
+		indexes = indexes[:t.n]
+	}
+	return indexes
+}
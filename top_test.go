@@ -0,0 +1,49 @@
+package jsonq
+
+import "testing"
+
+func TestKeepTop(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"players": [
+		{"name": "a", "score": 10, "team": "x"},
+		{"name": "b", "score": 30, "team": "y"},
+		{"name": "c", "team": "x"},
+		{"name": "d", "score": 20, "team": "x"},
+		{"name": "e", "score": 30, "team": "x"}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"{players{top(3, by: score desc), name}}", `{"players":[{"name":"b"},{"name":"e"},{"name":"d"}]}`},
+		{"{players{top(2, by: score), name}}", `{"players":[{"name":"b"},{"name":"e"}]}`},
+		{"{players{top(2, by: score asc), name}}", `{"players":[{"name":"a"},{"name":"d"}]}`},
+		{"{players(team = x){top(10, by: score asc), name}}", `{"players":[{"name":"a"},{"name":"d"},{"name":"e"},{"name":"c"}]}`},
+	}
+	for _, tt := range tests {
+		got, err := v.Keep(*MustParseQuery(tt.query))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseTopErrors(t *testing.T) {
+	for _, query := range []string{
+		"{players{top(3)}}",
+		"{players{top(x, by: score)}}",
+		"{players{top(3, score)}}",
+		"{players{top(3, by: score up)}}",
+		"{players{top(3, by: score) as best}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package jsonq
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// ellipsis marks the places where Truncate cut data.
+const ellipsis = "…"
+
+// Truncate returns a shortened copy of v suitable for logging huge
+// payloads:
+//
+//   - strings longer than maxStringLen bytes are cut, on a character
+//     boundary, and end with "…";
+//   - arrays longer than maxArrayLen keep their first maxArrayLen elements,
+//     followed by a "… N more" string;
+//   - objects and arrays nested deeper than maxDepth levels are replaced by
+//     the "{…}" and "[…]" strings.
+//
+// A limit lower than or equal to 0 disables the corresponding truncation.
+//
+// v is not modified, but the result shares its untouched scalars, so it is
+// valid as long as v is.
+func (v *Value) Truncate(maxStringLen, maxArrayLen, maxDepth int) *Value {
+	if v == nil {
+		return nil
+	}
+	return v.truncate(maxStringLen, maxArrayLen, maxDepth, 0)
+}
+
+func (v *Value) truncate(maxStringLen, maxArrayLen, maxDepth, depth int) *Value {
+	switch v.Type() {
+	case TypeObject:
+		if maxDepth > 0 && depth >= maxDepth {
+			return &Value{t: TypeString, s: "{" + ellipsis + "}"}
+		}
+		v.o.unescapeKeys()
+		t := &Value{t: TypeObject}
+		t.o.keysUnescaped = true
+		t.o.kvs = make([]kv, len(v.o.kvs))
+		for i, kv := range v.o.kvs {
+			t.o.kvs[i].k = kv.k
+			t.o.kvs[i].v = kv.v.truncate(maxStringLen, maxArrayLen, maxDepth, depth+1)
+		}
+		return t
+	case TypeArray:
+		if maxDepth > 0 && depth >= maxDepth {
+			return &Value{t: TypeString, s: "[" + ellipsis + "]"}
+		}
+		n := len(v.a)
+		if maxArrayLen > 0 && n > maxArrayLen {
+			n = maxArrayLen
+		}
+		t := &Value{t: TypeArray, a: make([]*Value, n, n+1)}
+		for i := range t.a {
+			t.a[i] = v.a[i].truncate(maxStringLen, maxArrayLen, maxDepth, depth+1)
+		}
+		if n < len(v.a) {
+			more := ellipsis + " " + strconv.Itoa(len(v.a)-n) + " more"
+			t.a = append(t.a, &Value{t: TypeString, s: more})
+		}
+		return t
+	case TypeString:
+		if maxStringLen <= 0 || len(v.s) <= maxStringLen {
+			return v
+		}
+		n := maxStringLen
+		for n > 0 && !utf8.RuneStart(v.s[n]) {
+			n--
+		}
+		return &Value{t: TypeString, s: v.s[:n] + ellipsis}
+	}
+	return v
+}
@@ -0,0 +1,30 @@
+package jsonq
+
+import (
+	"testing"
+)
+
+func TestValueTruncate(t *testing.T) {
+	f := func(s string, maxStringLen, maxArrayLen, maxDepth int, expected string) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		truncated := v.Truncate(maxStringLen, maxArrayLen, maxDepth)
+		if truncated.String() != expected {
+			t.Fatalf("unexpected truncation of %q; got %s; want %s", s, truncated, expected)
+		}
+		if v.String() != s {
+			t.Fatalf("the truncated value was modified; got %s; want %s", v, s)
+		}
+	}
+	f(`{"a":"abcdef","b":[1,2,3,4],"c":{"d":{"e":1}}}`, 0, 0, 0, `{"a":"abcdef","b":[1,2,3,4],"c":{"d":{"e":1}}}`)
+	f(`{"a":"abcdef","b":[1,2,3,4]}`, 3, 0, 0, `{"a":"abc…","b":[1,2,3,4]}`)
+	f(`"héllo"`, 2, 0, 0, `"h…"`)
+	f(`[1,2,3,4]`, 0, 2, 0, `[1,2,"… 2 more"]`)
+	f(`[1,2]`, 0, 2, 0, `[1,2]`)
+	f(`{"c":{"d":{"e":1}},"f":[[]],"g":1}`, 0, 0, 2, `{"c":{"d":"{…}"},"f":["[…]"],"g":1}`)
+	f(`[{"a":1}]`, 0, 0, 1, `["{…}"]`)
+}
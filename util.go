@@ -9,11 +9,11 @@ func b2s(b []byte) string {
 	return *(*string)(unsafe.Pointer(&b))
 }
 
-func s2b(s string) []byte {
+func s2b(s string) (b []byte) {
 	strh := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	var sh reflect.SliceHeader
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
 	sh.Data = strh.Data
 	sh.Len = strh.Len
 	sh.Cap = strh.Len
-	return *(*[]byte)(unsafe.Pointer(&sh))
+	return b
 }
@@ -0,0 +1,114 @@
+package jsonq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseWildcard parses the attributes of a retrieve block selecting all
+// the keys of a level : the wildcard *, and the exclusions !key that leave
+// keys out of it, as in {users{*, !password}}. ok is false for the other
+// attributes.
+func (q *Query) parseWildcard(attr string) (ok bool, err error) {
+	switch {
+	case attr == "*":
+		q.all = true
+	case strings.HasPrefix(attr, "!"):
+		key := strings.TrimSpace(attr[1:])
+		if !isName(key) {
+			return true, fmt.Errorf("mal formated exclusion : %q", attr)
+		}
+		if !q.excludes(key) {
+			q.excluded = append(q.excluded, key)
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// excludes reports whether q leaves the key out of its wildcard.
+func (q *Query) excludes(key string) bool {
+	for _, excluded := range q.excluded {
+		if excluded == key {
+			return true
+		}
+	}
+	return false
+}
+
+// produces reports whether the key is written by a sub level, a join, a
+// lookup, a computed field, a count, a zip or the descent of q, rather than
+// retrieved whole.
+func (q *Query) produces(key string) bool {
+	if _, ok := q.next[key]; ok {
+		return true
+	}
+	if q.descent != nil && q.descent.collects(key) {
+		return true
+	}
+	for _, j := range q.joins {
+		if j.as == key {
+			return true
+		}
+	}
+	for _, l := range q.lookups {
+		if l.as == key {
+			return true
+		}
+	}
+	for _, c := range q.computed {
+		if c.as == key {
+			return true
+		}
+	}
+	for _, z := range q.zips {
+		if z.as == key {
+			return true
+		}
+	}
+	for _, c := range q.counts {
+		if c.as == key {
+			return true
+		}
+	}
+	return false
+}
+
+// eachField calls f for the fields of o retrieved whole by the level : the
+// keys of its retrieve block, in their order, or with the wildcard, the
+// keys of o in document order, but the excluded ones and those produced by
+// the level. A key listed explicitly is retrieved even if it is excluded.
+func (q *Query) eachField(o *Object, f func(key string, v *Value)) {
+	if !q.all {
+		for _, retrieve := range q.retrieve {
+			if v := o.Get(retrieve); v != nil {
+				f(retrieve, v)
+			}
+		}
+		return
+	}
+	o.Visit(func(key []byte, v *Value) {
+		k := string(key)
+		if (!q.excludes(k) || q.retrieves(k)) && !q.produces(k) {
+			f(k, v)
+		}
+	})
+}
+
+// mergeWildcard adds the wildcard of other to q. A key excluded by one of
+// them is only left out if the other does not select it.
+func (q *Query) mergeWildcard(other *Query) {
+	switch {
+	case q.all && other.all:
+		var excluded []string
+		for _, key := range q.excluded {
+			if other.excludes(key) {
+				excluded = append(excluded, key)
+			}
+		}
+		q.excluded = excluded
+	case other.all:
+		q.all, q.excluded = true, other.excluded
+	}
+}
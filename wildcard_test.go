@@ -0,0 +1,45 @@
+package jsonq
+
+import "testing"
+
+func TestKeepWildcard(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"users": [{"name": "Al", "password": "x", "address": {"city": "Lyon", "zip": "69000"}}, {"name": "Bo", "age": 3}], "total": 2}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"{*}", `{"users":[{"name":"Al","password":"x","address":{"city":"Lyon","zip":"69000"}},{"name":"Bo","age":3}],"total":2}`},
+		{"{users{*, !password}}", `{"users":[{"name":"Al","address":{"city":"Lyon","zip":"69000"}},{"name":"Bo","age":3}]}`},
+		{"{*, users{*, !password, !address}, !total}", `{"users":[{"name":"Al"},{"name":"Bo","age":3}]}`},
+		{"{users{*, address{city}}}", `{"users":[{"name":"Al","password":"x","address":{"city":"Lyon"}},{"name":"Bo","age":3}]}`},
+		{"{users(name = Bo){*}}", `{"users":[{"name":"Bo","age":3}]}`},
+		{"{users{*, !password}, users{password}}", `{"users":[{"name":"Al","password":"x","address":{"city":"Lyon","zip":"69000"}},{"name":"Bo","age":3}]}`},
+		{"{users{*, !password}, users{*, !age}}", `{"users":[{"name":"Al","password":"x","address":{"city":"Lyon","zip":"69000"}},{"name":"Bo","age":3}]}`},
+		{"{users{*, !password, !age}, users{*, !age}}", `{"users":[{"name":"Al","password":"x","address":{"city":"Lyon","zip":"69000"}},{"name":"Bo"}]}`},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.cmd)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) unexpected error: %s", tt.cmd, err)
+		}
+		got, err := v.Keep(*q)
+		if err != nil {
+			t.Fatalf("Keep(%q) unexpected error: %s", tt.cmd, err)
+		}
+		if got != tt.want {
+			t.Errorf("Keep(%q) = %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestParseWildcardErrors(t *testing.T) {
+	for _, cmd := range []string{"{users{name, !password}}", "{users{*, !}}", "{*, !a b}"} {
+		if _, err := ParseQuery(cmd); err == nil {
+			t.Errorf("ParseQuery(%q) expecting an error", cmd)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jsonq
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// zip combines parallel arrays of an object into an array of objects. It
+// is written in a retrieve block with the arrays, the name of the result
+// and the names of the fields of its elements, in the order of the arrays:
+//
+//	{products{zip(names, prices) as items{name, price}}}
+//
+// turns {"names":["a","b"],"prices":[1,2]} into
+// {"items":[{"name":"a","price":1},{"name":"b","price":2}]}. The arrays
+// must have the same length. Nothing is written when one is missing.
+type zip struct {
+	arrays []Path
+	as     string
+	fields []string
+}
+
+func parseZip(cmd string) (*zip, error) {
+	n, err := scanGroup(cmd[len("zip"):], '(', ')')
+	if err != nil {
+		return nil, err
+	}
+	z := &zip{}
+	for _, arg := range splitComa(cmd[len("zip(") : len("zip")+n-1]) {
+		array, err := parseField(arg)
+		if err != nil {
+			return nil, err
+		}
+		z.arrays = append(z.arrays, array)
+	}
+	rest := strings.TrimSpace(cmd[len("zip")+n:])
+	i := strings.IndexByte(rest, '{')
+	if !strings.HasPrefix(rest, "as ") || i < 0 || rest[len(rest)-1] != '}' {
+		return nil, fmt.Errorf("zip expects as name{fields} : %q", cmd)
+	}
+	z.as = strings.TrimSpace(rest[len("as "):i])
+	z.fields = splitComa(rest[i+1 : len(rest)-1])
+	if !isName(z.as) {
+		return nil, fmt.Errorf("mal formated zip name : %q", z.as)
+	}
+	for _, field := range z.fields {
+		if !isName(field) {
+			return nil, fmt.Errorf("mal formated zip field : %q", field)
+		}
+	}
+	if len(z.arrays) == 0 || len(z.arrays) != len(z.fields) {
+		return nil, fmt.Errorf("zip expects a field per array : %q", cmd)
+	}
+	return z, nil
+}
+
+// keep returns the JSON array zipped from o, found at path in the
+// document. ok is false when nothing must be written.
+func (z *zip) keep(o *Object, path Path, e *execution) (value string, ok bool, err error) {
+	arrays := make([][]*Value, len(z.arrays))
+	for i, array := range z.arrays {
+		v := o.Get(array[0]).Get(array[1:]...)
+		if v == nil {
+			return "", false, nil
+		}
+		if v.Type() != TypeArray {
+			return "", false, e.fail(path.child(z.as), fmt.Errorf("cannot zip %q: not an array", array.String()))
+		}
+		arrays[i] = v.a
+		if len(arrays[i]) != len(arrays[0]) {
+			return "", false, e.fail(path.child(z.as), fmt.Errorf("cannot zip arrays of different lengths"))
+		}
+	}
+	w := bytes.Buffer{}
+	w.WriteRune('[')
+	for index := range arrays[0] {
+		if index > 0 {
+			w.WriteRune(',')
+		}
+		w.WriteRune('{')
+		for i, field := range z.fields {
+			writeField(&w, i == 0, field, arrays[i][index].raw())
+		}
+		w.WriteRune('}')
+	}
+	w.WriteRune(']')
+	return w.String(), true, nil
+}
@@ -0,0 +1,45 @@
+package jsonq
+
+import "testing"
+
+func TestKeepZip(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"products": [
+		{"id": 1, "names": ["a", "b"], "prices": [1, 2.5]},
+		{"id": 2, "names": ["c"]},
+		{"id": 3, "names": [], "prices": []}
+	]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	got, err := v.Keep(*MustParseQuery("{products{id, zip(names, prices) as items{name, price}}}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	const want = `{"products":[{"id":1,"items":[{"name":"a","price":1},{"name":"b","price":2.5}]},{"id":2},{"id":3,"items":[]}]}`
+	if got != want {
+		t.Errorf("Keep() = %s, want %s", got, want)
+	}
+
+	v, err = p.Parse(`{"names": ["a", "b"], "prices": [1]}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	_, err = v.Keep(*MustParseQuery("{zip(names, prices) as items{name, price}}"))
+	if pe, ok := err.(*PathError); !ok || pe.Path.String() != "items" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestParseZipErrors(t *testing.T) {
+	for _, query := range []string{
+		"{zip(names, prices)}",
+		"{zip(names, prices) as items{name}}",
+		"{zip(names, prices) items{name, price}}",
+		"{zip(names, prices) as it ems{name, price}}",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%s) expecting non-nil error", query)
+		}
+	}
+}